@@ -0,0 +1,189 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/base_images"
+	"github.com/werf/werf/v2/pkg/config"
+	"github.com/werf/werf/v2/pkg/docker_registry"
+	"github.com/werf/werf/v2/pkg/tmp_manager"
+	"github.com/werf/werf/v2/pkg/true_git"
+)
+
+var (
+	commonCmdData common.CmdData
+	all           bool
+)
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:                   "update [REFERENCE...]",
+		DisableFlagsInUseLine: true,
+		Short:                 fmt.Sprintf("Create or update base images lock file (%s).", base_images.DefaultLockFileName),
+		Long: fmt.Sprintf(`Create or update base images lock file (%s).
+
+The base images of the stapel images and the FROM instructions of the Dockerfiles are resolved to their digests in the container registry. The lock file should be committed: when it exists, werf builds the images from the locked digests and giterminism forbids the base images missing in the lock file.
+
+By default, only the base images missing in the lock file are resolved and the unused ones are removed. All base images are resolved again if --all is specified, the specified REFERENCEs are resolved again otherwise.`, base_images.DefaultLockFileName),
+		Example: `  # Lock the new base images
+  $ werf base-images update
+
+  # Update the digest of a certain base image
+  $ werf base-images update alpine:3.20
+
+  # Update the digests of all base images
+  $ werf base-images update --all`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if len(args) > 0 && all {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("REFERENCE arguments cannot be used with --all")
+			}
+
+			_, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
+				Cmd:                &commonCmdData,
+				InitWerf:           true,
+				InitGitDataManager: true,
+				InitTrueGitWithOptions: &common.InitTrueGitOptions{
+					Options: true_git.Options{LiveGitOutput: *commonCmdData.LogDebug},
+				},
+				InitDockerRegistry: true,
+			})
+			if err != nil {
+				return fmt.Errorf("component init error: %w", err)
+			}
+
+			defer func() {
+				if err := tmp_manager.DelegateCleanup(ctx); err != nil {
+					logboek.Context(ctx).Warn().LogF("Temporary files cleanup preparation failed: %s\n", err)
+				}
+			}()
+
+			return runUpdate(ctx, args)
+		},
+	})
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigRenderPath(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupGiterminismConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read base images from the container registry")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupContainerRegistryMirror(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	commonCmdData.SetupDebugTemplates(cmd)
+	commonCmdData.SetupAllowIncludesUpdate(cmd)
+
+	cmd.Flags().BoolVarP(&all, "all", "", false, "Resolve the digests of all base images again")
+
+	return cmd
+}
+
+func runUpdate(ctx context.Context, references []string) error {
+	giterminismManager, err := common.GetGiterminismManager(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	customWerfConfigRelPath, err := common.GetCustomWerfConfigRelPath(giterminismManager, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	customWerfConfigTemplatesDirRelPath, err := common.GetCustomWerfConfigTemplatesDirRelPath(giterminismManager, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	customWerfConfigRenderPath, err := common.GetCustomWerfConfigRenderPath(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	_, werfConfig, err := config.GetWerfConfig(ctx, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath, customWerfConfigRenderPath, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+	if err != nil {
+		return err
+	}
+
+	usedReferences, err := base_images.CollectReferences(ctx, werfConfig, giterminismManager.FileManager.ReadDockerfile)
+	if err != nil {
+		return err
+	}
+
+	lockPath := filepath.Join(giterminismManager.ProjectDir(), base_images.DefaultLockFileName)
+
+	current := base_images.NewLock()
+	if data, err := os.ReadFile(lockPath); err == nil {
+		if current, err = base_images.ParseLock(data); err != nil {
+			return fmt.Errorf("%s: %w", base_images.DefaultLockFileName, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to read %s: %w", lockPath, err)
+	}
+
+	refresh := references
+	if all {
+		refresh = usedReferences
+	}
+
+	var lock *base_images.Lock
+	if err := logboek.Context(ctx).Default().LogProcess("Resolving %d base images", len(usedReferences)).DoError(func() error {
+		lock, err = base_images.Update(ctx, current, usedReferences, refresh, getDigest)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	data, err := lock.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(lockPath, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write %s: %w", lockPath, err)
+	}
+
+	logboek.Context(ctx).Default().LogOptionalLn()
+	logboek.Context(ctx).Default().LogLn("Base images lock file updated successfully")
+
+	return nil
+}
+
+func getDigest(ctx context.Context, reference string) (string, error) {
+	desc, err := docker_registry.API().GetRepoImageDescriptor(ctx, reference)
+	if err != nil {
+		return "", err
+	}
+
+	logboek.Context(ctx).Default().LogF("%s: %s\n", reference, desc.Digest)
+
+	return desc.Digest.String(), nil
+}
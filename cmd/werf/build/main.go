@@ -96,6 +96,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupSaveBuildReport(&commonCmdData, cmd)
 	common.SetupBuildReportPath(&commonCmdData, cmd)
+	common.SetupProvenance(&commonCmdData, cmd)
 
 	common.SetupAddCustomTag(&commonCmdData, cmd)
 	common.SetupVirtualMerge(&commonCmdData, cmd)
@@ -119,6 +120,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	commonCmdData.SetupFinalImagesOnly(cmd, false)
 	commonCmdData.SetupAllowIncludesUpdate(cmd)
+	commonCmdData.SetupVerifyProjectHeadCommitSignature(cmd)
 
 	lo.Must0(common.SetupMinimalKubeConnectionFlags(&commonCmdData, cmd))
 
@@ -3,6 +3,7 @@ package apply
 import (
 	"cmp"
 	"context"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -13,7 +14,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 
+	"github.com/werf/3p-helm/pkg/chart/loader"
 	"github.com/werf/3p-helm/pkg/engine"
 	"github.com/werf/3p-helm/pkg/werf/helmopts"
 	"github.com/werf/common-go/pkg/util"
@@ -21,8 +24,10 @@ import (
 	"github.com/werf/nelm/pkg/action"
 	"github.com/werf/nelm/pkg/log"
 	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/deploy/audit"
 	"github.com/werf/werf/v2/pkg/deploy/bundles"
 	"github.com/werf/werf/v2/pkg/deploy/helm/chart_extender/helpers"
+	"github.com/werf/werf/v2/pkg/deploy/policy"
 	"github.com/werf/werf/v2/pkg/docker"
 	"github.com/werf/werf/v2/pkg/tmp_manager"
 	"github.com/werf/werf/v2/pkg/werf"
@@ -32,6 +37,8 @@ import (
 var cmdData struct {
 	Tag          string
 	AutoRollback bool
+	VerifyKey    string
+	Channel      string
 }
 
 var commonCmdData common.CmdData
@@ -56,6 +63,11 @@ func NewCmd(ctx context.Context) *cobra.Command {
 				return err
 			}
 
+			// The tag is either specified explicitly or with $WERF_TAG, otherwise it is latest.
+			if cmdData.Channel != "" && (cmd.Flags().Changed("tag") || cmdData.Tag != "latest") {
+				return fmt.Errorf("only one of --tag or --channel should be specified, but both provided")
+			}
+
 			common.LogVersion()
 
 			return common.LogRunningTime(func() error { return runApply(ctx) })
@@ -86,6 +98,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupAddAnnotations(&commonCmdData, cmd)
 	common.SetupAddLabels(&commonCmdData, cmd)
+	common.SetupAuditLog(&commonCmdData, cmd)
 	common.SetupChartProvenanceKeyring(&commonCmdData, cmd)
 	common.SetupChartProvenanceStrategy(&commonCmdData, cmd)
 	common.SetupDefaultDeletePropagation(&commonCmdData, cmd)
@@ -99,6 +112,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupNoInstallCRDs(&commonCmdData, cmd)
 	common.SetupNoRemoveManualChanges(&commonCmdData, cmd)
 	common.SetupNoShowNotes(&commonCmdData, cmd)
+	common.SetupPolicies(&commonCmdData, cmd, common.SetupPoliciesOptions{WithoutProjectDir: true})
 	common.SetupRelease(&commonCmdData, cmd, false)
 	common.SetupReleaseInfoAnnotations(&commonCmdData, cmd)
 	common.SetupReleaseLabel(&commonCmdData, cmd)
@@ -119,6 +133,8 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	cmd.Flags().StringVarP(&cmdData.Tag, "tag", "", defaultTag, "Provide exact tag version or semver-based pattern, werf will install or upgrade to the latest version of the specified bundle ($WERF_TAG or latest by default)")
 	cmd.Flags().BoolVarP(&cmdData.AutoRollback, "auto-rollback", "R", util.GetBoolEnvironmentDefaultFalse("WERF_AUTO_ROLLBACK"), "Enable auto rollback of the failed release to the previous deployed release version when current deploy process have failed ($WERF_AUTO_ROLLBACK by default)")
 	cmd.Flags().BoolVarP(&cmdData.AutoRollback, "atomic", "", util.GetBoolEnvironmentDefaultFalse("WERF_ATOMIC"), "Enable auto rollback of the failed release to the previous deployed release version when current deploy process have failed ($WERF_ATOMIC by default)")
+	cmd.Flags().StringVarP(&cmdData.Channel, "channel", "", os.Getenv("WERF_CHANNEL"), "Apply the bundle tag of the specified release channel from the channels manifest of the bundle repo instead of --tag, channels are managed by werf bundle promote (default $WERF_CHANNEL)")
+	cmd.Flags().StringVarP(&cmdData.VerifyKey, "verify-key", "", os.Getenv("WERF_VERIFY_KEY"), "Verify bundle signature with the specified PEM encoded public key and refuse to apply unsigned or tampered bundle (default $WERF_VERIFY_KEY)")
 
 	return cmd
 }
@@ -126,6 +142,14 @@ func NewCmd(ctx context.Context) *cobra.Command {
 func runApply(ctx context.Context) error {
 	global_warnings.PostponeMultiwerfNotUpToDateWarning(ctx)
 
+	var verifyKey crypto.PublicKey
+	if cmdData.VerifyKey != "" {
+		var err error
+		if verifyKey, err = bundles.LoadVerificationKey(cmdData.VerifyKey); err != nil {
+			return fmt.Errorf("unable to load --verify-key: %w", err)
+		}
+	}
+
 	_, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
 		Cmd:                &commonCmdData,
 		InitDockerRegistry: true,
@@ -147,6 +171,21 @@ func runApply(ctx context.Context) error {
 		return fmt.Errorf("get repo address: %w", err)
 	}
 
+	tag := cmdData.Tag
+	if cmdData.Channel != "" {
+		registryClient, err := common.CreateDockerRegistry(ctx, repoAddress, *commonCmdData.InsecureRegistry, *commonCmdData.SkipTlsVerifyRegistry)
+		if err != nil {
+			return err
+		}
+
+		tag, err = bundles.ResolveChannelTag(ctx, registryClient, repoAddress, cmdData.Channel)
+		if err != nil {
+			return fmt.Errorf("resolve release channel: %w", err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Using bundle tag %q of the release channel %q\n", tag, cmdData.Channel)
+	}
+
 	releaseNamespace := common.GetNamespace(&commonCmdData)
 	releaseName, err := common.GetRequiredRelease(&commonCmdData)
 	if err != nil {
@@ -183,7 +222,7 @@ func runApply(ctx context.Context) error {
 		return fmt.Errorf("get current working directory: %w", err)
 	}
 
-	if err := bundles.Pull(ctx, fmt.Sprintf("%s:%s", repoAddress, cmdData.Tag), bundlePath, bundlesRegistryClient, helmopts.HelmOptions{
+	if err := bundles.Pull(ctx, fmt.Sprintf("%s:%s", repoAddress, tag), bundlePath, bundlesRegistryClient, helmopts.HelmOptions{
 		ChartLoadOpts: helmopts.ChartLoadOptions{
 			DefaultSecretValuesDisable: commonCmdData.DefaultSecretValuesDisable,
 			DefaultValuesDisable:       commonCmdData.DefaultValuesDisable,
@@ -196,6 +235,12 @@ func runApply(ctx context.Context) error {
 		return fmt.Errorf("pull bundle: %w", err)
 	}
 
+	if verifyKey != nil {
+		if err := verifyBundle(ctx, bundlePath, repoAddress, verifyKey); err != nil {
+			return err
+		}
+	}
+
 	serviceAnnotations, extraAnnotations, extraLabels, err := getAnnotationsAndLabels(bundlePath)
 	if err != nil {
 		return fmt.Errorf("get annotations and labels: %w", err)
@@ -214,6 +259,30 @@ func runApply(ctx context.Context) error {
 	})
 	engine.Debug = commonCmdData.DebugTemplates
 
+	if commonCmdData.PoliciesDir != "" {
+		chartRenderOptions := common.NewChartRenderOptions(&commonCmdData, action.ChartRenderOptions{
+			ChartDirPath:               bundlePath,
+			ExtraAnnotations:           extraAnnotations,
+			ExtraLabels:                extraLabels,
+			ExtraRuntimeAnnotations:    extraRuntimeAnnotations,
+			LegacyChartType:            helmopts.ChartTypeBundle,
+			LegacyExtraValues:          serviceValues,
+			LegacyLogRegistryStreamOut: os.Stdout,
+			RegistryCredentialsPath:    registryCredentialsPath,
+			ReleaseName:                releaseName,
+			ReleaseNamespace:           releaseNamespace,
+		})
+
+		if err := common.CheckPolicies(ctx, &commonCmdData, policy.NewLocalFileReader(), common.NewChartResourcesGetter(ctx, chartRenderOptions)); err != nil {
+			return err
+		}
+	}
+
+	auditRecord, err := newAuditRecord(bundlePath, releaseName, releaseNamespace, serviceAnnotations)
+	if err != nil {
+		return err
+	}
+
 	if err := action.ReleaseInstall(ctx, releaseName, releaseNamespace, action.ReleaseInstallOptions{
 		KubeConnectionOptions:       commonCmdData.KubeConnectionOptions,
 		ChartRepoConnectionOptions:  commonCmdData.ChartRepoConnectionOptions,
@@ -250,10 +319,59 @@ func runApply(ctx context.Context) error {
 		ShowSubchartNotes:           commonCmdData.ShowSubchartNotes,
 		TemplatesAllowDNS:           commonCmdData.TemplatesAllowDNS,
 	}); err != nil {
-		return fmt.Errorf("release install: %w", err)
+		return common.WriteAuditRecord(ctx, &commonCmdData, auditRecord, fmt.Errorf("release install: %w", err), common.WriteAuditRecordOptions{HashValues: true})
 	}
 
-	return nil
+	return common.WriteAuditRecord(ctx, &commonCmdData, auditRecord, nil, common.WriteAuditRecordOptions{HashValues: true})
+}
+
+// newAuditRecord starts the audit record with the images and the commit of the bundle values.
+func newAuditRecord(bundlePath, releaseName, releaseNamespace string, serviceAnnotations map[string]string) (*audit.Record, error) {
+	auditRecord := audit.NewRecord("bundle apply")
+	auditRecord.Release = releaseName
+	auditRecord.Namespace = releaseNamespace
+	auditRecord.Project = serviceAnnotations["project.werf.io/name"]
+	auditRecord.Commit = serviceAnnotations["ci.werf.io/commit"]
+
+	data, err := os.ReadFile(filepath.Join(bundlePath, "values.yaml"))
+	if os.IsNotExist(err) {
+		return auditRecord, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read bundle values.yaml: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("unmarshal bundle values.yaml: %w", err)
+	}
+
+	auditRecord.Images = audit.ImagesFromValues(values)
+	if commit := audit.CommitFromValues(values); commit != "" {
+		auditRecord.Commit = commit
+	}
+
+	return auditRecord, nil
+}
+
+func verifyBundle(ctx context.Context, bundlePath, repoAddress string, verifyKey crypto.PublicKey) error {
+	ch, err := loader.Load(bundlePath, helmopts.HelmOptions{
+		ChartLoadOpts: helmopts.ChartLoadOptions{
+			ChartType: helmopts.ChartTypeBundle,
+			NoSecrets: true,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("load bundle: %w", err)
+	}
+
+	registryClient, err := common.CreateDockerRegistry(ctx, repoAddress, *commonCmdData.InsecureRegistry, *commonCmdData.SkipTlsVerifyRegistry)
+	if err != nil {
+		return err
+	}
+
+	return logboek.Context(ctx).LogProcess("Verifying bundle signature").DoError(func() error {
+		return bundles.VerifyChartSignature(ctx, ch, verifyKey, bundles.GetRemoteImageIDGetter(registryClient))
+	})
 }
 
 func getAnnotationsAndLabels(bundleDir string) (map[string]string, map[string]string, map[string]string, error) {
@@ -2,6 +2,7 @@ package copy
 
 import (
 	"context"
+	"crypto"
 	"fmt"
 	"os"
 
@@ -18,8 +19,10 @@ import (
 )
 
 var cmdData struct {
-	From string
-	To   string
+	From      string
+	To        string
+	DeltaBase string
+	VerifyKey string
 }
 
 var commonCmdData common.CmdData
@@ -69,6 +72,9 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	cmd.Flags().StringVarP(&cmdData.From, "from", "", os.Getenv("WERF_FROM"), "Source address of the bundle to copy, specify bundle archive using schema `archive:PATH_TO_ARCHIVE.tar.gz`, specify remote bundle with schema `[docker://]REPO:TAG` or without schema.")
 	cmd.Flags().StringVarP(&cmdData.To, "to", "", os.Getenv("WERF_TO"), "Destination address of the bundle to copy, specify bundle archive using schema `archive:PATH_TO_ARCHIVE.tar.gz`, specify remote bundle with schema `[docker://]REPO:TAG` or without schema.")
 
+	cmd.Flags().StringVarP(&cmdData.DeltaBase, "delta-base", "", os.Getenv("WERF_DELTA_BASE"), "Copy only image layers missing at the specified base into the destination bundle archive (default $WERF_DELTA_BASE). Base is either the destination repo `[docker://]REPO`, which is queried for existing layers, or a previously transferred bundle archive `archive:PATH_TO_ARCHIVE.tar.gz`. Resulting delta archive is applied with the regular copy into the destination repo, which should already contain the base layers.")
+	cmd.Flags().StringVarP(&cmdData.VerifyKey, "verify-key", "", os.Getenv("WERF_VERIFY_KEY"), "Verify source bundle signature with the specified PEM encoded public key before copying (default $WERF_VERIFY_KEY). Bundle archive is verified offline using images from the archive")
+
 	return cmd
 }
 
@@ -133,6 +139,35 @@ func runCopy(ctx context.Context) error {
 		}
 	}
 
+	var deltaBaseAddr *bundles.Addr
+	var deltaBaseRegistry docker_registry.Interface
+
+	if cmdData.DeltaBase != "" {
+		if toAddr.ArchiveAddress == nil {
+			return fmt.Errorf("--delta-base=ADDRESS param could be used only with the bundle archive destination --to=archive:PATH_TO_ARCHIVE.tar.gz")
+		}
+
+		deltaBaseAddr, err = bundles.ParseAddr(cmdData.DeltaBase)
+		if err != nil {
+			return fmt.Errorf("invalid delta base addr %q: %w", cmdData.DeltaBase, err)
+		}
+
+		if deltaBaseAddr.RegistryAddress != nil {
+			deltaBaseRegistry, err = common.CreateDockerRegistry(ctx, deltaBaseAddr.RegistryAddress.Repo, *commonCmdData.InsecureRegistry, *commonCmdData.SkipTlsVerifyRegistry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var verifyKey crypto.PublicKey
+	if cmdData.VerifyKey != "" {
+		verifyKey, err = bundles.LoadVerificationKey(cmdData.VerifyKey)
+		if err != nil {
+			return fmt.Errorf("unable to load --verify-key: %w", err)
+		}
+	}
+
 	if commonCmdData.HelmCompatibleChart && commonCmdData.RenameChart != "" {
 		return fmt.Errorf("incompatible options specified, could not use --helm-compatible-chart and --rename-chart=%q at the same time", commonCmdData.RenameChart)
 	}
@@ -140,13 +175,19 @@ func runCopy(ctx context.Context) error {
 	return logboek.Context(ctx).LogProcess("Copy bundle").DoError(func() error {
 		logboek.Context(ctx).LogFDetails("From: %s\n", fromAddr.String())
 		logboek.Context(ctx).LogFDetails("To: %s\n", toAddr.String())
+		if deltaBaseAddr != nil {
+			logboek.Context(ctx).LogFDetails("Delta base: %s\n", deltaBaseAddr.String())
+		}
 
 		return bundles.Copy(ctx, fromAddr, toAddr, bundles.CopyOptions{
-			BundlesRegistryClient: bundlesRegistryClient,
-			FromRegistryClient:    fromRegistry,
-			ToRegistryClient:      toRegistry,
-			HelmCompatibleChart:   commonCmdData.HelmCompatibleChart,
-			RenameChart:           commonCmdData.RenameChart,
+			BundlesRegistryClient:   bundlesRegistryClient,
+			FromRegistryClient:      fromRegistry,
+			ToRegistryClient:        toRegistry,
+			HelmCompatibleChart:     commonCmdData.HelmCompatibleChart,
+			RenameChart:             commonCmdData.RenameChart,
+			DeltaBase:               deltaBaseAddr,
+			DeltaBaseRegistryClient: deltaBaseRegistry,
+			VerifyKey:               verifyKey,
 			HelmOptions: helmopts.HelmOptions{
 				ChartLoadOpts: helmopts.ChartLoadOptions{
 					ChartType: helmopts.ChartTypeBundle,
@@ -0,0 +1,314 @@
+package diff
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/3p-helm/pkg/werf/helmopts"
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm/pkg/action"
+	nelmcommon "github.com/werf/nelm/pkg/common"
+	"github.com/werf/nelm/pkg/log"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/deploy/bundles"
+	bundles_registry "github.com/werf/werf/v2/pkg/deploy/bundles/registry"
+	"github.com/werf/werf/v2/pkg/deploy/helm/chart_extender/helpers"
+	"github.com/werf/werf/v2/pkg/docker"
+	"github.com/werf/werf/v2/pkg/docker_registry"
+	"github.com/werf/werf/v2/pkg/storage"
+	"github.com/werf/werf/v2/pkg/tmp_manager"
+	"github.com/werf/werf/v2/pkg/werf"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+)
+
+var cmdData struct {
+	OutputFormat     string
+	DetailedExitCode bool
+	DiffContextLines int
+	SkipRender       bool
+	SkipImageDigests bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "diff FROM_TAG TO_TAG",
+		Short: "Show changes between two published bundle versions",
+		Long: common.GetLongCommandDescription(`Take two bundle versions from the specified container registry using specified version tags and show what changes between them: images (including image digests), chart dependencies, default values, chart templates and Kubernetes manifests rendered with the same values.
+
+Use this command to review bundle upgrade before running werf bundle apply.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			defer global_warnings.PrintGlobalWarnings(ctx)
+
+			if err := common.ValidateArgumentCount(2, args, cmd); err != nil {
+				return err
+			}
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			common.LogVersion()
+
+			return runDiff(ctx, args[0], args[1])
+		},
+	})
+
+	common.SetupEnvironment(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+
+	common.SetupRepoOptions(&commonCmdData, cmd, common.RepoDataOptions{})
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read and pull images from the specified repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupContainerRegistryMirror(&commonCmdData, cmd)
+
+	common.SetupLogOptionsDefaultQuiet(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	lo.Must0(common.SetupChartRepoConnectionFlags(&commonCmdData, cmd))
+	lo.Must0(common.SetupValuesFlags(&commonCmdData, cmd))
+	lo.Must0(common.SetupSecretValuesFlags(&commonCmdData, cmd))
+
+	common.SetupExtraAPIVersions(&commonCmdData, cmd)
+	common.SetupKubeVersion(&commonCmdData, cmd)
+	common.SetupNamespace(&commonCmdData, cmd, false)
+	common.SetupNetworkParallelism(&commonCmdData, cmd)
+	common.SetupRelease(&commonCmdData, cmd, false)
+	common.SetupSetDockerConfigJsonValue(&commonCmdData, cmd)
+	common.SetupTemplatesAllowDNS(&commonCmdData, cmd)
+	commonCmdData.SetupSkipDependenciesRepoRefresh(cmd)
+
+	common.SetupOutputFormat(cmd, &cmdData.OutputFormat, "WERF_BUNDLE_DIFF_OUTPUT_FORMAT", outputFormatText, outputFormatJSON)
+	cmd.Flags().BoolVarP(&cmdData.DetailedExitCode, "exit-code", "", util.GetBoolEnvironmentDefaultFalse("WERF_EXIT_CODE"), "If true, returns exit code 0 if no changes, exit code 2 if bundles differ or exit code 1 in case of an error (default $WERF_EXIT_CODE or false)")
+	cmd.Flags().BoolVarP(&cmdData.SkipRender, "skip-render", "", util.GetBoolEnvironmentDefaultFalse("WERF_SKIP_RENDER"), "Do not render bundles, compare only chart files, values and images (default $WERF_SKIP_RENDER or false)")
+	cmd.Flags().BoolVarP(&cmdData.SkipImageDigests, "skip-image-digests", "", util.GetBoolEnvironmentDefaultFalse("WERF_SKIP_IMAGE_DIGESTS"), "Do not query container registry for image digests, compare only image references (default $WERF_SKIP_IMAGE_DIGESTS or false)")
+
+	var defaultDiffLines int
+	if lines := lo.Must(util.GetIntEnvVar("WERF_DIFF_CONTEXT_LINES")); lines != nil {
+		defaultDiffLines = int(*lines)
+	} else {
+		defaultDiffLines = nelmcommon.DefaultDiffContextLines
+	}
+	cmd.Flags().IntVarP(&cmdData.DiffContextLines, "diff-context-lines", "", defaultDiffLines, "Show N lines of context around diffs ($WERF_DIFF_CONTEXT_LINES by default)")
+
+	return cmd
+}
+
+func runDiff(ctx context.Context, fromTag, toTag string) error {
+	global_warnings.PostponeMultiwerfNotUpToDateWarning(ctx)
+
+	switch cmdData.OutputFormat {
+	case outputFormatText, outputFormatJSON:
+	default:
+		return fmt.Errorf("unsupported --output-format=%q, expected %s or %s", cmdData.OutputFormat, outputFormatText, outputFormatJSON)
+	}
+
+	switch *commonCmdData.Repo.Address {
+	case "":
+		return fmt.Errorf("--repo=ADDRESS param required")
+	case storage.LocalStorageAddress:
+		return fmt.Errorf("--repo %s is not allowed, specify remote storage address", storage.LocalStorageAddress)
+	}
+
+	_, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
+		Cmd:                &commonCmdData,
+		InitDockerRegistry: true,
+		InitWerf:           true,
+	})
+	if err != nil {
+		return fmt.Errorf("component init error: %w", err)
+	}
+
+	defer func() {
+		if err := tmp_manager.DelegateCleanup(ctx); err != nil {
+			logboek.Context(ctx).Warn().LogF("Temporary files cleanup preparation failed: %s\n", err)
+		}
+	}()
+
+	repoAddress, err := commonCmdData.Repo.GetAddress()
+	if err != nil {
+		return fmt.Errorf("get repo address: %w", err)
+	}
+
+	bundlesRegistryClient, err := common.NewBundlesRegistryClient(ctx, &commonCmdData)
+	if err != nil {
+		return fmt.Errorf("construct bundles registry client: %w", err)
+	}
+
+	registryClient, err := common.CreateDockerRegistry(ctx, repoAddress, *commonCmdData.InsecureRegistry, *commonCmdData.SkipTlsVerifyRegistry)
+	if err != nil {
+		return err
+	}
+
+	releaseNamespace := common.GetNamespace(&commonCmdData)
+	registryCredentialsPath := docker.GetDockerConfigCredentialsFile(*commonCmdData.DockerConfig)
+
+	serviceValues, err := helpers.GetBundleServiceValues(ctx, helpers.ServiceValuesOptions{
+		Env:                      commonCmdData.Environment,
+		Namespace:                releaseNamespace,
+		SetDockerConfigJsonValue: *commonCmdData.SetDockerConfigJsonValue,
+		DockerConfigPath:         filepath.Dir(registryCredentialsPath),
+	})
+	if err != nil {
+		return fmt.Errorf("get service values: %w", err)
+	}
+
+	secretWorkDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current working directory: %w", err)
+	}
+
+	helmOpts := helmopts.HelmOptions{
+		ChartLoadOpts: helmopts.ChartLoadOptions{
+			ChartType:                  helmopts.ChartTypeBundle,
+			DefaultSecretValuesDisable: commonCmdData.DefaultSecretValuesDisable,
+			DefaultValuesDisable:       commonCmdData.DefaultValuesDisable,
+			ExtraValues:                serviceValues,
+			SecretKeyIgnore:            commonCmdData.SecretKeyIgnore,
+			SecretValuesFiles:          commonCmdData.SecretValuesFiles,
+			SecretWorkDir:              secretWorkDir,
+		},
+	}
+
+	fromChart, err := readBundleChart(ctx, repoAddress, fromTag, bundlesRegistryClient, registryClient, helmOpts)
+	if err != nil {
+		return err
+	}
+
+	toChart, err := readBundleChart(ctx, repoAddress, toTag, bundlesRegistryClient, registryClient, helmOpts)
+	if err != nil {
+		return err
+	}
+
+	diffOpts := bundles.DiffChartsOptions{ContextLines: cmdData.DiffContextLines}
+	if !cmdData.SkipImageDigests {
+		diffOpts.GetImageDigest = func(ctx context.Context, reference string) (string, error) {
+			info, err := registryClient.GetRepoImage(ctx, reference)
+			if err != nil {
+				return "", err
+			}
+			return info.GetDigest(), nil
+		}
+	}
+
+	bundleDiff, err := bundles.DiffCharts(ctx, fromChart, toChart, diffOpts)
+	if err != nil {
+		return fmt.Errorf("unable to diff bundles: %w", err)
+	}
+
+	if !cmdData.SkipRender {
+		fromManifests, err := renderBundle(ctx, fromChart, serviceValues, registryCredentialsPath, releaseNamespace)
+		if err != nil {
+			return fmt.Errorf("render bundle %s:%s: %w", repoAddress, fromTag, err)
+		}
+
+		toManifests, err := renderBundle(ctx, toChart, serviceValues, registryCredentialsPath, releaseNamespace)
+		if err != nil {
+			return fmt.Errorf("render bundle %s:%s: %w", repoAddress, toTag, err)
+		}
+
+		bundleDiff.SetManifests(fromManifests, toManifests, cmdData.DiffContextLines)
+	}
+
+	switch cmdData.OutputFormat {
+	case outputFormatJSON:
+		data, err := json.MarshalIndent(bundleDiff, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal bundle diff: %w", err)
+		}
+		fmt.Printf("%s\n", data)
+	default:
+		if err := bundleDiff.WriteText(os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	if cmdData.DetailedExitCode && bundleDiff.HasChanges() {
+		return action.ErrChangesPlanned
+	}
+
+	return nil
+}
+
+func readBundleChart(ctx context.Context, repoAddress, tag string, bundlesRegistryClient bundles.BundlesRegistryClient, registryClient docker_registry.Interface, helmOpts helmopts.HelmOptions) (*chart.Chart, error) {
+	ref, err := bundles_registry.ParseReference(fmt.Sprintf("%s:%s", repoAddress, tag))
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := bundles.NewRemoteBundle(&bundles.RegistryAddress{Reference: ref}, bundlesRegistryClient, registryClient)
+
+	ch, err := bundle.ReadChart(ctx, helmOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read bundle %s: %w", ref.FullName(), err)
+	}
+
+	return ch, nil
+}
+
+// renderBundle renders bundle chart and returns resulting manifests by resource id.
+func renderBundle(ctx context.Context, ch *chart.Chart, serviceValues map[string]interface{}, registryCredentialsPath, releaseNamespace string) (map[string]string, error) {
+	bundlePath := filepath.Join(werf.GetServiceDir(), "tmp", "bundles", uuid.NewString())
+	defer os.RemoveAll(bundlePath)
+
+	if err := chartutil.SaveIntoDir(ch, bundlePath); err != nil {
+		return nil, fmt.Errorf("unable to save chart into directory %q: %w", bundlePath, err)
+	}
+
+	renderCtx := log.SetupLogging(ctx, cmp.Or(common.GetNelmLogLevel(&commonCmdData), action.DefaultChartRenderLogLevel), log.SetupLoggingOptions{
+		ColorMode:      log.LogColorModeOff,
+		LogIsParseable: true,
+	})
+
+	res, err := action.ChartRender(renderCtx, common.NewChartRenderOptions(&commonCmdData, action.ChartRenderOptions{
+		ChartDirPath:            bundlePath,
+		LegacyChartType:         helmopts.ChartTypeBundle,
+		LegacyExtraValues:       serviceValues,
+		RegistryCredentialsPath: registryCredentialsPath,
+		ReleaseName:             common.GetOptionalRelease(&commonCmdData),
+		ReleaseNamespace:        releaseNamespace,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("chart render: %w", err)
+	}
+
+	manifests := make(map[string]string)
+	for _, r := range res.Resources {
+		data, err := yaml.Marshal(r.Unstruct.Object)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal resource %s: %w", r.IDHuman(), err)
+		}
+		manifests[r.IDHuman()] = string(data)
+	}
+
+	return manifests, nil
+}
@@ -0,0 +1,133 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/deploy/bundles"
+	"github.com/werf/werf/v2/pkg/image"
+	"github.com/werf/werf/v2/pkg/tmp_manager"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+var cmdData struct {
+	FromChannel string
+	ToChannel   string
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "promote REPO[:TAG]",
+		Short: "Promote bundle into the release channel",
+		Long: common.GetLongCommandDescription(`Set the release channel of the bundle repo either to the specified bundle tag or to the bundle tag of another release channel.
+
+Release channels (alpha, beta, stable, etc.) are stored in the channels manifest next to the bundles in the container registry, werf bundle apply --channel deploys the bundle tag of the specified release channel.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			defer global_warnings.PrintGlobalWarnings(ctx)
+
+			if err := common.ValidateArgumentCount(1, args, cmd); err != nil {
+				return err
+			}
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error { return runPromote(ctx, args[0]) })
+		},
+	})
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read and push release channels manifest into the specified repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupContainerRegistryMirror(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.FromChannel, "from", "", os.Getenv("WERF_FROM_CHANNEL"), "Release channel to take the bundle tag from, should not be used with the REPO:TAG argument (default $WERF_FROM_CHANNEL)")
+	cmd.Flags().StringVarP(&cmdData.ToChannel, "to", "", os.Getenv("WERF_TO_CHANNEL"), "Release channel to promote the bundle into (default $WERF_TO_CHANNEL)")
+
+	return cmd
+}
+
+func runPromote(ctx context.Context, ref string) error {
+	repo, tag := parseRepoAndOptionalTag(ref)
+
+	switch {
+	case cmdData.ToChannel == "":
+		return fmt.Errorf("--to=CHANNEL param required")
+	case cmdData.FromChannel == "" && tag == "":
+		return fmt.Errorf("either REPO:TAG argument or --from=CHANNEL param required")
+	case cmdData.FromChannel != "" && tag != "":
+		return fmt.Errorf("only one of REPO:TAG argument or --from=CHANNEL param should be specified, but both provided")
+	case cmdData.FromChannel == cmdData.ToChannel:
+		return fmt.Errorf("--from and --to release channels should differ")
+	}
+
+	_, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
+		Cmd:                &commonCmdData,
+		InitDockerRegistry: true,
+		InitWerf:           true,
+	})
+	if err != nil {
+		return fmt.Errorf("component init error: %w", err)
+	}
+
+	defer func() {
+		if err := tmp_manager.DelegateCleanup(ctx); err != nil {
+			logboek.Context(ctx).Warn().LogF("Temporary files cleanup preparation failed: %s\n", err)
+		}
+	}()
+
+	registryClient, err := common.CreateDockerRegistry(ctx, repo, *commonCmdData.InsecureRegistry, *commonCmdData.SkipTlsVerifyRegistry)
+	if err != nil {
+		return err
+	}
+
+	return logboek.Context(ctx).LogProcess("Promote bundle").DoError(func() error {
+		logboek.Context(ctx).LogFDetails("Repo: %s\n", repo)
+		if tag != "" {
+			logboek.Context(ctx).LogFDetails("Tag: %s\n", tag)
+		} else {
+			logboek.Context(ctx).LogFDetails("From channel: %s\n", cmdData.FromChannel)
+		}
+		logboek.Context(ctx).LogFDetails("To channel: %s\n", cmdData.ToChannel)
+
+		return bundles.Promote(ctx, registryClient, repo, bundles.PromoteOptions{
+			FromChannel: cmdData.FromChannel,
+			Tag:         tag,
+			ToChannel:   cmdData.ToChannel,
+		})
+	})
+}
+
+// parseRepoAndOptionalTag handles registry address with port (registry:5000/repo) without tag.
+func parseRepoAndOptionalTag(ref string) (string, string) {
+	repo, tag := image.ParseRepositoryAndTag(ref)
+	if strings.Contains(tag, "/") {
+		return ref, ""
+	}
+	return repo, tag
+}
@@ -1,7 +1,9 @@
 package publish
 
 import (
+	"cmp"
 	"context"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -30,11 +32,15 @@ import (
 	"github.com/werf/common-go/pkg/secrets_manager"
 	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/logboek"
+	"github.com/werf/nelm/pkg/action"
+	nelmcommon "github.com/werf/nelm/pkg/common"
+	"github.com/werf/nelm/pkg/log"
 	"github.com/werf/werf/v2/cmd/werf/common"
 	"github.com/werf/werf/v2/pkg/build"
 	"github.com/werf/werf/v2/pkg/config"
 	"github.com/werf/werf/v2/pkg/deploy/bundles"
 	"github.com/werf/werf/v2/pkg/deploy/helm/chart_extender/helpers"
+	"github.com/werf/werf/v2/pkg/docker"
 	"github.com/werf/werf/v2/pkg/image"
 	"github.com/werf/werf/v2/pkg/tmp_manager"
 	"github.com/werf/werf/v2/pkg/true_git"
@@ -43,7 +49,10 @@ import (
 )
 
 var cmdData struct {
-	Tag string
+	Tag                  string
+	SignKey              string
+	PinImageDigests      bool
+	CopyThirdPartyImages bool
 }
 
 var commonCmdData common.CmdData
@@ -150,12 +159,28 @@ func NewCmd(ctx context.Context) *cobra.Command {
 		defaultTag = "latest"
 	}
 	cmd.Flags().StringVarP(&cmdData.Tag, "tag", "", defaultTag, "Publish bundle into container registry repo by the provided tag ($WERF_TAG or latest by default)")
+	cmd.Flags().StringVarP(&cmdData.SignKey, "sign-key", "", os.Getenv("WERF_SIGN_KEY"), "Sign bundle chart and bundle image IDs with the specified PEM encoded private key (ed25519, ECDSA or RSA). Image IDs (config digests) are signed instead of manifest digests, because image manifests are rebuilt when images are copied from the bundle archive. Signature is verified by werf bundle apply and werf bundle copy with --verify-key option (default $WERF_SIGN_KEY)")
+	cmd.Flags().BoolVarP(&cmdData.PinImageDigests, "pin-image-digests", "", util.GetBoolEnvironmentDefaultFalse("WERF_PIN_IMAGE_DIGESTS"), "Rewrite werf images and third-party images used in the rendered bundle templates to immutable REPO:TAG@DIGEST references in the bundle values. Third-party image is pinned only when its reference is set in the values (default $WERF_PIN_IMAGE_DIGESTS)")
+	cmd.Flags().BoolVarP(&cmdData.CopyThirdPartyImages, "copy-third-party-images", "", util.GetBoolEnvironmentDefaultFalse("WERF_COPY_THIRD_PARTY_IMAGES"), "Copy third-party images pinned with --pin-image-digests into the bundle repo (default $WERF_COPY_THIRD_PARTY_IMAGES)")
 
 	return cmd
 }
 
 func runPublish(ctx context.Context, imageNameListFromArgs []string) error {
 	global_warnings.PostponeMultiwerfNotUpToDateWarning(ctx)
+
+	if cmdData.CopyThirdPartyImages && !cmdData.PinImageDigests {
+		return fmt.Errorf("--copy-third-party-images requires --pin-image-digests")
+	}
+
+	var signKey crypto.Signer
+	if cmdData.SignKey != "" {
+		var err error
+		if signKey, err = bundles.LoadSigningKey(cmdData.SignKey); err != nil {
+			return fmt.Errorf("unable to load --sign-key: %w", err)
+		}
+	}
+
 	commonManager, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
 		Cmd: &commonCmdData,
 		InitTrueGitWithOptions: &common.InitTrueGitOptions{
@@ -423,15 +448,104 @@ func runPublish(ctx context.Context, imageNameListFromArgs []string) error {
 		bundleRepo = stagesStorage.Address()
 	}
 
+	if cmdData.PinImageDigests {
+		if err := pinBundleImageDigests(ctx, bundleTmpDir, bundleRepo, giterminismManager.ProjectDir()); err != nil {
+			return fmt.Errorf("pin bundle image digests: %w", err)
+		}
+	}
+
 	opts.ChartLoadOpts.ChartType = helmopts.ChartTypeBundle
 
-	return bundles.Publish(ctx, bundleTmpDir, fmt.Sprintf("%s:%s", bundleRepo, cmdData.Tag), bundlesRegistryClient, bundles.PublishOptions{
+	publishOpts := bundles.PublishOptions{
 		HelmCompatibleChart: commonCmdData.HelmCompatibleChart,
 		RenameChart:         commonCmdData.RenameChart,
 		HelmOptions:         opts,
+	}
+
+	if signKey != nil {
+		registryClient, err := common.CreateDockerRegistry(ctx, bundleRepo, *commonCmdData.InsecureRegistry, *commonCmdData.SkipTlsVerifyRegistry)
+		if err != nil {
+			return err
+		}
+
+		publishOpts.SignKey = signKey
+		publishOpts.GetImageID = bundles.GetRemoteImageIDGetter(registryClient)
+	}
+
+	return bundles.Publish(ctx, bundleTmpDir, fmt.Sprintf("%s:%s", bundleRepo, cmdData.Tag), bundlesRegistryClient, publishOpts)
+}
+
+// pinBundleImageDigests rewrites images of the bundle values.yaml to the REPO:TAG@DIGEST references.
+// Third-party images are detected in the rendered bundle templates.
+func pinBundleImageDigests(ctx context.Context, bundleDir, bundleRepo, projectDir string) error {
+	return logboek.Context(ctx).LogProcess("Pinning bundle image digests").DoError(func() error {
+		registryClient, err := common.CreateDockerRegistry(ctx, bundleRepo, *commonCmdData.InsecureRegistry, *commonCmdData.SkipTlsVerifyRegistry)
+		if err != nil {
+			return err
+		}
+
+		manifests, err := renderBundleManifests(ctx, bundleDir, projectDir)
+		if err != nil {
+			return err
+		}
+
+		valuesFile := filepath.Join(bundleDir, "values.yaml")
+
+		valsData, err := os.ReadFile(valuesFile)
+		if err != nil {
+			return fmt.Errorf("unable to read %q: %w", valuesFile, err)
+		}
+
+		vals := make(map[string]interface{})
+		if err := yaml.Unmarshal(valsData, &vals); err != nil {
+			return fmt.Errorf("unable to unmarshal %q: %w", valuesFile, err)
+		}
+
+		pinOpts := bundles.PinImageDigestsOptions{ThirdPartyImages: bundles.GetManifestsImages(manifests)}
+		if cmdData.CopyThirdPartyImages {
+			pinOpts.CopyThirdPartyImagesToRepo = bundleRepo
+		}
+
+		if err := bundles.PinImageDigests(ctx, registryClient, vals, pinOpts); err != nil {
+			return err
+		}
+
+		valsData, err = yaml.Marshal(vals)
+		if err != nil {
+			return fmt.Errorf("unable to marshal bundle values: %w", err)
+		}
+
+		if err := os.WriteFile(valuesFile, valsData, os.ModePerm); err != nil {
+			return fmt.Errorf("unable to write %q: %w", valuesFile, err)
+		}
+
+		return nil
 	})
 }
 
+func renderBundleManifests(ctx context.Context, bundleDir, projectDir string) ([]map[string]interface{}, error) {
+	// Values and secret values files are already merged into the bundle.
+	secretValuesOptions := commonCmdData.SecretValuesOptions
+	secretValuesOptions.SecretValuesFiles = nil
+	secretValuesOptions.SecretKeyIgnore = secretValuesOptions.SecretKeyIgnore || secrets_manager.Manager.IsMissedSecretKeyModeEnabled()
+	secretValuesOptions.SecretWorkDir = projectDir
+
+	renderCtx := log.SetupLogging(ctx, cmp.Or(common.GetNelmLogLevel(&commonCmdData), action.DefaultChartRenderLogLevel), log.SetupLoggingOptions{
+		ColorMode:      log.LogColorModeOff,
+		LogIsParseable: true,
+	})
+
+	renderOptions := common.NewChartRenderOptions(&commonCmdData, action.ChartRenderOptions{
+		ChartDirPath:            bundleDir,
+		LegacyChartType:         helmopts.ChartTypeBundle,
+		RegistryCredentialsPath: docker.GetDockerConfigCredentialsFile(*commonCmdData.DockerConfig),
+	})
+	renderOptions.ValuesOptions = nelmcommon.ValuesOptions{}
+	renderOptions.SecretValuesOptions = secretValuesOptions
+
+	return common.RenderChartResources(renderCtx, renderOptions)
+}
+
 func createNewBundle(
 	ctx context.Context,
 	serviceValues map[string]interface{},
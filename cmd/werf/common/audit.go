@@ -0,0 +1,179 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/3p-helm/pkg/werf/file"
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/kubedog/pkg/kube"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm/pkg/action"
+	"github.com/werf/werf/v2/pkg/deploy/audit"
+	"github.com/werf/werf/v2/pkg/werf"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+func SetupAuditLog(cmdData *CmdData, cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&cmdData.AuditLog, "audit-log", "", []string{}, `Append the audit record of the release operation to the sink (can specify multiple).
+Sink is configmap://[NAME] or secret://[NAME] in the release namespace (NAME is werf-audit-log by default), http(s):// webhook URL (bearer token can be specified with $WERF_AUDIT_WEBHOOK_TOKEN) or local JSONL file path.
+Records of the ConfigMap, Secret and file sinks are chained with HMAC-SHA256 hashes keyed by $WERF_AUDIT_KEY (plain SHA-256 if not set), webhook records are not chained.
+ConfigMap and Secret sinks keep the last 500 records. The values hash is recorded only if $WERF_AUDIT_KEY is set.
+Also, can be specified with $WERF_AUDIT_LOG_* (e.g. $WERF_AUDIT_LOG_1=configmap://, $WERF_AUDIT_LOG_2=https://audit.example.com/records)`)
+	cmd.Flags().BoolVarP(&cmdData.AuditLogStrict, "audit-log-strict", "", util.GetBoolEnvironmentDefaultFalse("WERF_AUDIT_LOG_STRICT"), `Fail the succeeded release operation if the audit record cannot be written (default $WERF_AUDIT_LOG_STRICT or false, the warning is printed otherwise)`)
+}
+
+func GetAuditLog(cmdData *CmdData) []string {
+	return append(util.PredefinedValuesByEnvNamePrefix("WERF_AUDIT_LOG_"), cmdData.AuditLog...)
+}
+
+// ValidateAuditLogForNamespaceDeletion fails if the audit record should be written to the ConfigMap or Secret sink in the namespace,
+// which is deleted by the command: the audit history would be deleted with the namespace and the record cannot be written.
+func ValidateAuditLogForNamespaceDeletion(cmdData *CmdData) error {
+	for _, spec := range GetAuditLog(cmdData) {
+		if audit.IsKubeSinkSpec(spec) {
+			return fmt.Errorf("audit log %q is stored in the release namespace, which is deleted: use the webhook or file audit log sink", spec)
+		}
+	}
+	return nil
+}
+
+// SetAuditRecordRelease sets the images and the commit of the latest release revision to the record if the audit log is enabled.
+// The error is reported as the warning, so that the release operation is not affected.
+func SetAuditRecordRelease(ctx context.Context, cmdData *CmdData, record *audit.Record) {
+	if len(GetAuditLog(cmdData)) == 0 {
+		return
+	}
+
+	result, err := action.ReleaseGet(ctx, record.Release, record.Namespace, action.ReleaseGetOptions{
+		KubeConnectionOptions:       cmdData.KubeConnectionOptions,
+		NetworkParallelism:          cmdData.NetworkParallelism,
+		OutputNoPrint:               true,
+		ReleaseStorageDriver:        cmdData.ReleaseStorageDriver,
+		ReleaseStorageSQLConnection: cmdData.ReleaseStorageSQLConnection,
+	})
+	if err != nil {
+		global_warnings.GlobalWarningLn(ctx, fmt.Sprintf("Unable to get release images and commit for the audit record: %s", err))
+		return
+	}
+
+	record.Images = audit.ImagesFromValues(result.Values)
+	record.Commit = audit.CommitFromValues(result.Values)
+}
+
+type WriteAuditRecordOptions struct {
+	// HashValues sets the hash of the values files and the --set* options to the record (only if the audit key is specified).
+	HashValues bool
+}
+
+// WriteAuditRecord finishes the record with the result of the release operation and appends it to all audit log sinks.
+// The error of the operation is returned as is, the audit log errors are reported as warnings
+// or returned if the operation succeeded and the audit log is strict.
+func WriteAuditRecord(ctx context.Context, cmdData *CmdData, record *audit.Record, operationErr error, opts WriteAuditRecordOptions) error {
+	sinkSpecs := GetAuditLog(cmdData)
+	if len(sinkSpecs) == 0 {
+		return operationErr
+	}
+
+	record.Finish(operationErr)
+	record.Environment = cmdData.Environment
+	record.WerfVersion = werf.Version
+
+	key := []byte(os.Getenv("WERF_AUDIT_KEY"))
+
+	if opts.HashValues && len(key) > 0 {
+		valuesHash, err := GetValuesHash(ctx, cmdData, key)
+		if err != nil {
+			return auditError(ctx, cmdData, operationErr, err)
+		}
+		record.ValuesHash = valuesHash
+	}
+
+	sinkOpts := audit.SinkOptions{
+		Namespace:     record.Namespace,
+		WebhookToken:  os.Getenv("WERF_AUDIT_WEBHOOK_TOKEN"),
+		GetKubeClient: getAuditKubeClient(cmdData),
+		Key:           key,
+	}
+
+	var errs []error
+	for _, spec := range sinkSpecs {
+		sink, err := audit.NewSink(spec, sinkOpts)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		head, err := sink.Append(ctx, *record)
+		if err != nil && !errors.Is(err, audit.ErrChainBroken) {
+			errs = append(errs, fmt.Errorf("unable to append audit record to %s: %w", sink, err))
+			continue
+		}
+
+		// The head hash is reported outside the sink to detect removal of the last records.
+		logboek.Context(ctx).Default().LogF("Audit record %s appended to %s\n", head, sink)
+		if err != nil {
+			global_warnings.GlobalWarningLn(ctx, fmt.Sprintf("Audit record is appended to %s, but the history is modified: %s", sink, err))
+		}
+	}
+
+	return auditError(ctx, cmdData, operationErr, errors.Join(errs...))
+}
+
+// GetValuesHash returns the keyed hash of the values files, the secret values files and the --set* options of the command.
+func GetValuesHash(ctx context.Context, cmdData *CmdData, key []byte) (string, error) {
+	var sources [][]byte
+
+	for _, files := range [][]string{cmdData.ValuesFiles, cmdData.SecretValuesFiles} {
+		for _, path := range files {
+			data, err := readValuesFile(ctx, path)
+			if err != nil {
+				return "", fmt.Errorf("unable to read values file %q: %w", path, err)
+			}
+			sources = append(sources, []byte(path), data)
+		}
+	}
+
+	for _, values := range [][]string{cmdData.ValuesSet, cmdData.ValuesSetString, cmdData.ValuesSetFile, cmdData.ValuesSetJSON, cmdData.ValuesSetLiteral} {
+		for _, v := range values {
+			sources = append(sources, []byte(v))
+		}
+	}
+
+	return audit.HashValues(key, sources...), nil
+}
+
+func readValuesFile(ctx context.Context, path string) ([]byte, error) {
+	if file.ChartFileReader != nil {
+		return file.ChartFileReader.ReadChartFile(ctx, path)
+	}
+	return os.ReadFile(path)
+}
+
+func getAuditKubeClient(cmdData *CmdData) func(ctx context.Context) (kubernetes.Interface, error) {
+	return func(ctx context.Context) (kubernetes.Interface, error) {
+		SetupOndemandKubeInitializer(cmdData.KubeContextCurrent, cmdData.LegacyKubeConfigPath, cmdData.KubeConfigBase64, cmdData.LegacyKubeConfigPathsMergeList)
+		if err := GetOndemandKubeInitializer().Init(ctx); err != nil {
+			return nil, err
+		}
+		return kube.Client, nil
+	}
+}
+
+func auditError(ctx context.Context, cmdData *CmdData, operationErr, auditErr error) error {
+	if auditErr == nil {
+		return operationErr
+	}
+
+	if operationErr == nil && cmdData.AuditLogStrict {
+		return fmt.Errorf("audit log: %w", auditErr)
+	}
+
+	global_warnings.GlobalWarningLn(ctx, fmt.Sprintf("Unable to write audit log: %s", auditErr))
+	return operationErr
+}
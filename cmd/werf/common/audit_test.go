@@ -0,0 +1,24 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestValidateAuditLogForNamespaceDeletion(t *testing.T) {
+	tests := []struct {
+		auditLog []string
+		wantErr  bool
+	}{
+		{auditLog: nil},
+		{auditLog: []string{"https://audit.example.com/records", "/var/log/werf/audit.jsonl"}},
+		{auditLog: []string{"configmap://"}, wantErr: true},
+		{auditLog: []string{"https://audit.example.com/records", "secret://audit"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateAuditLogForNamespaceDeletion(&CmdData{AuditLog: tt.auditLog})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateAuditLogForNamespaceDeletion(%q) error = %v, wantErr %v", tt.auditLog, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/werf/nelm/pkg/action"
+)
+
+// NewChartRenderOptions returns options of rendering the chart manifests without printing, which are inspected
+// by the command (policies, rollouts, drift, bundle diff). Chart, release and service values options are taken
+// from opts, values, chart repository, kube and release storage options are taken from the command options.
+func NewChartRenderOptions(cmdData *CmdData, opts action.ChartRenderOptions) action.ChartRenderOptions {
+	opts.KubeConnectionOptions = cmdData.KubeConnectionOptions
+	opts.ChartRepoConnectionOptions = cmdData.ChartRepoConnectionOptions
+	opts.ValuesOptions = cmdData.ValuesOptions
+	opts.SecretValuesOptions = cmdData.SecretValuesOptions
+	opts.ChartProvenanceKeyring = cmdData.ChartProvenanceKeyring
+	opts.ChartProvenanceStrategy = cmdData.ChartProvenanceStrategy
+	opts.ChartRepoSkipUpdate = cmdData.ChartRepoSkipUpdate
+	opts.ExtraAPIVersions = cmdData.ExtraAPIVersions
+	opts.ForceAdoption = cmdData.ForceAdoption
+	opts.LocalKubeVersion = cmdData.KubeVersion
+	opts.NetworkParallelism = cmdData.NetworkParallelism
+	opts.OutputNoPrint = true
+	opts.ReleaseStorageDriver = cmdData.ReleaseStorageDriver
+	opts.ReleaseStorageSQLConnection = cmdData.ReleaseStorageSQLConnection
+	opts.ShowStandaloneCRDs = true
+	opts.TemplatesAllowDNS = cmdData.TemplatesAllowDNS
+
+	return opts
+}
+
+// ChartResourcesGetter returns the rendered chart resources, the chart is rendered on the first call only,
+// so all checks of the command share the same rendered manifests.
+type ChartResourcesGetter func() ([]map[string]interface{}, error)
+
+func NewChartResourcesGetter(ctx context.Context, renderOptions action.ChartRenderOptions) ChartResourcesGetter {
+	return sync.OnceValues(func() ([]map[string]interface{}, error) {
+		return RenderChartResources(ctx, renderOptions)
+	})
+}
+
+// RenderChartResources renders the chart without printing and returns the rendered resources including standalone CRDs.
+func RenderChartResources(ctx context.Context, renderOptions action.ChartRenderOptions) ([]map[string]interface{}, error) {
+	renderOptions.OutputNoPrint = true
+	renderOptions.ShowStandaloneCRDs = true
+
+	res, err := action.ChartRender(ctx, renderOptions)
+	if err != nil {
+		return nil, fmt.Errorf("chart render: %w", err)
+	}
+
+	var resources []map[string]interface{}
+	for _, r := range res.Resources {
+		resources = append(resources, r.Unstruct.Object)
+	}
+
+	return resources, nil
+}
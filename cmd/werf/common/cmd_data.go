@@ -10,6 +10,7 @@ import (
 
 	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/nelm/pkg/common"
+	"github.com/werf/werf/v2/pkg/commit_signature"
 	"github.com/werf/werf/v2/pkg/util/option"
 )
 
@@ -48,6 +49,9 @@ type CmdData struct {
 	RequireBuiltImages     *bool
 	StubTags               *bool
 
+	WaitForImages        *bool
+	WaitForImagesTimeout *string
+
 	AddCustomTag *[]string
 	UseCustomTag *string
 
@@ -87,6 +91,7 @@ type CmdData struct {
 
 	SaveBuildReport *bool
 	BuildReportPath *string
+	Provenance      *string
 
 	VirtualMerge *bool
 
@@ -105,9 +110,14 @@ type CmdData struct {
 	SkipImageSpecStage *bool
 	IncludesLsFilter   *string
 
-	CreateIncludesLockFile bool
-	AllowIncludesUpdate    bool
+	SkipIncludesInit    bool
+	AllowIncludesUpdate bool
+
+	VerifyProjectHeadCommitSignature bool
+	CommitSignaturesTrustedKeys      []string
 
+	AuditLog                         []string
+	AuditLogStrict                   bool
 	ChartProvenanceKeyring           string
 	ChartProvenanceStrategy          string
 	ChartRepoSkipUpdate              bool
@@ -132,6 +142,7 @@ type CmdData struct {
 	NoInstallStandaloneCRDs          bool
 	NoRemoveManualChanges            bool
 	NoShowNotes                      bool
+	PoliciesDir                      string
 	Release                          string
 	ReleaseHistoryLimit              int
 	ReleaseInfoAnnotations           map[string]string
@@ -143,8 +154,12 @@ type CmdData struct {
 	RollbackReportPath               string
 	SaveDeployReport                 bool
 	SaveRollbackReport               bool
+	SaveStackReport                  bool
 	SaveUninstallReport              bool
 	ShowSubchartNotes                bool
+	SkipPolicies                     bool
+	StackConfigPath                  string
+	StackReportPath                  string
 	TemplatesAllowDNS                bool
 	UninstallGraphPath               string
 	UninstallReportPath              string
@@ -216,14 +231,31 @@ func (cmdData *CmdData) SetupSkipImageSpecStage(cmd *cobra.Command) {
 	cmd.Flags().MarkHidden("skip-image-spec-stage")
 }
 
-func (cmdData *CmdData) SetupCreateIncludesLockFile() {
-	cmdData.CreateIncludesLockFile = true
+// SetupSkipIncludesInit is used by the commands, which manage werf-includes.lock themselves.
+func (cmdData *CmdData) SetupSkipIncludesInit() {
+	cmdData.SkipIncludesInit = true
 }
 
 func (cmdData *CmdData) SetupAllowIncludesUpdate(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&cmdData.AllowIncludesUpdate, "allow-includes-update", "", util.GetBoolEnvironmentDefaultFalse("WERF_ALLOW_INCLUDES_UPDATE"), `Allow use includes latest versions (default $WERF_ALLOW_INCLUDES_UPDATE or false)`)
 }
 
+func (cmdData *CmdData) SetupVerifyProjectHeadCommitSignature(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&cmdData.VerifyProjectHeadCommitSignature, "verify-project-head-commit-signature", "", util.GetBoolEnvironmentDefaultFalse("WERF_VERIFY_PROJECT_HEAD_COMMIT_SIGNATURE"), `Verify that the project HEAD commit is signed with one of the --commit-signatures-trusted-keys (default $WERF_VERIFY_PROJECT_HEAD_COMMIT_SIGNATURE or false)`)
+	cmd.Flags().StringArrayVarP(&cmdData.CommitSignaturesTrustedKeys, "commit-signatures-trusted-keys", "", []string{}, `Trusted SSH public keys in the authorized_keys format or armored GPG public keys to verify the project HEAD commit signature (can specify multiple).
+Also, can be specified with $WERF_COMMIT_SIGNATURES_TRUSTED_KEYS`)
+}
+
+// GetCommitSignaturesTrustedKeys returns the trusted keys of $WERF_COMMIT_SIGNATURES_TRUSTED_KEYS and --commit-signatures-trusted-keys,
+// each value can contain several keys.
+func (cmdData *CmdData) GetCommitSignaturesTrustedKeys() []string {
+	keys := commit_signature.SplitTrustedKeys(os.Getenv("WERF_COMMIT_SIGNATURES_TRUSTED_KEYS"))
+	for _, value := range cmdData.CommitSignaturesTrustedKeys {
+		keys = append(keys, commit_signature.SplitTrustedKeys(value)...)
+	}
+	return keys
+}
+
 func (cmdData *CmdData) SetupIncludesLsFilter(cmd *cobra.Command) {
 	cmdData.IncludesLsFilter = new(string)
 	cmd.Flags().StringVar(cmdData.IncludesLsFilter, "filter", os.Getenv("WERF_INCLUDES_LIST_FILTER"), "Filter by source, e.g. --filter=source=local,remoteRepo (default $WERF_INCLUDES_LIST_FILTER or all sources).")
@@ -22,6 +22,7 @@ import (
 	"github.com/werf/nelm/pkg/common"
 	"github.com/werf/nelm/pkg/log"
 	"github.com/werf/werf/v2/pkg/build"
+	"github.com/werf/werf/v2/pkg/build/provenance"
 	"github.com/werf/werf/v2/pkg/build/stage"
 	"github.com/werf/werf/v2/pkg/config"
 	"github.com/werf/werf/v2/pkg/container_backend"
@@ -52,7 +53,11 @@ const (
 	DefaultRollbackReportPathJSON  = ".werf-rollback-report.json"
 	DefaultUninstallReportPathJSON = ".werf-uninstall-report.json"
 	DefaultSaveUninstallReport     = false
-	TemplateErrHint                = "Use --debug-templates or $WERF_DEBUG_TEMPLATES to get more details about this error."
+
+	DefaultWaitForImagesTimeout = 30 * time.Minute
+	WaitForImagesPollInterval   = 10 * time.Second
+
+	TemplateErrHint = "Use --debug-templates or $WERF_DEBUG_TEMPLATES to get more details about this error."
 )
 
 func init() {
@@ -176,6 +181,15 @@ func SetupBuildReportPath(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().StringVarP(cmdData.BuildReportPath, "build-report-path", "", os.Getenv("WERF_BUILD_REPORT_PATH"), fmt.Sprintf("Change build report path and format (by default $WERF_BUILD_REPORT_PATH or %q if not set). Extension must be either .json for JSON format or .env for env-file format. If extension not specified, then .json is used", DefaultBuildReportPathJSON))
 }
 
+func SetupProvenance(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.Provenance = new(string)
+	cmd.Flags().StringVarP(cmdData.Provenance, "provenance", "", os.Getenv("WERF_PROVENANCE"), fmt.Sprintf("Generate SLSA provenance of the final images: either path of the file to write in-toto statements in JSON Lines format or %q to attach statements to the images in the container registry as OCI referrers (default $WERF_PROVENANCE)", provenance.TargetRegistry))
+}
+
+func GetProvenance(cmdData *CmdData) string {
+	return option.PtrValueOrDefault(cmdData.Provenance, "")
+}
+
 func GetSaveBuildReport(cmdData *CmdData) bool {
 	return option.PtrValueOrDefault(cmdData.SaveBuildReport, false)
 }
@@ -742,6 +756,15 @@ func SetupRequireBuiltImages(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.RequireBuiltImages, "require-built-images", "Z", util.GetBoolEnvironmentDefaultFalse("WERF_REQUIRE_BUILT_IMAGES"), "Requires all used images to be previously built and exist in repo. Exits with error if needed images are not cached and so require to run build instructions (default $WERF_REQUIRE_BUILT_IMAGES)")
 }
 
+// SetupWaitForImages adds --wait-for-images and --wait-for-images-timeout flags.
+func SetupWaitForImages(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.WaitForImages = new(bool)
+	cmd.Flags().BoolVarP(cmdData.WaitForImages, "wait-for-images", "", util.GetBoolEnvironmentDefaultFalse("WERF_WAIT_FOR_IMAGES"), "Wait until all used images are built (e.g. by the concurrent build job) and exist in repo instead of building them. Exits with error if needed images do not appear within --wait-for-images-timeout (default $WERF_WAIT_FOR_IMAGES)")
+
+	cmdData.WaitForImagesTimeout = new(string)
+	cmd.Flags().StringVarP(cmdData.WaitForImagesTimeout, "wait-for-images-timeout", "", os.Getenv("WERF_WAIT_FOR_IMAGES_TIMEOUT"), fmt.Sprintf("Timeout of waiting for the images with --wait-for-images (default $WERF_WAIT_FOR_IMAGES_TIMEOUT or %s)", DefaultWaitForImagesTimeout))
+}
+
 func SetupCheckBuiltImages(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.CheckBuiltImages = new(bool)
 	cmd.Flags().BoolVarP(cmdData.CheckBuiltImages, "check-built-images", "", util.GetBoolEnvironmentDefaultFalse("WERF_CHECK_BUILT_IMAGES"), "Check that all used images are previously built and exist in repo. Exits with error if needed images are not cached and so require to run build instructions (default $WERF_CHECK_BUILT_IMAGES)")
@@ -1011,10 +1034,13 @@ func GetGiterminismManager(ctx context.Context, cmdData *CmdData) (*giterminism_
 			configRelPath := GetWerfGiterminismConfigRelPath(cmdData)
 
 			gm, err := giterminism_manager.NewManager(ctx, configRelPath, workingDir, localGitRepo, headCommit, giterminism_manager.NewManagerOptions{
-				LooseGiterminism:       *cmdData.LooseGiterminism,
-				Dev:                    *cmdData.Dev,
-				CreateIncludesLockFile: cmdData.CreateIncludesLockFile,
-				AllowIncludesUpdate:    cmdData.AllowIncludesUpdate,
+				LooseGiterminism:    *cmdData.LooseGiterminism,
+				Dev:                 *cmdData.Dev,
+				SkipIncludesInit:    cmdData.SkipIncludesInit,
+				AllowIncludesUpdate: cmdData.AllowIncludesUpdate,
+
+				VerifyProjectHeadCommitSignature: cmdData.VerifyProjectHeadCommitSignature,
+				ProjectHeadCommitTrustedKeys:     cmdData.GetCommitSignaturesTrustedKeys(),
 			})
 			if err != nil {
 				return err
@@ -1207,6 +1233,28 @@ func GetRequireBuiltImages(cmdData *CmdData) bool {
 	return option.PtrValueOrDefault(cmdData.RequireBuiltImages, false)
 }
 
+func GetWaitForImages(cmdData *CmdData) bool {
+	return option.PtrValueOrDefault(cmdData.WaitForImages, false)
+}
+
+func GetWaitForImagesTimeout(cmdData *CmdData) (time.Duration, error) {
+	value := option.PtrValueOrDefault(cmdData.WaitForImagesTimeout, "")
+	if value == "" {
+		return DefaultWaitForImagesTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("bad --wait-for-images-timeout value %q: %w", value, err)
+	}
+
+	if timeout <= 0 {
+		return 0, fmt.Errorf("--wait-for-images-timeout should be positive")
+	}
+
+	return timeout, nil
+}
+
 func GetIntrospectOptions(cmdData *CmdData, werfConfig *config.WerfConfig) (build.IntrospectOptions, error) {
 	isStageExist := func(sName string) bool {
 		for _, stageName := range allStagesNames() {
@@ -1567,6 +1615,18 @@ func SetupChartRepoInsecure(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&cmdData.ChartRepoInsecure, "insecure-helm-dependencies", "", util.GetBoolEnvironmentDefaultFalse("WERF_INSECURE_HELM_DEPENDENCIES"), "Allow insecure oci registries to be used in the Chart.yaml dependencies configuration (default $WERF_INSECURE_HELM_DEPENDENCIES)")
 }
 
+// SetupOutputFormat adds the --output-format flag with the default from the command env variable (e.g. $WERF_DRIFT_OUTPUT_FORMAT).
+// The first of the supported formats is used by default.
+func SetupOutputFormat(cmd *cobra.Command, outputFormat *string, envName string, formats ...string) {
+	defaultOutputFormat := os.Getenv(envName)
+	if defaultOutputFormat == "" {
+		defaultOutputFormat = formats[0]
+	}
+
+	formatsDesc := strings.Join(formats[:len(formats)-1], ", ") + " or " + formats[len(formats)-1]
+	cmd.Flags().StringVarP(outputFormat, "output-format", "", defaultOutputFormat, fmt.Sprintf("Output format: %s ($%s or %s by default)", formatsDesc, envName, formats[0]))
+}
+
 // TODO(v3): remove
 func StubSetupInsecureHelmDependencies(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVar(lo.ToPtr(false), "insecure-helm-dependencies", false, "No-op")
@@ -45,11 +45,11 @@ func GetConveyorOptions(ctx context.Context, commonCmdData *CmdData, imagesToPro
 
 // GetDeferredBuildLog returns true if build log should be catched and printed on error.
 // Default rules are follows:
-// - If --require-built-images is specified catch log and print on error.
+// - If --require-built-images or --wait-for-images is specified catch log and print on error.
 // - Hide log messages if --log-quiet is specified.
 // - Print "live" logs by default or if --log-verbose is specified.
 func GetDeferredBuildLog(ctx context.Context, commonCmdData *CmdData) bool {
-	requireBuiltImage := GetRequireBuiltImages(commonCmdData)
+	requireBuiltImage := GetRequireBuiltImages(commonCmdData) || GetWaitForImages(commonCmdData)
 	isVerbose := logboek.Context(ctx).IsAcceptedLevel(level.Default)
 	return requireBuiltImage || !isVerbose
 }
@@ -84,6 +84,26 @@ func GetShouldBeBuiltOptions(commonCmdData *CmdData, imagesToProcess config.Imag
 	return options, nil
 }
 
+func GetWaitForImagesOptions(commonCmdData *CmdData, imagesToProcess config.ImagesToProcess) (options build.WaitForImagesOptions, err error) {
+	if *commonCmdData.Repo.Address == "" || *commonCmdData.Repo.Address == storage.LocalStorageAddress {
+		return options, fmt.Errorf("images can only be waited for in remote storage: --repo=ADDRESS param required")
+	}
+
+	options.Timeout, err = GetWaitForImagesTimeout(commonCmdData)
+	if err != nil {
+		return options, err
+	}
+
+	options.ShouldBeBuiltOptions, err = GetShouldBeBuiltOptions(commonCmdData, imagesToProcess)
+	if err != nil {
+		return options, err
+	}
+
+	options.PollInterval = WaitForImagesPollInterval
+
+	return options, nil
+}
+
 func GetBuildOptions(ctx context.Context, commonCmdData *CmdData, werfConfig *config.WerfConfig, imagesToProcess config.ImagesToProcess) (buildOptions build.BuildOptions, err error) {
 	introspectOptions, err := GetIntrospectOptions(commonCmdData, werfConfig)
 	if err != nil {
@@ -113,6 +133,12 @@ func GetBuildOptions(ctx context.Context, commonCmdData *CmdData, werfConfig *co
 		}
 	}
 
+	if buildOptions.Provenance = GetProvenance(commonCmdData); buildOptions.Provenance != "" {
+		if *commonCmdData.Repo.Address == "" || *commonCmdData.Repo.Address == storage.LocalStorageAddress {
+			return buildOptions, fmt.Errorf("provenance can only be generated for images in remote storage: --repo=ADDRESS param required")
+		}
+	}
+
 	return buildOptions, nil
 }
 
@@ -125,6 +151,19 @@ func getCustomTagFuncList(tagOptionValues []string, commonCmdData *CmdData, imag
 		return nil, fmt.Errorf("custom tags can only be used with remote storage: --repo=ADDRESS param required")
 	}
 
+	return newCustomTagFuncList(tagOptionValues, imagesToProcess)
+}
+
+// GetAddCustomTagFuncList returns --add-custom-tag functions for the commands, which do not use --repo param.
+func GetAddCustomTagFuncList(commonCmdData *CmdData, imagesToProcess config.ImagesToProcess) ([]image.CustomTagFunc, error) {
+	return newCustomTagFuncList(getCustomTagOptionValues(commonCmdData), imagesToProcess)
+}
+
+func newCustomTagFuncList(tagOptionValues []string, imagesToProcess config.ImagesToProcess) ([]image.CustomTagFunc, error) {
+	if len(tagOptionValues) == 0 {
+		return nil, nil
+	}
+
 	templateName := "--add/use-custom-tag"
 	tmpl := template.New(templateName).Delims("%", "%")
 	tmpl = tmpl.Funcs(map[string]interface{}{
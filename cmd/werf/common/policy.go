@@ -0,0 +1,56 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/deploy/policy"
+)
+
+type SetupPoliciesOptions struct {
+	// WithoutProjectDir is used by commands, which do not work with the project git repository,
+	// policies are read from the local directory and checked only if the directory is specified.
+	WithoutProjectDir bool
+}
+
+func SetupPolicies(cmdData *CmdData, cmd *cobra.Command, opts SetupPoliciesOptions) {
+	if opts.WithoutProjectDir {
+		cmd.Flags().StringVarP(&cmdData.PoliciesDir, "policies-dir", "", os.Getenv("WERF_POLICIES_DIR"), "Local directory with policy files, rules of which are checked against the rendered manifests, policies are not checked if not specified (default $WERF_POLICIES_DIR)")
+	} else {
+		cmd.Flags().StringVarP(&cmdData.PoliciesDir, "policies-dir", "", os.Getenv("WERF_POLICIES_DIR"), "Directory with policy files, rules of which are checked against the rendered manifests (default $WERF_POLICIES_DIR or .werf/policies)")
+	}
+	cmd.Flags().BoolVarP(&cmdData.SkipPolicies, "skip-policies", "", util.GetBoolEnvironmentDefaultFalse("WERF_SKIP_POLICIES"), "Do not check the rendered manifests against the policy rules (default $WERF_SKIP_POLICIES)")
+}
+
+// CheckPolicies checks the rendered resources against the policy rules.
+// The chart is not rendered if there are no rules. The resources are rendered by the ChartResourcesGetter
+// shared with the other checks of the command, because nelm lint, plan and install actions do not return
+// the manifests they render.
+func CheckPolicies(ctx context.Context, cmdData *CmdData, fileReader policy.FileReader, getResources ChartResourcesGetter) error {
+	if cmdData.SkipPolicies {
+		return nil
+	}
+
+	p, err := policy.LoadPolicy(ctx, fileReader, cmdData.PoliciesDir)
+	if err != nil {
+		return fmt.Errorf("load policy: %w", err)
+	}
+
+	if len(p.Rules) == 0 {
+		return nil
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Checking policies").DoError(func() error {
+		resources, err := getResources()
+		if err != nil {
+			return err
+		}
+
+		return policy.ReportViolations(ctx, p.Evaluate(resources))
+	})
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/deploy/stack"
+	"github.com/werf/werf/v2/pkg/true_git"
+	"github.com/werf/werf/v2/pkg/werf"
+)
+
+const DefaultStackReportPath = ".werf-stack-report.json"
+
+func SetupStackConfigPath(cmdData *CmdData, cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&cmdData.StackConfigPath, "stack-config", "", os.Getenv("WERF_STACK_CONFIG"), fmt.Sprintf("Path to the stack config (default $WERF_STACK_CONFIG or %q)", stack.DefaultConfigFileName))
+}
+
+func SetupSaveStackReport(cmdData *CmdData, cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&cmdData.SaveStackReport, "save-stack-report", "", util.GetBoolEnvironmentDefaultFalse("WERF_SAVE_STACK_REPORT"), "Save the combined report of the stack projects with their deploy reports (by default $WERF_SAVE_STACK_REPORT or false). Its path configured with --stack-report-path")
+}
+
+func SetupStackReportPath(cmdData *CmdData, cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&cmdData.StackReportPath, "stack-report-path", "", os.Getenv("WERF_STACK_REPORT_PATH"), fmt.Sprintf("Change stack report path (by default $WERF_STACK_REPORT_PATH or %q if not set)", DefaultStackReportPath))
+}
+
+func GetStackReportPath(cmdData *CmdData) string {
+	if cmdData.StackReportPath == "" {
+		return DefaultStackReportPath
+	}
+	return cmdData.StackReportPath
+}
+
+// RunStack processes the projects of the stack config with the separate werf processes in the order of their dependencies
+// and logs the summary. The error is returned if the stack cannot be processed, the failed projects are reported in the result.
+func RunStack(ctx context.Context, cmdData *CmdData, action stack.Action) (*stack.Result, error) {
+	_, ctx, err := InitCommonComponents(ctx, InitCommonComponentsOptions{
+		Cmd:                cmdData,
+		InitWerf:           true,
+		InitGitDataManager: true,
+		InitTrueGitWithOptions: &InitTrueGitOptions{
+			Options: true_git.Options{LiveGitOutput: *cmdData.LogDebug},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("component init error: %w", err)
+	}
+
+	configPath := cmdData.StackConfigPath
+	if configPath == "" {
+		configPath = stack.DefaultConfigFileName
+	}
+
+	config, err := stack.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	werfPath, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get werf executable path: %w", err)
+	}
+
+	reportDir, err := os.MkdirTemp(werf.GetTmpDir(), "werf-stack-")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create tmp dir: %w", err)
+	}
+	defer os.RemoveAll(reportDir)
+
+	executor := &stack.Executor{
+		Config:      config,
+		Action:      action,
+		WerfPath:    werfPath,
+		Environment: cmdData.Environment,
+		ReportDir:   reportDir,
+		Out:         logboek.Context(ctx).OutStream(),
+	}
+
+	runOpts := stack.RunOptions{
+		Reverse:            action == stack.ActionDismiss,
+		ParallelTasksLimit: 1,
+	}
+	if GetParallel(cmdData) {
+		runOpts.ParallelTasksLimit = int(GetParallelTasksLimit(cmdData))
+	}
+
+	var result *stack.Result
+	logboek.Context(ctx).Default().LogProcess("Running %s for %d stack projects", action, len(config.Projects)).Do(func() {
+		result = stack.Run(ctx, config, runOpts, executor.RunProject)
+	})
+
+	logStackResult(ctx, result)
+
+	return result, nil
+}
+
+func logStackResult(ctx context.Context, result *stack.Result) {
+	logboek.Context(ctx).Default().LogOptionalLn()
+	logboek.Context(ctx).Default().LogLnDetails("Stack projects:")
+
+	for _, p := range result.Projects {
+		line := fmt.Sprintf("  %s: %s", p.Name, p.Status)
+		if p.ChangesPlanned {
+			line += ", changes planned"
+		}
+		if p.Duration != "" {
+			line += fmt.Sprintf(" (%s)", p.Duration)
+		}
+		line += ", " + p.Source
+
+		logboek.Context(ctx).Default().LogLn(line)
+		if p.Error != "" {
+			logboek.Context(ctx).Default().LogLn("    " + strings.ReplaceAll(p.Error, "\n", "\n    "))
+		}
+	}
+}
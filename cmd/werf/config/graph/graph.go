@@ -2,20 +2,35 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/logboek"
 	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/build/image_graph"
 	"github.com/werf/werf/v2/pkg/config"
 	"github.com/werf/werf/v2/pkg/tmp_manager"
 	"github.com/werf/werf/v2/pkg/true_git"
 	"github.com/werf/werf/v2/pkg/werf/global_warnings"
 )
 
+const (
+	outputFormatYAML    = "yaml"
+	outputFormatJSON    = "json"
+	outputFormatDOT     = "dot"
+	outputFormatMermaid = "mermaid"
+)
+
+var cmdData struct {
+	OutputFormat string
+	Stages       bool
+}
+
 var commonCmdData common.CmdData
 
 func NewCmd(ctx context.Context) *cobra.Command {
@@ -46,12 +61,31 @@ func NewCmd(ctx context.Context) *cobra.Command {
       from: baseImage
       import:
       - app1
+
+  # Render the stage graph of the images with Graphviz
+  $ werf config graph --stages --output-format dot | dot -Tsvg > graph.svg
+
+  # Print dependency graph as Mermaid flowchart
+  $ werf config graph --output-format mermaid
+  flowchart LR
+    n0["app1"]
+    n1["app2"]
+    n2["baseImage"]
+    n2 -->|"from"| n0
+    n2 -->|"from"| n1
+    n0 -.->|"import"| n1
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
 			global_warnings.SuppressGlobalWarnings = true
 
+			switch cmdData.OutputFormat {
+			case outputFormatYAML, outputFormatJSON, outputFormatDOT, outputFormatMermaid:
+			default:
+				return fmt.Errorf("unsupported --output-format=%q, expected %s, %s, %s or %s", cmdData.OutputFormat, outputFormatYAML, outputFormatJSON, outputFormatDOT, outputFormatMermaid)
+			}
+
 			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
 				common.PrintHelp(cmd)
 				return err
@@ -107,17 +141,41 @@ func NewCmd(ctx context.Context) *cobra.Command {
 				return err
 			}
 
-			graphList, err := werfConfig.GetImageGraphList(imagesToProcess)
+			// The image graph is printed in YAML and JSON as the list of the images with their dependencies
+			var graph *image_graph.Graph
+			var output interface{}
+			switch {
+			case cmdData.Stages:
+				graph, err = image_graph.NewStageGraph(ctx, werfConfig, imagesToProcess, giterminismManager.FileManager)
+				output = graph
+			case cmdData.OutputFormat == outputFormatYAML || cmdData.OutputFormat == outputFormatJSON:
+				output, err = werfConfig.GetImageGraphList(imagesToProcess)
+			default:
+				graph, err = image_graph.NewImageGraph(werfConfig, imagesToProcess)
+			}
 			if err != nil {
 				return err
 			}
 
-			data, err := yaml.Marshal(graphList)
-			if err != nil {
-				return err
+			switch cmdData.OutputFormat {
+			case outputFormatDOT:
+				fmt.Print(graph.DOT())
+			case outputFormatMermaid:
+				fmt.Print(graph.Mermaid())
+			case outputFormatJSON:
+				data, err := json.MarshalIndent(output, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			default:
+				data, err := yaml.Marshal(output)
+				if err != nil {
+					return err
+				}
+				fmt.Println(strings.TrimSpace(string(data)))
 			}
 
-			fmt.Println(strings.TrimSpace(string(data)))
 			return nil
 		},
 	})
@@ -142,5 +200,8 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	commonCmdData.SetupAllowIncludesUpdate(cmd)
 
+	common.SetupOutputFormat(cmd, &cmdData.OutputFormat, "WERF_CONFIG_GRAPH_OUTPUT_FORMAT", outputFormatYAML, outputFormatJSON, outputFormatDOT, outputFormatMermaid)
+	cmd.Flags().BoolVarP(&cmdData.Stages, "stages", "", util.GetBoolEnvironmentDefaultFalse("WERF_CONFIG_GRAPH_STAGES"), "Expand each image into its stapel or staged Dockerfile stages and show the from, import and dependency edges between the stages (default $WERF_CONFIG_GRAPH_STAGES)")
+
 	return cmd
 }
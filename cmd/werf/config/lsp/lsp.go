@@ -0,0 +1,27 @@
+package lsp
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/config/lsp"
+)
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:                   "lsp",
+		DisableFlagsInUseLine: true,
+		Short:                 GetLspDocs().Short,
+		Annotations: map[string]string{
+			common.DocsLongMD: GetLspDocs().ShortMD,
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return lsp.NewServer(os.Stdin, os.Stdout).Run(cmd.Context())
+		},
+	})
+
+	return cmd
+}
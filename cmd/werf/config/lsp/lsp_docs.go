@@ -0,0 +1,12 @@
+package lsp
+
+import "github.com/werf/werf/v2/cmd/werf/docs/structs"
+
+func GetLspDocs() structs.DocsShortStruct {
+	var docs structs.DocsShortStruct
+
+	docs.Short = "Run the werf.yaml language server over stdio."
+	docs.ShortMD = "Run the `werf.yaml` language server over stdio. The server completes the keys and reports the problems found by the werf config parser. The documents with Go template actions are not checked."
+
+	return docs
+}
@@ -0,0 +1,51 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/config"
+	giterminism_config "github.com/werf/werf/v2/pkg/giterminism_manager/config"
+)
+
+var cmdData struct {
+	Giterminism bool
+}
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:                   "schema",
+		DisableFlagsInUseLine: true,
+		Short:                 GetSchemaDocs().Short,
+		Annotations: map[string]string{
+			common.DocsLongMD: GetSchemaDocs().ShortMD,
+		},
+		Example: `  # Save the schema of werf.yaml
+  $ werf config schema > werf.schema.json
+
+  # Save the schema of werf-giterminism.yaml
+  $ werf config schema --giterminism > werf-giterminism.schema.json`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			var schema interface{} = config.WerfConfigJSONSchema()
+			if cmdData.Giterminism {
+				schema = giterminism_config.JSONSchema()
+			}
+
+			data, err := json.MarshalIndent(schema, "", "  ")
+			if err != nil {
+				return fmt.Errorf("unable to marshal schema: %w", err)
+			}
+
+			fmt.Printf("%s\n", data)
+			return nil
+		},
+	})
+
+	cmd.Flags().BoolVarP(&cmdData.Giterminism, "giterminism", "", false, "Print JSON Schema of werf-giterminism.yaml instead of werf.yaml")
+
+	return cmd
+}
@@ -0,0 +1,12 @@
+package schema
+
+import "github.com/werf/werf/v2/cmd/werf/docs/structs"
+
+func GetSchemaDocs() structs.DocsShortStruct {
+	var docs structs.DocsShortStruct
+
+	docs.Short = "Print JSON Schema of werf.yaml."
+	docs.ShortMD = "Print JSON Schema of `werf.yaml` or `werf-giterminism.yaml`. The schema can be used by the editors to validate and complete the config, e.g. with the `# yaml-language-server: $schema=werf.schema.json` comment."
+
+	return docs
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
@@ -15,6 +16,7 @@ import (
 	"github.com/werf/3p-helm/pkg/engine"
 	"github.com/werf/3p-helm/pkg/werf/file"
 	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/kubedog/pkg/kube"
 	"github.com/werf/logboek"
 	"github.com/werf/nelm/pkg/action"
 	"github.com/werf/nelm/pkg/log"
@@ -23,7 +25,10 @@ import (
 	"github.com/werf/werf/v2/pkg/config"
 	"github.com/werf/werf/v2/pkg/config/deploy_params"
 	"github.com/werf/werf/v2/pkg/container_backend"
+	"github.com/werf/werf/v2/pkg/deploy/audit"
+	"github.com/werf/werf/v2/pkg/deploy/helm"
 	"github.com/werf/werf/v2/pkg/deploy/helm/chart_extender/helpers"
+	"github.com/werf/werf/v2/pkg/deploy/rollout"
 	"github.com/werf/werf/v2/pkg/docker"
 	"github.com/werf/werf/v2/pkg/giterminism_manager"
 	"github.com/werf/werf/v2/pkg/image"
@@ -34,7 +39,9 @@ import (
 )
 
 var cmdData struct {
-	AutoRollback bool
+	AutoRollback       bool
+	ProgressiveRollout bool
+	ReleaseTTL         string
 }
 
 var commonCmdData common.CmdData
@@ -133,6 +140,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 
 	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
 	common.SetupRequireBuiltImages(&commonCmdData, cmd)
+	common.SetupWaitForImages(&commonCmdData, cmd)
 	commonCmdData.SetupPlatform(cmd)
 	common.SetupFollow(&commonCmdData, cmd)
 
@@ -146,6 +154,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 
 	commonCmdData.SetupDebugTemplates(cmd)
 	commonCmdData.SetupAllowIncludesUpdate(cmd)
+	commonCmdData.SetupVerifyProjectHeadCommitSignature(cmd)
 	commonCmdData.SetupSkipImageSpecStage(cmd)
 
 	lo.Must0(common.SetupKubeConnectionFlags(&commonCmdData, cmd))
@@ -156,6 +165,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 
 	common.SetupAddAnnotations(&commonCmdData, cmd)
 	common.SetupAddLabels(&commonCmdData, cmd)
+	common.SetupAuditLog(&commonCmdData, cmd)
 	common.SetupChartProvenanceKeyring(&commonCmdData, cmd)
 	common.SetupChartProvenanceStrategy(&commonCmdData, cmd)
 	common.SetupDefaultDeletePropagation(&commonCmdData, cmd)
@@ -169,6 +179,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	common.SetupNoInstallCRDs(&commonCmdData, cmd)
 	common.SetupNoRemoveManualChanges(&commonCmdData, cmd)
 	common.SetupNoShowNotes(&commonCmdData, cmd)
+	common.SetupPolicies(&commonCmdData, cmd, common.SetupPoliciesOptions{})
 	common.SetupRelease(&commonCmdData, cmd, true)
 	common.SetupReleaseInfoAnnotations(&commonCmdData, cmd)
 	common.SetupReleaseLabel(&commonCmdData, cmd)
@@ -185,9 +196,30 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	cmd.Flags().BoolVarP(&cmdData.AutoRollback, "auto-rollback", "R", util.GetBoolEnvironmentDefaultFalse("WERF_AUTO_ROLLBACK"), "Enable auto rollback of the failed release to the previous deployed release version when current deploy process have failed ($WERF_AUTO_ROLLBACK by default)")
 	cmd.Flags().BoolVarP(&cmdData.AutoRollback, "atomic", "", util.GetBoolEnvironmentDefaultFalse("WERF_ATOMIC"), "Enable auto rollback of the failed release to the previous deployed release version when current deploy process have failed ($WERF_ATOMIC by default)")
 
+	cmd.Flags().BoolVarP(&cmdData.ProgressiveRollout, "progressive-rollout", "", util.GetBoolEnvironmentDefaultFalse("WERF_PROGRESSIVE_ROLLOUT"), "Roll out changed Deployments annotated with werf.io/rollout-strategy (canary or blue-green) step by step with the paired Deployment before deploying the release (default $WERF_PROGRESSIVE_ROLLOUT)")
+
+	cmd.Flags().StringVarP(&cmdData.ReleaseTTL, "release-ttl", "", os.Getenv("WERF_RELEASE_TTL"), "Record the release expiry time (now + TTL, e.g. 72h) in the release metadata, expired releases are deleted by werf dismiss-expired (default $WERF_RELEASE_TTL)")
+
 	return cmd
 }
 
+func getReleaseTTL() (time.Duration, error) {
+	if cmdData.ReleaseTTL == "" {
+		return 0, nil
+	}
+
+	ttl, err := time.ParseDuration(cmdData.ReleaseTTL)
+	if err != nil {
+		return 0, fmt.Errorf("bad --release-ttl value %q: %w", cmdData.ReleaseTTL, err)
+	}
+
+	if ttl < 0 {
+		return 0, fmt.Errorf("--release-ttl should be positive")
+	}
+
+	return ttl, nil
+}
+
 func runMain(ctx context.Context, imageNameListFromArgs []string) error {
 	global_warnings.PostponeMultiwerfNotUpToDateWarning(ctx)
 
@@ -307,7 +339,19 @@ func run(
 		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, giterminismManager, giterminismManager.ProjectDir(), projectTmpDir, containerBackend, storageManager, storageManager.StorageLockManager, conveyorOptions)
 		defer conveyorWithRetry.Terminate()
 
-		if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
+		if common.GetWaitForImages(&commonCmdData) {
+			waitForImagesOptions, err := common.GetWaitForImagesOptions(&commonCmdData, imagesToProcess)
+			if err != nil {
+				return err
+			}
+
+			if err := conveyorWithRetry.WithWaitForImagesBlock(ctx, waitForImagesOptions, func(c *build.Conveyor) error {
+				imagesInfoGetters, err = c.GetImageInfoGetters(image.InfoGetterOptions{CustomTagFunc: useCustomTagFunc})
+				return err
+			}); err != nil {
+				return err
+			}
+		} else if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
 			if common.GetRequireBuiltImages(&commonCmdData) {
 				shouldBeBuiltOptions, err := common.GetShouldBeBuiltOptions(&commonCmdData, imagesToProcess)
 				if err != nil {
@@ -387,6 +431,15 @@ func run(
 	extraRuntimeAnnotations := lo.Assign(commonCmdData.ExtraRuntimeAnnotations, serviceAnnotations)
 	releaseInfoAnnotations := lo.Assign(commonCmdData.ReleaseInfoAnnotations, serviceAnnotations)
 
+	releaseTTL, err := getReleaseTTL()
+	if err != nil {
+		return err
+	}
+
+	if releaseTTL > 0 {
+		releaseInfoAnnotations[helm.ReleaseExpiresAtAnnoName] = helm.GetReleaseExpiresAt(releaseTTL, time.Now())
+	}
+
 	extraLabels, err := common.GetUserExtraLabels(&commonCmdData)
 	if err != nil {
 		return fmt.Errorf("get user extra labels: %w", err)
@@ -433,6 +486,43 @@ func run(
 	})
 	engine.Debug = commonCmdData.DebugTemplates
 
+	chartRenderOptions := common.NewChartRenderOptions(&commonCmdData, action.ChartRenderOptions{
+		ChartAppVersion:            common.GetHelmChartConfigAppVersion(werfConfig),
+		ChartDirPath:               relChartPath,
+		DefaultChartAPIVersion:     chart.APIVersionV2,
+		DefaultChartName:           werfConfig.Meta.Project,
+		DefaultChartVersion:        "1.0.0",
+		ExtraAnnotations:           extraAnnotations,
+		ExtraLabels:                extraLabels,
+		ExtraRuntimeAnnotations:    extraRuntimeAnnotations,
+		LegacyExtraValues:          serviceValues,
+		LegacyLogRegistryStreamOut: os.Stdout,
+		RegistryCredentialsPath:    registryCredentialsPath,
+		ReleaseName:                releaseName,
+		ReleaseNamespace:           releaseNamespace,
+	})
+	getChartResources := common.NewChartResourcesGetter(ctx, chartRenderOptions)
+
+	if err := common.CheckPolicies(ctx, &commonCmdData, giterminismManager.FileReader(), getChartResources); err != nil {
+		return err
+	}
+
+	auditRecord := audit.NewRecord("converge")
+	auditRecord.Project = projectName
+	auditRecord.Release = releaseName
+	auditRecord.Namespace = releaseNamespace
+	auditRecord.Commit = headHash
+	auditRecord.Images = audit.ImagesFromValues(serviceValues)
+	auditOptions := common.WriteAuditRecordOptions{HashValues: true}
+
+	var rollouts []*rollout.Rollout
+	if cmdData.ProgressiveRollout {
+		rollouts, err = runRollouts(ctx, releaseNamespace, getChartResources)
+		if err != nil {
+			return common.WriteAuditRecord(ctx, &commonCmdData, auditRecord, err, auditOptions)
+		}
+	}
+
 	if err := action.ReleaseInstall(ctx, releaseName, releaseNamespace, action.ReleaseInstallOptions{
 		KubeConnectionOptions:       commonCmdData.KubeConnectionOptions,
 		ChartRepoConnectionOptions:  commonCmdData.ChartRepoConnectionOptions,
@@ -472,8 +562,70 @@ func run(
 		ShowSubchartNotes:           commonCmdData.ShowSubchartNotes,
 		TemplatesAllowDNS:           commonCmdData.TemplatesAllowDNS,
 	}); err != nil {
-		return fmt.Errorf("release install: %w", err)
+		abortRollouts(ctx, rollouts)
+		return common.WriteAuditRecord(ctx, &commonCmdData, auditRecord, fmt.Errorf("release install: %w", err), auditOptions)
+	}
+
+	return common.WriteAuditRecord(ctx, &commonCmdData, auditRecord, finishRollouts(ctx, rollouts), auditOptions)
+}
+
+func runRollouts(ctx context.Context, releaseNamespace string, getChartResources common.ChartResourcesGetter) ([]*rollout.Rollout, error) {
+	var rollouts []*rollout.Rollout
+	if err := logboek.Context(ctx).Default().LogProcess("Running progressive rollouts").DoError(func() error {
+		resources, err := getChartResources()
+		if err != nil {
+			return err
+		}
+
+		rollouts, err = rollout.NewRollouts(resources, releaseNamespace)
+		if err != nil {
+			return err
+		}
+
+		if len(rollouts) == 0 {
+			return nil
+		}
+
+		common.SetupOndemandKubeInitializer(commonCmdData.KubeContextCurrent, commonCmdData.LegacyKubeConfigPath, commonCmdData.KubeConfigBase64, commonCmdData.LegacyKubeConfigPathsMergeList)
+		if err := common.GetOndemandKubeInitializer().Init(ctx); err != nil {
+			return err
+		}
+
+		for _, r := range rollouts {
+			if err := r.Run(ctx, kube.Client, rollout.RunOptions{}); err != nil {
+				abortRollouts(ctx, rollouts)
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return rollouts, nil
+}
+
+func finishRollouts(ctx context.Context, rollouts []*rollout.Rollout) error {
+	if len(rollouts) == 0 {
+		return nil
 	}
 
-	return nil
+	return logboek.Context(ctx).Default().LogProcess("Finishing progressive rollouts").DoError(func() error {
+		for _, r := range rollouts {
+			if err := r.Finish(ctx, kube.Client, rollout.RunOptions{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// abortRollouts returns the stable Deployments to their state before the rollout, errors are only logged.
+func abortRollouts(ctx context.Context, rollouts []*rollout.Rollout) {
+	for _, r := range rollouts {
+		if err := r.Abort(ctx, kube.Client); err != nil {
+			logboek.Context(ctx).Warn().LogF("WARNING: Unable to abort Deployment %s/%s rollout: %s\n", r.Namespace, r.Name, err)
+		}
+	}
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/werf/nelm/pkg/log"
 	"github.com/werf/werf/v2/cmd/werf/common"
 	"github.com/werf/werf/v2/pkg/config/deploy_params"
+	"github.com/werf/werf/v2/pkg/deploy/audit"
 	"github.com/werf/werf/v2/pkg/giterminism_manager"
 	"github.com/werf/werf/v2/pkg/tmp_manager"
 	"github.com/werf/werf/v2/pkg/true_git"
@@ -115,6 +116,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	lo.Must0(common.SetupKubeConnectionFlags(&commonCmdData, cmd))
 	lo.Must0(common.SetupTrackingFlags(&commonCmdData, cmd))
 
+	common.SetupAuditLog(&commonCmdData, cmd)
 	common.SetupDeployReportPath(&commonCmdData, cmd)
 	common.SetupNamespace(&commonCmdData, cmd, true)
 	common.SetupNetworkParallelism(&commonCmdData, cmd)
@@ -141,6 +143,12 @@ func NewCmd(ctx context.Context) *cobra.Command {
 }
 
 func runDismiss(ctx context.Context) error {
+	if cmdData.WithNamespace {
+		if err := common.ValidateAuditLogForNamespaceDeletion(&commonCmdData); err != nil {
+			return fmt.Errorf("--with-namespace: %w", err)
+		}
+	}
+
 	_, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
 		Cmd: &commonCmdData,
 		InitTrueGitWithOptions: &common.InitTrueGitOptions{
@@ -185,6 +193,15 @@ func runDismiss(ctx context.Context) error {
 		ColorMode: *commonCmdData.LogColorMode,
 	})
 
+	auditRecord := audit.NewRecord("dismiss")
+	auditRecord.Release = releaseName
+	auditRecord.Namespace = releaseNamespace
+	common.SetAuditRecordRelease(ctx, &commonCmdData, auditRecord)
+
+	return common.WriteAuditRecord(ctx, &commonCmdData, auditRecord, uninstallRelease(ctx, releaseName, releaseNamespace), common.WriteAuditRecordOptions{})
+}
+
+func uninstallRelease(ctx context.Context, releaseName, releaseNamespace string) error {
 	if util.GetBoolEnvironmentDefaultFalse("WERF_EXPERIMENT_NEW_DISMISS") {
 		var uninstallReportPath string
 		if commonCmdData.SaveUninstallReport {
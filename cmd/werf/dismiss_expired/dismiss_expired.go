@@ -0,0 +1,154 @@
+package dismiss_expired
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm/pkg/action"
+	"github.com/werf/nelm/pkg/log"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/deploy/helm"
+	"github.com/werf/werf/v2/pkg/tmp_manager"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+var cmdData struct {
+	NamespaceSelector string
+	WithNamespace     bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "dismiss-expired",
+		Short: "Delete expired werf releases from Kubernetes",
+		Long: common.GetLongCommandDescription(`Delete werf releases deployed with werf converge --release-ttl, which TTL has expired, along with their namespaces.
+
+All contexts from the kube config are scanned (or only the context specified with option --kube-context) in the same way as werf cleanup scans them for used images. The namespaces to scan can be limited with the --namespace-selector.`),
+		Example: `  # Deploy the review release, which expires in 3 days:
+  $ werf converge --env review-42 --release-ttl 72h
+
+  # Delete expired review releases along with their namespaces:
+  $ werf dismiss-expired --namespace-selector app.kubernetes.io/part-of=review`,
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			defer global_warnings.PrintGlobalWarnings(ctx)
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runDismissExpired(ctx)
+			})
+		},
+	})
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	common.SetupDryRun(&commonCmdData, cmd)
+
+	lo.Must0(common.SetupKubeConnectionFlags(&commonCmdData, cmd))
+	lo.Must0(common.SetupTrackingFlags(&commonCmdData, cmd))
+
+	common.SetupNetworkParallelism(&commonCmdData, cmd)
+	common.SetupReleaseStorageDriver(&commonCmdData, cmd)
+	common.SetupReleasesHistoryMax(&commonCmdData, cmd)
+
+	common.SetupScanContextNamespaceOnly(&commonCmdData, cmd)
+	cmd.Flags().StringVarP(&cmdData.NamespaceSelector, "namespace-selector", "", os.Getenv("WERF_NAMESPACE_SELECTOR"), "Scan for expired releases only in namespaces matching the label selector, e.g. env=review (default $WERF_NAMESPACE_SELECTOR)")
+	cmd.Flags().BoolVarP(&cmdData.WithNamespace, "with-namespace", "", util.GetBoolEnvironmentDefaultTrue("WERF_WITH_NAMESPACE"), "Delete Kubernetes Namespace after purging Helm Release (default $WERF_WITH_NAMESPACE or true)")
+
+	return cmd
+}
+
+func runDismissExpired(ctx context.Context) error {
+	_, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
+		Cmd:      &commonCmdData,
+		InitWerf: true,
+	})
+	if err != nil {
+		return fmt.Errorf("component init error: %w", err)
+	}
+
+	defer func() {
+		if err := tmp_manager.DelegateCleanup(ctx); err != nil {
+			logboek.Context(ctx).Warn().LogF("Temporary files cleanup preparation failed: %s\n", err)
+		}
+	}()
+
+	kubernetesContextClients, err := common.GetKubernetesContextClients(commonCmdData.LegacyKubeConfigPath, commonCmdData.KubeConfigBase64, commonCmdData.LegacyKubeConfigPathsMergeList, commonCmdData.KubeContextCurrent)
+	if err != nil {
+		return fmt.Errorf("unable to get Kubernetes clusters connections: %w", err)
+	}
+
+	kubernetesNamespaceRestrictionByContext := common.GetKubernetesNamespaceRestrictionByContext(&commonCmdData, kubernetesContextClients)
+
+	ctx = log.SetupLogging(ctx, cmp.Or(common.GetNelmLogLevel(&commonCmdData), action.DefaultLegacyReleaseUninstallLogLevel), log.SetupLoggingOptions{
+		ColorMode: *commonCmdData.LogColorMode,
+	})
+
+	now := time.Now()
+
+	for _, contextClient := range kubernetesContextClients {
+		var expiredReleases []*helm.ExpiredRelease
+		if err := logboek.Context(ctx).LogProcessInline("Getting expired releases (context %s)", contextClient.ContextName).DoError(func() error {
+			expiredReleases, err = helm.GetExpiredReleases(ctx, contextClient.Client, helm.GetExpiredReleasesOptions{
+				RestrictionNamespace: kubernetesNamespaceRestrictionByContext[contextClient.ContextName],
+				NamespaceSelector:    cmdData.NamespaceSelector,
+				ReleaseStorageDriver: commonCmdData.ReleaseStorageDriver,
+				Now:                  now,
+			})
+			return err
+		}); err != nil {
+			return fmt.Errorf("cannot get expired releases: %w", err)
+		}
+
+		kubeConnectionOptions := commonCmdData.KubeConnectionOptions
+		// Single context is used as is, so the in-cluster config keeps working.
+		if len(kubernetesContextClients) > 1 {
+			kubeConnectionOptions.KubeContextCurrent = contextClient.ContextName
+		}
+
+		for _, rel := range expiredReleases {
+			logboek.Context(ctx).Default().LogF("Release %q in namespace %q (context %s) expired at %s\n", rel.Name, rel.Namespace, contextClient.ContextName, rel.ExpiresAt.Format(time.RFC3339))
+
+			if *commonCmdData.DryRun {
+				continue
+			}
+
+			if err := action.LegacyReleaseUninstall(ctx, rel.Name, rel.Namespace, action.LegacyReleaseUninstallOptions{
+				KubeConnectionOptions:  kubeConnectionOptions,
+				TrackingOptions:        commonCmdData.TrackingOptions,
+				DeleteReleaseNamespace: cmdData.WithNamespace,
+				NetworkParallelism:     commonCmdData.NetworkParallelism,
+				ReleaseHistoryLimit:    commonCmdData.ReleaseHistoryLimit,
+				ReleaseStorageDriver:   commonCmdData.ReleaseStorageDriver,
+			}); err != nil {
+				return fmt.Errorf("release %q in namespace %q uninstall: %w", rel.Name, rel.Namespace, err)
+			}
+		}
+	}
+
+	return nil
+}
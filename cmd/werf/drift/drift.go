@@ -0,0 +1,487 @@
+package drift
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/engine"
+	"github.com/werf/3p-helm/pkg/werf/file"
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/kubedog/pkg/kube"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm/pkg/action"
+	"github.com/werf/nelm/pkg/log"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/build"
+	"github.com/werf/werf/v2/pkg/config"
+	"github.com/werf/werf/v2/pkg/config/deploy_params"
+	"github.com/werf/werf/v2/pkg/container_backend"
+	"github.com/werf/werf/v2/pkg/deploy/drift"
+	"github.com/werf/werf/v2/pkg/deploy/helm/chart_extender/helpers"
+	"github.com/werf/werf/v2/pkg/docker"
+	"github.com/werf/werf/v2/pkg/giterminism_manager"
+	"github.com/werf/werf/v2/pkg/image"
+	"github.com/werf/werf/v2/pkg/storage"
+	"github.com/werf/werf/v2/pkg/tmp_manager"
+	"github.com/werf/werf/v2/pkg/true_git"
+	"github.com/werf/werf/v2/pkg/werf"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+)
+
+var cmdData struct {
+	OutputFormat     string
+	DetailedExitCode bool
+}
+
+var commonCmdData common.CmdData
+
+func isSpecificImagesEnabled() bool {
+	return util.GetBoolEnvironmentDefaultFalse("WERF_CONVERGE_ENABLE_IMAGES_PARAMS")
+}
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+
+	var useMsg string
+	if isSpecificImagesEnabled() {
+		useMsg = "drift [IMAGE_NAME ...]"
+	} else {
+		useMsg = "drift"
+	}
+
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   useMsg,
+		Short: "Compare live state of the release resources in a Kubernetes cluster with the chart and show manual changes",
+		Long: common.GetLongCommandDescription(`Render the chart exactly as werf converge does and compare each resource with the live object in the Kubernetes cluster.
+
+Only fields of the rendered manifests are compared, so the defaults and the fields populated by the server are ignored. Fields added manually with kubectl (edit, patch, annotate, etc.), which are not removed on deploy with --no-remove-manual-changes, are reported as well. Hooks are not compared.
+
+Use --exit-code in a scheduled job to detect manual changes of the release resources in the cluster.`),
+		Example: `# Show manual changes of the production release resources
+werf drift --repo registry.mydomain.com/web --env production
+
+# Fail if the release resources have been changed manually
+werf drift --repo registry.mydomain.com/web --env production --require-built-images --exit-code --output-format json`,
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfDebugAnsibleArgs, common.WerfSecretKey),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			// Stdout is reserved for the JSON drift, so the logs are written to stderr.
+			if cmdData.OutputFormat == outputFormatJSON {
+				ctx = logboek.NewContext(ctx, logboek.Context(ctx).NewSubLogger(os.Stderr, os.Stderr))
+			}
+
+			defer global_warnings.PrintGlobalWarnings(ctx)
+
+			logboek.Context(ctx).LogF("Version: %s\n", werf.Version)
+
+			start := time.Now()
+			defer func() {
+				logboek.Context(ctx).Default().LogFHighlight("Running time %0.2f seconds\n", time.Since(start).Seconds())
+			}()
+
+			var imageNameListFromArgs []string
+			if isSpecificImagesEnabled() {
+				imageNameListFromArgs = args
+			}
+
+			return runMain(ctx, imageNameListFromArgs)
+		},
+	})
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigRenderPath(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupGiterminismConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupIntrospectAfterError(&commonCmdData, cmd)
+	common.SetupIntrospectBeforeError(&commonCmdData, cmd)
+	common.SetupIntrospectStage(&commonCmdData, cmd)
+
+	common.SetupSecondaryStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupCacheStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupRepoOptions(&commonCmdData, cmd, common.RepoDataOptions{OptionalRepo: true})
+	common.SetupFinalRepo(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified repo, to pull base images")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupContainerRegistryMirror(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+
+	commonCmdData.SetupWithoutImages(cmd)
+	commonCmdData.SetupFinalImagesOnly(cmd, true)
+	common.SetupStubTags(&commonCmdData, cmd)
+
+	common.SetupSaveBuildReport(&commonCmdData, cmd)
+	common.SetupBuildReportPath(&commonCmdData, cmd)
+
+	common.SetupUseCustomTag(&commonCmdData, cmd)
+	common.SetupAddCustomTag(&commonCmdData, cmd)
+	common.SetupVirtualMerge(&commonCmdData, cmd)
+
+	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
+	common.SetupRequireBuiltImages(&commonCmdData, cmd)
+	commonCmdData.SetupPlatform(cmd)
+
+	common.SetupDisableAutoHostCleanup(&commonCmdData, cmd)
+	common.SetupAllowedBackendStorageVolumeUsage(&commonCmdData, cmd)
+	common.SetupAllowedBackendStorageVolumeUsageMargin(&commonCmdData, cmd)
+	common.SetupAllowedLocalCacheVolumeUsage(&commonCmdData, cmd)
+	common.SetupAllowedLocalCacheVolumeUsageMargin(&commonCmdData, cmd)
+	common.SetupBackendStoragePath(&commonCmdData, cmd)
+	common.SetupProjectName(&commonCmdData, cmd, false)
+
+	commonCmdData.SetupSkipImageSpecStage(cmd)
+	commonCmdData.SetupDebugTemplates(cmd)
+	commonCmdData.SetupAllowIncludesUpdate(cmd)
+
+	lo.Must0(common.SetupKubeConnectionFlags(&commonCmdData, cmd))
+	lo.Must0(common.SetupChartRepoConnectionFlags(&commonCmdData, cmd))
+	lo.Must0(common.SetupValuesFlags(&commonCmdData, cmd))
+	lo.Must0(common.SetupSecretValuesFlags(&commonCmdData, cmd))
+
+	common.SetupAddAnnotations(&commonCmdData, cmd)
+	common.SetupAddLabels(&commonCmdData, cmd)
+	common.SetupChartProvenanceKeyring(&commonCmdData, cmd)
+	common.SetupChartProvenanceStrategy(&commonCmdData, cmd)
+	common.SetupForceAdoption(&commonCmdData, cmd)
+	common.SetupNamespace(&commonCmdData, cmd, true)
+	common.SetupNetworkParallelism(&commonCmdData, cmd)
+	common.SetupRelease(&commonCmdData, cmd, true)
+	common.SetupReleaseStorageDriver(&commonCmdData, cmd)
+	common.SetupReleaseStorageSQLConnection(&commonCmdData, cmd)
+	common.SetupSetDockerConfigJsonValue(&commonCmdData, cmd)
+	common.SetupTemplatesAllowDNS(&commonCmdData, cmd)
+	commonCmdData.SetupSkipDependenciesRepoRefresh(cmd)
+
+	common.SetupOutputFormat(cmd, &cmdData.OutputFormat, "WERF_DRIFT_OUTPUT_FORMAT", outputFormatText, outputFormatJSON)
+	cmd.Flags().BoolVarP(&cmdData.DetailedExitCode, "exit-code", "", util.GetBoolEnvironmentDefaultFalse("WERF_EXIT_CODE"), "If true, returns exit code 0 if no drift, exit code 2 if the release resources have been changed in the cluster or exit code 1 in case of an error (default $WERF_EXIT_CODE or false)")
+
+	return cmd
+}
+
+func runMain(ctx context.Context, imageNameListFromArgs []string) error {
+	global_warnings.PostponeMultiwerfNotUpToDateWarning(ctx)
+
+	switch cmdData.OutputFormat {
+	case outputFormatText, outputFormatJSON:
+	default:
+		return fmt.Errorf("unsupported --output-format=%q, expected %s or %s", cmdData.OutputFormat, outputFormatText, outputFormatJSON)
+	}
+
+	commonManager, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
+		Cmd: &commonCmdData,
+		InitTrueGitWithOptions: &common.InitTrueGitOptions{
+			Options: true_git.Options{LiveGitOutput: *commonCmdData.LogDebug},
+		},
+		InitDockerRegistry:          true,
+		InitProcessContainerBackend: true,
+		InitWerf:                    true,
+		InitGitDataManager:          true,
+		InitManifestCache:           true,
+		InitLRUImagesCache:          true,
+		InitSSHAgent:                true,
+	})
+	if err != nil {
+		return fmt.Errorf("component init error: %w", err)
+	}
+
+	defer func() {
+		if err := tmp_manager.DelegateCleanup(ctx); err != nil {
+			logboek.Context(ctx).Warn().LogF("Temporary files cleanup preparation failed: %s\n", err)
+		}
+	}()
+
+	containerBackend := commonManager.ContainerBackend()
+
+	defer func() {
+		if err := common.RunAutoHostCleanup(ctx, &commonCmdData, containerBackend); err != nil {
+			logboek.Context(ctx).Error().LogF("Auto host cleanup failed: %s\n", err)
+		}
+	}()
+
+	defer func() {
+		commonManager.TerminateSSHAgent()
+	}()
+
+	giterminismManager, err := common.GetGiterminismManager(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, giterminismManager.ProjectDir())
+
+	return run(ctx, containerBackend, giterminismManager, imageNameListFromArgs)
+}
+
+func run(
+	ctx context.Context,
+	containerBackend container_backend.ContainerBackend,
+	giterminismManager *giterminism_manager.Manager,
+	imageNameListFromArgs []string,
+) error {
+	werfConfigPath, werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %w", err)
+	}
+
+	imagesToProcess, err := config.NewImagesToProcess(werfConfig, imageNameListFromArgs, *commonCmdData.FinalImagesOnly, *commonCmdData.WithoutImages)
+	if err != nil {
+		return err
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %w", err)
+	}
+
+	buildOptions, err := common.GetBuildOptions(ctx, &commonCmdData, werfConfig, imagesToProcess)
+	if err != nil {
+		return err
+	}
+
+	var imagesInfoGetters []*image.InfoGetter
+	var imagesRepository string
+	var isStub bool
+	var stubImageNameList []string
+
+	addr, err := commonCmdData.Repo.GetAddress()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case imagesToProcess.WithoutImages:
+	case *commonCmdData.StubTags || addr == storage.LocalStorageAddress:
+		imagesRepository = "REPO"
+		isStub = true
+		stubImageNameList = append(stubImageNameList, imagesToProcess.FinalImageNameList...)
+	default:
+		logboek.Context(ctx).LogOptionalLn()
+		common.SetupOndemandKubeInitializer(commonCmdData.KubeContextCurrent, commonCmdData.LegacyKubeConfigPath, commonCmdData.KubeConfigBase64, commonCmdData.LegacyKubeConfigPathsMergeList)
+		if err := common.GetOndemandKubeInitializer().Init(ctx); err != nil {
+			return err
+		}
+
+		useCustomTagFunc, err := common.GetUseCustomTagFunc(&commonCmdData, giterminismManager, imagesToProcess)
+		if err != nil {
+			return err
+		}
+
+		storageManager, err := common.NewStorageManager(ctx, &common.NewStorageManagerConfig{
+			ProjectName:                    projectName,
+			ContainerBackend:               containerBackend,
+			CmdData:                        &commonCmdData,
+			CleanupDisabled:                werfConfig.Meta.Cleanup.DisableCleanup,
+			GitHistoryBasedCleanupDisabled: werfConfig.Meta.Cleanup.DisableGitHistoryBasedPolicy,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to init storage manager: %w", err)
+		}
+
+		imagesRepository = storageManager.GetServiceValuesRepo()
+
+		conveyorOptions, err := common.GetConveyorOptionsWithParallel(ctx, &commonCmdData, imagesToProcess, buildOptions)
+		if err != nil {
+			return err
+		}
+
+		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, giterminismManager, giterminismManager.ProjectDir(), projectTmpDir, containerBackend, storageManager, storageManager.StorageLockManager, conveyorOptions)
+		defer conveyorWithRetry.Terminate()
+
+		if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
+			if common.GetRequireBuiltImages(&commonCmdData) {
+				shouldBeBuiltOptions, err := common.GetShouldBeBuiltOptions(&commonCmdData, imagesToProcess)
+				if err != nil {
+					return err
+				}
+
+				if _, err := c.ShouldBeBuilt(ctx, shouldBeBuiltOptions); err != nil {
+					return err
+				}
+			} else {
+				if _, err := c.Build(ctx, buildOptions); err != nil {
+					return err
+				}
+			}
+
+			imagesInfoGetters, err = c.GetImageInfoGetters(image.InfoGetterOptions{CustomTagFunc: useCustomTagFunc})
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		logboek.Context(ctx).LogOptionalLn()
+	}
+
+	relChartPath, err := common.GetHelmChartDir(
+		werfConfigPath,
+		werfConfig,
+		giterminismManager,
+	)
+	if err != nil {
+		return fmt.Errorf("get relative helm chart directory: %w", err)
+	}
+
+	releaseNamespace, err := deploy_params.GetKubernetesNamespace(
+		commonCmdData.Namespace,
+		commonCmdData.Environment,
+		werfConfig,
+	)
+	if err != nil {
+		return fmt.Errorf("get kubernetes namespace: %w", err)
+	}
+
+	releaseName, err := deploy_params.GetHelmRelease(
+		commonCmdData.Release,
+		commonCmdData.Environment,
+		releaseNamespace,
+		werfConfig,
+	)
+	if err != nil {
+		return fmt.Errorf("get helm release: %w", err)
+	}
+
+	// Service annotations are runtime annotations, which change on each deploy, so they are not compared.
+	extraAnnotations := map[string]string{}
+	if annos, err := common.GetUserExtraAnnotations(&commonCmdData); err != nil {
+		return fmt.Errorf("get user extra annotations: %w", err)
+	} else {
+		for key, value := range annos {
+			if !strings.HasPrefix(key, "project.werf.io/") &&
+				!strings.Contains(key, "ci.werf.io/") &&
+				key != "werf.io/release-channel" {
+				extraAnnotations[key] = value
+			}
+		}
+	}
+
+	extraLabels, err := common.GetUserExtraLabels(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("get user extra labels: %w", err)
+	}
+
+	headHash, err := giterminismManager.LocalGitRepo().HeadCommitHash(ctx)
+	if err != nil {
+		return fmt.Errorf("get HEAD commit hash: %w", err)
+	}
+
+	headTime, err := giterminismManager.LocalGitRepo().HeadCommitTime(ctx)
+	if err != nil {
+		return fmt.Errorf("get HEAD commit time: %w", err)
+	}
+
+	registryCredentialsPath := docker.GetDockerConfigCredentialsFile(*commonCmdData.DockerConfig)
+
+	serviceValues, err := helpers.GetServiceValues(ctx, werfConfig.Meta.Project, imagesRepository, imagesInfoGetters, helpers.ServiceValuesOptions{
+		Namespace:                releaseNamespace,
+		Env:                      commonCmdData.Environment,
+		IsStub:                   isStub,
+		DisableEnvStub:           true,
+		StubImageNameList:        stubImageNameList,
+		SetDockerConfigJsonValue: *commonCmdData.SetDockerConfigJsonValue,
+		DockerConfigPath:         filepath.Dir(registryCredentialsPath),
+		CommitHash:               headHash,
+		CommitDate:               headTime,
+	})
+	if err != nil {
+		return fmt.Errorf("get service values: %w", err)
+	}
+
+	file.ChartFileReader = giterminismManager.FileManager
+
+	ctx = log.SetupLogging(ctx, cmp.Or(common.GetNelmLogLevel(&commonCmdData), action.DefaultChartRenderLogLevel), log.SetupLoggingOptions{
+		ColorMode: *commonCmdData.LogColorMode,
+	})
+	engine.Debug = commonCmdData.DebugTemplates
+
+	resources, err := common.RenderChartResources(ctx, common.NewChartRenderOptions(&commonCmdData, action.ChartRenderOptions{
+		ChartAppVersion:            common.GetHelmChartConfigAppVersion(werfConfig),
+		ChartDirPath:               relChartPath,
+		DefaultChartAPIVersion:     chart.APIVersionV2,
+		DefaultChartName:           werfConfig.Meta.Project,
+		DefaultChartVersion:        "1.0.0",
+		ExtraAnnotations:           extraAnnotations,
+		ExtraLabels:                extraLabels,
+		LegacyExtraValues:          serviceValues,
+		LegacyLogRegistryStreamOut: logboek.Context(ctx).OutStream(),
+		RegistryCredentialsPath:    registryCredentialsPath,
+		ReleaseName:                releaseName,
+		ReleaseNamespace:           releaseNamespace,
+	}))
+	if err != nil {
+		return err
+	}
+
+	common.SetupOndemandKubeInitializer(commonCmdData.KubeContextCurrent, commonCmdData.LegacyKubeConfigPath, commonCmdData.KubeConfigBase64, commonCmdData.LegacyKubeConfigPathsMergeList)
+	if err := common.GetOndemandKubeInitializer().Init(ctx); err != nil {
+		return err
+	}
+
+	releaseDrift, err := drift.Detect(ctx, resources, drift.NewGetLiveFunc(kube.DynamicClient, kube.Mapper, releaseNamespace))
+	if err != nil {
+		return fmt.Errorf("detect drift: %w", err)
+	}
+
+	switch cmdData.OutputFormat {
+	case outputFormatJSON:
+		data, err := json.MarshalIndent(releaseDrift, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal drift: %w", err)
+		}
+		fmt.Printf("%s\n", data)
+	default:
+		if err := releaseDrift.WriteText(os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	if cmdData.DetailedExitCode && releaseDrift.HasDrift() {
+		return action.ErrChangesPlanned
+	}
+
+	return nil
+}
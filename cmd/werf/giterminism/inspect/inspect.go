@@ -0,0 +1,206 @@
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/base_images"
+	"github.com/werf/werf/v2/pkg/config"
+	"github.com/werf/werf/v2/pkg/giterminism_inspect"
+	"github.com/werf/werf/v2/pkg/giterminism_manager"
+	"github.com/werf/werf/v2/pkg/includes"
+	"github.com/werf/werf/v2/pkg/path_matcher"
+	"github.com/werf/werf/v2/pkg/tmp_manager"
+	"github.com/werf/werf/v2/pkg/true_git"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+)
+
+var cmdData struct {
+	OutputFormat string
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:                   "inspect",
+		DisableFlagsInUseLine: true,
+		Short:                 "Report the inputs of the build outside of the git repository and the giterminism rules permitting them.",
+		Long: `Report the inputs of the build outside of the git repository and the giterminism rules permitting them.
+
+The command renders werf.yaml, walks all images and reports at once the env variables used in the templates, build secrets, contextAddFiles, uncommitted files read due to the allowUncommitted* directives, mounts from the host, base images not pinned to digests and includes with branch refs. Each input is reported with the werf-giterminism.yaml directive, the lock file or the option permitting it. The not permitted inputs fail the build.`,
+		Example: `  # Print the table of the inputs
+  $ werf giterminism inspect
+
+  # Print the inputs in JSON
+  $ werf giterminism inspect --output-format=json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			global_warnings.SuppressGlobalWarnings = true
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			switch cmdData.OutputFormat {
+			case outputFormatText, outputFormatJSON:
+			default:
+				common.PrintHelp(cmd)
+				return fmt.Errorf("unsupported --output-format=%q, expected %s or %s", cmdData.OutputFormat, outputFormatText, outputFormatJSON)
+			}
+
+			_, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
+				Cmd:                &commonCmdData,
+				InitWerf:           true,
+				InitGitDataManager: true,
+				InitTrueGitWithOptions: &common.InitTrueGitOptions{
+					Options: true_git.Options{LiveGitOutput: *commonCmdData.LogDebug},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("component init error: %w", err)
+			}
+
+			defer func() {
+				if err := tmp_manager.DelegateCleanup(ctx); err != nil {
+					logboek.Context(ctx).Warn().LogF("Temporary files cleanup preparation failed: %s\n", err)
+				}
+			}()
+
+			return runInspect(ctx)
+		},
+	})
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigRenderPath(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupGiterminismConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	commonCmdData.SetupDebugTemplates(cmd)
+	commonCmdData.SetupAllowIncludesUpdate(cmd)
+
+	common.SetupOutputFormat(cmd, &cmdData.OutputFormat, "WERF_GITERMINISM_INSPECT_OUTPUT_FORMAT", outputFormatText, outputFormatJSON)
+
+	return cmd
+}
+
+func runInspect(ctx context.Context) error {
+	giterminismManager, err := common.GetGiterminismManager(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	collector := &giterminism_inspect.Collector{
+		Rules:            giterminismManager.Config(),
+		LooseGiterminism: giterminismManager.LooseGiterminism(),
+		Dev:              giterminismManager.Dev(),
+		ReadDockerfile:   giterminismManager.FileManager.ReadDockerfile,
+	}
+
+	if collector.BaseImagesLock, err = readBaseImagesLock(ctx, giterminismManager); err != nil {
+		return err
+	}
+
+	giterminismManager.SetInspector(collector.Inspector(giterminismManager.Inspector()))
+
+	customWerfConfigRelPath, err := common.GetCustomWerfConfigRelPath(giterminismManager, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	customWerfConfigTemplatesDirRelPath, err := common.GetCustomWerfConfigTemplatesDirRelPath(giterminismManager, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	customWerfConfigRenderPath, err := common.GetCustomWerfConfigRenderPath(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	werfConfigRelPath, werfConfig, err := config.GetWerfConfig(ctx, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath, customWerfConfigRenderPath, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+	if err != nil {
+		return err
+	}
+
+	if err := collector.AddImages(ctx, werfConfig); err != nil {
+		return err
+	}
+
+	uncommittedFiles, err := uncommittedFileList(ctx, giterminismManager)
+	if err != nil {
+		return err
+	}
+	collector.AddUncommittedFiles(werfConfigRelPath, uncommittedFiles)
+
+	includesConfig, err := includes.NewConfig(ctx, giterminismManager.FileReader(), includes.GetWerfIncludesConfigRelPath(), false)
+	if err != nil {
+		return err
+	}
+	collector.AddIncludes(includesConfig, commonCmdData.AllowIncludesUpdate)
+
+	if cmdData.OutputFormat == outputFormatJSON {
+		return giterminism_inspect.WriteJSON(os.Stdout, collector.Inputs())
+	}
+	return giterminism_inspect.WriteTable(os.Stdout, collector.Inputs())
+}
+
+func readBaseImagesLock(ctx context.Context, giterminismManager *giterminism_manager.Manager) (*base_images.Lock, error) {
+	exist, err := giterminismManager.FileReader().IsBaseImagesLockFileExistAnywhere(ctx, base_images.DefaultLockFileName)
+	if err != nil || !exist {
+		return nil, err
+	}
+
+	data, err := giterminismManager.FileReader().ReadBaseImagesLockFile(ctx, base_images.DefaultLockFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := base_images.ParseLock(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", base_images.DefaultLockFileName, err)
+	}
+
+	return lock, nil
+}
+
+// uncommittedFileList returns the uncommitted and untracked files of the project directory relative to it.
+func uncommittedFileList(ctx context.Context, giterminismManager *giterminism_manager.Manager) ([]string, error) {
+	relToGitProjectDir := giterminismManager.RelativeToGitProjectDir()
+
+	list, err := giterminismManager.LocalGitRepo().StatusPathList(ctx, path_matcher.NewPathMatcher(path_matcher.PathMatcherOptions{BasePath: relToGitProjectDir}))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get git status: %w", err)
+	}
+
+	var result []string
+	for _, relToGitPath := range list {
+		result = append(result, util.GetRelativeToBaseFilepath(relToGitProjectDir, relToGitPath))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package outdated
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/includes"
+	"github.com/werf/werf/v2/pkg/tmp_manager"
+	"github.com/werf/werf/v2/pkg/true_git"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+)
+
+var cmdData struct {
+	OutputFormat string
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "outdated [SOURCE...]",
+		Short: "List the locked and the latest versions of the includes.",
+		Long:  "List the locked version of each include in the includes lock file (default: werf-includes.lock) against the latest one matching the ref of the includes config. For the git includes the new commits and the changed files matched by the includes are listed as well.",
+		Example: `
+  # List all includes
+  $ werf includes outdated
+
+  # List the includes from the specified repository in JSON
+  $ werf includes outdated --output-format json https://github.com/werf/common-config
+  `,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			switch cmdData.OutputFormat {
+			case outputFormatText, outputFormatJSON:
+			default:
+				return fmt.Errorf("unsupported --output-format=%q, expected %s or %s", cmdData.OutputFormat, outputFormatText, outputFormatJSON)
+			}
+
+			_, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
+				Cmd:                &commonCmdData,
+				InitWerf:           true,
+				InitGitDataManager: true,
+				InitTrueGitWithOptions: &common.InitTrueGitOptions{
+					Options: true_git.Options{LiveGitOutput: *commonCmdData.LogDebug},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("component init error: %w", err)
+			}
+
+			defer func() {
+				if err := tmp_manager.DelegateCleanup(ctx); err != nil {
+					logboek.Context(ctx).Warn().LogF("Temporary files cleanup preparation failed: %s\n", err)
+				}
+			}()
+
+			gm, err := common.GetGiterminismManager(ctx, &commonCmdData)
+			if err != nil {
+				return err
+			}
+
+			plan, err := includes.PlanUpdate(ctx, includes.UpdateOptions{
+				FileReader: gm.FileReader(),
+				ProjectDir: gm.ProjectDir(),
+				Sources:    args,
+			})
+			if err != nil {
+				return err
+			}
+
+			switch cmdData.OutputFormat {
+			case outputFormatJSON:
+				data, err := json.MarshalIndent(plan, "", "  ")
+				if err != nil {
+					return fmt.Errorf("unable to marshal includes: %w", err)
+				}
+				fmt.Printf("%s\n", data)
+			default:
+				logboek.Context(ctx).Default().LogOptionalLn()
+				if err := plan.WriteText(os.Stdout); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}))
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupGiterminismConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupGiterminismOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	commonCmdData.SetupPlatform(cmd)
+
+	commonCmdData.SetupSkipIncludesInit()
+
+	common.SetupOutputFormat(cmd, &cmdData.OutputFormat, "WERF_INCLUDES_OUTDATED_OUTPUT_FORMAT", outputFormatText, outputFormatJSON)
+
+	return cmd
+}
@@ -3,23 +3,46 @@ package update
 import (
 	"context"
 	"fmt"
+	"os"
 
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 
+	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/logboek"
+	nelmcommon "github.com/werf/nelm/pkg/common"
 	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/includes"
 	"github.com/werf/werf/v2/pkg/tmp_manager"
 	"github.com/werf/werf/v2/pkg/true_git"
 )
 
+var cmdData struct {
+	DiffContextLines int
+}
+
 var commonCmdData common.CmdData
 
 func NewCmd(ctx context.Context) *cobra.Command {
 	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
 	cmd := common.SetCommandContext(ctx, common.SetCommandContext(ctx, &cobra.Command{
-		Use:   "update",
+		Use:   "update [SOURCE...]",
 		Short: "Create or update includes lock file (default: werf-includes.lock).",
-		Long:  "Create or update includes lock file by resolving git references in the includes config to their latest commits (default: werf-includes.lock).",
+		Long: `Create or update includes lock file by resolving git references in the includes config to their latest commits, OCI artifact tags to their digests and archives to their sha256 digests (default: werf-includes.lock).
+
+Pass the git repositories, the OCI repositories or the archives of the includes as arguments to update only these includes, the other locked includes are kept as is.
+
+Use --dry-run to show the lock file changes and the diff of every file of the includes that would change without updating the lock file.`,
+		Example: `
+  # Update all includes
+  $ werf includes update
+
+  # Update only the includes from the specified repository
+  $ werf includes update https://github.com/werf/common-config
+
+  # Show the changes without updating the lock file
+  $ werf includes update --dry-run
+  `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
@@ -41,11 +64,38 @@ func NewCmd(ctx context.Context) *cobra.Command {
 				}
 			}()
 
-			_, err = common.GetGiterminismManager(ctx, &commonCmdData)
+			gm, err := common.GetGiterminismManager(ctx, &commonCmdData)
+			if err != nil {
+				return err
+			}
+
+			plan, err := includes.PlanUpdate(ctx, includes.UpdateOptions{
+				FileReader: gm.FileReader(),
+				ProjectDir: gm.ProjectDir(),
+				Sources:    args,
+			})
 			if err != nil {
 				return err
 			}
 
+			if *commonCmdData.DryRun {
+				diffs, err := plan.FileDiffs(ctx, gm.FileManager.IsProjectFileExist, cmdData.DiffContextLines)
+				if err != nil {
+					return err
+				}
+
+				logboek.Context(ctx).Default().LogOptionalLn()
+
+				if err := plan.WriteText(os.Stdout); err != nil {
+					return err
+				}
+				return includes.WriteFileDiffsText(os.Stdout, diffs)
+			}
+
+			if err := plan.Apply(); err != nil {
+				return fmt.Errorf("unable to update %s: %w", includes.GetWerfIncludesLockConfigRelPath(), err)
+			}
+
 			logboek.Context(ctx).Default().LogOptionalLn()
 
 			logboek.Context(ctx).Default().LogLn("Includes updated successfully")
@@ -73,7 +123,17 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	commonCmdData.SetupPlatform(cmd)
 	common.SetupFollow(&commonCmdData, cmd)
 
-	commonCmdData.SetupCreateIncludesLockFile()
+	common.SetupDryRun(&commonCmdData, cmd)
+
+	commonCmdData.SetupSkipIncludesInit()
+
+	var defaultDiffLines int
+	if lines := lo.Must(util.GetIntEnvVar("WERF_DIFF_CONTEXT_LINES")); lines != nil {
+		defaultDiffLines = int(*lines)
+	} else {
+		defaultDiffLines = nelmcommon.DefaultDiffContextLines
+	}
+	cmd.Flags().IntVarP(&cmdData.DiffContextLines, "diff-context-lines", "", defaultDiffLines, "Show N lines of context around diffs ($WERF_DIFF_CONTEXT_LINES by default)")
 
 	return cmd
 }
@@ -136,6 +136,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupNetworkParallelism(&commonCmdData, cmd)
 	common.SetupNoFinalTrackingFlag(&commonCmdData, cmd)
 	common.SetupNoRemoveManualChanges(&commonCmdData, cmd)
+	common.SetupPolicies(&commonCmdData, cmd, common.SetupPoliciesOptions{})
 	common.SetupRelease(&commonCmdData, cmd, true)
 	common.SetupReleaseStorageDriver(&commonCmdData, cmd)
 	common.SetupReleaseStorageSQLConnection(&commonCmdData, cmd)
@@ -373,6 +374,27 @@ func runLint(ctx context.Context, imageNameListFromArgs []string) error {
 	})
 	engine.Debug = commonCmdData.DebugTemplates
 
+	chartRenderOptions := common.NewChartRenderOptions(&commonCmdData, action.ChartRenderOptions{
+		ChartAppVersion:            common.GetHelmChartConfigAppVersion(werfConfig),
+		ChartDirPath:               relChartPath,
+		DefaultChartAPIVersion:     chart.APIVersionV2,
+		DefaultChartName:           werfConfig.Meta.Project,
+		DefaultChartVersion:        "1.0.0",
+		ExtraAnnotations:           extraAnnotations,
+		ExtraLabels:                extraLabels,
+		ExtraRuntimeAnnotations:    extraRuntimeAnnotations,
+		LegacyExtraValues:          serviceValues,
+		LegacyLogRegistryStreamOut: os.Stdout,
+		RegistryCredentialsPath:    registryCredentialsPath,
+		ReleaseName:                releaseName,
+		ReleaseNamespace:           releaseNamespace,
+		Remote:                     cmdData.Validate,
+	})
+
+	if err := common.CheckPolicies(ctx, &commonCmdData, giterminismManager.FileReader(), common.NewChartResourcesGetter(ctx, chartRenderOptions)); err != nil {
+		return err
+	}
+
 	if err := action.ChartLint(ctx, action.ChartLintOptions{
 		KubeConnectionOptions:       commonCmdData.KubeConnectionOptions,
 		ChartRepoConnectionOptions:  commonCmdData.ChartRepoConnectionOptions,
@@ -174,6 +174,7 @@ werf plan --repo registry.mydomain.com/web --env production`,
 	common.SetupNoFinalTrackingFlag(&commonCmdData, cmd)
 	common.SetupNoInstallCRDs(&commonCmdData, cmd)
 	common.SetupNoRemoveManualChanges(&commonCmdData, cmd)
+	common.SetupPolicies(&commonCmdData, cmd, common.SetupPoliciesOptions{})
 	common.SetupRelease(&commonCmdData, cmd, true)
 	common.SetupReleaseInfoAnnotations(&commonCmdData, cmd)
 	common.SetupReleaseLabel(&commonCmdData, cmd)
@@ -463,6 +464,26 @@ func run(
 	})
 	engine.Debug = commonCmdData.DebugTemplates
 
+	chartRenderOptions := common.NewChartRenderOptions(&commonCmdData, action.ChartRenderOptions{
+		ChartAppVersion:            common.GetHelmChartConfigAppVersion(werfConfig),
+		ChartDirPath:               relChartPath,
+		DefaultChartAPIVersion:     chart.APIVersionV2,
+		DefaultChartName:           werfConfig.Meta.Project,
+		DefaultChartVersion:        "1.0.0",
+		ExtraAnnotations:           extraAnnotations,
+		ExtraLabels:                extraLabels,
+		ExtraRuntimeAnnotations:    extraRuntimeAnnotations,
+		LegacyExtraValues:          serviceValues,
+		LegacyLogRegistryStreamOut: os.Stdout,
+		RegistryCredentialsPath:    registryCredentialsPath,
+		ReleaseName:                releaseName,
+		ReleaseNamespace:           releaseNamespace,
+	})
+
+	if err := common.CheckPolicies(ctx, &commonCmdData, giterminismManager.FileReader(), common.NewChartResourcesGetter(ctx, chartRenderOptions)); err != nil {
+		return err
+	}
+
 	if err := action.ReleasePlanInstall(ctx, releaseName, releaseNamespace, action.ReleasePlanInstallOptions{
 		KubeConnectionOptions:       commonCmdData.KubeConnectionOptions,
 		ChartRepoConnectionOptions:  commonCmdData.ChartRepoConnectionOptions,
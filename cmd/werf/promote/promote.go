@@ -0,0 +1,194 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/config"
+	"github.com/werf/werf/v2/pkg/storage/manager"
+	"github.com/werf/werf/v2/pkg/tmp_manager"
+	"github.com/werf/werf/v2/pkg/true_git"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+var cmdData struct {
+	FromRepo *common.RepoData
+	ToRepo   *common.RepoData
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "promote [IMAGE_NAME...]",
+		Short: "Promote final images of the current commit from one repo into another without rebuild",
+		Long: common.GetLongCommandDescription(`Promote final images of the current commit from one repo into another without rebuild.
+
+Images built for the current git commit are found by the image metadata of the --from-repo and copied into the --to-repo, which becomes the primary repo of the target environment: managed images and image metadata are registered in it, so werf converge and werf cleanup with --repo=TO_REPO work with the promoted images as with the built ones.
+
+The command fails if any of the images is not built for the current commit in the --from-repo. All final images of the werf config are promoted by default, the list can be limited by the IMAGE_NAME arguments.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			defer global_warnings.PrintGlobalWarnings(ctx)
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error { return runPromote(ctx, args) })
+		},
+	})
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigRenderPath(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupGiterminismConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	cmdData.FromRepo = common.NewRepoData("from-repo", common.RepoDataOptions{})
+	cmdData.FromRepo.SetupCmd(cmd)
+	cmdData.ToRepo = common.NewRepoData("to-repo", common.RepoDataOptions{})
+	cmdData.ToRepo.SetupCmd(cmd)
+
+	common.SetupAddCustomTag(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read images from the --from-repo and to write images into the --to-repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupContainerRegistryMirror(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+
+	commonCmdData.SetupPlatform(cmd)
+	commonCmdData.SetupDebugTemplates(cmd)
+	commonCmdData.SetupAllowIncludesUpdate(cmd)
+
+	return cmd
+}
+
+func runPromote(ctx context.Context, imageNameList []string) error {
+	commonManager, ctx, err := common.InitCommonComponents(ctx, common.InitCommonComponentsOptions{
+		Cmd: &commonCmdData,
+		InitTrueGitWithOptions: &common.InitTrueGitOptions{
+			Options: true_git.Options{LiveGitOutput: *commonCmdData.LogDebug},
+		},
+		InitDockerRegistry:          true,
+		InitProcessContainerBackend: true,
+		InitWerf:                    true,
+		InitGitDataManager:          true,
+		InitManifestCache:           true,
+		InitLRUImagesCache:          true,
+	})
+	if err != nil {
+		return fmt.Errorf("component init error: %w", err)
+	}
+
+	defer func() {
+		if err := tmp_manager.DelegateCleanup(ctx); err != nil {
+			logboek.Context(ctx).Warn().LogF("Temporary files cleanup preparation failed: %s\n", err)
+		}
+	}()
+
+	containerBackend := commonManager.ContainerBackend()
+
+	giterminismManager, err := common.GetGiterminismManager(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	_, werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %w", err)
+	}
+
+	imagesToProcess, err := config.NewImagesToProcess(werfConfig, imageNameList, true, false)
+	if err != nil {
+		return err
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	createStagesStorageOptions := &common.CreateStagesStorageOptions{
+		ContainerBackend:               containerBackend,
+		InsecureRegistry:               *commonCmdData.InsecureRegistry,
+		SkipTlsVerifyRegistry:          *commonCmdData.SkipTlsVerifyRegistry,
+		CleanupDisabled:                werfConfig.Meta.Cleanup.DisableCleanup,
+		GitHistoryBasedCleanupDisabled: werfConfig.Meta.Cleanup.DisableGitHistoryBasedPolicy,
+	}
+
+	fromStagesStorage, err := cmdData.FromRepo.CreateStagesStorage(ctx, createStagesStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	toStagesStorage, err := cmdData.ToRepo.CreateStagesStorage(ctx, createStagesStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	if fromStagesStorage.String() == toStagesStorage.String() {
+		return fmt.Errorf("--from-repo and --to-repo should differ")
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, toStagesStorage)
+	if err != nil {
+		return fmt.Errorf("error get synchronization: %w", err)
+	}
+
+	storageLockManager, err := synchronization.GetStorageLockManager(ctx)
+	if err != nil {
+		return fmt.Errorf("error get storage lock manager: %w", err)
+	}
+
+	customTagFuncList, err := common.GetAddCustomTagFuncList(&commonCmdData, imagesToProcess)
+	if err != nil {
+		return err
+	}
+
+	storageManager := manager.NewStorageManager(projectName, fromStagesStorage, toStagesStorage, nil, nil, storageLockManager)
+	commit := giterminismManager.HeadCommit(ctx)
+
+	return logboek.Context(ctx).LogProcess("Promote images").DoError(func() error {
+		logboek.Context(ctx).LogFDetails("From repo: %s\n", fromStagesStorage.String())
+		logboek.Context(ctx).LogFDetails("To repo: %s\n", toStagesStorage.String())
+		logboek.Context(ctx).LogFDetails("Commit: %s\n", commit)
+
+		descs, err := storageManager.PromoteImages(ctx, imagesToProcess.FinalImageNameList, manager.PromoteImagesOptions{
+			Commit:            commit,
+			CustomTagFuncList: customTagFuncList,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, imageName := range imagesToProcess.FinalImageNameList {
+			logboek.Context(ctx).LogFDetails("Image %s: %s\n", imageName, descs[imageName].Info.Name)
+		}
+
+		return nil
+	})
+}
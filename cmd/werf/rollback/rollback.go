@@ -15,6 +15,7 @@ import (
 	"github.com/werf/nelm/pkg/log"
 	"github.com/werf/werf/v2/cmd/werf/common"
 	"github.com/werf/werf/v2/pkg/config/deploy_params"
+	"github.com/werf/werf/v2/pkg/deploy/audit"
 	"github.com/werf/werf/v2/pkg/giterminism_manager"
 	"github.com/werf/werf/v2/pkg/true_git"
 	"github.com/werf/werf/v2/pkg/werf"
@@ -81,6 +82,7 @@ werf rollback --revision 10`,
 
 	common.SetupAddAnnotations(&commonCmdData, cmd)
 	common.SetupAddLabels(&commonCmdData, cmd)
+	common.SetupAuditLog(&commonCmdData, cmd)
 	common.SetupDefaultDeletePropagation(&commonCmdData, cmd)
 	common.SetupExtraRuntimeAnnotations(&commonCmdData, cmd)
 	common.SetupExtraRuntimeLabels(&commonCmdData, cmd)
@@ -175,6 +177,11 @@ func run(ctx context.Context) error {
 		ColorMode: *commonCmdData.LogColorMode,
 	})
 
+	auditRecord := audit.NewRecord("rollback")
+	auditRecord.Project = projectName
+	auditRecord.Release = releaseName
+	auditRecord.Namespace = releaseNamespace
+
 	if err := action.ReleaseRollback(ctx, releaseName, releaseNamespace, action.ReleaseRollbackOptions{
 		DefaultDeletePropagation:    commonCmdData.DefaultDeletePropagation,
 		ExtraRuntimeAnnotations:     extraRuntimeAnnotations,
@@ -194,10 +201,14 @@ func run(ctx context.Context) error {
 		RollbackReportPath:          rollbackReportPath,
 		TrackingOptions:             commonCmdData.TrackingOptions,
 	}); err != nil {
-		return fmt.Errorf("release install: %w", err)
+		common.SetAuditRecordRelease(ctx, &commonCmdData, auditRecord)
+		return common.WriteAuditRecord(ctx, &commonCmdData, auditRecord, fmt.Errorf("release install: %w", err), common.WriteAuditRecordOptions{})
 	}
 
-	return nil
+	// The new revision is created with the values of the target revision, so the record gets the rolled back images and commit.
+	common.SetAuditRecordRelease(ctx, &commonCmdData, auditRecord)
+
+	return common.WriteAuditRecord(ctx, &commonCmdData, auditRecord, nil, common.WriteAuditRecordOptions{})
 }
 
 func getNamespaceAndRelease(ctx context.Context, gitFound bool, giterminismMgr giterminism_manager.Interface) (string, string, string, error) {
@@ -11,10 +11,13 @@ import (
 
 	"github.com/spf13/cobra"
 
+	base_images_update "github.com/werf/werf/v2/cmd/werf/base_images/update"
 	"github.com/werf/werf/v2/cmd/werf/build"
 	bundle_apply "github.com/werf/werf/v2/cmd/werf/bundle/apply"
 	bundle_copy "github.com/werf/werf/v2/cmd/werf/bundle/copy"
+	bundle_diff "github.com/werf/werf/v2/cmd/werf/bundle/diff"
 	bundle_plan "github.com/werf/werf/v2/cmd/werf/bundle/plan"
+	bundle_promote "github.com/werf/werf/v2/cmd/werf/bundle/promote"
 	bundle_publish "github.com/werf/werf/v2/cmd/werf/bundle/publish"
 	bundle_render "github.com/werf/werf/v2/cmd/werf/bundle/render"
 	"github.com/werf/werf/v2/cmd/werf/ci_env"
@@ -25,19 +28,25 @@ import (
 	"github.com/werf/werf/v2/cmd/werf/compose"
 	config_graph "github.com/werf/werf/v2/cmd/werf/config/graph"
 	config_list "github.com/werf/werf/v2/cmd/werf/config/list"
+	config_lsp "github.com/werf/werf/v2/cmd/werf/config/lsp"
 	config_render "github.com/werf/werf/v2/cmd/werf/config/render"
+	config_schema "github.com/werf/werf/v2/cmd/werf/config/schema"
 	"github.com/werf/werf/v2/cmd/werf/converge"
 	cr_login "github.com/werf/werf/v2/cmd/werf/cr/login"
 	cr_logout "github.com/werf/werf/v2/cmd/werf/cr/logout"
 	"github.com/werf/werf/v2/cmd/werf/dismiss"
+	dismiss_expired "github.com/werf/werf/v2/cmd/werf/dismiss_expired"
 	"github.com/werf/werf/v2/cmd/werf/docs"
 	kubectl2 "github.com/werf/werf/v2/cmd/werf/docs/replacers/kubectl"
+	"github.com/werf/werf/v2/cmd/werf/drift"
 	"github.com/werf/werf/v2/cmd/werf/export"
+	giterminism_inspect "github.com/werf/werf/v2/cmd/werf/giterminism/inspect"
 	"github.com/werf/werf/v2/cmd/werf/helm"
 	host_cleanup "github.com/werf/werf/v2/cmd/werf/host/cleanup"
 	host_purge "github.com/werf/werf/v2/cmd/werf/host/purge"
 	includes_getfile "github.com/werf/werf/v2/cmd/werf/includes/get-file"
 	includes_lsfiles "github.com/werf/werf/v2/cmd/werf/includes/ls-files"
+	includes_outdated "github.com/werf/werf/v2/cmd/werf/includes/outdated"
 	includes_update "github.com/werf/werf/v2/cmd/werf/includes/update"
 	"github.com/werf/werf/v2/cmd/werf/kube_run"
 	"github.com/werf/werf/v2/cmd/werf/kubectl"
@@ -46,11 +55,15 @@ import (
 	managed_images_ls "github.com/werf/werf/v2/cmd/werf/managed_images/ls"
 	managed_images_rm "github.com/werf/werf/v2/cmd/werf/managed_images/rm"
 	"github.com/werf/werf/v2/cmd/werf/plan"
+	"github.com/werf/werf/v2/cmd/werf/promote"
 	"github.com/werf/werf/v2/cmd/werf/purge"
 	"github.com/werf/werf/v2/cmd/werf/render"
 	"github.com/werf/werf/v2/cmd/werf/rollback"
 	"github.com/werf/werf/v2/cmd/werf/run"
 	"github.com/werf/werf/v2/cmd/werf/slugify"
+	stack_converge "github.com/werf/werf/v2/cmd/werf/stack/converge"
+	stack_dismiss "github.com/werf/werf/v2/cmd/werf/stack/dismiss"
+	stack_plan "github.com/werf/werf/v2/cmd/werf/stack/plan"
 	stage_image "github.com/werf/werf/v2/cmd/werf/stage/image"
 	"github.com/werf/werf/v2/cmd/werf/synchronization"
 	"github.com/werf/werf/v2/cmd/werf/version"
@@ -83,8 +96,12 @@ func ConstructRootCmd(ctx context.Context) (*cobra.Command, error) {
 				converge.NewCmd(ctx),
 				rollback.NewCmd(ctx),
 				plan.NewCmd(ctx),
+				drift.NewCmd(ctx),
 				dismiss.NewCmd(ctx),
+				dismiss_expired.NewCmd(ctx),
+				promote.NewCmd(ctx),
 				bundleCmd(ctx),
+				stackCmd(ctx),
 			},
 		},
 		{
@@ -107,6 +124,8 @@ func ConstructRootCmd(ctx context.Context) (*cobra.Command, error) {
 				render.NewCmd(ctx),
 				lint.NewCmd(ctx),
 				includesCmd(ctx),
+				baseImagesCmd(ctx),
+				giterminismCmd(ctx),
 			},
 		},
 		{
@@ -177,6 +196,22 @@ func bundleCmd(ctx context.Context) *cobra.Command {
 		bundle_plan.NewCmd(ctx),
 		bundle_render.NewCmd(ctx),
 		bundle_copy.NewCmd(ctx),
+		bundle_diff.NewCmd(ctx),
+		bundle_promote.NewCmd(ctx),
+	)
+
+	return cmd
+}
+
+func stackCmd(ctx context.Context) *cobra.Command {
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "stack",
+		Short: "Deploy several werf projects described in werf-stack.yaml in the order of their dependencies",
+	})
+	cmd.AddCommand(
+		stack_converge.NewCmd(ctx),
+		stack_plan.NewCmd(ctx),
+		stack_dismiss.NewCmd(ctx),
 	)
 
 	return cmd
@@ -191,6 +226,8 @@ func configCmd(ctx context.Context) *cobra.Command {
 		config_render.NewCmd(ctx),
 		config_list.NewCmd(ctx),
 		config_graph.NewCmd(ctx),
+		config_schema.NewCmd(ctx),
+		config_lsp.NewCmd(ctx),
 	)
 
 	return cmd
@@ -243,6 +280,7 @@ func includesCmd(ctx context.Context) *cobra.Command {
 	})
 	cmd.AddCommand(
 		includes_update.NewCmd(ctx),
+		includes_outdated.NewCmd(ctx),
 		includes_lsfiles.NewCmd(ctx),
 		includes_getfile.NewCmd(ctx),
 	)
@@ -250,6 +288,30 @@ func includesCmd(ctx context.Context) *cobra.Command {
 	return cmd
 }
 
+func baseImagesCmd(ctx context.Context) *cobra.Command {
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "base-images",
+		Short: "Work with base images lock file",
+	})
+	cmd.AddCommand(
+		base_images_update.NewCmd(ctx),
+	)
+
+	return cmd
+}
+
+func giterminismCmd(ctx context.Context) *cobra.Command {
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "giterminism",
+		Short: "Work with giterminism",
+	})
+	cmd.AddCommand(
+		giterminism_inspect.NewCmd(ctx),
+	)
+
+	return cmd
+}
+
 func SetupTelemetryInit(rootCmd *cobra.Command) {
 	commandsQueue := []*cobra.Command{rootCmd}
 
@@ -0,0 +1,77 @@
+package converge
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/deploy/stack"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "converge",
+		Short: "Deploy all projects of the stack in the order of their dependencies",
+		Long: common.GetLongCommandDescription(`Deploy all projects of the stack config (werf-stack.yaml by default) in the order of their dependencies.
+
+Each project is deployed with the separate werf process: werf converge for the local directory or the git repository and werf bundle apply for the bundle. Independent projects are deployed in parallel. After the first failure new projects are not started and reported as skipped.`),
+		Example: `# Deploy the stack to production
+werf stack converge --env production
+
+# Deploy the stack one project at a time and save the combined report
+werf stack converge --env production --parallel=false --save-stack-report`,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			defer global_warnings.PrintGlobalWarnings(ctx)
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runConverge(ctx)
+			})
+		},
+	})
+
+	common.SetupStackConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
+
+	common.SetupSaveStackReport(&commonCmdData, cmd)
+	common.SetupStackReportPath(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runConverge(ctx context.Context) error {
+	result, err := common.RunStack(ctx, &commonCmdData, stack.ActionConverge)
+	if err != nil {
+		return err
+	}
+
+	if commonCmdData.SaveStackReport {
+		reportPath := common.GetStackReportPath(&commonCmdData)
+		if err := result.WriteFile(reportPath); err != nil {
+			return err
+		}
+		logboek.Context(ctx).Default().LogF("Stack report saved to %s\n", reportPath)
+	}
+
+	return result.Err()
+}
@@ -0,0 +1,62 @@
+package dismiss
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/deploy/stack"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "dismiss",
+		Short: "Delete releases of all projects of the stack in the reverse order of their dependencies",
+		Long: common.GetLongCommandDescription(`Delete releases of all projects of the stack config (werf-stack.yaml by default) with werf dismiss.
+
+Each project is dismissed after the projects depending on it. The release and the namespace of the project are used if specified, otherwise they are generated from werf.yaml of the project.`),
+		Example: `# Delete releases of the stack projects
+werf stack dismiss --env dev`,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			defer global_warnings.PrintGlobalWarnings(ctx)
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runDismiss(ctx)
+			})
+		},
+	})
+
+	common.SetupStackConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runDismiss(ctx context.Context) error {
+	result, err := common.RunStack(ctx, &commonCmdData, stack.ActionDismiss)
+	if err != nil {
+		return err
+	}
+
+	return result.Err()
+}
@@ -0,0 +1,81 @@
+package plan
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/nelm/pkg/action"
+	"github.com/werf/werf/v2/cmd/werf/common"
+	"github.com/werf/werf/v2/pkg/deploy/stack"
+	"github.com/werf/werf/v2/pkg/werf/global_warnings"
+)
+
+var cmdData struct {
+	DetailedExitCode bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "plan",
+		Short: "Show how resources of all projects of the stack would change on next deploy",
+		Long: common.GetLongCommandDescription(`Prepare deploy plans of all projects of the stack config (werf-stack.yaml by default).
+
+Each project is planned with the separate werf process: werf plan for the local directory or the git repository and werf bundle plan for the bundle. Projects are planned in the order of their dependencies, independent projects are planned in parallel.`),
+		Example: `# Show deploy plans of the stack projects
+werf stack plan --env production
+
+# Fail with exit code 2 if any project of the stack would change
+werf stack plan --env production --exit-code`,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			defer global_warnings.PrintGlobalWarnings(ctx)
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runPlan(ctx)
+			})
+		},
+	})
+
+	common.SetupStackConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&cmdData.DetailedExitCode, "exit-code", "", util.GetBoolEnvironmentDefaultFalse("WERF_EXIT_CODE"), "If true, returns exit code 0 if no changes, exit code 2 if any project of the stack has changes planned or exit code 1 in case of an error (default $WERF_EXIT_CODE or false)")
+
+	return cmd
+}
+
+func runPlan(ctx context.Context) error {
+	result, err := common.RunStack(ctx, &commonCmdData, stack.ActionPlan)
+	if err != nil {
+		return err
+	}
+
+	if err := result.Err(); err != nil {
+		return err
+	}
+
+	if cmdData.DetailedExitCode && result.ChangesPlanned() {
+		return action.ErrChangesPlanned
+	}
+
+	return nil
+}
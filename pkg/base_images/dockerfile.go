@@ -0,0 +1,155 @@
+package base_images
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/moby/buildkit/frontend/dockerfile/shell"
+)
+
+type dockerfileFrom struct {
+	node *parser.Node
+	// reference is the base image reference with the resolved meta args, empty if the base is another stage.
+	reference string
+}
+
+// DockerfileReferences returns the lockable base image references of the Dockerfile stages.
+// The meta args are resolved with the build args, the args set by werf dependencies are left unresolved.
+func DockerfileReferences(data []byte, buildArgs map[string]string, dependenciesArgsKeys []string) ([]string, error) {
+	froms, err := parseDockerfileFroms(data, buildArgs, dependenciesArgsKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	var references []string
+	for _, from := range froms {
+		if IsLockable(from.reference) && !slices.Contains(references, from.reference) {
+			references = append(references, from.reference)
+		}
+	}
+
+	return references, nil
+}
+
+// rewriteDockerfileFroms replaces the base images of the FROM instructions with the references returned by resolve.
+// The line count is preserved, so the build errors point to the original lines.
+func rewriteDockerfileFroms(data []byte, buildArgs map[string]string, dependenciesArgsKeys []string, resolve func(reference string) (string, error)) ([]byte, error) {
+	froms, err := parseDockerfileFroms(data, buildArgs, dependenciesArgsKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	var changed bool
+	for _, from := range froms {
+		if !IsLockable(from.reference) {
+			continue
+		}
+
+		resolved, err := resolve(from.reference)
+		if err != nil {
+			return nil, err
+		}
+		if resolved == from.reference {
+			continue
+		}
+
+		instruction := []string{"FROM"}
+		instruction = append(instruction, from.node.Flags...)
+		instruction = append(instruction, resolved)
+		if name := stageName(from.node); name != "" {
+			instruction = append(instruction, "AS", name)
+		}
+
+		start, end := from.node.StartLine-1, from.node.EndLine-1
+		if start < 0 || end >= len(lines) {
+			return nil, fmt.Errorf("unexpected location of FROM instruction: lines %d-%d", from.node.StartLine, from.node.EndLine)
+		}
+
+		lines[start] = strings.Join(instruction, " ")
+		for i := start + 1; i <= end; i++ {
+			lines[i] = ""
+		}
+		changed = true
+	}
+
+	if !changed {
+		return data, nil
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+func parseDockerfileFroms(data []byte, buildArgs map[string]string, dependenciesArgsKeys []string) ([]dockerfileFrom, error) {
+	p, err := parser.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse dockerfile: %w", err)
+	}
+
+	lex := shell.NewLex(p.EscapeToken)
+	lex.SkipUnsetEnv = true
+
+	metaArgs := map[string]string{}
+	stageNames := map[string]bool{}
+	var froms []dockerfileFrom
+
+	for _, node := range p.AST.Children {
+		switch strings.ToLower(node.Value) {
+		case "arg":
+			// Only the meta args declared before the first FROM are available in FROM instructions.
+			if len(froms) > 0 {
+				continue
+			}
+
+			for n := node.Next; n != nil; n = n.Next {
+				key, value, hasValue := strings.Cut(n.Value, "=")
+				switch {
+				case slices.Contains(dependenciesArgsKeys, key):
+					delete(metaArgs, key)
+				case hasBuildArg(buildArgs, key):
+					metaArgs[key] = buildArgs[key]
+				case hasValue:
+					if metaArgs[key], err = lex.ProcessWordWithMap(value, metaArgs); err != nil {
+						return nil, fmt.Errorf("unable to expand meta arg %q: %w", key, err)
+					}
+				}
+			}
+		case "from":
+			if node.Next == nil {
+				return nil, fmt.Errorf("FROM requires base image at line %d", node.StartLine)
+			}
+
+			baseName, err := lex.ProcessWordWithMap(node.Next.Value, metaArgs)
+			if err != nil {
+				return nil, fmt.Errorf("unable to expand base image %q at line %d: %w", node.Next.Value, node.StartLine, err)
+			}
+
+			from := dockerfileFrom{node: node, reference: baseName}
+			if stageNames[strings.ToLower(baseName)] {
+				from.reference = ""
+			}
+			froms = append(froms, from)
+
+			if name := stageName(node); name != "" {
+				stageNames[strings.ToLower(name)] = true
+			}
+		}
+	}
+
+	return froms, nil
+}
+
+func stageName(fromNode *parser.Node) string {
+	if n := fromNode.Next.Next; n != nil && strings.EqualFold(n.Value, "as") && n.Next != nil {
+		return n.Next.Value
+	}
+	return ""
+}
+
+func hasBuildArg(buildArgs map[string]string, key string) bool {
+	_, ok := buildArgs[key]
+	return ok
+}
@@ -0,0 +1,63 @@
+package base_images
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dockerfile", func() {
+	dockerfile := `ARG BASE=node:20
+ARG RUNTIME
+ARG DEP_IMAGE
+FROM --platform=$BUILDPLATFORM ${BASE} AS build
+RUN npm ci
+
+FROM \
+  alpine:3.20 \
+  AS runtime
+COPY --from=build /app /app
+
+FROM build AS test
+FROM $RUNTIME
+FROM $DEP_IMAGE
+FROM scratch
+`
+
+	It("should return the lockable references", func() {
+		references, err := DockerfileReferences([]byte(dockerfile), map[string]string{"RUNTIME": "debian:12"}, []string{"DEP_IMAGE"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(references).To(Equal([]string{"node:20", "alpine:3.20", "debian:12"}))
+	})
+
+	It("should use the build args for the meta args", func() {
+		references, err := DockerfileReferences([]byte(dockerfile), map[string]string{"BASE": "node:22"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(references).To(Equal([]string{"node:22", "alpine:3.20"}))
+	})
+
+	It("should pin the base images preserving the lines", func() {
+		lock := NewLock()
+		lock.Set("node:20", nodeDigest)
+		lock.Set("alpine:3.20", alpineDigest)
+		lock.Set("debian:12", alpineDigest)
+
+		data, err := (&Resolver{Lock: lock}).ResolveDockerfile([]byte(dockerfile), map[string]string{"RUNTIME": "debian:12"}, []string{"DEP_IMAGE"})
+		Expect(err).NotTo(HaveOccurred())
+
+		lines := strings.Split(string(data), "\n")
+		Expect(lines).To(HaveLen(len(strings.Split(dockerfile, "\n"))))
+		Expect(lines[3]).To(Equal("FROM --platform=$BUILDPLATFORM node:20@" + nodeDigest + " AS build"))
+		Expect(lines[6:9]).To(Equal([]string{"FROM alpine:3.20@" + alpineDigest + " AS runtime", "", ""}))
+		Expect(lines[11]).To(Equal("FROM build AS test"))
+		Expect(lines[12]).To(Equal("FROM debian:12@" + alpineDigest))
+		Expect(lines[13]).To(Equal("FROM $DEP_IMAGE"))
+		Expect(lines[14]).To(Equal("FROM scratch"))
+	})
+
+	It("should return the Dockerfile as is without lock", func() {
+		var resolver *Resolver
+		Expect(resolver.ResolveDockerfile([]byte(dockerfile), nil, nil)).To(Equal([]byte(dockerfile)))
+	})
+})
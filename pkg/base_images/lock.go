@@ -0,0 +1,92 @@
+package base_images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultLockFileName = "werf-base-images.lock"
+
+const lockFileHeader = "# Generated by `werf base-images update`. Do not edit manually.\n"
+
+var digestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Lock maps base image references, as they are written in werf.yaml and Dockerfiles, to their digests.
+type Lock struct {
+	Images map[string]string `yaml:"images"`
+}
+
+func NewLock() *Lock {
+	return &Lock{Images: map[string]string{}}
+}
+
+func ParseLock(data []byte) (*Lock, error) {
+	lock := NewLock()
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(lock); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to parse base images lock: %w", err)
+	}
+
+	if lock.Images == nil {
+		lock.Images = map[string]string{}
+	}
+
+	for reference, digest := range lock.Images {
+		if !IsLockable(reference) {
+			return nil, fmt.Errorf("invalid base images lock: reference %q cannot be locked", reference)
+		}
+		if !digestRegexp.MatchString(digest) {
+			return nil, fmt.Errorf("invalid base images lock: invalid digest %q of %q, expected sha256:<hex>", digest, reference)
+		}
+	}
+
+	return lock, nil
+}
+
+func (l *Lock) Marshal() ([]byte, error) {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal base images lock: %w", err)
+	}
+
+	return append([]byte(lockFileHeader), data...), nil
+}
+
+func (l *Lock) Digest(reference string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+
+	digest, ok := l.Images[reference]
+	return digest, ok
+}
+
+func (l *Lock) Set(reference, digest string) {
+	l.Images[reference] = digest
+}
+
+// IsLockable returns false for the references, which are reproducible as is or cannot be resolved:
+// scratch, references with digest and references with unresolved build args.
+func IsLockable(reference string) bool {
+	switch {
+	case reference == "", strings.EqualFold(reference, "scratch"):
+		return false
+	case strings.Contains(reference, "@"), strings.Contains(reference, "$"):
+		return false
+	default:
+		return true
+	}
+}
+
+// LockedReference returns the reference pinned to the digest, e.g. node:20@sha256:...
+func LockedReference(reference, digest string) string {
+	return reference + "@" + digest
+}
@@ -0,0 +1,126 @@
+package base_images
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	alpineDigest = "sha256:" + strings.Repeat("a", 64)
+	nodeDigest   = "sha256:" + strings.Repeat("b", 64)
+)
+
+var _ = Describe("Lock", func() {
+	It("should be parsed after marshaling", func() {
+		lock := NewLock()
+		lock.Set("alpine:3.20", alpineDigest)
+		lock.Set("node:20", nodeDigest)
+
+		data, err := lock.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(HavePrefix("# Generated by `werf base-images update`"))
+
+		parsed, err := ParseLock(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.Images).To(Equal(lock.Images))
+	})
+
+	It("should parse the empty lock", func() {
+		lock, err := ParseLock(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Images).To(BeEmpty())
+	})
+
+	DescribeTable("should reject the invalid lock",
+		func(data, expectedErr string) {
+			_, err := ParseLock([]byte(data))
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("unknown field", "imagez: {}", "field imagez not found"),
+		Entry("invalid digest", "images:\n  alpine:3.20: latest", `invalid digest "latest"`),
+		Entry("reference with digest", "images:\n  alpine@"+alpineDigest+": "+alpineDigest, "cannot be locked"),
+	)
+})
+
+var _ = Describe("Resolver", func() {
+	var lock *Lock
+
+	BeforeEach(func() {
+		lock = NewLock()
+		lock.Set("alpine:3.20", alpineDigest)
+	})
+
+	It("should pin the locked reference", func() {
+		resolver := &Resolver{Lock: lock}
+		Expect(resolver.Resolve("alpine:3.20")).To(Equal("alpine:3.20@" + alpineDigest))
+	})
+
+	It("should keep the not lockable references", func() {
+		resolver := &Resolver{Lock: lock, InspectUnlocked: func(string) error { return errors.New("unlocked") }}
+		Expect(resolver.Resolve("scratch")).To(Equal("scratch"))
+		Expect(resolver.Resolve("alpine@" + alpineDigest)).To(Equal("alpine@" + alpineDigest))
+	})
+
+	It("should inspect the unlocked reference", func() {
+		var inspected []string
+		resolver := &Resolver{Lock: lock, InspectUnlocked: func(reference string) error {
+			inspected = append(inspected, reference)
+			return nil
+		}}
+		Expect(resolver.Resolve("node:20")).To(Equal("node:20"))
+		Expect(inspected).To(Equal([]string{"node:20"}))
+
+		resolver.InspectUnlocked = func(string) error { return errors.New("forbidden") }
+		_, err := resolver.Resolve("node:20")
+		Expect(err).To(MatchError(`base image "node:20" not found in werf-base-images.lock: forbidden`))
+	})
+
+	It("should return references as is without lock", func() {
+		var resolver *Resolver
+		Expect(resolver.Resolve("node:20")).To(Equal("node:20"))
+	})
+})
+
+var _ = Describe("Update", func() {
+	ctx := context.Background()
+
+	getDigest := func(resolved *[]string) GetDigestFunc {
+		return func(_ context.Context, reference string) (string, error) {
+			*resolved = append(*resolved, reference)
+			return nodeDigest, nil
+		}
+	}
+
+	It("should resolve the missing references and remove the unused ones", func() {
+		current := NewLock()
+		current.Set("alpine:3.20", alpineDigest)
+		current.Set("debian:12", alpineDigest)
+
+		var resolved []string
+		lock, err := Update(ctx, current, []string{"alpine:3.20", "node:20"}, nil, getDigest(&resolved))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved).To(Equal([]string{"node:20"}))
+		Expect(lock.Images).To(Equal(map[string]string{"alpine:3.20": alpineDigest, "node:20": nodeDigest}))
+	})
+
+	It("should resolve the references to refresh", func() {
+		current := NewLock()
+		current.Set("alpine:3.20", alpineDigest)
+
+		var resolved []string
+		lock, err := Update(ctx, current, []string{"alpine:3.20"}, []string{"alpine:3.20"}, getDigest(&resolved))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved).To(Equal([]string{"alpine:3.20"}))
+		Expect(lock.Images).To(Equal(map[string]string{"alpine:3.20": nodeDigest}))
+	})
+
+	It("should fail for the unused reference to refresh", func() {
+		var resolved []string
+		_, err := Update(ctx, NewLock(), []string{"alpine:3.20"}, []string{"node:20"}, getDigest(&resolved))
+		Expect(err).To(MatchError(`base image "node:20" is not used in werf.yaml and Dockerfiles`))
+	})
+})
@@ -0,0 +1,37 @@
+package base_images
+
+import "fmt"
+
+// Resolver pins base image references to the digests of the lock. The nil Resolver returns references as is.
+type Resolver struct {
+	Lock *Lock
+	// InspectUnlocked is called for the lockable reference missing in the lock, the error fails the resolving.
+	InspectUnlocked func(reference string) error
+}
+
+func (r *Resolver) Resolve(reference string) (string, error) {
+	if r == nil || r.Lock == nil || !IsLockable(reference) {
+		return reference, nil
+	}
+
+	if digest, ok := r.Lock.Digest(reference); ok {
+		return LockedReference(reference, digest), nil
+	}
+
+	if r.InspectUnlocked != nil {
+		if err := r.InspectUnlocked(reference); err != nil {
+			return "", fmt.Errorf("base image %q not found in %s: %w", reference, DefaultLockFileName, err)
+		}
+	}
+
+	return reference, nil
+}
+
+// ResolveDockerfile returns the Dockerfile with the base images of FROM instructions pinned to the digests of the lock.
+func (r *Resolver) ResolveDockerfile(data []byte, buildArgs map[string]string, dependenciesArgsKeys []string) ([]byte, error) {
+	if r == nil || r.Lock == nil {
+		return data, nil
+	}
+
+	return rewriteDockerfileFroms(data, buildArgs, dependenciesArgsKeys, r.Resolve)
+}
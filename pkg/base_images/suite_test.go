@@ -0,0 +1,13 @@
+package base_images
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBaseImages(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "base_images suite")
+}
@@ -0,0 +1,88 @@
+package base_images
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/werf/v2/pkg/build/stage"
+	"github.com/werf/werf/v2/pkg/config"
+)
+
+type ReadDockerfileFunc func(ctx context.Context, relPath string) ([]byte, error)
+
+type GetDigestFunc func(ctx context.Context, reference string) (string, error)
+
+// CollectReferences returns the sorted lockable base image references of the stapel images (except fromLatest ones)
+// and the Dockerfile images of the werf config.
+func CollectReferences(ctx context.Context, werfConfig *config.WerfConfig, readDockerfile ReadDockerfileFunc) ([]string, error) {
+	var references []string
+	add := func(reference string) {
+		if IsLockable(reference) && !slices.Contains(references, reference) {
+			references = append(references, reference)
+		}
+	}
+
+	for _, img := range werfConfig.Images(false) {
+		switch typedImg := img.(type) {
+		case config.StapelImageInterface:
+			imageBaseConfig := typedImg.ImageBaseConfig()
+			if IsStapelFromExternal(werfConfig, imageBaseConfig) && !imageBaseConfig.FromLatest {
+				add(imageBaseConfig.From)
+			}
+		case *config.ImageFromDockerfile:
+			relDockerfilePath := filepath.Join(typedImg.Context, typedImg.Dockerfile)
+			data, err := readDockerfile(ctx, relDockerfilePath)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read dockerfile %s: %w", relDockerfilePath, err)
+			}
+
+			dockerfileReferences, err := DockerfileReferences(data, util.MapStringInterfaceToMapStringString(typedImg.Args), stage.GetDependenciesArgsKeys(typedImg.Dependencies))
+			if err != nil {
+				return nil, fmt.Errorf("image %q: dockerfile %s: %w", typedImg.Name, relDockerfilePath, err)
+			}
+
+			for _, reference := range dockerfileReferences {
+				add(reference)
+			}
+		}
+	}
+
+	sort.Strings(references)
+
+	return references, nil
+}
+
+// Update returns the lock with the digests of the references, the references missing in the current lock
+// and the references to refresh are resolved with getDigest, unused references are removed.
+func Update(ctx context.Context, current *Lock, references, refresh []string, getDigest GetDigestFunc) (*Lock, error) {
+	for _, reference := range refresh {
+		if !slices.Contains(references, reference) {
+			return nil, fmt.Errorf("base image %q is not used in werf.yaml and Dockerfiles", reference)
+		}
+	}
+
+	lock := NewLock()
+	for _, reference := range references {
+		digest, ok := current.Digest(reference)
+		if !ok || slices.Contains(refresh, reference) {
+			var err error
+			if digest, err = getDigest(ctx, reference); err != nil {
+				return nil, fmt.Errorf("unable to get digest of base image %q: %w", reference, err)
+			}
+		}
+
+		lock.Set(reference, digest)
+	}
+
+	return lock, nil
+}
+
+// IsStapelFromExternal returns true if the base image of the stapel image is pulled from the registry,
+// StapelImageBase.FromExternal is set only when the images are grouped for the build.
+func IsStapelFromExternal(werfConfig *config.WerfConfig, img *config.StapelImageBase) bool {
+	return img.FromArtifactName == "" && img.From != "" && werfConfig.GetImage(img.From) == nil
+}
@@ -1,6 +1,7 @@
 package build
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"os"
@@ -44,6 +45,9 @@ type BuildOptions struct {
 	ReportPath   string
 	ReportFormat ReportFormat
 
+	// Provenance is the path of the file to write images provenance or provenance.TargetRegistry to attach it to the images.
+	Provenance string
+
 	SkipImageMetadataPublication bool
 	SkipAddManagedImagesRecords  bool
 	CustomTagFuncList            []imagePkg.CustomTagFunc
@@ -73,6 +77,8 @@ func NewBuildPhase(c *Conveyor, opts BuildPhaseOptions) *BuildPhase {
 		BasePhase:         BasePhase{c},
 		BuildPhaseOptions: opts,
 		ImagesReport:      NewImagesReport(),
+
+		provenanceStatements: &provenanceStatements{},
 	}
 }
 
@@ -84,6 +90,8 @@ type BuildPhase struct {
 	ImagesReport   *ImagesReport
 
 	buildContextArchive container_backend.BuildContextArchiver
+
+	provenanceStatements *provenanceStatements
 }
 
 func GenerateImageEnv(werfImageName, imageName string) string {
@@ -153,6 +161,13 @@ func (phase *BuildPhase) AfterImages(ctx context.Context) error {
 				if err := phase.publishImageMetadata(ctx, name, img); err != nil {
 					return fmt.Errorf("unable to publish image %q metadata: %w", name, err)
 				}
+
+				if img.IsFinal && phase.Provenance != "" {
+					stageImage := img.GetLastNonEmptyStage().GetStageImage().Image
+					if err := phase.createImageProvenance(ctx, name, images, cmp.Or(stageImage.GetFinalStageDesc(), stageImage.GetStageDesc())); err != nil {
+						return err
+					}
+				}
 			}
 		} else {
 			img := image.NewMultiplatformImage(name, images, taskId, len(imagesPairs))
@@ -181,6 +196,12 @@ func (phase *BuildPhase) AfterImages(ctx context.Context) error {
 					}
 				}
 			}
+
+			if _, isLocal := phase.Conveyor.StorageManager.GetStagesStorage().(*storage.LocalStagesStorage); !isLocal && img.IsFinal && phase.Provenance != "" {
+				if err := phase.createImageProvenance(ctx, name, images, cmp.Or(img.GetFinalStageDesc(), img.GetStageDesc())); err != nil {
+					return err
+				}
+			}
 		}
 
 		return nil
@@ -188,6 +209,10 @@ func (phase *BuildPhase) AfterImages(ctx context.Context) error {
 		return err
 	}
 
+	if err := phase.writeProvenance(ctx); err != nil {
+		return err
+	}
+
 	return phase.createReport(ctx)
 }
 
@@ -637,7 +662,7 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *image.Image, stg
 	if !foundSuitableSecondaryStage {
 		if phase.ShouldBeBuiltMode {
 			phase.printShouldBeBuiltError(ctx, img, stg)
-			return fmt.Errorf("stages required")
+			return ErrStagesRequired
 		}
 
 		start := time.Now()
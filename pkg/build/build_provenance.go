@@ -0,0 +1,200 @@
+package build
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/build/image"
+	"github.com/werf/werf/v2/pkg/build/provenance"
+	"github.com/werf/werf/v2/pkg/config"
+	imagePkg "github.com/werf/werf/v2/pkg/image"
+	"github.com/werf/werf/v2/pkg/storage"
+	"github.com/werf/werf/v2/pkg/werf"
+)
+
+type provenanceStatements struct {
+	mux  sync.Mutex
+	list []*provenance.Statement
+}
+
+func (s *provenanceStatements) Add(statement *provenance.Statement) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.list = append(s.list, statement)
+}
+
+// createImageProvenance generates the provenance statement of the final image (single or multiplatform)
+// and attaches it to the image in the registry or keeps it to write into the file after build.
+func (phase *BuildPhase) createImageProvenance(ctx context.Context, name string, images []*image.Image, stageDesc *imagePkg.StageDesc) error {
+	statement := phase.newProvenanceStatement(ctx, name, images, stageDesc)
+
+	if phase.Provenance != provenance.TargetRegistry {
+		phase.provenanceStatements.Add(statement)
+		return nil
+	}
+
+	stagesStorage := phase.Conveyor.StorageManager.GetFinalStagesStorage()
+	if stagesStorage == nil {
+		stagesStorage = phase.Conveyor.StorageManager.GetStagesStorage()
+	}
+
+	repoStagesStorage, ok := stagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return fmt.Errorf("unable to attach image %q provenance: %s is not a container registry", name, stagesStorage.String())
+	}
+
+	return logboek.Context(ctx).Info().LogProcess("Attaching provenance to image %s", stageDesc.Info.Name).DoError(func() error {
+		subject, err := repoStagesStorage.DockerRegistry.GetRepoImageDescriptor(ctx, stageDesc.Info.Name)
+		if err != nil {
+			return err
+		}
+
+		artifact, err := provenance.NewArtifact(statement, *subject)
+		if err != nil {
+			return err
+		}
+
+		digest, err := artifact.Digest()
+		if err != nil {
+			return fmt.Errorf("unable to calculate provenance artifact digest: %w", err)
+		}
+
+		if err := repoStagesStorage.DockerRegistry.PushImageFrom(ctx, artifact, fmt.Sprintf("%s@%s", stageDesc.Info.Repository, digest)); err != nil {
+			return fmt.Errorf("unable to push image %q provenance: %w", name, err)
+		}
+
+		return nil
+	})
+}
+
+func (phase *BuildPhase) writeProvenance(ctx context.Context) error {
+	if phase.Provenance == "" || phase.Provenance == provenance.TargetRegistry {
+		return nil
+	}
+
+	logboek.Context(ctx).Debug().LogF("Writing provenance of %d images to the %q\n", len(phase.provenanceStatements.list), phase.Provenance)
+
+	return provenance.WriteFile(phase.Provenance, phase.provenanceStatements.list)
+}
+
+func (phase *BuildPhase) newProvenanceStatement(ctx context.Context, name string, images []*image.Image, stageDesc *imagePkg.StageDesc) *provenance.Statement {
+	buildDefinition := provenance.BuildDefinition{
+		ExternalParameters: provenance.ExternalParameters{Image: name},
+		InternalParameters: provenance.InternalParameters{
+			Project:  phase.Conveyor.ProjectName(),
+			StageIDs: map[string]string{},
+		},
+	}
+
+	gitDependency := provenance.ResourceDescriptor{
+		Name:   "git",
+		Digest: map[string]string{"gitCommit": phase.Conveyor.giterminismManager.HeadCommit(ctx)},
+	}
+	if originURL, err := phase.Conveyor.giterminismManager.LocalGitRepo().RemoteOriginUrl(ctx); err != nil {
+		logboek.Context(ctx).Debug().LogF("Unable to get git remote origin url: %s\n", err)
+	} else if originURL != "" {
+		gitDependency.URI = "git+" + originURL
+	}
+	buildDefinition.ResolvedDependencies = append(buildDefinition.ResolvedDependencies, gitDependency)
+
+	if render := phase.Conveyor.werfConfig.GetImageRenderContent(name); render != nil {
+		sum := sha256.Sum256(render)
+		buildDefinition.ResolvedDependencies = append(buildDefinition.ResolvedDependencies, provenance.ResourceDescriptor{
+			Name:   "werf.yaml",
+			Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])},
+		})
+	}
+
+	var imageConfig config.ImageInterface
+	for _, c := range phase.Conveyor.werfConfig.Images(false) {
+		if c.GetName() == name {
+			imageConfig = c
+			break
+		}
+	}
+
+	if dockerfileImageConfig, ok := imageConfig.(*config.ImageFromDockerfile); ok {
+		buildDefinition.ExternalParameters.Dockerfile = dockerfileImageConfig.Dockerfile
+		buildDefinition.ExternalParameters.Context = dockerfileImageConfig.Context
+		buildDefinition.ExternalParameters.Target = dockerfileImageConfig.Target
+		buildDefinition.ExternalParameters.BuildArgs = dockerfileImageConfig.Args
+	}
+
+	for _, img := range images {
+		buildDefinition.ExternalParameters.Platforms = append(buildDefinition.ExternalParameters.Platforms, img.TargetPlatform)
+		buildDefinition.InternalParameters.StageIDs[img.TargetPlatform] = img.GetStageID()
+
+		var annotations map[string]string
+		if len(images) > 1 {
+			annotations = map[string]string{"platform": img.TargetPlatform}
+		}
+
+		for _, dep := range phase.getImageProvenanceDependencies(img, imageConfig) {
+			dep.Annotations = annotations
+			buildDefinition.ResolvedDependencies = append(buildDefinition.ResolvedDependencies, dep)
+		}
+	}
+
+	subject := provenance.ResourceDescriptor{
+		Name:   stageDesc.Info.Name,
+		Digest: provenance.NewDigestSet(stageDesc.Info.GetDigest()),
+	}
+
+	return provenance.NewStatement(subject, buildDefinition, werf.Version, time.Now())
+}
+
+// getImageProvenanceDependencies returns the base image, imported images and dependencies of the image for the target platform.
+func (phase *BuildPhase) getImageProvenanceDependencies(img *image.Image, imageConfig config.ImageInterface) []provenance.ResourceDescriptor {
+	var deps []provenance.ResourceDescriptor
+
+	if ref := img.GetBaseImageReference(); ref != "" {
+		dep := provenance.ResourceDescriptor{Name: "base-image", URI: ref}
+
+		digest := img.GetBaseImageRepoDigest()
+		if baseStageImage := img.GetBaseStageImage(); digest == "" && baseStageImage != nil {
+			if desc := baseStageImage.Image.GetStageDesc(); desc != nil && desc.Info != nil {
+				digest = desc.Info.GetDigest()
+			}
+		}
+		dep.Digest = provenance.NewDigestSet(digest)
+
+		deps = append(deps, dep)
+	}
+
+	newImageStageDependency := func(kind, imageName, stageName string) provenance.ResourceDescriptor {
+		desc := phase.Conveyor.getImageStage(img.TargetPlatform, imageName, stageName).GetStageImage().Image.GetStageDesc()
+		return provenance.ResourceDescriptor{
+			Name:   fmt.Sprintf("%s/%s", kind, imageName),
+			URI:    desc.Info.Name,
+			Digest: provenance.NewDigestSet(desc.Info.GetDigest()),
+		}
+	}
+
+	var dependencies []*config.Dependency
+	switch c := imageConfig.(type) {
+	case *config.StapelImage:
+		for _, imp := range c.Import {
+			importImageName := cmp.Or(imp.ImageName, imp.ArtifactName)
+			if imp.ExternalImage {
+				deps = append(deps, provenance.ResourceDescriptor{Name: "import/" + importImageName, URI: importImageName})
+				continue
+			}
+			deps = append(deps, newImageStageDependency("import", importImageName, imp.Stage))
+		}
+		dependencies = c.Dependencies
+	case *config.ImageFromDockerfile:
+		dependencies = c.Dependencies
+	}
+
+	for _, dep := range dependencies {
+		deps = append(deps, newImageStageDependency("dependency", dep.ImageName, ""))
+	}
+
+	return deps
+}
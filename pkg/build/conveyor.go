@@ -17,6 +17,7 @@ import (
 	"github.com/werf/logboek"
 	stylePkg "github.com/werf/logboek/pkg/style"
 	"github.com/werf/logboek/pkg/types"
+	"github.com/werf/werf/v2/pkg/base_images"
 	"github.com/werf/werf/v2/pkg/build/image"
 	"github.com/werf/werf/v2/pkg/build/import_server"
 	"github.com/werf/werf/v2/pkg/build/stage"
@@ -42,6 +43,10 @@ type Conveyor struct {
 	baseImagesRepoIdsCache map[string]string
 	baseImagesRepoErrCache map[string]error
 
+	baseImagesResolver     *base_images.Resolver
+	baseImagesResolverErr  error
+	baseImagesResolverOnce sync.Once
+
 	imagesTree *image.ImagesTree
 
 	stageImages        map[string]*stage.StageImage
@@ -220,6 +225,41 @@ func (c *Conveyor) SetBaseImagesRepoErrCache(key string, err error) {
 	c.baseImagesRepoErrCache[key] = err
 }
 
+// GetBaseImagesResolver returns the resolver of the base images locked in werf-base-images.lock.
+// The missing lock file is the empty lock, so the base images should be allowed explicitly by giterminism.
+func (c *Conveyor) GetBaseImagesResolver(ctx context.Context) (*base_images.Resolver, error) {
+	c.baseImagesResolverOnce.Do(func() {
+		c.baseImagesResolver, c.baseImagesResolverErr = c.newBaseImagesResolver(ctx)
+	})
+
+	return c.baseImagesResolver, c.baseImagesResolverErr
+}
+
+func (c *Conveyor) newBaseImagesResolver(ctx context.Context) (*base_images.Resolver, error) {
+	exist, err := c.giterminismManager.FileReader().IsBaseImagesLockFileExistAnywhere(ctx, base_images.DefaultLockFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := base_images.NewLock()
+	if exist {
+		data, err := c.giterminismManager.FileReader().ReadBaseImagesLockFile(ctx, base_images.DefaultLockFileName)
+		if err != nil {
+			return nil, err
+		}
+
+		lock, err = base_images.ParseLock(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", base_images.DefaultLockFileName, err)
+		}
+	}
+
+	return &base_images.Resolver{
+		Lock:            lock,
+		InspectUnlocked: c.giterminismManager.Inspector().InspectUnlockedBaseImage,
+	}, nil
+}
+
 func (c *Conveyor) GetStageDigestMutex(stage string) *sync.Mutex {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -445,7 +485,13 @@ func (c *Conveyor) GetImageInfoGetters(opts imagePkg.InfoGetterOptions) ([]*imag
 
 		if len(platforms) == 1 {
 			img := images[0]
-			getter := c.StorageManager.GetImageInfoGetter(img.Name, img.GetLastNonEmptyStage().GetStageImage().Image.GetStageDesc(), opts)
+			stageImage := img.GetLastNonEmptyStage().GetStageImage().Image
+			stageDesc := stageImage.GetFinalStageDesc()
+			if stageDesc == nil {
+				stageDesc = stageImage.GetStageDesc()
+			}
+			getter := c.StorageManager.GetImageInfoGetter(img.Name, stageDesc, opts)
+			getter.Platforms = platforms
 			imagesGetters = append(imagesGetters, getter)
 		} else {
 			img := c.imagesTree.GetMultiplatformImage(name)
@@ -454,6 +500,7 @@ func (c *Conveyor) GetImageInfoGetters(opts imagePkg.InfoGetterOptions) ([]*imag
 				stageDesc = img.GetStageDesc()
 			}
 			getter := c.StorageManager.GetImageInfoGetter(img.Name, stageDesc, opts)
+			getter.Platforms = platforms
 			imagesGetters = append(imagesGetters, getter)
 		}
 	}
@@ -1,8 +1,16 @@
 package build
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/werf/werf/v2/pkg/storage"
+	"github.com/werf/werf/v2/pkg/storage/manager"
+)
 
 var (
+	// ErrStagesRequired is returned by ShouldBeBuilt if some stages of the required images do not exist in the repo.
+	ErrStagesRequired = errors.New("stages required")
+
 	ErrMutableStageLocalStorage = errors.New(`local storage is not supported. Please specify a repo using the --repo flag or the WERF_REPO environment variable.
 
 Building a stage without a repo is not supported due to the excessive overhead caused by build backend limitations.`)
@@ -11,3 +19,8 @@ Building a stage without a repo is not supported due to the excessive overhead c
 
 To debug the build locally, consider running a local registry or skipping the imageSpec stage using the option --skip-image-spec-stage.`)
 )
+
+// IsErrStagesRequired returns true if the images should be built, because their stages, final images or custom tags do not exist in the repo.
+func IsErrStagesRequired(err error) bool {
+	return errors.Is(err, ErrStagesRequired) || errors.Is(err, manager.ErrStageNotFound) || errors.Is(err, storage.ErrCustomTagNotFound)
+}
@@ -1,8 +1,10 @@
 package image
 
 import (
+	"context"
 	"sync"
 
+	"github.com/werf/werf/v2/pkg/base_images"
 	"github.com/werf/werf/v2/pkg/build/stage"
 	"github.com/werf/werf/v2/pkg/git_repo"
 )
@@ -25,6 +27,8 @@ type Conveyor interface {
 	GetBaseImagesRepoErrCache(key string) error
 	SetBaseImagesRepoErrCache(key string, err error)
 
+	GetBaseImagesResolver(ctx context.Context) (*base_images.Resolver, error)
+
 	GetServiceRWMutex(service string) *sync.RWMutex
 
 	SetRemoteGitRepo(key string, repo *git_repo.Remote)
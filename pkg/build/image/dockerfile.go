@@ -28,9 +28,9 @@ import (
 func MapDockerfileConfigToImagesSets(ctx context.Context, metaConfig *config.Meta, dockerfileImageConfig *config.ImageFromDockerfile, targetPlatform string, opts CommonImageOptions) (ImagesSets, error) {
 	if dockerfileImageConfig.Staged {
 		relDockerfilePath := filepath.Join(dockerfileImageConfig.Context, dockerfileImageConfig.Dockerfile)
-		dockerfileData, err := opts.GiterminismManager.FileManager.ReadDockerfile(ctx, relDockerfilePath)
+		dockerfileData, err := readDockerfileWithLockedBaseImages(ctx, dockerfileImageConfig, opts)
 		if err != nil {
-			return nil, fmt.Errorf("unable to read dockerfile %s: %w", relDockerfilePath, err)
+			return nil, err
 		}
 
 		dockerfileID := util.Sha256Hash(filepath.Clean(relDockerfilePath))
@@ -295,9 +295,9 @@ func mapLegacyDockerfileToImage(ctx context.Context, metaConfig *config.Meta, do
 	}
 
 	relDockerfilePath := filepath.Join(dockerfileImageConfig.Context, dockerfileImageConfig.Dockerfile)
-	dockerfileData, err := opts.GiterminismManager.FileManager.ReadDockerfile(ctx, relDockerfilePath)
+	dockerfileData, err := readDockerfileWithLockedBaseImages(ctx, dockerfileImageConfig, opts)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read dockerfile %s: %w", relDockerfilePath, err)
+		return nil, err
 	}
 
 	p, err := parser.Parse(bytes.NewReader(dockerfileData))
@@ -412,3 +412,25 @@ func createDockerIgnorePathMatcher(ctx context.Context, giterminismMgr gitermini
 
 	return dockerIgnorePathMatcher, nil
 }
+
+// readDockerfileWithLockedBaseImages reads the Dockerfile with the base images pinned to the digests of werf-base-images.lock,
+// thus the locked digests are the part of the stage digests.
+func readDockerfileWithLockedBaseImages(ctx context.Context, dockerfileImageConfig *config.ImageFromDockerfile, opts CommonImageOptions) ([]byte, error) {
+	relDockerfilePath := filepath.Join(dockerfileImageConfig.Context, dockerfileImageConfig.Dockerfile)
+	dockerfileData, err := opts.GiterminismManager.FileManager.ReadDockerfile(ctx, relDockerfilePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dockerfile %s: %w", relDockerfilePath, err)
+	}
+
+	resolver, err := opts.Conveyor.GetBaseImagesResolver(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerfileData, err = resolver.ResolveDockerfile(dockerfileData, util.MapStringInterfaceToMapStringString(dockerfileImageConfig.Args), stage.GetDependenciesArgsKeys(dockerfileImageConfig.Dependencies))
+	if err != nil {
+		return nil, fmt.Errorf("image %q: dockerfile %s: %w", dockerfileImageConfig.Name, relDockerfilePath, err)
+	}
+
+	return dockerfileData, nil
+}
@@ -39,6 +39,17 @@ func mapStapelConfigToImage(ctx context.Context, metaConfig *config.Meta, stapel
 		baseImageType = ImageFromRegistryAsBaseImage
 		imageOpts.BaseImageReference = imageBaseConfig.From
 		imageOpts.FetchLatestBaseImage = imageBaseConfig.FromLatest
+
+		if !imageBaseConfig.FromLatest {
+			resolver, err := opts.Conveyor.GetBaseImagesResolver(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			if imageOpts.BaseImageReference, err = resolver.Resolve(imageBaseConfig.From); err != nil {
+				return nil, fmt.Errorf("image %q: %w", imageName, err)
+			}
+		}
 	} else {
 		fromImage := imageBaseConfig.From
 		baseImageType = StageAsBaseImage
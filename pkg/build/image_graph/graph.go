@@ -0,0 +1,111 @@
+package image_graph
+
+import (
+	"fmt"
+
+	"github.com/werf/werf/v2/pkg/config"
+)
+
+type EdgeType string
+
+const (
+	// StageEdge connects the stage with the previous stage of the same image.
+	StageEdge      EdgeType = "stage"
+	FromEdge       EdgeType = "from"
+	ImportEdge     EdgeType = "import"
+	DependencyEdge EdgeType = "dependency"
+)
+
+// Graph is the dependency graph of the images or of the image stages.
+type Graph struct {
+	Nodes []*Node `json:"nodes" yaml:"nodes"`
+	Edges []*Edge `json:"edges" yaml:"edges"`
+}
+
+// Node is the image or the image stage if the stage is set.
+type Node struct {
+	ID    string `json:"id" yaml:"id"`
+	Image string `json:"image" yaml:"image"`
+	Stage string `json:"stage,omitempty" yaml:"stage,omitempty"`
+}
+
+// Edge points from the dependency to the dependent node.
+type Edge struct {
+	From  string   `json:"from" yaml:"from"`
+	To    string   `json:"to" yaml:"to"`
+	Type  EdgeType `json:"type" yaml:"type"`
+	Label string   `json:"label,omitempty" yaml:"label,omitempty"`
+}
+
+// NewImageGraph returns the graph of the images to process and the images they depend on.
+func NewImageGraph(werfConfig *config.WerfConfig, imagesToProcess config.ImagesToProcess) (*Graph, error) {
+	graphList, err := werfConfig.GetImageGraphList(imagesToProcess)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph{}
+	for _, item := range graphList {
+		g.addNode(&Node{ID: item.ImageName, Image: item.ImageName})
+	}
+
+	for _, item := range graphList {
+		if item.DependsOn.From != "" {
+			g.addImageEdge(item.DependsOn.From, item.ImageName, FromEdge)
+		}
+		for _, name := range item.DependsOn.Imports {
+			g.addImageEdge(name, item.ImageName, ImportEdge)
+		}
+		for _, name := range item.DependsOn.Dependencies {
+			g.addImageEdge(name, item.ImageName, DependencyEdge)
+		}
+	}
+
+	return g, nil
+}
+
+func (g *Graph) addImageEdge(from, to string, edgeType EdgeType) {
+	g.addNode(&Node{ID: from, Image: from})
+	g.addEdge(&Edge{From: from, To: to, Type: edgeType})
+}
+
+func (g *Graph) addNode(node *Node) {
+	if g.node(node.ID) == nil {
+		g.Nodes = append(g.Nodes, node)
+	}
+}
+
+func (g *Graph) node(id string) *Node {
+	for _, n := range g.Nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+func (g *Graph) addEdge(edge *Edge) {
+	for _, e := range g.Edges {
+		if *e == *edge {
+			return
+		}
+	}
+	g.Edges = append(g.Edges, edge)
+}
+
+// images returns the image names in the order of the first node of each image.
+func (g *Graph) images() []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, n := range g.Nodes {
+		if !seen[n.Image] {
+			seen[n.Image] = true
+			res = append(res, n.Image)
+		}
+	}
+	return res
+}
+
+func stageNodeID(imageName, stageName string) string {
+	return fmt.Sprintf("%s/%s", imageName, stageName)
+}
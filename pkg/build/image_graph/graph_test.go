@@ -0,0 +1,186 @@
+package image_graph
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/v2/pkg/config"
+)
+
+type dockerfileReaderStub map[string]string
+
+func (r dockerfileReaderStub) ReadDockerfile(_ context.Context, relPath string) ([]byte, error) {
+	data, ok := r[relPath]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", relPath)
+	}
+	return []byte(data), nil
+}
+
+func newTestWerfConfig() *config.WerfConfig {
+	return config.NewWerfConfig(&config.Meta{}, []config.ImageInterface{
+		&config.StapelImage{StapelImageBase: &config.StapelImageBase{
+			Name:  "base",
+			From:  "alpine",
+			Shell: &config.Shell{Install: []string{"apk add curl"}},
+		}},
+		&config.StapelImageArtifact{StapelImageBase: &config.StapelImageBase{
+			Name:  "builder",
+			From:  "golang",
+			Shell: &config.Shell{BeforeInstall: []string{"go mod download"}, Setup: []string{"go build"}},
+		}},
+		&config.StapelImage{StapelImageBase: &config.StapelImageBase{
+			Name:         "app",
+			From:         "base",
+			Import:       []*config.Import{{ArtifactName: "builder", After: "install", Stage: "install"}},
+			Dependencies: []*config.Dependency{{ImageName: "web", Before: "setup"}},
+			ImageSpec:    &config.ImageSpec{},
+		}},
+		&config.ImageFromDockerfile{
+			Name:       "web",
+			Dockerfile: "Dockerfile",
+		},
+		&config.ImageFromDockerfile{
+			Name:         "staged",
+			Context:      "staged",
+			Dockerfile:   "Dockerfile",
+			Staged:       true,
+			Dependencies: []*config.Dependency{{ImageName: "base"}},
+		},
+	})
+}
+
+var testDockerfileReader = dockerfileReaderStub{
+	"staged/Dockerfile": `FROM alpine AS build
+RUN make
+
+FROM alpine
+ARG VERSION
+COPY --from=build /out /out
+`,
+}
+
+var _ = Describe("image graph", func() {
+	It("should connect the images with the from, import and dependency edges", func() {
+		g, err := NewImageGraph(newTestWerfConfig(), config.ImagesToProcess{ImageNameList: []string{"app"}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(g.Nodes).To(Equal([]*Node{
+			{ID: "app", Image: "app"},
+			{ID: "base", Image: "base"},
+			{ID: "builder", Image: "builder"},
+			{ID: "web", Image: "web"},
+		}))
+		Expect(g.Edges).To(Equal([]*Edge{
+			{From: "base", To: "app", Type: FromEdge},
+			{From: "builder", To: "app", Type: ImportEdge},
+			{From: "web", To: "app", Type: DependencyEdge},
+		}))
+	})
+})
+
+var _ = Describe("stage graph", func() {
+	It("should expand the stapel images into the stages and connect the dependencies with the stages using them", func() {
+		g, err := NewStageGraph(context.Background(), newTestWerfConfig(), config.ImagesToProcess{ImageNameList: []string{"app"}}, testDockerfileReader)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var nodeIDs []string
+		for _, n := range g.Nodes {
+			nodeIDs = append(nodeIDs, n.ID)
+		}
+		Expect(nodeIDs).To(Equal([]string{
+			"app/from", "app/dependenciesAfterInstall", "app/dependenciesBeforeSetup", "app/imageSpec",
+			"base/from", "base/install",
+			"builder/from", "builder/beforeInstall", "builder/setup",
+			"web/dockerfile",
+		}))
+
+		Expect(g.Edges).To(ContainElements(
+			&Edge{From: "app/from", To: "app/dependenciesAfterInstall", Type: StageEdge},
+			&Edge{From: "base/install", To: "app/from", Type: FromEdge},
+			&Edge{From: "builder/beforeInstall", To: "app/dependenciesAfterInstall", Type: ImportEdge, Label: "after install"},
+			&Edge{From: "web/dockerfile", To: "app/dependenciesBeforeSetup", Type: DependencyEdge, Label: "before setup"},
+		))
+		Expect(g.Edges).To(HaveLen(9))
+	})
+
+	It("should expand the staged Dockerfile image into the instructions of the Dockerfile stages", func() {
+		g, err := NewStageGraph(context.Background(), newTestWerfConfig(), config.ImagesToProcess{ImageNameList: []string{"staged"}}, testDockerfileReader)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(g.Nodes).To(ContainElements(
+			&Node{ID: "staged/COPY1", Image: "staged", Stage: "COPY1"},
+			&Node{ID: "staged/stage/build/RUN1", Image: "staged/stage/build", Stage: "RUN1"},
+		))
+		Expect(g.Edges).To(ConsistOf(
+			&Edge{From: "base/from", To: "base/install", Type: StageEdge},
+			&Edge{From: "staged/stage/build/RUN1", To: "staged/COPY1", Type: ImportEdge},
+			&Edge{From: "base/install", To: "staged/COPY1", Type: DependencyEdge},
+		))
+	})
+
+	It("should fail if the Dockerfile of the staged image cannot be read", func() {
+		_, err := NewStageGraph(context.Background(), newTestWerfConfig(), config.ImagesToProcess{ImageNameList: []string{"staged"}}, dockerfileReaderStub{})
+		Expect(err).Should(MatchError(ContainSubstring("unable to read dockerfile staged/Dockerfile")))
+	})
+})
+
+var _ = Describe("graph rendering", func() {
+	graph := &Graph{
+		Nodes: []*Node{
+			{ID: "app/from", Image: "app", Stage: "from"},
+			{ID: "app/dependenciesAfterInstall", Image: "app", Stage: "dependenciesAfterInstall"},
+			{ID: "builder/install", Image: "builder", Stage: "install"},
+		},
+		Edges: []*Edge{
+			{From: "app/from", To: "app/dependenciesAfterInstall", Type: StageEdge},
+			{From: "builder/install", To: "app/dependenciesAfterInstall", Type: ImportEdge, Label: "after install"},
+		},
+	}
+
+	It("should render the stages of the images as the DOT clusters", func() {
+		Expect(graph.DOT()).To(Equal(`digraph werf {
+  rankdir=LR;
+  node [shape=box];
+  subgraph "cluster_0" {
+    label="app";
+    "app/from" [label="from"];
+    "app/dependenciesAfterInstall" [label="dependenciesAfterInstall"];
+  }
+  subgraph "cluster_1" {
+    label="builder";
+    "builder/install" [label="install"];
+  }
+  "app/from" -> "app/dependenciesAfterInstall";
+  "builder/install" -> "app/dependenciesAfterInstall" [label="import after install", style=dashed];
+}
+`))
+	})
+
+	It("should render the stages of the images as the Mermaid subgraphs", func() {
+		Expect(graph.Mermaid()).To(Equal(`flowchart LR
+  subgraph s0 ["app"]
+    n0["from"]
+    n1["dependenciesAfterInstall"]
+  end
+  subgraph s1 ["builder"]
+    n2["install"]
+  end
+  n0 --> n1
+  n2 -.->|"import after install"| n1
+`))
+	})
+
+	It("should render the images without the stages as the plain nodes", func() {
+		g := &Graph{
+			Nodes: []*Node{{ID: "app", Image: "app"}, {ID: "base", Image: "base"}},
+			Edges: []*Edge{{From: "base", To: "app", Type: FromEdge}},
+		}
+
+		Expect(g.DOT()).To(ContainSubstring("  \"app\";\n  \"base\";\n  \"base\" -> \"app\" [label=\"from\"];\n"))
+		Expect(g.Mermaid()).To(Equal("flowchart LR\n  n0[\"app\"]\n  n1[\"base\"]\n  n1 -->|\"from\"| n0\n"))
+	})
+})
@@ -0,0 +1,13 @@
+package image_graph
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestImageGraph(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Image Graph Suite")
+}
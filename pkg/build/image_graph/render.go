@@ -0,0 +1,113 @@
+package image_graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DOT returns the graph in the Graphviz DOT language, the stages are grouped into the clusters of the images.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph werf {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for i, image := range g.images() {
+		nodes := g.imageNodes(image)
+		if nodes[0].Stage == "" {
+			fmt.Fprintf(&b, "  %s;\n", strconv.Quote(nodes[0].ID))
+			continue
+		}
+
+		fmt.Fprintf(&b, "  subgraph %s {\n", strconv.Quote(fmt.Sprintf("cluster_%d", i)))
+		fmt.Fprintf(&b, "    label=%s;\n", strconv.Quote(image))
+		for _, n := range nodes {
+			fmt.Fprintf(&b, "    %s [label=%s];\n", strconv.Quote(n.ID), strconv.Quote(n.Stage))
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, e := range g.Edges {
+		var attrs []string
+		if label := e.label(); label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%s", strconv.Quote(label)))
+		}
+		if e.Type == ImportEdge || e.Type == DependencyEdge {
+			attrs = append(attrs, "style=dashed")
+		}
+
+		fmt.Fprintf(&b, "  %s -> %s", strconv.Quote(e.From), strconv.Quote(e.To))
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid returns the graph as the Mermaid flowchart, the stages are grouped into the subgraphs of the images.
+func (g *Graph) Mermaid() string {
+	ids := map[string]string{}
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	for i, image := range g.images() {
+		nodes := g.imageNodes(image)
+		if nodes[0].Stage == "" {
+			fmt.Fprintf(&b, "  %s[%s]\n", ids[nodes[0].ID], mermaidText(image))
+			continue
+		}
+
+		fmt.Fprintf(&b, "  subgraph s%d [%s]\n", i, mermaidText(image))
+		for _, n := range nodes {
+			fmt.Fprintf(&b, "    %s[%s]\n", ids[n.ID], mermaidText(n.Stage))
+		}
+		b.WriteString("  end\n")
+	}
+
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Type == ImportEdge || e.Type == DependencyEdge {
+			arrow = "-.->"
+		}
+
+		if label := e.label(); label != "" {
+			fmt.Fprintf(&b, "  %s %s|%s| %s\n", ids[e.From], arrow, mermaidText(label), ids[e.To])
+		} else {
+			fmt.Fprintf(&b, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+		}
+	}
+
+	return b.String()
+}
+
+func (g *Graph) imageNodes(image string) []*Node {
+	var res []*Node
+	for _, n := range g.Nodes {
+		if n.Image == image {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// label returns the edge type with the details, e.g. "import before install", the edges between the stages of the same image are not labeled.
+func (e *Edge) label() string {
+	if e.Type == StageEdge {
+		return ""
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("%s %s", e.Type, e.Label))
+}
+
+// mermaidText returns the quoted text escaping the characters Mermaid does not allow inside the quotes.
+func mermaidText(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,350 @@
+package image_graph
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/werf/v2/pkg/build/stage"
+	"github.com/werf/werf/v2/pkg/config"
+	"github.com/werf/werf/v2/pkg/dockerfile"
+	"github.com/werf/werf/v2/pkg/dockerfile/frontend"
+	"github.com/werf/werf/v2/pkg/util/option"
+	"github.com/werf/werf/v2/pkg/werf"
+)
+
+// DockerfileReader reads the Dockerfiles of the staged Dockerfile images, e.g. the giterminism file manager.
+type DockerfileReader interface {
+	ReadDockerfile(ctx context.Context, relPath string) ([]byte, error)
+}
+
+type stageGraphBuilder struct {
+	graph            *Graph
+	dockerfileReader DockerfileReader
+
+	// imageStages are the stage names of the images in the build order.
+	imageStages map[string][]string
+}
+
+// NewStageGraph returns the graph of the stages of the images to process and of the images they depend on.
+// The stapel images are expanded into the stages werf builds for the config, the staged Dockerfile images into the
+// instructions of the target Dockerfile stage and of the Dockerfile stages it depends on, and the other Dockerfile
+// images are represented by the single dockerfile stage.
+func NewStageGraph(ctx context.Context, werfConfig *config.WerfConfig, imagesToProcess config.ImagesToProcess, dockerfileReader DockerfileReader) (*Graph, error) {
+	b := &stageGraphBuilder{
+		graph:            &Graph{},
+		dockerfileReader: dockerfileReader,
+		imageStages:      map[string][]string{},
+	}
+
+	var images []config.ImageInterface
+	dependsOn := map[string]config.DependsOn{}
+	queue := append([]string{}, imagesToProcess.ImageNameList...)
+	for len(queue) > 0 {
+		imageName := queue[0]
+		queue = queue[1:]
+
+		if _, ok := dependsOn[imageName]; ok {
+			continue
+		}
+
+		graphList, err := werfConfig.GetImageGraphList(config.ImagesToProcess{ImageNameList: []string{imageName}})
+		if err != nil {
+			return nil, err
+		}
+
+		d := graphList[0].DependsOn
+		dependsOn[imageName] = d
+		images = append(images, werfConfig.GetImage(imageName))
+
+		if d.From != "" {
+			queue = append(queue, d.From)
+		}
+		queue = append(queue, d.Imports...)
+		queue = append(queue, d.Dependencies...)
+	}
+
+	for _, img := range images {
+		switch imageConfig := img.(type) {
+		case config.StapelImageInterface:
+			b.addStapelImage(ctx, imageConfig)
+		case *config.ImageFromDockerfile:
+			if err := b.addDockerfileImage(ctx, imageConfig); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, img := range images {
+		if stapelImageConfig, ok := img.(config.StapelImageInterface); ok {
+			b.addStapelImageDependencies(stapelImageConfig.ImageBaseConfig(), dependsOn[img.GetName()])
+		} else if dockerfileImageConfig, ok := img.(*config.ImageFromDockerfile); ok {
+			b.addDockerfileImageDependencies(dockerfileImageConfig)
+		}
+	}
+
+	return b.graph, nil
+}
+
+func (b *stageGraphBuilder) addStapelImage(ctx context.Context, imageConfig config.StapelImageInterface) {
+	imageBaseConfig := imageConfig.ImageBaseConfig()
+	baseStageOptions := &stage.BaseStageOptions{ImageName: imageBaseConfig.Name}
+	gitPatchStageOptions := &stage.NewGitPatchStageOptions{}
+	gitMappingsExist := imageBaseConfig.Git != nil && len(imageBaseConfig.Git.Local)+len(imageBaseConfig.Git.Remote) > 0
+
+	stages := []stage.StageName{stage.From}
+	if stage.GenerateBeforeInstallStage(ctx, imageBaseConfig, baseStageOptions) != nil {
+		stages = append(stages, stage.BeforeInstall)
+	}
+	if stage.GenerateDependenciesBeforeInstallStage(imageBaseConfig, baseStageOptions) != nil {
+		stages = append(stages, stage.DependenciesBeforeInstall)
+	}
+	if gitMappingsExist {
+		stages = append(stages, stage.GitArchive)
+	}
+	if stage.GenerateInstallStage(ctx, imageBaseConfig, gitPatchStageOptions, baseStageOptions) != nil {
+		stages = append(stages, stage.Install)
+	}
+	if stage.GenerateDependenciesAfterInstallStage(imageBaseConfig, baseStageOptions) != nil {
+		stages = append(stages, stage.DependenciesAfterInstall)
+	}
+	if stage.GenerateBeforeSetupStage(ctx, imageBaseConfig, gitPatchStageOptions, baseStageOptions) != nil {
+		stages = append(stages, stage.BeforeSetup)
+	}
+	if stage.GenerateDependenciesBeforeSetupStage(imageBaseConfig, baseStageOptions) != nil {
+		stages = append(stages, stage.DependenciesBeforeSetup)
+	}
+	if stage.GenerateSetupStage(ctx, imageBaseConfig, gitPatchStageOptions, baseStageOptions) != nil {
+		stages = append(stages, stage.Setup)
+	}
+	if stage.GenerateDependenciesAfterSetupStage(imageBaseConfig, baseStageOptions) != nil {
+		stages = append(stages, stage.DependenciesAfterSetup)
+	}
+	if !imageConfig.IsGitAfterPatchDisabled() {
+		if gitMappingsExist {
+			stages = append(stages, stage.GitCache, stage.GitLatestPatch)
+		}
+		if stapelImageConfig, ok := imageConfig.(*config.StapelImage); ok && stapelImageConfig.Docker != nil {
+			stages = append(stages, stage.DockerInstructions)
+		}
+	}
+	if imageBaseConfig.ImageSpec != nil {
+		stages = append(stages, stage.ImageSpec)
+	}
+
+	var stageNames []string
+	for _, s := range stages {
+		stageNames = append(stageNames, string(s))
+	}
+	b.addImageStages(imageBaseConfig.Name, stageNames)
+}
+
+func (b *stageGraphBuilder) addStapelImageDependencies(imageBaseConfig *config.StapelImageBase, dependsOn config.DependsOn) {
+	imageName := imageBaseConfig.Name
+
+	if dependsOn.From != "" {
+		b.addStageEdge(dependsOn.From, b.lastStage(dependsOn.From, ""), imageName, string(stage.From), FromEdge, "")
+	}
+
+	for _, imp := range imageBaseConfig.Import {
+		fromImageName := option.ValueOrDefault(imp.ImageName, imp.ArtifactName)
+		if fromImageName == "" || (imp.ImageName != "" && imp.ExternalImage) {
+			continue
+		}
+
+		stageName, label := dependenciesStage(imp.Before, imp.After)
+		b.addStageEdge(fromImageName, b.lastStage(fromImageName, imp.Stage), imageName, stageName, ImportEdge, label)
+	}
+
+	for _, dep := range imageBaseConfig.Dependencies {
+		stageName, label := dependenciesStage(dep.Before, dep.After)
+		b.addStageEdge(dep.ImageName, b.lastStage(dep.ImageName, ""), imageName, stageName, DependencyEdge, label)
+	}
+}
+
+func (b *stageGraphBuilder) addDockerfileImage(ctx context.Context, imageConfig *config.ImageFromDockerfile) error {
+	if !imageConfig.Staged {
+		stageNames := []string{string(stage.Dockerfile)}
+		if imageConfig.ImageSpec != nil {
+			stageNames = append(stageNames, string(stage.ImageSpec))
+		}
+		b.addImageStages(imageConfig.Name, stageNames)
+		return nil
+	}
+
+	relDockerfilePath := filepath.Join(imageConfig.Context, imageConfig.Dockerfile)
+	dockerfileData, err := b.dockerfileReader.ReadDockerfile(ctx, relDockerfilePath)
+	if err != nil {
+		return fmt.Errorf("unable to read dockerfile %s: %w", relDockerfilePath, err)
+	}
+
+	d, err := frontend.ParseDockerfileWithBuildkit(util.Sha256Hash(filepath.Clean(relDockerfilePath)), dockerfileData, imageConfig.Name, dockerfile.DockerfileOptions{
+		Target:               imageConfig.Target,
+		BuildArgs:            util.MapStringInterfaceToMapStringString(imageConfig.Args),
+		DependenciesArgsKeys: stage.GetDependenciesArgsKeys(imageConfig.Dependencies),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to parse dockerfile %s: %w", relDockerfilePath, err)
+	}
+
+	targetStage, err := d.GetTargetStage()
+	if err != nil {
+		return fmt.Errorf("unable to get target dockerfile stage: %w", err)
+	}
+
+	type queueItem struct {
+		werfImageName string
+		stage         *dockerfile.DockerfileStage
+	}
+
+	type importItem struct {
+		fromImageName, toImageName, toStageName string
+	}
+
+	var fromItems, importItems []importItem
+	queue := []queueItem{{werfImageName: imageConfig.Name, stage: targetStage}}
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+
+		if _, ok := b.imageStages[item.werfImageName]; ok {
+			continue
+		}
+
+		var stageNames []string
+		instrNum := 0
+		if werf.GetStagedDockerfileVersion() == werf.StagedDockerfileV2 {
+			stageNames = append(stageNames, "FROM1")
+			instrNum = 1
+		}
+
+		for _, instr := range item.stage.Instructions {
+			if _, ok := any(instr).(*dockerfile.DockerfileStageInstruction[*instructions.ArgCommand]); ok {
+				continue
+			}
+
+			stageName := fmt.Sprintf("%s%d", strings.ToUpper(instr.GetInstructionData().Name()), instrNum+1)
+			stageNames = append(stageNames, stageName)
+
+			deps := instr.GetDependenciesByStageRef()
+			refs := make([]string, 0, len(deps))
+			for ref := range deps {
+				refs = append(refs, ref)
+			}
+			sort.Strings(refs)
+
+			for _, ref := range refs {
+				dep := deps[ref]
+				importItems = append(importItems, importItem{fromImageName: dep.GetWerfImageName(), toImageName: item.werfImageName, toStageName: stageName})
+				queue = append(queue, queueItem{werfImageName: dep.GetWerfImageName(), stage: dep})
+			}
+
+			instrNum++
+		}
+
+		if len(stageNames) == 0 {
+			stageNames = append(stageNames, string(stage.From))
+		}
+
+		if baseStage := d.FindStage(item.stage.BaseName); baseStage != nil {
+			fromItems = append(fromItems, importItem{fromImageName: baseStage.GetWerfImageName(), toImageName: item.werfImageName, toStageName: stageNames[0]})
+			queue = append(queue, queueItem{werfImageName: baseStage.GetWerfImageName(), stage: baseStage})
+		}
+
+		b.addImageStages(item.werfImageName, stageNames)
+	}
+
+	for _, item := range fromItems {
+		b.addStageEdge(item.fromImageName, b.lastStage(item.fromImageName, ""), item.toImageName, item.toStageName, FromEdge, "")
+	}
+
+	for _, item := range importItems {
+		b.addStageEdge(item.fromImageName, b.lastStage(item.fromImageName, ""), item.toImageName, item.toStageName, ImportEdge, "")
+	}
+
+	return nil
+}
+
+func (b *stageGraphBuilder) addDockerfileImageDependencies(imageConfig *config.ImageFromDockerfile) {
+	stageNames := b.imageStages[imageConfig.Name]
+	if len(stageNames) == 0 {
+		return
+	}
+
+	for _, dep := range imageConfig.Dependencies {
+		b.addStageEdge(dep.ImageName, b.lastStage(dep.ImageName, ""), imageConfig.Name, stageNames[0], DependencyEdge, "")
+	}
+}
+
+func (b *stageGraphBuilder) addImageStages(imageName string, stageNames []string) {
+	b.imageStages[imageName] = stageNames
+
+	for i, stageName := range stageNames {
+		b.graph.addNode(&Node{ID: stageNodeID(imageName, stageName), Image: imageName, Stage: stageName})
+
+		if i > 0 {
+			b.graph.addEdge(&Edge{From: stageNodeID(imageName, stageNames[i-1]), To: stageNodeID(imageName, stageName), Type: StageEdge})
+		}
+	}
+}
+
+func (b *stageGraphBuilder) addStageEdge(fromImageName, fromStageName, toImageName, toStageName string, edgeType EdgeType, label string) {
+	if fromStageName == "" {
+		return
+	}
+
+	b.graph.addEdge(&Edge{
+		From:  stageNodeID(fromImageName, fromStageName),
+		To:    stageNodeID(toImageName, toStageName),
+		Type:  edgeType,
+		Label: label,
+	})
+}
+
+// lastStage returns the last stage of the image, or the last stage up to the stapel stage if it is set
+// (the stage is used by the import even if the image does not have it).
+func (b *stageGraphBuilder) lastStage(imageName, upToStage string) string {
+	stageNames := b.imageStages[imageName]
+	if len(stageNames) == 0 {
+		return ""
+	}
+
+	if upToStage != "" {
+		upToIndex := stapelStageIndex(upToStage)
+		for i := len(stageNames) - 1; i >= 0; i-- {
+			if index := stapelStageIndex(stageNames[i]); index >= 0 && upToIndex >= 0 && index <= upToIndex {
+				return stageNames[i]
+			}
+		}
+	}
+
+	return stageNames[len(stageNames)-1]
+}
+
+func stapelStageIndex(stageName string) int {
+	for i, s := range stage.AllStages {
+		if string(s) == stageName {
+			return i
+		}
+	}
+	return -1
+}
+
+// dependenciesStage returns the stapel stage of the import or the dependency with the before or after directive.
+func dependenciesStage(before, after string) (string, string) {
+	switch {
+	case before == string(stage.Install):
+		return string(stage.DependenciesBeforeInstall), "before install"
+	case after == string(stage.Install):
+		return string(stage.DependenciesAfterInstall), "after install"
+	case before == string(stage.Setup):
+		return string(stage.DependenciesBeforeSetup), "before setup"
+	default:
+		return string(stage.DependenciesAfterSetup), "after setup"
+	}
+}
@@ -0,0 +1,157 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://slsa.dev/provenance/v1"
+	BuildType     = "https://werf.io/provenance/build/v1"
+	BuilderID     = "https://werf.io"
+
+	// MediaType is the media type of the in-toto statement, it is used as the artifact type of the OCI referrer.
+	MediaType = "application/vnd.in-toto+json"
+
+	// TargetRegistry is the --provenance value to attach statements to the images as OCI referrers.
+	TargetRegistry = "registry"
+)
+
+type Statement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     Predicate            `json:"predicate"`
+}
+
+type ResourceDescriptor struct {
+	Name        string            `json:"name,omitempty"`
+	URI         string            `json:"uri,omitempty"`
+	Digest      map[string]string `json:"digest,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   ExternalParameters   `json:"externalParameters"`
+	InternalParameters   InternalParameters   `json:"internalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// ExternalParameters are the user controlled build inputs of the image from werf.yaml.
+type ExternalParameters struct {
+	Image      string                 `json:"image"`
+	Platforms  []string               `json:"platforms,omitempty"`
+	Dockerfile string                 `json:"dockerfile,omitempty"`
+	Context    string                 `json:"context,omitempty"`
+	Target     string                 `json:"target,omitempty"`
+	BuildArgs  map[string]interface{} `json:"buildArgs,omitempty"`
+}
+
+// InternalParameters are the werf build inputs, which are not controlled by the user directly.
+type InternalParameters struct {
+	Project  string            `json:"project"`
+	StageIDs map[string]string `json:"stageIDs,omitempty"`
+}
+
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type Metadata struct {
+	FinishedOn string `json:"finishedOn,omitempty"`
+}
+
+func NewStatement(subject ResourceDescriptor, buildDefinition BuildDefinition, werfVersion string, finishedOn time.Time) *Statement {
+	buildDefinition.BuildType = BuildType
+
+	return &Statement{
+		Type:          StatementType,
+		Subject:       []ResourceDescriptor{subject},
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			BuildDefinition: buildDefinition,
+			RunDetails: RunDetails{
+				Builder: Builder{
+					ID:      BuilderID,
+					Version: map[string]string{"werf": werfVersion},
+				},
+				Metadata: Metadata{FinishedOn: finishedOn.UTC().Format(time.RFC3339)},
+			},
+		},
+	}
+}
+
+// NewDigestSet converts the digest in the form algorithm:hex into the in-toto digest set.
+func NewDigestSet(digest string) map[string]string {
+	algorithm, hex, ok := strings.Cut(digest, ":")
+	if !ok || algorithm == "" || hex == "" {
+		return nil
+	}
+	return map[string]string{algorithm: hex}
+}
+
+// NewArtifact returns OCI artifact with the statement, which refers to the subject image and can be pushed as OCI referrer.
+func NewArtifact(statement *Statement, subject v1.Descriptor) (v1.Image, error) {
+	data, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal statement: %w", err)
+	}
+
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: static.NewLayer(data, MediaType)})
+	if err != nil {
+		return nil, fmt.Errorf("unable to append statement layer: %w", err)
+	}
+
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, MediaType)
+
+	return mutate.Subject(img, subject).(v1.Image), nil
+}
+
+// WriteFile writes the statements into the file in the JSON Lines format (one statement per line) ordered by the subject name.
+func WriteFile(path string, statements []*Statement) error {
+	statements = slices.Clone(statements)
+	slices.SortFunc(statements, func(a, b *Statement) int {
+		return strings.Compare(a.Subject[0].Name, b.Subject[0].Name)
+	})
+
+	var buf bytes.Buffer
+	for _, statement := range statements {
+		data, err := json.Marshal(statement)
+		if err != nil {
+			return fmt.Errorf("unable to marshal statement: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteString("\n")
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("unable to write provenance to %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package provenance
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("provenance", func() {
+	newStatement := func(name string) *Statement {
+		return NewStatement(
+			ResourceDescriptor{Name: name, Digest: NewDigestSet("sha256:0123")},
+			BuildDefinition{
+				ExternalParameters: ExternalParameters{Image: "backend", BuildArgs: map[string]interface{}{"VERSION": "1"}},
+				ResolvedDependencies: []ResourceDescriptor{
+					{URI: "git+https://example.com/project.git", Digest: map[string]string{"gitCommit": "abc"}},
+				},
+			},
+			"v2.0.0",
+			time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		)
+	}
+
+	It("should create SLSA provenance statement", func() {
+		statement := newStatement("registry.example.com/project:tag")
+
+		Expect(statement.Type).To(Equal(StatementType))
+		Expect(statement.PredicateType).To(Equal(PredicateType))
+		Expect(statement.Subject).To(Equal([]ResourceDescriptor{{Name: "registry.example.com/project:tag", Digest: map[string]string{"sha256": "0123"}}}))
+		Expect(statement.Predicate.BuildDefinition.BuildType).To(Equal(BuildType))
+		Expect(statement.Predicate.RunDetails.Builder).To(Equal(Builder{ID: BuilderID, Version: map[string]string{"werf": "v2.0.0"}}))
+		Expect(statement.Predicate.RunDetails.Metadata.FinishedOn).To(Equal("2025-01-01T00:00:00Z"))
+	})
+
+	DescribeTable("NewDigestSet",
+		func(digest string, expected map[string]string) {
+			Expect(NewDigestSet(digest)).To(Equal(expected))
+		},
+		Entry("sha256 digest", "sha256:0123", map[string]string{"sha256": "0123"}),
+		Entry("empty digest", "", nil),
+		Entry("digest without algorithm", "0123", nil),
+	)
+
+	It("should create OCI artifact referring to the subject", func() {
+		subject := v1.Descriptor{
+			MediaType: types.OCIImageIndex,
+			Digest:    v1.Hash{Algorithm: "sha256", Hex: "0123"},
+			Size:      100,
+		}
+
+		artifact, err := NewArtifact(newStatement("registry.example.com/project:tag"), subject)
+		Expect(err).To(Succeed())
+
+		manifest, err := artifact.Manifest()
+		Expect(err).To(Succeed())
+		Expect(manifest.MediaType).To(Equal(types.OCIManifestSchema1))
+		Expect(manifest.Config.MediaType).To(Equal(types.MediaType(MediaType)))
+		Expect(manifest.Subject).To(Equal(&subject))
+		Expect(manifest.Layers).To(HaveLen(1))
+		Expect(manifest.Layers[0].MediaType).To(Equal(types.MediaType(MediaType)))
+
+		layers, err := artifact.Layers()
+		Expect(err).To(Succeed())
+		rc, err := layers[0].Uncompressed()
+		Expect(err).To(Succeed())
+		defer rc.Close()
+
+		var statement Statement
+		Expect(json.NewDecoder(rc).Decode(&statement)).To(Succeed())
+		Expect(statement.Subject[0].Name).To(Equal("registry.example.com/project:tag"))
+	})
+
+	It("should write statements in JSON Lines format ordered by subject", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "provenance.intoto.jsonl")
+
+		Expect(WriteFile(path, []*Statement{newStatement("repo:b"), newStatement("repo:a")})).To(Succeed())
+
+		f, err := os.Open(path)
+		Expect(err).To(Succeed())
+		defer f.Close()
+
+		var names []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var statement Statement
+			Expect(json.Unmarshal(scanner.Bytes(), &statement)).To(Succeed())
+			names = append(names, statement.Subject[0].Name)
+		}
+		Expect(scanner.Err()).To(Succeed())
+		Expect(names).To(Equal([]string{"repo:a", "repo:b"}))
+	})
+})
@@ -0,0 +1,71 @@
+package build
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/werf/logboek"
+)
+
+type WaitForImagesOptions struct {
+	ShouldBeBuiltOptions
+
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// WithWaitForImagesBlock waits until the required images are built and exist in the repo (e.g. built concurrently by another runner)
+// and runs f with the conveyor, which has checked the images like ShouldBeBuilt.
+// The repo is polled quietly, the last check is performed with the regular output
+// and returns the should be built error if the images are still missing after the timeout.
+func (wrapper *ConveyorWithRetryWrapper) WithWaitForImagesBlock(ctx context.Context, opts WaitForImagesOptions, f func(c *Conveyor) error) error {
+	quietConveyorOptions := wrapper.ConveyorOptions
+	quietConveyorOptions.DeferBuildLog = false
+	quietWrapper := NewConveyorWithRetryWrapper(wrapper.WerfConfig, wrapper.GiterminismManager, wrapper.ProjectDir, wrapper.BaseTmpDir, wrapper.ContainerBackend, wrapper.StorageManager, wrapper.StorageLockManager, quietConveyorOptions)
+	defer quietWrapper.Terminate()
+
+	quietCtx := logboek.NewContext(ctx, logboek.NewLogger(io.Discard, io.Discard))
+
+	if err := waitForImages(ctx, opts, func() error {
+		defer wrapper.StorageManager.ResetCache()
+
+		return quietWrapper.WithRetryBlock(quietCtx, func(c *Conveyor) error {
+			_, err := c.ShouldBeBuilt(quietCtx, opts.ShouldBeBuiltOptions)
+			return err
+		})
+	}); err != nil && !IsErrStagesRequired(err) {
+		return err
+	}
+
+	return wrapper.WithRetryBlock(ctx, func(c *Conveyor) error {
+		if _, err := c.ShouldBeBuilt(ctx, opts.ShouldBeBuiltOptions); err != nil {
+			return err
+		}
+
+		return f(c)
+	})
+}
+
+// waitForImages polls the repo with checkImages until the images exist or the timeout is exceeded.
+// The last check error is returned, checkImages errors other than the should be built error are returned immediately.
+func waitForImages(ctx context.Context, opts WaitForImagesOptions, checkImages func() error) error {
+	start := time.Now()
+	for {
+		err := checkImages()
+
+		elapsed := time.Since(start)
+		if !IsErrStagesRequired(err) || elapsed+opts.PollInterval > opts.Timeout {
+			return err
+		}
+
+		logboek.Context(ctx).Info().LogF("Images check: %s\n", err)
+		logboek.Context(ctx).Default().LogF("Waiting for required images to be built (%s elapsed, timeout %s)\n", elapsed.Truncate(time.Second), opts.Timeout)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
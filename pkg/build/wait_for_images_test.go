@@ -0,0 +1,109 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/v2/pkg/logging"
+	"github.com/werf/werf/v2/pkg/storage/manager"
+)
+
+var _ = Describe("waitForImages", func() {
+	var ctx context.Context
+	var storage *imagesStorageStub
+
+	opts := WaitForImagesOptions{Timeout: time.Second, PollInterval: time.Millisecond}
+
+	BeforeEach(func() {
+		ctx = logging.WithLogger(context.Background())
+		storage = &imagesStorageStub{images: map[string]bool{}, pushedByCheck: map[int][]string{}}
+	})
+
+	It("should wait until the images are pushed by the concurrent build", func() {
+		storage.pushedByCheck[2] = []string{"backend"}
+		storage.pushedByCheck[4] = []string{"frontend"}
+
+		err := waitForImages(ctx, opts, func() error {
+			return storage.checkImages("backend", "frontend")
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(storage.checks).To(Equal(4))
+	})
+
+	It("should not wait if the images exist", func() {
+		storage.images["backend"] = true
+
+		err := waitForImages(ctx, opts, func() error {
+			return storage.checkImages("backend")
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(storage.checks).To(Equal(1))
+	})
+
+	It("should return the should be built error if the images are missing after the timeout", func() {
+		storage.pushedByCheck[2] = []string{"backend"}
+
+		start := time.Now()
+		err := waitForImages(ctx, WaitForImagesOptions{Timeout: 50 * time.Millisecond, PollInterval: 10 * time.Millisecond}, func() error {
+			return storage.checkImages("backend", "frontend")
+		})
+		Expect(IsErrStagesRequired(err)).To(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("frontend")))
+		Expect(time.Since(start)).To(BeNumerically("<", 50*time.Millisecond))
+		Expect(storage.checks).To(BeNumerically(">=", 2))
+	})
+
+	It("should not wait if the images check failed", func() {
+		storage.err = errors.New("unauthorized")
+
+		err := waitForImages(ctx, opts, func() error {
+			return storage.checkImages("backend")
+		})
+		Expect(err).To(MatchError("unauthorized"))
+		Expect(storage.checks).To(Equal(1))
+	})
+
+	It("should stop waiting when the context is canceled", func() {
+		ctx, cancel := context.WithCancel(ctx)
+
+		err := waitForImages(ctx, opts, func() error {
+			cancel()
+			return storage.checkImages("backend")
+		})
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(storage.checks).To(Equal(1))
+	})
+})
+
+// imagesStorageStub is the repo, in which the images are pushed by the concurrent build before the specified check.
+type imagesStorageStub struct {
+	images        map[string]bool
+	pushedByCheck map[int][]string
+	err           error
+	checks        int
+}
+
+func (storage *imagesStorageStub) checkImages(names ...string) error {
+	storage.checks++
+
+	if storage.err != nil {
+		return storage.err
+	}
+
+	for _, name := range storage.pushedByCheck[storage.checks] {
+		storage.images[name] = true
+	}
+
+	for _, name := range names {
+		if !storage.images[name] {
+			return fmt.Errorf("image %s: %w", name, manager.ErrStageNotFound)
+		}
+	}
+
+	return nil
+}
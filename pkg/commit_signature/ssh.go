@@ -0,0 +1,121 @@
+package commit_signature
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// The SSH signature format is described in https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig.
+const (
+	sshSignatureHeader    = "-----BEGIN SSH SIGNATURE-----"
+	sshSignatureFooter    = "-----END SSH SIGNATURE-----"
+	sshSignatureMagic     = "SSHSIG"
+	sshSignatureVersion   = 1
+	sshSignatureNamespace = "git"
+)
+
+type sshSignature struct {
+	publicKey     ssh.PublicKey
+	hashAlgorithm string
+	signature     *ssh.Signature
+}
+
+type sshSignatureBlob struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+func parseSSHSignature(armored string) (*sshSignature, error) {
+	body := strings.TrimSpace(armored)
+	body = strings.TrimPrefix(body, sshSignatureHeader)
+	body = strings.TrimSuffix(body, sshSignatureFooter)
+	body = strings.Join(strings.Fields(body), "")
+
+	data, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode SSH signature: %w", err)
+	}
+
+	if !strings.HasPrefix(string(data), sshSignatureMagic) {
+		return nil, fmt.Errorf("invalid SSH signature: no %s magic preamble", sshSignatureMagic)
+	}
+
+	var blob sshSignatureBlob
+	if err := ssh.Unmarshal(data[len(sshSignatureMagic):], &blob); err != nil {
+		return nil, fmt.Errorf("unable to parse SSH signature: %w", err)
+	}
+
+	if blob.Version != sshSignatureVersion {
+		return nil, fmt.Errorf("unsupported SSH signature version %d", blob.Version)
+	}
+
+	if blob.Namespace != sshSignatureNamespace {
+		return nil, fmt.Errorf("unexpected SSH signature namespace %q, expected %q", blob.Namespace, sshSignatureNamespace)
+	}
+
+	publicKey, err := ssh.ParsePublicKey(blob.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse SSH signature public key: %w", err)
+	}
+
+	signature := &ssh.Signature{}
+	if err := ssh.Unmarshal(blob.Signature, signature); err != nil {
+		return nil, fmt.Errorf("unable to parse SSH signature: %w", err)
+	}
+
+	return &sshSignature{
+		publicKey:     publicKey,
+		hashAlgorithm: blob.HashAlgorithm,
+		signature:     signature,
+	}, nil
+}
+
+func (s *sshSignature) verify(payload []byte) error {
+	signedData, err := sshSignatureSignedData(payload, s.hashAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	if err := s.publicKey.Verify(signedData, s.signature); err != nil {
+		return fmt.Errorf("invalid SSH signature: %w", err)
+	}
+
+	return nil
+}
+
+// sshSignatureSignedData returns the data signed by the SSH key for the payload.
+func sshSignatureSignedData(payload []byte, hashAlgorithm string) ([]byte, error) {
+	var h hash.Hash
+	switch hashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return nil, fmt.Errorf("unsupported SSH signature hash algorithm %q", hashAlgorithm)
+	}
+	h.Write(payload)
+
+	return append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sshSignatureNamespace,
+		HashAlgorithm: hashAlgorithm,
+		Hash:          h.Sum(nil),
+	})...), nil
+}
@@ -0,0 +1,13 @@
+package commit_signature
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCommitSignature(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "commit_signature suite")
+}
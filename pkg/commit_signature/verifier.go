@@ -0,0 +1,158 @@
+package commit_signature
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	pgpPublicKeyBlockHeader = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	pgpPublicKeyBlockFooter = "-----END PGP PUBLIC KEY BLOCK-----"
+)
+
+// Verifier checks that the commits are signed with one of the trusted GPG or SSH keys.
+type Verifier struct {
+	gpgKeyRing openpgp.EntityList
+	sshKeys    []ssh.PublicKey
+}
+
+// NewVerifier parses the trusted keys: the armored GPG public keys and the SSH public keys in the authorized_keys format.
+func NewVerifier(trustedKeys []string) (*Verifier, error) {
+	v := &Verifier{}
+
+	for _, key := range trustedKeys {
+		key = strings.TrimSpace(key)
+
+		if strings.HasPrefix(key, pgpPublicKeyBlockHeader) {
+			entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse trusted GPG key: %w", err)
+			}
+			v.gpgKeyRing = append(v.gpgKeyRing, entities...)
+			continue
+		}
+
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse trusted key %q: expected armored GPG public key or SSH public key: %w", shortKey(key), err)
+		}
+		v.sshKeys = append(v.sshKeys, publicKey)
+	}
+
+	if len(v.gpgKeyRing) == 0 && len(v.sshKeys) == 0 {
+		return nil, fmt.Errorf("no trusted keys specified")
+	}
+
+	return v, nil
+}
+
+// SplitTrustedKeys splits the text into the trusted keys: the armored GPG public key blocks and the SSH public keys, one per line.
+// Empty lines and comments are skipped.
+func SplitTrustedKeys(text string) []string {
+	var keys []string
+	var block []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case block != nil:
+			block = append(block, line)
+			if line == pgpPublicKeyBlockFooter {
+				keys = append(keys, strings.Join(block, "\n"))
+				block = nil
+			}
+		case line == pgpPublicKeyBlockHeader:
+			block = []string{line}
+		case line != "" && !strings.HasPrefix(line, "#"):
+			keys = append(keys, line)
+		}
+	}
+
+	if block != nil {
+		keys = append(keys, strings.Join(block, "\n"))
+	}
+
+	return keys
+}
+
+// Verify returns the fingerprint of the trusted key the commit is signed with.
+func (v *Verifier) Verify(commit *object.Commit) (string, error) {
+	if commit.PGPSignature == "" {
+		return "", fmt.Errorf("commit %s is not signed", commit.Hash)
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return "", fmt.Errorf("unable to encode commit %s: %w", commit.Hash, err)
+	}
+
+	reader, err := encoded.Reader()
+	if err != nil {
+		return "", fmt.Errorf("unable to read commit %s: %w", commit.Hash, err)
+	}
+	defer reader.Close()
+
+	var payload bytes.Buffer
+	if _, err := payload.ReadFrom(reader); err != nil {
+		return "", fmt.Errorf("unable to read commit %s: %w", commit.Hash, err)
+	}
+
+	var fingerprint string
+	if strings.HasPrefix(strings.TrimSpace(commit.PGPSignature), sshSignatureHeader) {
+		fingerprint, err = v.verifySSH(payload.Bytes(), commit.PGPSignature)
+	} else {
+		fingerprint, err = v.verifyGPG(payload.Bytes(), commit.PGPSignature)
+	}
+	if err != nil {
+		return "", fmt.Errorf("commit %s signature verification failed: %w", commit.Hash, err)
+	}
+
+	return fingerprint, nil
+}
+
+func (v *Verifier) verifyGPG(payload []byte, signature string) (string, error) {
+	if len(v.gpgKeyRing) == 0 {
+		return "", fmt.Errorf("commit is signed with GPG key, but no trusted GPG keys specified")
+	}
+
+	entity, err := openpgp.CheckArmoredDetachedSignature(v.gpgKeyRing, bytes.NewReader(payload), strings.NewReader(signature), nil)
+	if err != nil {
+		return "", fmt.Errorf("signature is not made by trusted GPG key: %w", err)
+	}
+
+	return fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint), nil
+}
+
+func (v *Verifier) verifySSH(payload []byte, signature string) (string, error) {
+	sig, err := parseSSHSignature(signature)
+	if err != nil {
+		return "", err
+	}
+
+	for _, key := range v.sshKeys {
+		if !bytes.Equal(key.Marshal(), sig.publicKey.Marshal()) {
+			continue
+		}
+
+		if err := sig.verify(payload); err != nil {
+			return "", err
+		}
+
+		return ssh.FingerprintSHA256(key), nil
+	}
+
+	return "", fmt.Errorf("signature is not made by trusted SSH key, signed with %s", ssh.FingerprintSHA256(sig.publicKey))
+}
+
+func shortKey(key string) string {
+	if len(key) > 32 {
+		return key[:32] + "..."
+	}
+	return key
+}
@@ -0,0 +1,189 @@
+package commit_signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+)
+
+var _ = Describe("Verifier", func() {
+	var gpgEntity *openpgp.Entity
+	var sshSigner ssh.Signer
+
+	BeforeEach(func() {
+		var err error
+		gpgEntity, err = openpgp.NewEntity("Platform Team", "", "platform@example.com", nil)
+		Expect(err).To(Succeed())
+
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(Succeed())
+		sshSigner, err = ssh.NewSignerFromKey(privateKey)
+		Expect(err).To(Succeed())
+	})
+
+	It("should accept the commit signed with the trusted GPG key", func() {
+		verifier, err := NewVerifier([]string{armoredGPGPublicKey(gpgEntity)})
+		Expect(err).To(Succeed())
+
+		commit := newCommit()
+		commit.PGPSignature = signGPG(gpgEntity, commit)
+
+		Expect(verifier.Verify(commit)).To(Equal(fmt.Sprintf("%X", gpgEntity.PrimaryKey.Fingerprint)))
+	})
+
+	It("should accept the commit signed with the trusted SSH key", func() {
+		verifier, err := NewVerifier([]string{string(ssh.MarshalAuthorizedKey(sshSigner.PublicKey()))})
+		Expect(err).To(Succeed())
+
+		commit := newCommit()
+		commit.PGPSignature = signSSH(sshSigner, commit)
+
+		Expect(verifier.Verify(commit)).To(Equal(ssh.FingerprintSHA256(sshSigner.PublicKey())))
+	})
+
+	It("should reject the unsigned commit", func() {
+		verifier, err := NewVerifier([]string{armoredGPGPublicKey(gpgEntity)})
+		Expect(err).To(Succeed())
+
+		_, err = verifier.Verify(newCommit())
+		Expect(err).To(MatchError(ContainSubstring("is not signed")))
+	})
+
+	It("should reject the commit signed with the untrusted key", func() {
+		untrustedEntity, err := openpgp.NewEntity("Attacker", "", "attacker@example.com", nil)
+		Expect(err).To(Succeed())
+
+		verifier, err := NewVerifier([]string{armoredGPGPublicKey(gpgEntity), string(ssh.MarshalAuthorizedKey(sshSigner.PublicKey()))})
+		Expect(err).To(Succeed())
+
+		commit := newCommit()
+		commit.PGPSignature = signGPG(untrustedEntity, commit)
+		_, err = verifier.Verify(commit)
+		Expect(err).To(MatchError(ContainSubstring("signature is not made by trusted GPG key")))
+
+		_, untrustedPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(Succeed())
+		untrustedSigner, err := ssh.NewSignerFromKey(untrustedPrivateKey)
+		Expect(err).To(Succeed())
+
+		commit.PGPSignature = signSSH(untrustedSigner, commit)
+		_, err = verifier.Verify(commit)
+		Expect(err).To(MatchError(ContainSubstring("signature is not made by trusted SSH key")))
+	})
+
+	It("should reject the modified commit", func() {
+		verifier, err := NewVerifier([]string{string(ssh.MarshalAuthorizedKey(sshSigner.PublicKey()))})
+		Expect(err).To(Succeed())
+
+		commit := newCommit()
+		commit.PGPSignature = signSSH(sshSigner, commit)
+		commit.Message = "Inject build step\n"
+
+		_, err = verifier.Verify(commit)
+		Expect(err).To(MatchError(ContainSubstring("invalid SSH signature")))
+	})
+
+	It("should split the trusted keys", func() {
+		sshKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshSigner.PublicKey())))
+		gpgKey := strings.TrimSpace(armoredGPGPublicKey(gpgEntity))
+
+		keys := SplitTrustedKeys("# platform team\n" + sshKey + "\n\n" + gpgKey + "\n")
+		Expect(keys).To(Equal([]string{sshKey, gpgKey}))
+
+		verifier, err := NewVerifier(keys)
+		Expect(err).To(Succeed())
+
+		commit := newCommit()
+		commit.PGPSignature = signGPG(gpgEntity, commit)
+		Expect(verifier.Verify(commit)).Error().To(Succeed())
+	})
+
+	It("should fail on invalid trusted keys", func() {
+		_, err := NewVerifier(nil)
+		Expect(err).To(MatchError(ContainSubstring("no trusted keys specified")))
+
+		_, err = NewVerifier([]string{"not a key"})
+		Expect(err).To(MatchError(ContainSubstring("expected armored GPG public key or SSH public key")))
+	})
+})
+
+func newCommit() *object.Commit {
+	signature := object.Signature{Name: "Platform Team", Email: "platform@example.com", When: time.Unix(1700000000, 0).UTC()}
+	return &object.Commit{
+		Author:    signature,
+		Committer: signature,
+		Message:   "Update templates\n",
+		TreeHash:  plumbing.NewHash("4b825dc642cb6eb9a060e54bf8d69288fbee4904"),
+	}
+}
+
+func commitPayload(commit *object.Commit) []byte {
+	encoded := &plumbing.MemoryObject{}
+	Expect(commit.EncodeWithoutSignature(encoded)).To(Succeed())
+
+	reader, err := encoded.Reader()
+	Expect(err).To(Succeed())
+	data, err := io.ReadAll(reader)
+	Expect(err).To(Succeed())
+
+	return data
+}
+
+func armoredGPGPublicKey(entity *openpgp.Entity) string {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	Expect(err).To(Succeed())
+	Expect(entity.Serialize(w)).To(Succeed())
+	Expect(w.Close()).To(Succeed())
+	return buf.String()
+}
+
+func signGPG(entity *openpgp.Entity, commit *object.Commit) string {
+	var buf bytes.Buffer
+	Expect(openpgp.ArmoredDetachSign(&buf, entity, bytes.NewReader(commitPayload(commit)), nil)).To(Succeed())
+	return buf.String()
+}
+
+func signSSH(signer ssh.Signer, commit *object.Commit) string {
+	hash := sha512.Sum512(commitPayload(commit))
+	signedData := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sshSignatureNamespace,
+		HashAlgorithm: "sha512",
+		Hash:          hash[:],
+	})...)
+
+	signature, err := signer.Sign(rand.Reader, signedData)
+	Expect(err).To(Succeed())
+
+	blob := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignatureBlob{
+		Version:       sshSignatureVersion,
+		PublicKey:     signer.PublicKey().Marshal(),
+		Namespace:     sshSignatureNamespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(signature),
+	})...)
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var lines []string
+	for len(encoded) > 70 {
+		lines = append(lines, encoded[:70])
+		encoded = encoded[70:]
+	}
+	lines = append(lines, encoded)
+
+	return sshSignatureHeader + "\n" + strings.Join(lines, "\n") + "\n" + sshSignatureFooter + "\n"
+}
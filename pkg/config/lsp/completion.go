@@ -0,0 +1,66 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/werf/werf/v2/pkg/config"
+)
+
+var (
+	completionPrefixRegexp = regexp.MustCompile(`^\s*(-\s+)?[A-Za-z0-9_]*$`)
+	mappingKeyRegexp       = regexp.MustCompile(`^(\s*)(-\s+)?([A-Za-z0-9_]+)\s*:`)
+)
+
+// completionKeys returns the keys the schema allows at the position if the key is being typed there.
+func completionKeys(schema *config.JSONSchema, text string, pos position) []string {
+	lines := strings.Split(text, "\n")
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return nil
+	}
+
+	prefix := lines[pos.Line]
+	if pos.Character < len(prefix) {
+		prefix = prefix[:pos.Character]
+	}
+
+	if !completionPrefixRegexp.MatchString(prefix) {
+		return nil
+	}
+
+	return schema.PropertyNames(keyPath(lines[:pos.Line], keyIndent(prefix)))
+}
+
+// keyPath returns the keys of the mappings enclosing the key with the indent, the lines before the key are walked up to the document start.
+func keyPath(lines []string, indent int) []string {
+	var path []string
+	for i := len(lines) - 1; i >= 0 && indent > 0; i-- {
+		line := lines[i]
+		if strings.HasPrefix(line, "---") {
+			break
+		}
+
+		res := mappingKeyRegexp.FindStringSubmatch(line)
+		if res == nil {
+			continue
+		}
+
+		if lineIndent := keyIndent(res[1] + res[2]); lineIndent < indent {
+			path = append([]string{res[3]}, path...)
+			indent = lineIndent
+		}
+	}
+
+	return path
+}
+
+// keyIndent returns the column of the key following the prefix, the list item dash is counted as the indentation.
+func keyIndent(prefix string) int {
+	trimmed := strings.TrimLeft(prefix, " ")
+	indent := len(prefix) - len(trimmed)
+	if strings.HasPrefix(trimmed, "-") {
+		indent += len(trimmed) - len(strings.TrimLeft(trimmed[1:], " "))
+	}
+
+	return indent
+}
@@ -0,0 +1,13 @@
+package lsp
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLsp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LSP Suite")
+}
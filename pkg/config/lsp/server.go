@@ -0,0 +1,290 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/werf/werf/v2/pkg/config"
+)
+
+const (
+	errorCodeParseError     = -32700
+	errorCodeMethodNotFound = -32601
+
+	textDocumentSyncKindFull = 1
+	diagnosticSeverityError  = 1
+	completionItemKindField  = 5
+)
+
+// Server is the werf.yaml language server speaking LSP over the JSON-RPC stream with the Content-Length framing.
+// The full text synchronization is used, the diagnostics are published on every change.
+type Server struct {
+	in     *bufio.Reader
+	out    io.Writer
+	schema *config.JSONSchema
+
+	documents map[string]string
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		schema:    config.WerfConfigJSONSchema(),
+		documents: map[string]string{},
+	}
+}
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type textRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type diagnostic struct {
+	Range    textRange `json:"range"`
+	Severity int       `json:"severity"`
+	Source   string    `json:"source"`
+	Message  string    `json:"message"`
+}
+
+type completionItem struct {
+	Label      string `json:"label"`
+	Kind       int    `json:"kind"`
+	InsertText string `json:"insertText"`
+}
+
+// Run serves the requests until the exit notification or the end of the input.
+func (s *Server) Run(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		msg, err := s.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if msg == nil {
+			if err := s.reply(nil, nil, &responseError{Code: errorCodeParseError, Message: "invalid JSON-RPC message"}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) error {
+	switch msg.Method {
+	case "initialize":
+		return s.reply(msg.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   textDocumentSyncKindFull,
+				"completionProvider": map[string]interface{}{},
+			},
+			"serverInfo": map[string]interface{}{"name": "werf"},
+		}, nil)
+	case "shutdown":
+		return s.reply(msg.ID, nil, nil)
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument textDocumentItem `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+
+		s.documents[params.TextDocument.URI] = params.TextDocument.Text
+		return s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   textDocumentItem `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+
+		s.documents[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+		return s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument textDocumentItem `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+
+		delete(s.documents, params.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+			"uri":         params.TextDocument.URI,
+			"diagnostics": []diagnostic{},
+		})
+	case "textDocument/completion":
+		var params struct {
+			TextDocument textDocumentItem `json:"textDocument"`
+			Position     position         `json:"position"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.reply(msg.ID, nil, &responseError{Code: errorCodeParseError, Message: err.Error()})
+		}
+
+		items := []completionItem{}
+		for _, key := range completionKeys(s.schema, s.documents[params.TextDocument.URI], params.Position) {
+			items = append(items, completionItem{Label: key, Kind: completionItemKindField, InsertText: key + ": "})
+		}
+		return s.reply(msg.ID, items, nil)
+	default:
+		if msg.ID == nil {
+			// The unsupported notifications are ignored.
+			return nil
+		}
+		return s.reply(msg.ID, nil, &responseError{Code: errorCodeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)})
+	}
+}
+
+func (s *Server) publishDiagnostics(uri string) error {
+	text := s.documents[uri]
+	lines := strings.Split(text, "\n")
+
+	diagnostics := []diagnostic{}
+	for _, problem := range config.ValidateWerfConfigContent(text, uriPath(uri)) {
+		line := problem.Line - 1
+		if line < 0 || line >= len(lines) {
+			line = 0
+		}
+
+		diagnostics = append(diagnostics, diagnostic{
+			Range: textRange{
+				Start: position{Line: line},
+				End:   position{Line: line, Character: len(lines[line])},
+			},
+			Severity: diagnosticSeverityError,
+			Source:   "werf",
+			Message:  problem.Message,
+		})
+	}
+
+	return s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+}
+
+// read returns the next message or nil if the message body is not a valid JSON-RPC message.
+func (s *Server) read() (*message, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header %q: %w", header.Get("Content-Length"), err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, fmt.Errorf("unable to read message: %w", err)
+	}
+
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, nil
+	}
+
+	return msg, nil
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, respErr *responseError) error {
+	msg := &message{JSONRPC: "2.0", ID: id, Error: respErr}
+	if id == nil {
+		null := json.RawMessage("null")
+		msg.ID = &null
+	}
+
+	if respErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("unable to marshal result: %w", err)
+		}
+		raw := json.RawMessage(data)
+		msg.Result = &raw
+	}
+
+	return s.write(msg)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("unable to marshal params: %w", err)
+	}
+
+	return s.write(&message{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+func (s *Server) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("unable to marshal message: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		return fmt.Errorf("unable to write message: %w", err)
+	}
+
+	return nil
+}
+
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
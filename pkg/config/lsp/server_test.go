@@ -0,0 +1,103 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/v2/pkg/config"
+)
+
+var _ = Describe("completionKeys", func() {
+	schema := config.WerfConfigJSONSchema()
+
+	DescribeTable("should complete the keys allowed at the position",
+		func(text string, expected []string) {
+			lines := strings.Split(text, "\n")
+			pos := position{Line: len(lines) - 1, Character: len(lines[len(lines)-1])}
+			Expect(completionKeys(schema, text, pos)).To(Equal(expected))
+		},
+		Entry("the list item",
+			"image: app\ngit:\n- add: /\n  to: /app\n  stageDep",
+			schema.PropertyNames([]string{"git"}),
+		),
+		Entry("the nested mapping in the list item",
+			"configVersion: 1\ncleanup:\n  keepPolicies:\n  - references:\n      tag: /.*/\n      limit:\n        ",
+			[]string{"in", "last", "operator"},
+		),
+		Entry("the new list item",
+			"image: app\nimport:\n- ",
+			schema.PropertyNames([]string{"import"}),
+		),
+		Entry("the document after the separator",
+			"configVersion: 1\ncleanup:\n  disable: true\n---\n",
+			schema.PropertyNames(nil),
+		),
+		Entry("the value",
+			"image: app\nfrom: alp",
+			nil,
+		),
+	)
+})
+
+var _ = Describe("Server", func() {
+	It("should publish the diagnostics and complete the keys", func() {
+		var in bytes.Buffer
+		for _, msg := range []string{
+			`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+			`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///project/werf.yaml","text":"configVersion: 1\nproject: test\n---\nimage: app\nfromm: alpine\n"}}}`,
+			`{"jsonrpc":"2.0","id":2,"method":"textDocument/completion","params":{"textDocument":{"uri":"file:///project/werf.yaml"},"position":{"line":4,"character":4}}}`,
+			`{"jsonrpc":"2.0","id":3,"method":"unknown"}`,
+			`{"jsonrpc":"2.0","id":4,"method":"shutdown"}`,
+			`{"jsonrpc":"2.0","method":"exit"}`,
+		} {
+			fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+		}
+
+		var out bytes.Buffer
+		Expect(NewServer(&in, &out).Run(context.Background())).To(Succeed())
+		Expect(out.String()).To(HaveSuffix(`{"jsonrpc":"2.0","id":4,"result":null}`))
+
+		responses := readMessages(&out)
+		Expect(responses).To(HaveLen(5))
+
+		Expect(string(*responses[0].ID)).To(Equal("1"))
+		Expect(string(*responses[0].Result)).To(ContainSubstring(`"textDocumentSync":1`))
+
+		Expect(responses[1].Method).To(Equal("textDocument/publishDiagnostics"))
+		var diagnostics struct {
+			Diagnostics []diagnostic `json:"diagnostics"`
+		}
+		Expect(json.Unmarshal(responses[1].Params, &diagnostics)).To(Succeed())
+		Expect(diagnostics.Diagnostics).To(Equal([]diagnostic{{
+			Range:    textRange{Start: position{Line: 4}, End: position{Line: 4, Character: 13}},
+			Severity: diagnosticSeverityError,
+			Source:   "werf",
+			Message:  "unknown fields: `fromm`!",
+		}}))
+
+		var items []completionItem
+		Expect(json.Unmarshal(*responses[2].Result, &items)).To(Succeed())
+		Expect(items).To(ContainElement(completionItem{Label: "from", Kind: completionItemKindField, InsertText: "from: "}))
+
+		Expect(responses[3].Error.Code).To(Equal(errorCodeMethodNotFound))
+	})
+})
+
+func readMessages(out *bytes.Buffer) []*message {
+	var res []*message
+	s := &Server{in: bufio.NewReader(out)}
+	for {
+		msg, err := s.read()
+		if err != nil {
+			return res
+		}
+		res = append(res, msg)
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const matrixKey = "matrix"
+
+// expandWerfConfigMatrix replaces each image section with the matrix by the image variants.
+// Each matrix item is the variant name and the directives merged onto the image section the same way as the overlay
+// (the mappings are merged, the lists are replaced), the variant image is named as <image>-<variant name>, e.g. app-py3.11.
+//
+// The content without the matrix is returned as is.
+func expandWerfConfigMatrix(content string) (string, error) {
+	var res []string
+	var expanded bool
+	for i, docContent := range splitContent([]byte(content)) {
+		res = append(res, strings.TrimSuffix(string(docContent), "\n")+"\n")
+
+		if emptyDocContent(docContent) {
+			continue
+		}
+
+		// The invalid documents are left to the parser
+		var section yaml.MapSlice
+		if err := yaml.Unmarshal(docContent, &section); err != nil {
+			continue
+		}
+
+		if _, ok := mapSliceValue(section, matrixKey); !ok {
+			continue
+		}
+
+		variants, err := expandImageMatrix(section)
+		if err != nil {
+			return "", fmt.Errorf("unable to expand image matrix in document %d: %w", i+1, err)
+		}
+
+		res = res[:len(res)-1]
+		for _, variant := range variants {
+			data, err := yaml.Marshal(variant)
+			if err != nil {
+				return "", fmt.Errorf("unable to marshal image variant: %w", err)
+			}
+			res = append(res, string(data))
+		}
+
+		expanded = true
+	}
+
+	if !expanded {
+		return content, nil
+	}
+
+	return strings.Join(res, "---\n"), nil
+}
+
+// expandImageMatrix returns the image sections of the matrix variants.
+func expandImageMatrix(section yaml.MapSlice) ([]yaml.MapSlice, error) {
+	if _, ok := mapSliceValue(section, "artifact"); ok {
+		return nil, fmt.Errorf("matrix is not supported for artifact")
+	}
+
+	imageName, ok := mapSliceValue(section, "image")
+	if !ok {
+		return nil, fmt.Errorf("matrix can only be defined in the image section")
+	}
+
+	name, ok := imageName.(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("matrix requires the single image name, got %v", imageName)
+	}
+
+	matrix, _ := mapSliceValue(section, matrixKey)
+	items, ok := matrix.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("image %q: matrix should be a non-empty list of variants", name)
+	}
+
+	var base yaml.MapSlice
+	for _, item := range section {
+		if item.Key != matrixKey {
+			base = append(base, item)
+		}
+	}
+
+	variantNames := map[string]bool{}
+	var res []yaml.MapSlice
+	for i, item := range items {
+		variant, ok := item.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("image %q: matrix variant %d should be a mapping", name, i+1)
+		}
+
+		variantName, _ := mapSliceValue(variant, "name")
+		if variantName == nil || fmt.Sprint(variantName) == "" {
+			return nil, fmt.Errorf("image %q: matrix variant %d should have the name", name, i+1)
+		}
+
+		if variantNames[fmt.Sprint(variantName)] {
+			return nil, fmt.Errorf("image %q: duplicate matrix variant %q", name, variantName)
+		}
+		variantNames[fmt.Sprint(variantName)] = true
+
+		var patch yaml.MapSlice
+		for _, variantItem := range variant {
+			switch variantItem.Key {
+			case "name":
+			case "image", "artifact", matrixKey:
+				return nil, fmt.Errorf("image %q: matrix variant %q cannot redefine %s", name, variantName, variantItem.Key)
+			default:
+				patch = append(patch, variantItem)
+			}
+		}
+
+		variantSection := mergeOverlayMapping(base, patch)
+		for j := range variantSection {
+			if variantSection[j].Key == "image" {
+				variantSection[j].Value = fmt.Sprintf("%s-%v", name, variantName)
+			}
+		}
+
+		res = append(res, variantSection)
+	}
+
+	return res, nil
+}
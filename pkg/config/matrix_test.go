@@ -0,0 +1,106 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("werf config matrix", func() {
+	DescribeTable("expandWerfConfigMatrix",
+		func(content, expected string) {
+			res, err := expandWerfConfigMatrix(content)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res).To(Equal(expected))
+		},
+		Entry("should keep the config without the matrix as is",
+			"configVersion: 1\nproject: app\n---\nimage: app # comment\nfrom: alpine\n",
+			"configVersion: 1\nproject: app\n---\nimage: app # comment\nfrom: alpine\n",
+		),
+		Entry("should replace the image section with the variants, merging the variant directives onto the section",
+			`configVersion: 1
+project: app
+---
+image: app
+dockerfile: Dockerfile
+args:
+  BASE: python
+  PYTHON_VERSION: "3.10"
+platform: [linux/amd64]
+matrix:
+- name: py3.11
+  args:
+    PYTHON_VERSION: "3.11"
+- name: py3.12
+  args:
+    PYTHON_VERSION: "3.12"
+  platform: [linux/amd64, linux/arm64]
+---
+image: web # comment
+dockerfile: Dockerfile
+`,
+			`configVersion: 1
+project: app
+---
+image: app-py3.11
+dockerfile: Dockerfile
+args:
+  BASE: python
+  PYTHON_VERSION: "3.11"
+platform:
+- linux/amd64
+---
+image: app-py3.12
+dockerfile: Dockerfile
+args:
+  BASE: python
+  PYTHON_VERSION: "3.12"
+platform:
+- linux/amd64
+- linux/arm64
+---
+image: web # comment
+dockerfile: Dockerfile
+`,
+		),
+		Entry("should override the from of the stapel image and remove the directives set to null",
+			`image: app
+from: python:3.10
+fromCacheVersion: "1"
+matrix:
+- name: "3.11"
+  from: python:3.11
+  fromCacheVersion: null
+`,
+			`image: app-3.11
+from: python:3.11
+`,
+		),
+	)
+
+	DescribeTable("expandWerfConfigMatrix errors",
+		func(content, expectedErr string) {
+			_, err := expandWerfConfigMatrix(content)
+			Expect(err).Should(MatchError(expectedErr))
+		},
+		Entry("artifact",
+			"artifact: app\nfrom: alpine\nmatrix:\n- name: a\n",
+			"unable to expand image matrix in document 1: matrix is not supported for artifact",
+		),
+		Entry("several image names",
+			"image: [app, web]\nfrom: alpine\nmatrix:\n- name: a\n",
+			"unable to expand image matrix in document 1: matrix requires the single image name, got [app web]",
+		),
+		Entry("variant without name",
+			"image: app\nfrom: alpine\nmatrix:\n- from: ubuntu\n",
+			"unable to expand image matrix in document 1: image \"app\": matrix variant 1 should have the name",
+		),
+		Entry("duplicate variant",
+			"image: app\nfrom: alpine\nmatrix:\n- name: a\n- name: a\n",
+			"unable to expand image matrix in document 1: image \"app\": duplicate matrix variant \"a\"",
+		),
+		Entry("variant redefining the image name",
+			"image: app\nfrom: alpine\nmatrix:\n- name: a\n  image: web\n",
+			"unable to expand image matrix in document 1: image \"app\": matrix variant \"a\" cannot redefine image",
+		),
+	)
+})
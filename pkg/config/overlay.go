@@ -0,0 +1,259 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/giterminism_manager"
+)
+
+const metaSectionID = "meta"
+
+// overlayListMergeKeys are the keys the list items are merged by, the other lists are replaced by the overlay.
+var overlayListMergeKeys = map[string]string{
+	"secrets":      "id",
+	"dependencies": "image",
+}
+
+type renderWerfConfigOverlayOpts struct {
+	tmpl               *template.Template
+	templateData       map[string]interface{}
+	giterminismManager *giterminism_manager.Manager
+	configPath         string
+	env                string
+	debugTemplates     bool
+}
+
+// renderWerfConfigOverlay renders the environment overlay of the config (e.g. werf.prod.yaml for werf.yaml and the prod environment)
+// with the same template data and merges it onto the rendered config.
+func renderWerfConfigOverlay(ctx context.Context, configContent string, opts renderWerfConfigOverlayOpts) (string, error) {
+	overlayPath, overlayData, err := opts.giterminismManager.FileManager.ReadConfigOverlay(ctx, werfConfigOverlayPath(opts.configPath, opts.env))
+	if err != nil {
+		return "", err
+	}
+
+	if overlayPath == "" {
+		return configContent, nil
+	}
+
+	logboek.Context(ctx).Debug().LogF("Using werf config overlay: %s\n", overlayPath)
+
+	if err := addTemplate(opts.tmpl, "werfConfigOverlay", string(overlayData)); err != nil {
+		return "", fmt.Errorf("unable to parse werf config overlay %q: %w", overlayPath, err)
+	}
+
+	overlayContent, err := executeTemplate(opts.tmpl, "werfConfigOverlay", opts.templateData)
+	if err != nil {
+		return "", detailedTemplateError(opts.tmpl, detailedTemplateErrorData{
+			templateName: "werfConfigOverlay",
+		}, opts.debugTemplates, err)
+	}
+
+	res, err := mergeWerfConfigOverlay(configContent, overlayContent)
+	if err != nil {
+		return "", fmt.Errorf("unable to merge werf config overlay %q: %w", overlayPath, err)
+	}
+
+	return res, nil
+}
+
+// werfConfigOverlayPath returns the path of the config overlay for the environment, e.g. werf.prod.yaml for werf.yaml.
+func werfConfigOverlayPath(configPath, env string) string {
+	ext := filepath.Ext(configPath)
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(configPath, ext), env, ext)
+}
+
+// mergeWerfConfigOverlay strategic-merges the overlay documents onto the config documents:
+//   - the image and artifact sections patch the sections with the same names, the other sections are appended;
+//   - the sections without image or artifact patch the meta section;
+//   - the mappings are merged recursively, the null value removes the key;
+//   - the lists are replaced except the secrets and the dependencies merged by id and image.
+//
+// The documents not patched by the overlay are kept as is.
+func mergeWerfConfigOverlay(content, overlayContent string) (string, error) {
+	docs := splitContent([]byte(content))
+	sections := make([]yaml.MapSlice, len(docs))
+	sectionIndexes := map[string]int{}
+	for i, docContent := range docs {
+		if emptyDocContent(docContent) {
+			continue
+		}
+
+		// The invalid documents are left to the parser
+		if err := yaml.Unmarshal(docContent, &sections[i]); err != nil {
+			continue
+		}
+
+		if _, ok := sectionIndexes[werfConfigSectionID(sections[i])]; !ok {
+			sectionIndexes[werfConfigSectionID(sections[i])] = i
+		}
+	}
+
+	patched := map[int]bool{}
+	var appended []yaml.MapSlice
+	for i, overlayDocContent := range splitContent([]byte(overlayContent)) {
+		if emptyDocContent(overlayDocContent) {
+			continue
+		}
+
+		var overlaySection yaml.MapSlice
+		if err := yaml.Unmarshal(overlayDocContent, &overlaySection); err != nil {
+			return "", fmt.Errorf("unable to parse overlay document %d: %w", i+1, err)
+		}
+
+		id := werfConfigSectionID(overlaySection)
+		index, ok := sectionIndexes[id]
+		switch {
+		case ok:
+			// The names of the patched section are kept as is
+			var patch yaml.MapSlice
+			for _, item := range overlaySection {
+				if id == metaSectionID || (item.Key != "image" && item.Key != "artifact") {
+					patch = append(patch, item)
+				}
+			}
+
+			sections[index] = mergeOverlayMapping(sections[index], patch)
+			patched[index] = true
+		case id == metaSectionID:
+			return "", fmt.Errorf("unable to patch meta config section in overlay document %d: meta config section is not defined", i+1)
+		default:
+			appended = append(appended, overlaySection)
+		}
+	}
+
+	var res []string
+	for i, docContent := range docs {
+		if patched[i] {
+			data, err := yaml.Marshal(sections[i])
+			if err != nil {
+				return "", fmt.Errorf("unable to marshal config section: %w", err)
+			}
+			docContent = data
+		}
+		res = append(res, strings.TrimSuffix(string(docContent), "\n")+"\n")
+	}
+
+	for _, section := range appended {
+		data, err := yaml.Marshal(section)
+		if err != nil {
+			return "", fmt.Errorf("unable to marshal config section: %w", err)
+		}
+		res = append(res, string(data))
+	}
+
+	return strings.Join(res, "---\n"), nil
+}
+
+// werfConfigSectionID returns the kind and the names of the image or the artifact section, e.g. "image backend", or "meta" for the other sections.
+func werfConfigSectionID(section yaml.MapSlice) string {
+	for _, key := range []string{"image", "artifact"} {
+		value, ok := mapSliceValue(section, key)
+		if !ok {
+			continue
+		}
+
+		var names []string
+		switch v := value.(type) {
+		case []interface{}:
+			for _, name := range v {
+				names = append(names, fmt.Sprint(name))
+			}
+			sort.Strings(names)
+		case nil:
+			names = []string{""}
+		default:
+			names = []string{fmt.Sprint(v)}
+		}
+
+		return fmt.Sprintf("%s %s", key, strings.Join(names, ","))
+	}
+
+	return metaSectionID
+}
+
+func mergeOverlayMapping(base, overlay yaml.MapSlice) yaml.MapSlice {
+	res := append(yaml.MapSlice{}, base...)
+	for _, item := range overlay {
+		index := -1
+		for i := range res {
+			if res[i].Key == item.Key {
+				index = i
+				break
+			}
+		}
+
+		switch {
+		case item.Value == nil:
+			if index >= 0 {
+				res = append(res[:index], res[index+1:]...)
+			}
+		case index >= 0:
+			res[index].Value = mergeOverlayValue(fmt.Sprint(item.Key), res[index].Value, item.Value)
+		default:
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
+
+func mergeOverlayValue(key string, base, overlay interface{}) interface{} {
+	switch o := overlay.(type) {
+	case yaml.MapSlice:
+		if b, ok := base.(yaml.MapSlice); ok {
+			return mergeOverlayMapping(b, o)
+		}
+	case []interface{}:
+		if mergeKey, ok := overlayListMergeKeys[key]; ok {
+			if b, ok := base.([]interface{}); ok {
+				return mergeOverlayList(b, o, mergeKey)
+			}
+		}
+	}
+
+	return overlay
+}
+
+func mergeOverlayList(base, overlay []interface{}, mergeKey string) []interface{} {
+	res := append([]interface{}{}, base...)
+
+overlayItems:
+	for _, item := range overlay {
+		if itemMapping, ok := item.(yaml.MapSlice); ok {
+			if id, ok := mapSliceValue(itemMapping, mergeKey); ok {
+				for i := range res {
+					resMapping, ok := res[i].(yaml.MapSlice)
+					if !ok {
+						continue
+					}
+
+					if resID, ok := mapSliceValue(resMapping, mergeKey); ok && fmt.Sprint(resID) == fmt.Sprint(id) {
+						res[i] = mergeOverlayMapping(resMapping, itemMapping)
+						continue overlayItems
+					}
+				}
+			}
+		}
+
+		res = append(res, item)
+	}
+
+	return res
+}
+
+func mapSliceValue(m yaml.MapSlice, key string) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
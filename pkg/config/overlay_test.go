@@ -0,0 +1,128 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("werf config overlay", func() {
+	DescribeTable("werfConfigOverlayPath",
+		func(configPath, expected string) {
+			Expect(werfConfigOverlayPath(configPath, "prod")).To(Equal(expected))
+		},
+		Entry("werf.yaml", "werf.yaml", "werf.prod.yaml"),
+		Entry("werf.yml", "werf.yml", "werf.prod.yml"),
+		Entry("custom path", ".werf/config.yaml", ".werf/config.prod.yaml"),
+	)
+
+	DescribeTable("mergeWerfConfigOverlay",
+		func(content, overlayContent, expected string) {
+			res, err := mergeWerfConfigOverlay(content, overlayContent)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res).To(Equal(expected))
+		},
+		Entry("should patch the meta and the image sections by name, keeping the other sections as is",
+			`configVersion: 1
+project: app
+---
+image: backend # comment
+dockerfile: Dockerfile
+---
+image: frontend
+dockerfile: Dockerfile
+platform: [linux/amd64]
+imageSpec:
+  config:
+    labels:
+      team: web
+      tier: dev
+`,
+			`build:
+  platform: [linux/arm64]
+---
+image: frontend
+platform: [linux/arm64]
+imageSpec:
+  config:
+    labels:
+      tier: prod
+---
+image: worker
+dockerfile: worker.Dockerfile
+`,
+			`configVersion: 1
+project: app
+build:
+  platform:
+  - linux/arm64
+---
+image: backend # comment
+dockerfile: Dockerfile
+---
+image: frontend
+dockerfile: Dockerfile
+platform:
+- linux/arm64
+imageSpec:
+  config:
+    labels:
+      team: web
+      tier: prod
+---
+image: worker
+dockerfile: worker.Dockerfile
+`,
+		),
+		Entry("should remove the keys with null and merge the secrets and the dependencies",
+			`configVersion: 1
+project: app
+---
+image: [backend, worker]
+dockerfile: Dockerfile
+target: dev
+secrets:
+- id: npmrc
+  src: .npmrc
+dependencies:
+- image: base
+  imports:
+  - type: ImageName
+    targetEnv: BASE_IMAGE
+`,
+			`image: [worker, backend]
+target: null
+secrets:
+- id: npmrc
+  src: .npmrc.prod
+- id: token
+  env: TOKEN
+dependencies:
+- image: tools
+`,
+			`configVersion: 1
+project: app
+---
+image:
+- backend
+- worker
+dockerfile: Dockerfile
+secrets:
+- id: npmrc
+  src: .npmrc.prod
+- id: token
+  env: TOKEN
+dependencies:
+- image: base
+  imports:
+  - type: ImageName
+    targetEnv: BASE_IMAGE
+- image: tools
+`,
+		),
+	)
+
+	It("should fail to patch the undefined meta section", func() {
+		_, err := mergeWerfConfigOverlay("image: backend\ndockerfile: Dockerfile\n", "build:\n  staged: true\n")
+		Expect(err).To(MatchError(ContainSubstring("meta config section is not defined")))
+	})
+})
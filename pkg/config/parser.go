@@ -9,8 +9,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"unicode"
 
@@ -23,6 +25,7 @@ import (
 	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/logboek"
 	"github.com/werf/werf/v2/pkg/file_manager"
+	"github.com/werf/werf/v2/pkg/git_repo"
 	"github.com/werf/werf/v2/pkg/giterminism_manager"
 	"github.com/werf/werf/v2/pkg/slug"
 	"github.com/werf/werf/v2/pkg/tmp_manager"
@@ -276,12 +279,23 @@ func renderWerfConfigYaml(ctx context.Context, opts renderWerfConfigYamlOpts) (s
 		return "", "", fmt.Errorf("unable to get HEAD commit time: %w", err)
 	}
 
-	templateData["Commit"] = map[string]interface{}{
-		"Hash": headHash,
-		"Date": map[string]string{
+	templateData["Commit"] = commit{
+		Hash: headHash,
+		Date: map[string]string{
 			"Human": headTime.String(),
 			"Unix":  strconv.FormatInt(headTime.Unix(), 10),
 		},
+		tags: sync.OnceValues(func() ([]string, error) {
+			if err := opts.giterminismManager.Inspector().InspectConfigGoTemplateRenderingGitRefs("{{ .Commit.Tags }}"); err != nil {
+				return nil, err
+			}
+
+			tags, err := opts.giterminismManager.LocalGitRepo().(*git_repo.Local).CommitTags(ctx, headHash)
+			if err != nil {
+				return nil, fmt.Errorf("{{ .Commit.Tags }}: unable to get HEAD commit tags: %w", err)
+			}
+			return tags, nil
+		}),
 	}
 
 	config, err := executeTemplate(tmpl, "werfConfig", templateData)
@@ -291,6 +305,25 @@ func renderWerfConfigYaml(ctx context.Context, opts renderWerfConfigYamlOpts) (s
 		}, opts.debugTemplates, err)
 	}
 
+	if opts.env != "" {
+		config, err = renderWerfConfigOverlay(ctx, config, renderWerfConfigOverlayOpts{
+			tmpl:               tmpl,
+			templateData:       templateData,
+			giterminismManager: opts.giterminismManager.(*giterminism_manager.Manager),
+			configPath:         configPath,
+			env:                opts.env,
+			debugTemplates:     opts.debugTemplates,
+		})
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	config, err = expandWerfConfigMatrix(config)
+	if err != nil {
+		return "", "", err
+	}
+
 	return configPath, config, nil
 }
 
@@ -436,6 +469,50 @@ func funcMap(ctx context.Context, tmpl *template.Template, giterminismManager gi
 		return val, nil
 	}
 
+	// git functions, the committed state of the project git repository is used,
+	// the branches and tags are not fixed by the commit, so the functions are allowed by giterminism explicitly
+	funcMap["gitBranch"] = func() (string, error) {
+		if err := giterminismManager.Inspector().InspectConfigGoTemplateRenderingGitRefs("gitBranch"); err != nil {
+			return "", err
+		}
+
+		return giterminismManager.LocalGitRepo().(*git_repo.Local).HeadBranch(ctx)
+	}
+
+	funcMap["gitTag"] = func() (string, error) {
+		if err := giterminismManager.Inspector().InspectConfigGoTemplateRenderingGitRefs("gitTag"); err != nil {
+			return "", err
+		}
+
+		tags, err := giterminismManager.LocalGitRepo().(*git_repo.Local).CommitTags(ctx, giterminismManager.HeadCommit(ctx))
+		if err != nil {
+			return "", err
+		}
+
+		if tag := git_repo.HighestSemverTag(tags); tag != "" {
+			return tag, nil
+		} else if len(tags) > 0 {
+			return tags[0], nil
+		}
+		return "", nil
+	}
+
+	funcMap["gitNearestSemverTag"] = func() (string, error) {
+		if err := giterminismManager.Inspector().InspectConfigGoTemplateRenderingGitRefs("gitNearestSemverTag"); err != nil {
+			return "", err
+		}
+
+		return giterminismManager.LocalGitRepo().(*git_repo.Local).NearestSemverTag(ctx, giterminismManager.HeadCommit(ctx))
+	}
+
+	funcMap["gitChangedFiles"] = func(ref string) ([]string, error) {
+		if err := giterminismManager.Inspector().InspectConfigGoTemplateRenderingGitRefs("gitChangedFiles"); err != nil {
+			return nil, err
+		}
+
+		return giterminismManager.LocalGitRepo().(*git_repo.Local).ChangedFiles(ctx, ref, giterminismManager.HeadCommit(ctx))
+	}
+
 	// debug functions
 	funcMap["tpl_debug"] = func(templateContent string, data interface{}) (string, error) {
 		templateName := buildTplTemplateName(templateContent)
@@ -562,6 +639,18 @@ func detailedTemplateError(tmpl *template.Template, d detailedTemplateErrorData,
 	return fmt.Errorf("%w\n%s", err, engine.TemplateErrHint)
 }
 
+type commit struct {
+	Hash string
+	Date map[string]string
+
+	tags func() ([]string, error)
+}
+
+// Tags returns the tags pointing to the commit, the tag refs are only read if the template uses them.
+func (c commit) Tags() ([]string, error) {
+	return c.tags()
+}
+
 type files struct {
 	ctx                context.Context
 	giterminismManager *giterminism_manager.Manager
@@ -597,6 +686,29 @@ func (f files) Glob(pattern string) map[string]interface{} {
 	}
 }
 
+// Checksum returns the checksum of the paths and the contents of the files matched by the glob, e.g. to use in cacheVersion.
+func (f files) Checksum(pattern string) (string, error) {
+	res, err := f.giterminismManager.FileManager.ConfigGoTemplateFilesGlob(f.ctx, pattern)
+	if err == nil && len(res) == 0 {
+		err = errors.New("no matches found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("{{ .Files.Checksum %q }}: %w", pattern, err)
+	}
+
+	paths := make([]string, 0, len(res))
+	for p := range res {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var args []string
+	for _, p := range paths {
+		args = append(args, p, fmt.Sprint(res[p]))
+	}
+	return util.Sha256Hash(args...), nil
+}
+
 func (f files) Exists(relPath string) bool {
 	exist, err := f.giterminismManager.FileManager.ConfigGoTemplateFilesExists(f.ctx, relPath)
 	if err != nil {
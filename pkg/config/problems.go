@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	problemLineRegexp          = regexp.MustCompile("line ([0-9]+)")
+	problemUnknownFieldsRegexp = regexp.MustCompile("^unknown fields: `([^`]+)`")
+)
+
+// ConfigProblem is the problem found by the werf.yaml parser.
+type ConfigProblem struct {
+	// Line is the 1-based line of the config.
+	Line    int
+	Message string
+}
+
+// ValidateWerfConfigContent parses each document of werf.yaml the same way werf does and returns the found problems.
+// The documents with Go template actions are skipped since they can only be parsed after rendering.
+func ValidateWerfConfigContent(content, path string) []ConfigProblem {
+	docs, err := splitByDocs(content, path)
+	if err != nil {
+		return []ConfigProblem{{Line: 1, Message: err.Error()}}
+	}
+
+	var problems []ConfigProblem
+	var metaDoc *doc
+	var rawStapelImages []*rawStapelImage
+	var rawImagesFromDockerfile []*rawImageFromDockerfile
+	var hasTemplates bool
+	for _, d := range docs {
+		if strings.Contains(string(d.Content), "{{") {
+			hasTemplates = true
+			continue
+		}
+
+		// The image variants of the matrix are located at the image section
+		expandedDocs := []*doc{d}
+		if expandedContent, err := expandWerfConfigMatrix(string(d.Content)); err != nil {
+			problems = append(problems, ConfigProblem{Line: d.Line + 1, Message: err.Error()})
+			continue
+		} else if expandedContent != string(d.Content) {
+			expandedDocs, _ = splitByDocs(expandedContent, path)
+			for _, expandedDoc := range expandedDocs {
+				expandedDoc.Line = d.Line
+			}
+		}
+
+		meta, stapelImages, imagesFromDockerfile, err := splitByMetaAndRawImages(expandedDocs)
+		if err != nil {
+			problems = append(problems, newConfigProblem(err, d))
+			continue
+		}
+
+		if meta != nil {
+			if metaDoc != nil {
+				problems = append(problems, ConfigProblem{Line: d.Line + 1, Message: "duplicate meta config section definition"})
+			}
+			metaDoc = d
+		}
+
+		rawStapelImages = append(rawStapelImages, stapelImages...)
+		rawImagesFromDockerfile = append(rawImagesFromDockerfile, imagesFromDockerfile...)
+	}
+
+	if len(problems) > 0 || hasTemplates {
+		return problems
+	}
+
+	if metaDoc == nil {
+		problems = append(problems, ConfigProblem{Line: 1, Message: "meta config section with `configVersion: 1` and `project` is not defined"})
+	}
+
+	if err := newImagePlatformValidator().Validate(rawStapelImages, rawImagesFromDockerfile); err != nil {
+		problems = append(problems, ConfigProblem{Line: 1, Message: fmt.Sprintf("invalid image platform cross-references: %s", err)})
+	}
+
+	return problems
+}
+
+// newConfigProblem returns the problem located at the line mentioned by the error, at the unknown field or at the start of the document.
+func newConfigProblem(err error, d *doc) ConfigProblem {
+	message := err.Error()
+	var confErr *configError
+	if errors.As(err, &confErr) {
+		message = confErr.message
+	}
+
+	problem := ConfigProblem{Line: d.Line + 1, Message: message}
+
+	if res := problemLineRegexp.FindStringSubmatch(message); len(res) == 2 {
+		if line, err := strconv.Atoi(res[1]); err == nil {
+			problem.Line = line
+		}
+	} else if res := problemUnknownFieldsRegexp.FindStringSubmatch(message); len(res) == 2 {
+		keyRegexp := regexp.MustCompile(fmt.Sprintf(`^\s*(-\s+)?%s\s*:`, regexp.QuoteMeta(res[1])))
+		for i, line := range getLines(d.Content) {
+			if keyRegexp.Match(line) {
+				problem.Line = d.Line + i + 1
+				break
+			}
+		}
+	}
+
+	return problem
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const jsonSchemaDefinitionsRefPrefix = "#/definitions/"
+
+// JSONSchema is the subset of JSON Schema draft-07 used to describe werf.yaml.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Definitions          map[string]*JSONSchema `json:"definitions,omitempty"`
+}
+
+// schemaEnums are the values of the fields validated by the directives rather than by the raw types.
+var schemaEnums = map[string][]interface{}{
+	"meta.configVersion":                               {1},
+	"metaCleanupKeepPolicyReferencesLimit.operator":    {string(OrOperator), string(AndOperator)},
+	"metaCleanupKeepPolicyImagesPerReference.operator": {string(OrOperator), string(AndOperator)},
+	"import.before":                                    {"install", "setup"},
+	"import.after":                                     {"install", "setup"},
+	"dependencyImport.type":                            {string(ImageNameImport), string(ImageTagImport), string(ImageRepoImport), string(ImageIDImport), string(ImageDigestImport)},
+}
+
+// The yaml.v2 decoder accepts any scalar for the string and null for the collections and the structs.
+var (
+	schemaStringTypes = []string{"string", "number", "boolean"}
+	schemaArrayTypes  = []string{"array", "null"}
+	schemaObjectTypes = []string{"object", "null"}
+	schemaStringArray = &JSONSchema{Type: "array", Items: &JSONSchema{Type: "string"}}
+)
+
+// WerfConfigJSONSchema returns the JSON Schema of the werf.yaml document generated from the raw config types.
+// The document is either the meta section, the stapel image or the Dockerfile image.
+func WerfConfigJSONSchema() *JSONSchema {
+	g := &schemaGenerator{definitions: map[string]*JSONSchema{}}
+
+	meta := g.structRef(reflect.TypeOf(rawMeta{}))
+	stapelImage := g.structRef(reflect.TypeOf(rawStapelImage{}))
+	imageFromDockerfile := g.structRef(reflect.TypeOf(rawImageFromDockerfile{}))
+
+	imageName := &JSONSchema{AnyOf: []*JSONSchema{{Type: "string"}, schemaStringArray, {Type: "null"}}}
+
+	// The matrix is expanded before the parsing, the variant directives are merged onto the image section.
+	imageMatrix := &JSONSchema{
+		Type: "array",
+		Items: &JSONSchema{
+			Type:                 "object",
+			Properties:           map[string]*JSONSchema{"name": {Type: schemaStringTypes}},
+			Required:             []string{"name"},
+			AdditionalProperties: true,
+		},
+	}
+
+	g.definition(meta).Required = []string{"configVersion", "project"}
+	g.definition(stapelImage).Properties["image"] = imageName
+	g.definition(stapelImage).Properties[matrixKey] = imageMatrix
+	g.definition(stapelImage).AnyOf = []*JSONSchema{{Required: []string{"image"}}, {Required: []string{"artifact"}}}
+	g.definition(imageFromDockerfile).Properties["image"] = imageName
+	g.definition(imageFromDockerfile).Properties[matrixKey] = imageMatrix
+	g.definition(imageFromDockerfile).Required = []string{"dockerfile"}
+
+	for key, enum := range schemaEnums {
+		parts := strings.SplitN(key, ".", 2)
+		if def, ok := g.definitions[parts[0]]; ok {
+			if prop, ok := def.Properties[parts[1]]; ok {
+				prop.Enum = enum
+			}
+		}
+	}
+
+	return &JSONSchema{
+		Schema:      "http://json-schema.org/draft-07/schema#",
+		Title:       "werf.yaml",
+		Description: "The werf.yaml document: the meta section, the stapel image or the Dockerfile image.",
+		AnyOf:       []*JSONSchema{meta, stapelImage, imageFromDockerfile},
+		Definitions: g.definitions,
+	}
+}
+
+// PropertyNames returns the sorted names of the properties the object at the path can have.
+// The path consists of the property names, the arrays are passed through to their items.
+func (s *JSONSchema) PropertyNames(path []string) []string {
+	current := s.objects(s)
+	for _, name := range path {
+		var next []*JSONSchema
+		for _, c := range current {
+			if prop, ok := c.Properties[name]; ok {
+				next = append(next, s.objects(prop)...)
+			}
+		}
+		current = next
+	}
+
+	names := map[string]bool{}
+	for _, c := range current {
+		for name := range c.Properties {
+			names[name] = true
+		}
+	}
+
+	var res []string
+	for name := range names {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// objects resolves the references of the schema and returns the object schemas the value can match.
+func (s *JSONSchema) objects(schema *JSONSchema) []*JSONSchema {
+	if schema == nil {
+		return nil
+	}
+
+	if schema.Ref != "" {
+		return s.objects(s.Definitions[strings.TrimPrefix(schema.Ref, jsonSchemaDefinitionsRefPrefix)])
+	}
+
+	var res []*JSONSchema
+	if schema.Properties != nil {
+		res = append(res, schema)
+	}
+	res = append(res, s.objects(schema.Items)...)
+	for _, a := range schema.AnyOf {
+		res = append(res, s.objects(a)...)
+	}
+
+	return res
+}
+
+type schemaGenerator struct {
+	definitions map[string]*JSONSchema
+}
+
+func (g *schemaGenerator) definition(ref *JSONSchema) *JSONSchema {
+	return g.definitions[strings.TrimPrefix(ref.Ref, jsonSchemaDefinitionsRefPrefix)]
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) *JSONSchema {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return &JSONSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: schemaStringTypes}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: schemaArrayTypes, Items: g.typeSchema(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &JSONSchema{Type: schemaObjectTypes}
+		}
+		return &JSONSchema{Type: schemaObjectTypes, AdditionalProperties: g.typeSchema(t.Elem())}
+	case reflect.Interface:
+		// The raw types use interface{} for the fields accepting a string or a list of strings.
+		return &JSONSchema{AnyOf: []*JSONSchema{{Type: "string"}, schemaStringArray, {Type: "null"}}}
+	case reflect.Struct:
+		return &JSONSchema{AnyOf: []*JSONSchema{g.structRef(t), {Type: "null"}}}
+	default:
+		return &JSONSchema{}
+	}
+}
+
+func (g *schemaGenerator) structRef(t reflect.Type) *JSONSchema {
+	name := schemaDefinitionName(t)
+	if _, ok := g.definitions[name]; !ok {
+		def := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}, AdditionalProperties: false}
+		g.definitions[name] = def
+		g.addProperties(def, t)
+	}
+
+	return &JSONSchema{Ref: jsonSchemaDefinitionsRefPrefix + name}
+}
+
+// addProperties adds the fields decoded by yaml.v2 as the properties, the inline structs are flattened.
+// The inline map named other than UnsupportedAttributes keeps the arbitrary fields (e.g. the ansible task module).
+func (g *schemaGenerator) addProperties(def *JSONSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if strings.Contains(opts, "inline") {
+			switch field.Type.Kind() {
+			case reflect.Map:
+				if field.Name != "UnsupportedAttributes" {
+					def.AdditionalProperties = true
+				}
+			case reflect.Struct:
+				g.addProperties(def, field.Type)
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		def.Properties[name] = g.typeSchema(field.Type)
+	}
+}
+
+// schemaDefinitionName returns the name of the type without the raw prefix, e.g. stapelImage for rawStapelImage.
+func schemaDefinitionName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "raw")
+	if name == "" {
+		return name
+	}
+
+	r := []rune(name)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
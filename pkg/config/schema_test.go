@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+)
+
+var _ = Describe("WerfConfigJSONSchema", func() {
+	schema := WerfConfigJSONSchema()
+
+	It("should describe the documents with the raw types", func() {
+		Expect(schema.AnyOf).To(HaveLen(3))
+		Expect(schema.Definitions).To(HaveKey("meta"))
+		Expect(schema.Definitions).To(HaveKey("stapelImage"))
+		Expect(schema.Definitions).To(HaveKey("imageFromDockerfile"))
+		Expect(schema.Definitions).To(HaveKey("metaCleanupKeepPolicyReferencesLimit"))
+
+		Expect(schema.Definitions["meta"].Required).To(Equal([]string{"configVersion", "project"}))
+		Expect(schema.Definitions["meta"].AdditionalProperties).To(Equal(false))
+		Expect(schema.Definitions["ansibleTask"].AdditionalProperties).To(Equal(true))
+		Expect(schema.Definitions["metaCleanupKeepPolicyReferencesLimit"].Properties["in"].Type).To(Equal("string"))
+		Expect(schema.Definitions["metaCleanupKeepPolicyReferencesLimit"].Properties["operator"].Enum).To(ConsistOf("And", "Or"))
+	})
+
+	It("should be marshalled to JSON", func() {
+		data, err := json.Marshal(schema)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`"$schema":"http://json-schema.org/draft-07/schema#"`))
+		Expect(string(data)).To(ContainSubstring(`"additionalProperties":false`))
+	})
+
+	DescribeTable("PropertyNames",
+		func(path []string, matcher types.GomegaMatcher) {
+			Expect(schema.PropertyNames(path)).To(matcher)
+		},
+		Entry("the documents", nil, ContainElements("configVersion", "image", "artifact", "dockerfile")),
+		Entry("the flattened inline structs", []string{"git"}, SatisfyAll(ContainElements("add", "includePaths", "url"), Not(ContainElement("UnsupportedAttributes")))),
+		Entry("the nested structs", []string{"cleanup", "keepPolicies", "references", "limit"}, Equal([]string{"in", "last", "operator"})),
+		Entry("the unknown key", []string{"unknown"}, BeEmpty()),
+	)
+})
+
+var _ = Describe("ValidateWerfConfigContent", func() {
+	DescribeTable("should report the parser problems",
+		func(content string, expected []ConfigProblem) {
+			Expect(ValidateWerfConfigContent(content, "werf.yaml")).To(Equal(expected))
+		},
+		Entry("valid config",
+			"configVersion: 1\nproject: test\n---\nimage: app\nfrom: alpine\n",
+			nil,
+		),
+		Entry("unknown field",
+			"configVersion: 1\nproject: test\n---\nimage: app\nfrom: alpine\nfromm: alpine\n",
+			[]ConfigProblem{{Line: 6, Message: "unknown fields: `fromm`!"}},
+		),
+		Entry("yaml error",
+			"configVersion: 1\nproject: test\n---\nimage: app\nfrom: [alpine\n",
+			[]ConfigProblem{{Line: 5, Message: "yaml: line 5: did not find expected ',' or ']'"}},
+		),
+		Entry("missing meta",
+			"image: app\nfrom: alpine\n",
+			[]ConfigProblem{{Line: 1, Message: "meta config section with `configVersion: 1` and `project` is not defined"}},
+		),
+		Entry("duplicate meta",
+			"configVersion: 1\nproject: test\n---\nconfigVersion: 1\nproject: test\n",
+			[]ConfigProblem{{Line: 4, Message: "duplicate meta config section definition"}},
+		),
+		Entry("image matrix",
+			"configVersion: 1\nproject: test\n---\nimage: app\nfrom: alpine\nmatrix:\n- name: a\n- name: b\n  fromm: alpine\n",
+			[]ConfigProblem{{Line: 9, Message: "unknown fields: `fromm`!"}},
+		),
+		Entry("invalid image matrix",
+			"configVersion: 1\nproject: test\n---\nimage: app\nfrom: alpine\nmatrix: []\n",
+			[]ConfigProblem{{Line: 4, Message: "unable to expand image matrix in document 1: image \"app\": matrix should be a non-empty list of variants"}},
+		),
+		Entry("templates",
+			"configVersion: 1\nproject: test\n---\n{{ range $i := list 1 2 }}\nimage: app\n{{ end }}\n",
+			nil,
+		),
+	)
+})
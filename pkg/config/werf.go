@@ -72,6 +72,17 @@ func (c *WerfConfig) GetImage(imageName string) ImageInterface {
 	return nil
 }
 
+// GetImageRenderContent returns the rendered werf.yaml document of the image.
+func (c *WerfConfig) GetImageRenderContent(imageName string) []byte {
+	for _, image := range c.images {
+		if image.GetName() == imageName {
+			return image.rawDoc().Content
+		}
+	}
+
+	return nil
+}
+
 func (c *WerfConfig) validateConflictBetweenImagesNames() error {
 	imageByName := map[string]ImageInterface{}
 	for _, image := range c.Images(false) {
@@ -201,14 +212,14 @@ func (c *WerfConfig) getImageRelativesInOrder(images []ImageInterface) map[Image
 }
 
 type imageGraph struct {
-	ImageName string    `yaml:"image,omitempty"`
-	DependsOn DependsOn `yaml:"dependsOn,omitempty"`
+	ImageName string    `json:"image,omitempty" yaml:"image,omitempty"`
+	DependsOn DependsOn `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
 }
 
 type DependsOn struct {
-	From         string   `yaml:"from,omitempty"`
-	Imports      []string `yaml:"import,omitempty"`
-	Dependencies []string `yaml:"dependencies,omitempty"`
+	From         string   `json:"from,omitempty" yaml:"from,omitempty"`
+	Imports      []string `json:"import,omitempty" yaml:"import,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
 }
 
 func (d DependsOn) relatedImageNameList() []string {
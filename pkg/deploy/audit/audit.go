@@ -0,0 +1,201 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"os/user"
+	"time"
+)
+
+type Result string
+
+const (
+	ResultSucceeded Result = "succeeded"
+	ResultFailed    Result = "failed"
+)
+
+// Record is the audit record of the single release operation (converge, rollback, bundle apply or dismiss).
+// Records of the sink are chained: the hash of each record covers the record and the hash of the previous record,
+// so a modified, removed or reordered record breaks the chain. The hash is HMAC-SHA256 with the audit key,
+// so the records cannot be rehashed without the key (plain SHA-256 is used if the key is not specified).
+// Removal of the last records is detected only by the head hash of the sink reported outside the sink.
+type Record struct {
+	Time        time.Time         `json:"time"`
+	Command     string            `json:"command"`
+	Project     string            `json:"project,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release"`
+	Namespace   string            `json:"namespace"`
+	User        string            `json:"user,omitempty"`
+	CIJob       string            `json:"ciJob,omitempty"`
+	Commit      string            `json:"commit,omitempty"`
+	Images      map[string]string `json:"images,omitempty"`
+	ValuesHash  string            `json:"valuesHash,omitempty"`
+	Result      Result            `json:"result"`
+	Error       string            `json:"error,omitempty"`
+	Duration    string            `json:"duration"`
+	WerfVersion string            `json:"werfVersion"`
+	PrevHash    string            `json:"prevHash,omitempty"`
+	Hash        string            `json:"hash"`
+}
+
+// NewRecord starts the record of the command, the user and the CI job are detected from the environment.
+func NewRecord(command string) *Record {
+	return &Record{
+		Time:    time.Now().UTC(),
+		Command: command,
+		User:    currentUser(),
+		CIJob:   currentCIJob(),
+	}
+}
+
+// Finish sets the result and the duration of the operation.
+func (r *Record) Finish(err error) {
+	r.Duration = time.Since(r.Time).Round(time.Millisecond).String()
+
+	if err != nil {
+		r.Result = ResultFailed
+		r.Error = err.Error()
+	} else {
+		r.Result = ResultSucceeded
+	}
+}
+
+// Seal chains the record to the previous record of the sink.
+func (r *Record) Seal(prevHash string, key []byte) error {
+	r.PrevHash = prevHash
+
+	hash, err := r.calculateHash(key)
+	if err != nil {
+		return err
+	}
+	r.Hash = hash
+
+	return nil
+}
+
+func (r *Record) calculateHash(key []byte) (string, error) {
+	record := *r
+	record.Hash = ""
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal audit record: %w", err)
+	}
+
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write([]byte(r.PrevHash))
+	h.Write([]byte{'\n'})
+	h.Write(data)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify checks the chain of the records ordered from the oldest to the newest with the audit key used to seal them.
+func Verify(records []*Record, key []byte) error {
+	return verify("", records, key)
+}
+
+// verify checks the chain starting from the record with the prevHash.
+func verify(prevHash string, records []*Record, key []byte) error {
+	for i, r := range records {
+		if r.PrevHash != prevHash {
+			return fmt.Errorf("record %d (%s at %s): previous record hash mismatch", i, r.Command, r.Time.Format(time.RFC3339))
+		}
+
+		recordHash, err := r.calculateHash(key)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal([]byte(recordHash), []byte(r.Hash)) {
+			return fmt.Errorf("record %d (%s at %s): hash mismatch", i, r.Command, r.Time.Format(time.RFC3339))
+		}
+
+		prevHash = r.Hash
+	}
+
+	return nil
+}
+
+// ImagesFromValues returns the images of the release by image name from the werf service values,
+// image references with digests are preferred.
+func ImagesFromValues(values map[string]interface{}) map[string]string {
+	werfValues, _ := values["werf"].(map[string]interface{})
+	if werfValues == nil {
+		return nil
+	}
+
+	images := map[string]string{}
+	for _, key := range []string{"image", "image_digest_ref"} {
+		refs, _ := werfValues[key].(map[string]interface{})
+		for name, ref := range refs {
+			if s, ok := ref.(string); ok && s != "" {
+				images[name] = s
+			}
+		}
+	}
+
+	if len(images) == 0 {
+		return nil
+	}
+	return images
+}
+
+// CommitFromValues returns the commit of the release from the werf service values.
+func CommitFromValues(values map[string]interface{}) string {
+	werfValues, _ := values["werf"].(map[string]interface{})
+	commit, _ := werfValues["commit"].(map[string]interface{})
+	hash, _ := commit["hash"].(string)
+	return hash
+}
+
+// HashValues returns the HMAC-SHA256 of the values sources (values files, --set options, etc.) passed in the order of their precedence.
+// The hash is keyed, because values often contain low-entropy secrets, which can be brute-forced by the plain hash stored in the log.
+func HashValues(key []byte, sources ...[]byte) string {
+	h := hmac.New(sha256.New, key)
+	for _, src := range sources {
+		fmt.Fprintf(h, "%d\n", len(src))
+		h.Write(src)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func currentUser() string {
+	for _, env := range []string{"GITLAB_USER_LOGIN", "GITHUB_ACTOR"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return ""
+}
+
+func currentCIJob() string {
+	if v := os.Getenv("CI_JOB_URL"); v != "" {
+		return v
+	}
+
+	if os.Getenv("GITHUB_RUN_ID") != "" && os.Getenv("GITHUB_REPOSITORY") != "" {
+		serverURL := os.Getenv("GITHUB_SERVER_URL")
+		if serverURL == "" {
+			serverURL = "https://github.com"
+		}
+		return fmt.Sprintf("%s/%s/actions/runs/%s", serverURL, os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"))
+	}
+
+	return ""
+}
@@ -0,0 +1,272 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var testKey = []byte("audit-key")
+
+func newTestRecord(command string) Record {
+	r := NewRecord(command)
+	r.Release = "app-production"
+	r.Namespace = "app-production"
+	r.Images = map[string]string{"backend": "registry.example.com/app@sha256:0123"}
+	r.Finish(nil)
+	return *r
+}
+
+var _ = Describe("Record", func() {
+	It("should detect the modified, removed and reordered records", func() {
+		var records []*Record
+		var prevHash string
+		for _, command := range []string{"converge", "rollback", "dismiss"} {
+			r := newTestRecord(command)
+			Expect(r.Seal(prevHash, testKey)).To(Succeed())
+			prevHash = r.Hash
+			records = append(records, &r)
+		}
+		Expect(Verify(records, testKey)).To(Succeed())
+
+		modified := *records[1]
+		modified.Result = ResultFailed
+		Expect(Verify([]*Record{records[0], &modified, records[2]}, testKey)).To(MatchError(ContainSubstring("record 1 (rollback")))
+
+		Expect(Verify([]*Record{records[0], records[2]}, testKey)).To(MatchError(ContainSubstring("previous record hash mismatch")))
+		Expect(Verify([]*Record{records[1], records[0], records[2]}, testKey)).NotTo(Succeed())
+
+		Expect(modified.Seal(modified.PrevHash, nil)).To(Succeed())
+		Expect(Verify([]*Record{records[0], &modified, records[2]}, testKey)).To(MatchError(ContainSubstring("record 1 (rollback")))
+		Expect(modified.Seal(modified.PrevHash, []byte("other-key"))).To(Succeed())
+		Expect(Verify([]*Record{records[0], &modified, records[2]}, testKey)).To(MatchError(ContainSubstring("record 1 (rollback")))
+	})
+
+	It("should set the result of the operation", func() {
+		r := NewRecord("converge")
+		r.Finish(errors.New("release install: timeout"))
+		Expect(r.Result).To(Equal(ResultFailed))
+		Expect(r.Error).To(Equal("release install: timeout"))
+		Expect(r.Duration).NotTo(BeEmpty())
+	})
+
+	It("should prefer image references with digests", func() {
+		Expect(ImagesFromValues(map[string]interface{}{
+			"werf": map[string]interface{}{
+				"image":            map[string]interface{}{"backend": "repo:tag1", "frontend": "repo:tag2"},
+				"image_digest_ref": map[string]interface{}{"backend": "repo@sha256:0123"},
+			},
+		})).To(Equal(map[string]string{"backend": "repo@sha256:0123", "frontend": "repo:tag2"}))
+
+		Expect(ImagesFromValues(map[string]interface{}{})).To(BeNil())
+	})
+
+	It("should distinguish the values sources", func() {
+		Expect(HashValues(testKey, []byte("a"), []byte("bc"))).NotTo(Equal(HashValues(testKey, []byte("ab"), []byte("c"))))
+		Expect(HashValues(testKey, []byte("a"))).To(Equal(HashValues(testKey, []byte("a"))))
+		Expect(HashValues(testKey, []byte("a"))).NotTo(Equal(HashValues([]byte("other-key"), []byte("a"))))
+	})
+})
+
+var _ = Describe("NewSink", func() {
+	getKubeClient := func(context.Context) (kubernetes.Interface, error) { return fake.NewSimpleClientset(), nil }
+
+	DescribeTable("should create the sink by the spec",
+		func(spec string, expected Sink) {
+			sink, err := NewSink(spec, SinkOptions{Namespace: "ns", GetKubeClient: getKubeClient})
+			Expect(err).To(Succeed())
+			Expect(sink.String()).To(Equal(expected.String()))
+		},
+		Entry("configmap with the default name", "configmap://", &KubeSink{Kind: "configmap", Name: DefaultKubeSinkName, Namespace: "ns"}),
+		Entry("secret", "secret://audit", &KubeSink{Kind: "secret", Name: "audit", Namespace: "ns"}),
+		Entry("webhook", "https://audit.example.com/records", &WebhookSink{URL: "https://audit.example.com/records"}),
+		Entry("file url", "file:///var/log/werf/audit.jsonl", &FileSink{Path: "/var/log/werf/audit.jsonl"}),
+		Entry("file path", "audit.jsonl", &FileSink{Path: "audit.jsonl"}),
+	)
+
+	It("should fail on the unsupported scheme", func() {
+		_, err := NewSink("s3://bucket/audit", SinkOptions{})
+		Expect(err).To(MatchError(ContainSubstring("unsupported audit log sink")))
+	})
+})
+
+var _ = Describe("FileSink", func() {
+	It("should append the chained records", func(ctx SpecContext) {
+		sink := &FileSink{Path: filepath.Join(GinkgoT().TempDir(), "audit", "audit.jsonl"), Key: testKey}
+		Expect(sink.Append(ctx, newTestRecord("converge"))).Error().To(Succeed())
+		head, err := sink.Append(ctx, newTestRecord("dismiss"))
+		Expect(err).To(Succeed())
+
+		records, err := sink.read()
+		Expect(err).To(Succeed())
+		Expect(records).To(HaveLen(2))
+		Expect(records[1].PrevHash).To(Equal(records[0].Hash))
+		Expect(records[1].Hash).To(Equal(head))
+		Expect(Verify(records, testKey)).To(Succeed())
+	})
+
+	It("should not fork the chain on the concurrent appends", func(ctx SpecContext) {
+		sink := &FileSink{Path: filepath.Join(GinkgoT().TempDir(), "audit.jsonl"), Key: testKey}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(sink.Append(ctx, newTestRecord("converge"))).Error().To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		records, err := sink.read()
+		Expect(err).To(Succeed())
+		Expect(records).To(HaveLen(10))
+		Expect(Verify(records, testKey)).To(Succeed())
+	})
+
+	It("should append the record and report the broken chain", func(ctx SpecContext) {
+		sink := &FileSink{Path: filepath.Join(GinkgoT().TempDir(), "audit.jsonl")}
+		Expect(sink.Append(ctx, newTestRecord("converge"))).Error().To(Succeed())
+
+		data, err := os.ReadFile(sink.Path)
+		Expect(err).To(Succeed())
+		Expect(os.WriteFile(sink.Path, []byte(strings.Replace(string(data), `"succeeded"`, `"failed"`, 1)), 0o644)).To(Succeed())
+
+		head, err := sink.Append(ctx, newTestRecord("rollback"))
+		Expect(err).To(MatchError(ErrChainBroken))
+		Expect(head).NotTo(BeEmpty())
+
+		records, err := sink.read()
+		Expect(err).To(Succeed())
+		Expect(records).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("KubeSink", func() {
+	DescribeTable("should keep the chained records in the object",
+		func(ctx SpecContext, kind string) {
+			client := fake.NewSimpleClientset()
+			sink := &KubeSink{Kind: kind, Name: DefaultKubeSinkName, Namespace: "ns", GetKubeClient: func(context.Context) (kubernetes.Interface, error) {
+				return client, nil
+			}}
+
+			Expect(sink.Append(ctx, newTestRecord("converge"))).Error().To(Succeed())
+			Expect(sink.Append(ctx, newTestRecord("rollback"))).Error().To(Succeed())
+
+			data, current, err := sink.get(ctx, client)
+			Expect(current).NotTo(BeNil())
+			Expect(err).To(Succeed())
+			Expect(data).To(HaveKey("000001.json"))
+			Expect(data).To(HaveKey("000002.json"))
+
+			records, nextSeq, err := parseKubeSinkData(data)
+			Expect(err).To(Succeed())
+			Expect(nextSeq).To(Equal(3))
+			Expect(records[1].Command).To(Equal("rollback"))
+			Expect(Verify(records, nil)).To(Succeed())
+		},
+		Entry("configmap", "configmap"),
+		Entry("secret", "secret"),
+	)
+
+	It("should not overwrite records after the removed key", func(ctx SpecContext) {
+		first := newTestRecord("converge")
+		Expect(first.Seal("", nil)).To(Succeed())
+		firstData, err := json.Marshal(first)
+		Expect(err).To(Succeed())
+
+		client := fake.NewSimpleClientset(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: DefaultKubeSinkName, Namespace: "ns"},
+			Data:       map[string]string{"000002.json": string(firstData)},
+		})
+		sink := &KubeSink{Kind: "configmap", Name: DefaultKubeSinkName, Namespace: "ns", GetKubeClient: func(context.Context) (kubernetes.Interface, error) {
+			return client, nil
+		}}
+
+		Expect(sink.Append(ctx, newTestRecord("rollback"))).Error().To(Succeed())
+
+		cm, err := client.CoreV1().ConfigMaps("ns").Get(ctx, DefaultKubeSinkName, metav1.GetOptions{})
+		Expect(err).To(Succeed())
+		Expect(cm.Data).To(HaveKey("000002.json"))
+		Expect(cm.Data).To(HaveKey("000003.json"))
+	})
+
+	It("should rotate the oldest records", func(ctx SpecContext) {
+		client := fake.NewSimpleClientset()
+		sink := &KubeSink{Kind: "configmap", Name: DefaultKubeSinkName, Namespace: "ns", MaxRecords: 2, Key: testKey, GetKubeClient: func(context.Context) (kubernetes.Interface, error) {
+			return client, nil
+		}}
+
+		for _, command := range []string{"converge", "rollback", "converge", "dismiss"} {
+			Expect(sink.Append(ctx, newTestRecord(command))).Error().To(Succeed())
+		}
+
+		cm, err := client.CoreV1().ConfigMaps("ns").Get(ctx, DefaultKubeSinkName, metav1.GetOptions{})
+		Expect(err).To(Succeed())
+		Expect(cm.Data).To(HaveLen(2))
+		Expect(cm.Data).To(HaveKey("000003.json"))
+		Expect(cm.Data).To(HaveKey("000004.json"))
+	})
+
+	It("should rotate the oldest records to fit the object size limit", func(ctx SpecContext) {
+		client := fake.NewSimpleClientset()
+		sink := &KubeSink{Kind: "secret", Name: DefaultKubeSinkName, Namespace: "ns", GetKubeClient: func(context.Context) (kubernetes.Interface, error) {
+			return client, nil
+		}}
+
+		for i := 0; i < 3; i++ {
+			record := newTestRecord("converge")
+			record.Error = strings.Repeat("x", 400*1024)
+			Expect(sink.Append(ctx, record)).Error().To(Succeed())
+		}
+
+		secret, err := client.CoreV1().Secrets("ns").Get(ctx, DefaultKubeSinkName, metav1.GetOptions{})
+		Expect(err).To(Succeed())
+		Expect(secret.Data).To(HaveLen(2))
+		Expect(secret.Data).To(HaveKey("000003.json"))
+	})
+})
+
+var _ = Describe("WebhookSink", func() {
+	It("should post the sealed record", func(ctx SpecContext) {
+		var received Record
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &received)
+		}))
+		defer server.Close()
+
+		sink := &WebhookSink{URL: server.URL, Token: "secret"}
+		Expect(sink.Append(ctx, newTestRecord("converge"))).Error().To(Succeed())
+
+		Expect(authorization).To(Equal("Bearer secret"))
+		Expect(received.Command).To(Equal("converge"))
+		Expect(Verify([]*Record{&received}, nil)).To(Succeed())
+	})
+
+	It("should fail on the error response", func(ctx SpecContext) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "storage is unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		Expect((&WebhookSink{URL: server.URL}).Append(ctx, newTestRecord("converge"))).Error().To(MatchError(ContainSubstring("storage is unavailable")))
+	})
+})
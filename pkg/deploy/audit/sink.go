@@ -0,0 +1,422 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	DefaultKubeSinkName       = "werf-audit-log"
+	DefaultKubeSinkMaxRecords = 500
+
+	// kubeSinkMaxDataSize keeps the object below the 1MiB size limit of the ConfigMap and Secret with the margin for the metadata.
+	kubeSinkMaxDataSize = 900 * 1024
+
+	kubeSinkComponentLabelName = "app.kubernetes.io/component"
+	kubeSinkComponentLabel     = "werf-audit-log"
+
+	webhookTimeout = 30 * time.Second
+
+	fileSinkLockRetryDelay = 100 * time.Millisecond
+)
+
+// ErrChainBroken is returned by the sink after the record is appended to the history with the broken chain of hashes.
+var ErrChainBroken = errors.New("audit log chain is broken")
+
+// Sink appends the sealed record and returns its hash, which is the new head of the chain.
+type Sink interface {
+	Append(ctx context.Context, record Record) (string, error)
+	String() string
+}
+
+type SinkOptions struct {
+	// Namespace is the release namespace, where the ConfigMap or Secret sink is stored.
+	Namespace string
+	// GetKubeClient is called only for the ConfigMap and Secret sinks.
+	GetKubeClient func(ctx context.Context) (kubernetes.Interface, error)
+	// WebhookToken is sent as the bearer token to the webhook sink.
+	WebhookToken string
+	// Key is the HMAC key of the record hashes.
+	Key []byte
+}
+
+// NewSink creates the sink by the spec:
+//   - configmap://[NAME] or secret://[NAME] in the release namespace (werf-audit-log by default);
+//   - http://... or https://... webhook;
+//   - file://PATH or PATH of the local JSONL file.
+func NewSink(spec string, opts SinkOptions) (Sink, error) {
+	switch {
+	case IsKubeSinkSpec(spec):
+		kind, name, _ := strings.Cut(spec, "://")
+		if name == "" {
+			name = DefaultKubeSinkName
+		}
+		if opts.GetKubeClient == nil {
+			return nil, fmt.Errorf("kube client is required for the %s sink", kind)
+		}
+		return &KubeSink{Kind: kind, Name: name, Namespace: opts.Namespace, GetKubeClient: opts.GetKubeClient, Key: opts.Key}, nil
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return &WebhookSink{URL: spec, Token: opts.WebhookToken, Key: opts.Key}, nil
+	case strings.HasPrefix(spec, "file://"):
+		spec = strings.TrimPrefix(spec, "file://")
+		fallthrough
+	default:
+		if spec == "" {
+			return nil, fmt.Errorf("empty audit log sink")
+		}
+		if strings.Contains(spec, "://") {
+			return nil, fmt.Errorf("unsupported audit log sink %q: expected configmap://, secret://, http(s)://, file:// or local path", spec)
+		}
+		return &FileSink{Path: spec, Key: opts.Key}, nil
+	}
+}
+
+// IsKubeSinkSpec returns true if the spec is the ConfigMap or Secret sink stored in the release namespace.
+func IsKubeSinkSpec(spec string) bool {
+	return strings.HasPrefix(spec, "configmap://") || strings.HasPrefix(spec, "secret://")
+}
+
+// FileSink appends records to the local file, one JSON record per line.
+// Concurrent appends are serialized with the lock of the PATH.lock file.
+type FileSink struct {
+	Path string
+	Key  []byte
+}
+
+func (s *FileSink) String() string {
+	return s.Path
+}
+
+func (s *FileSink) Append(ctx context.Context, record Record) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(s.Path), os.ModePerm); err != nil {
+		return "", fmt.Errorf("unable to create dir for %q: %w", s.Path, err)
+	}
+
+	lock := flock.New(s.Path + ".lock")
+	if _, err := lock.TryLockContext(ctx, fileSinkLockRetryDelay); err != nil {
+		return "", fmt.Errorf("unable to lock %q: %w", lock.Path(), err)
+	}
+	defer lock.Unlock()
+
+	records, err := s.read()
+	if err != nil {
+		return "", err
+	}
+
+	if err := record.Seal(lastHash(records), s.Key); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal audit record: %w", err)
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %q: %w", s.Path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return "", fmt.Errorf("unable to write %q: %w", s.Path, err)
+	}
+
+	return record.Hash, verifyChain("", records, s.Key)
+}
+
+func (s *FileSink) read() ([]*Record, error) {
+	f, err := os.Open(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open %q: %w", s.Path, err)
+	}
+	defer f.Close()
+
+	var records []*Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		record := &Record{}
+		if err := json.Unmarshal(line, record); err != nil {
+			return nil, fmt.Errorf("unable to parse %q: %w", s.Path, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %q: %w", s.Path, err)
+	}
+
+	return records, nil
+}
+
+// KubeSink keeps records in the ConfigMap or Secret, one key per record: 000001.json, 000002.json, etc.
+// The oldest records are rotated out to keep MaxRecords and to fit the object size limit,
+// so the chain is verified from the oldest kept record and removal of the oldest records is not detected.
+type KubeSink struct {
+	Kind          string
+	Name          string
+	Namespace     string
+	GetKubeClient func(ctx context.Context) (kubernetes.Interface, error)
+	Key           []byte
+	// MaxRecords is the number of the last records to keep, DefaultKubeSinkMaxRecords if not specified.
+	MaxRecords int
+}
+
+func (s *KubeSink) String() string {
+	return fmt.Sprintf("%s/%s (namespace %s)", s.Kind, s.Name, s.Namespace)
+}
+
+func (s *KubeSink) Append(ctx context.Context, record Record) (string, error) {
+	client, err := s.GetKubeClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var records []*Record
+	var head string
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		data, current, err := s.get(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		var nextSeq int
+		records, nextSeq, err = parseKubeSinkData(data)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s: %w", s, err)
+		}
+
+		r := record
+		if err := r.Seal(lastHash(records), s.Key); err != nil {
+			return err
+		}
+		head = r.Hash
+
+		recordData, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("unable to marshal audit record: %w", err)
+		}
+		data[fmt.Sprintf("%06d.json", nextSeq)] = recordData
+
+		maxRecords := s.MaxRecords
+		if maxRecords <= 0 {
+			maxRecords = DefaultKubeSinkMaxRecords
+		}
+		rotateKubeSinkData(data, maxRecords)
+
+		return s.save(ctx, client, data, current)
+	}); err != nil {
+		return "", fmt.Errorf("unable to append record to %s: %w", s, err)
+	}
+
+	var prevHash string
+	if len(records) > 0 {
+		prevHash = records[0].PrevHash
+	}
+
+	return head, verifyChain(prevHash, records, s.Key)
+}
+
+// get returns the data and the metadata of the object, the metadata is nil if the object does not exist.
+func (s *KubeSink) get(ctx context.Context, client kubernetes.Interface) (map[string][]byte, *metav1.ObjectMeta, error) {
+	data := map[string][]byte{}
+
+	switch s.Kind {
+	case "secret":
+		secret, err := client.CoreV1().Secrets(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return data, nil, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		for k, v := range secret.Data {
+			data[k] = v
+		}
+		return data, &secret.ObjectMeta, nil
+	default:
+		cm, err := client.CoreV1().ConfigMaps(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return data, nil, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+		return data, &cm.ObjectMeta, nil
+	}
+}
+
+// save creates the object or updates the current object with the optimistic lock by the resource version,
+// so the concurrent appends are retried instead of overwriting each other.
+func (s *KubeSink) save(ctx context.Context, client kubernetes.Interface, data map[string][]byte, current *metav1.ObjectMeta) error {
+	meta := metav1.ObjectMeta{
+		Name:      s.Name,
+		Namespace: s.Namespace,
+		Labels:    map[string]string{kubeSinkComponentLabelName: kubeSinkComponentLabel},
+	}
+	if current != nil {
+		meta = *current
+	}
+
+	var err error
+	switch s.Kind {
+	case "secret":
+		secret := &corev1.Secret{ObjectMeta: meta, Data: data}
+		if current == nil {
+			_, err = client.CoreV1().Secrets(s.Namespace).Create(ctx, secret, metav1.CreateOptions{})
+		} else {
+			_, err = client.CoreV1().Secrets(s.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		}
+	default:
+		stringData := map[string]string{}
+		for k, v := range data {
+			stringData[k] = string(v)
+		}
+
+		cm := &corev1.ConfigMap{ObjectMeta: meta, Data: stringData}
+		if current == nil {
+			_, err = client.CoreV1().ConfigMaps(s.Namespace).Create(ctx, cm, metav1.CreateOptions{})
+		} else {
+			_, err = client.CoreV1().ConfigMaps(s.Namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		}
+	}
+
+	if apierrors.IsAlreadyExists(err) {
+		return apierrors.NewConflict(corev1.Resource(s.Kind), s.Name, err)
+	}
+	return err
+}
+
+// parseKubeSinkData returns the records ordered by the keys and the sequence number of the next record.
+func parseKubeSinkData(data map[string][]byte) ([]*Record, int, error) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var records []*Record
+	var lastSeq int
+	for _, k := range keys {
+		record := &Record{}
+		if err := json.Unmarshal(data[k], record); err != nil {
+			return nil, 0, fmt.Errorf("key %q: %w", k, err)
+		}
+		records = append(records, record)
+
+		if seq, err := strconv.Atoi(strings.TrimSuffix(k, ".json")); err == nil {
+			lastSeq = max(lastSeq, seq)
+		}
+	}
+
+	return records, max(lastSeq, len(records)) + 1, nil
+}
+
+// rotateKubeSinkData removes the oldest records until at most maxRecords are left and the data fits the object size limit.
+// The newest record is always kept.
+func rotateKubeSinkData(data map[string][]byte, maxRecords int) {
+	keys := make([]string, 0, len(data))
+	var size int
+	for k, v := range data {
+		keys = append(keys, k)
+		size += len(k) + len(v)
+	}
+	sort.Strings(keys)
+
+	for len(keys) > 1 && (len(keys) > maxRecords || size > kubeSinkMaxDataSize) {
+		size -= len(keys[0]) + len(data[keys[0]])
+		delete(data, keys[0])
+		keys = keys[1:]
+	}
+}
+
+// WebhookSink posts each record as JSON to the URL. The webhook receives separate records, which are not chained:
+// the record is sealed without the previous hash, so the order and completeness of the history should be kept by the receiver.
+type WebhookSink struct {
+	URL   string
+	Token string
+	Key   []byte
+}
+
+func (s *WebhookSink) String() string {
+	return s.URL
+}
+
+func (s *WebhookSink) Append(ctx context.Context, record Record) (string, error) {
+	if err := record.Seal("", s.Key); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal audit record: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to post audit record to %s: %w", s.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("unable to post audit record to %s: %s: %s", s.URL, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return record.Hash, nil
+}
+
+func lastHash(records []*Record) string {
+	if len(records) == 0 {
+		return ""
+	}
+	return records[len(records)-1].Hash
+}
+
+func verifyChain(prevHash string, records []*Record, key []byte) error {
+	if err := verify(prevHash, records, key); err != nil {
+		return fmt.Errorf("%w: %w", ErrChainBroken, err)
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ type copyToOptions struct {
 	HelmCompatibleChart bool
 	RenameChart         string
 	HelmOptions         helmopts.HelmOptions
+	DeltaBase           DeltaBase
 }
 
 type BundleAccessor interface {
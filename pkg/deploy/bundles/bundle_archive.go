@@ -3,13 +3,17 @@ package bundles
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
+	"github.com/dustin/go-humanize"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
 	"github.com/werf/3p-helm/pkg/chart"
 	"github.com/werf/3p-helm/pkg/werf/helmopts"
 	"github.com/werf/logboek"
-	"github.com/werf/werf/v2/pkg/image"
 )
 
 const (
@@ -79,36 +83,37 @@ func (bundle *BundleArchive) CopyFromArchive(ctx context.Context, fromArchive *B
 	}
 
 	if err := logboek.Context(ctx).LogProcess("Copy images from bundle archive").DoError(func() error {
-		if werfVals, ok := ch.Values["werf"].(map[string]interface{}); ok {
-			if imageVals, ok := werfVals["image"].(map[string]interface{}); ok {
-				for imageName, v := range imageVals {
-					if imageRef, ok := v.(string); ok {
-						logboek.Context(ctx).Default().LogFDetails("Saving image %s\n", imageRef)
-
-						_, tag := image.ParseRepositoryAndTag(imageRef)
-
-						imageArchive, err := fromArchive.Reader.ReadImageArchive(tag)
-						if err != nil {
-							return fmt.Errorf("error reading image archive by tag %q from the bundle archive %q: %w", tag, fromArchive.Reader.String(), err)
-						}
-
-						imageBytes := bytes.NewBuffer(nil)
-
-						if _, err := io.Copy(imageBytes, imageArchive); err != nil {
-							return fmt.Errorf("error reading image archive by tag %q from the bundle archive %q: %w", tag, fromArchive.Reader.String(), err)
-						}
-
-						if err := imageArchive.Close(); err != nil {
-							return fmt.Errorf("unable to close image archive reader by tag %q from the bundle archive %q: %w", tag, fromArchive.Reader.String(), err)
-						}
-
-						if err := bundle.Writer.WriteImageArchive(tag, imageBytes.Bytes()); err != nil {
-							return fmt.Errorf("error writing image %q into bundle archive: %w", imageRef, err)
-						}
-					} else {
-						return fmt.Errorf("unexpected value .Values.werf.image.%s=%v", imageName, v)
-					}
+		images, err := getChartImageReferences(ch)
+		if err != nil {
+			return err
+		}
+
+		for _, imageRef := range images {
+			logboek.Context(ctx).Default().LogFDetails("Saving image %s\n", imageRef)
+
+			tag := getImageReferenceTag(imageRef)
+
+			if opts.DeltaBase != nil {
+				img, err := fromArchive.ReadImage(tag)
+				if err != nil {
+					return fmt.Errorf("error reading image by tag %q from the bundle archive %q: %w", tag, fromArchive.Reader.String(), err)
 				}
+
+				if err := bundle.writePartialImage(ctx, tag, img, opts.DeltaBase); err != nil {
+					return fmt.Errorf("error writing image %q into bundle archive: %w", imageRef, err)
+				}
+
+				continue
+			}
+
+			if err := copyImageArchive(fromArchive.Reader.ReadImageArchive, bundle.Writer.WriteImageArchive, tag); err == nil {
+				continue
+			} else if !errors.Is(err, ErrImageArchiveNotFound) {
+				return fmt.Errorf("error copying image archive by tag %q from the bundle archive %q: %w", tag, fromArchive.Reader.String(), err)
+			}
+
+			if err := copyImageArchive(fromArchive.Reader.ReadPartialImageArchive, bundle.Writer.WritePartialImageArchive, tag); err != nil {
+				return fmt.Errorf("error copying partial image archive by tag %q from the bundle archive %q: %w", tag, fromArchive.Reader.String(), err)
 			}
 		}
 
@@ -140,28 +145,38 @@ func (bundle *BundleArchive) CopyFromRemote(ctx context.Context, fromRemote *Rem
 		return err
 	}
 
-	if werfVals, ok := ch.Values["werf"].(map[string]interface{}); ok {
-		if imageVals, ok := werfVals["image"].(map[string]interface{}); ok {
-			for imageName, v := range imageVals {
-				if imageRef, ok := v.(string); ok {
-					logboek.Context(ctx).Default().LogFDetails("Saving image %s\n", imageRef)
+	images, err := getChartImageReferences(ch)
+	if err != nil {
+		return err
+	}
 
-					_, tag := image.ParseRepositoryAndTag(imageRef)
+	for _, imageRef := range images {
+		logboek.Context(ctx).Default().LogFDetails("Saving image %s\n", imageRef)
 
-					// TODO: maybe save into tmp file archive OR read resulting image size from the registry before pulling
-					imageBytes := bytes.NewBuffer(nil)
+		tag := getImageReferenceTag(imageRef)
 
-					if err := fromRemote.RegistryClient.PullImageArchive(ctx, imageBytes, imageRef); err != nil {
-						return fmt.Errorf("error pulling image %q archive: %w", imageRef, err)
-					}
+		if opts.DeltaBase != nil {
+			img, err := fromRemote.RegistryClient.PullImage(ctx, imageRef)
+			if err != nil {
+				return fmt.Errorf("error getting image %q: %w", imageRef, err)
+			}
 
-					if err := bundle.Writer.WriteImageArchive(tag, imageBytes.Bytes()); err != nil {
-						return fmt.Errorf("error writing image %q into bundle archive: %w", imageRef, err)
-					}
-				} else {
-					return fmt.Errorf("unexpected value .Values.werf.image.%s=%v", imageName, v)
-				}
+			if err := bundle.writePartialImage(ctx, tag, img, opts.DeltaBase); err != nil {
+				return fmt.Errorf("error writing image %q into bundle archive: %w", imageRef, err)
 			}
+
+			continue
+		}
+
+		// TODO: maybe save into tmp file archive OR read resulting image size from the registry before pulling
+		imageBytes := bytes.NewBuffer(nil)
+
+		if err := fromRemote.RegistryClient.PullImageArchive(ctx, imageBytes, imageRef); err != nil {
+			return fmt.Errorf("error pulling image %q archive: %w", imageRef, err)
+		}
+
+		if err := bundle.Writer.WriteImageArchive(tag, imageBytes.Bytes()); err != nil {
+			return fmt.Errorf("error writing image %q into bundle archive: %w", imageRef, err)
 		}
 	}
 
@@ -172,6 +187,89 @@ func (bundle *BundleArchive) CopyFromRemote(ctx context.Context, fromRemote *Rem
 	return nil
 }
 
+// ReadImage loads image stored in the bundle archive either as a full or as a partial image archive.
+func (bundle *BundleArchive) ReadImage(imageTag string) (v1.Image, error) {
+	img, err := bundle.ReadPartialImage(imageTag)
+	if err == nil {
+		return img, nil
+	} else if !errors.Is(err, ErrImageArchiveNotFound) {
+		return nil, err
+	}
+
+	opener := bundle.GetImageArchiveOpener(imageTag)
+
+	// Check that the archive exists to return an early ErrImageArchiveNotFound error.
+	rc, err := opener.Open()
+	if err != nil {
+		return nil, err
+	}
+	if err := rc.Close(); err != nil {
+		return nil, err
+	}
+
+	img, err = tarball.Image(opener.Open, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open image archive by tag %q: %w", imageTag, err)
+	}
+
+	return img, nil
+}
+
+func (bundle *BundleArchive) ReadPartialImage(imageTag string) (v1.Image, error) {
+	rc, err := bundle.Reader.ReadPartialImageArchive(imageTag)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	img, err := ReadPartialImageArchive(rc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read partial image archive by tag %q: %w", imageTag, err)
+	}
+
+	return img, nil
+}
+
+func (bundle *BundleArchive) writePartialImage(ctx context.Context, imageTag string, img v1.Image, base DeltaBase) error {
+	imageBytes := bytes.NewBuffer(nil)
+
+	stats, err := WritePartialImageArchive(ctx, imageBytes, img, base)
+	if err != nil {
+		return err
+	}
+
+	logboek.Context(ctx).Default().LogFDetails(
+		"Layers included: %d (%s), already at destination: %d (%s)\n",
+		stats.IncludedLayers, humanize.Bytes(uint64(stats.IncludedBytes)),
+		stats.SkippedLayers, humanize.Bytes(uint64(stats.SkippedBytes)),
+	)
+
+	return bundle.Writer.WritePartialImageArchive(imageTag, imageBytes.Bytes())
+}
+
+func copyImageArchive(read func(imageTag string) (*ImageArchiveReadCloser, error), write func(imageTag string, data []byte) error, imageTag string) error {
+	imageArchive, err := read(imageTag)
+	if err != nil {
+		return err
+	}
+
+	imageBytes := bytes.NewBuffer(nil)
+
+	if _, err := io.Copy(imageBytes, imageArchive); err != nil {
+		return fmt.Errorf("error reading image archive: %w", err)
+	}
+
+	if err := imageArchive.Close(); err != nil {
+		return fmt.Errorf("unable to close image archive reader: %w", err)
+	}
+
+	if err := write(imageTag, imageBytes.Bytes()); err != nil {
+		return fmt.Errorf("error writing image archive: %w", err)
+	}
+
+	return nil
+}
+
 type ImageArchiveOpener struct {
 	Archive  *BundleArchive
 	ImageTag string
@@ -4,15 +4,19 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 )
 
+var ErrImageArchiveNotFound = errors.New("image archive not found")
+
 type BundleArchiveReader interface {
 	String() string
 	ReadChartArchive() ([]byte, error)
 	ReadImageArchive(imageTag string) (*ImageArchiveReadCloser, error)
+	ReadPartialImageArchive(imageTag string) (*ImageArchiveReadCloser, error)
 }
 
 type BundleArchiveFileReader struct {
@@ -62,6 +66,14 @@ func (reader *BundleArchiveFileReader) ReadChartArchive() ([]byte, error) {
 }
 
 func (reader *BundleArchiveFileReader) ReadImageArchive(imageTag string) (*ImageArchiveReadCloser, error) {
+	return reader.readImageArchive(imageTag, imageArchiveFileName(imageTag))
+}
+
+func (reader *BundleArchiveFileReader) ReadPartialImageArchive(imageTag string) (*ImageArchiveReadCloser, error) {
+	return reader.readImageArchive(imageTag, partialImageArchiveFileName(imageTag))
+}
+
+func (reader *BundleArchiveFileReader) readImageArchive(imageTag, fileName string) (*ImageArchiveReadCloser, error) {
 	treader, closer, err := reader.openForReading()
 	if err != nil {
 		defer closer()
@@ -71,9 +83,11 @@ func (reader *BundleArchiveFileReader) ReadImageArchive(imageTag string) (*Image
 	for {
 		header, err := treader.Next()
 		if err == io.EOF {
-			return nil, fmt.Errorf("no image tag %q found in the bundle archive %q", imageTag, reader.Path)
+			defer closer()
+			return nil, fmt.Errorf("no image tag %q found in the bundle archive %q: %w", imageTag, reader.Path, ErrImageArchiveNotFound)
 		}
 		if err != nil {
+			defer closer()
 			return nil, fmt.Errorf("error reading tar archive: %w", err)
 		}
 
@@ -81,9 +95,10 @@ func (reader *BundleArchiveFileReader) ReadImageArchive(imageTag string) (*Image
 			continue
 		}
 
-		if header.Name == fmt.Sprintf("images/%s.tar.gz", imageTag) {
+		if header.Name == fileName {
 			unzipper, err := gzip.NewReader(treader)
 			if err != nil {
+				defer closer()
 				return nil, fmt.Errorf("unable to create gzip reader for image archive: %w", err)
 			}
 
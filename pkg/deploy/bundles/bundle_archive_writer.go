@@ -18,6 +18,7 @@ type BundleArchiveWriter interface {
 	Open() error
 	WriteChartArchive(data []byte, opts helmopts.HelmOptions) error
 	WriteImageArchive(imageTag string, data []byte) error
+	WritePartialImageArchive(imageTag string, data []byte) error
 	Save() error
 }
 
@@ -120,6 +121,14 @@ func (writer *BundleArchiveFileWriter) WriteChartArchive(data []byte, opts helmo
 }
 
 func (writer *BundleArchiveFileWriter) WriteImageArchive(imageTag string, data []byte) error {
+	return writer.writeImageArchive(imageTag, imageArchiveFileName(imageTag), data)
+}
+
+func (writer *BundleArchiveFileWriter) WritePartialImageArchive(imageTag string, data []byte) error {
+	return writer.writeImageArchive(imageTag, partialImageArchiveFileName(imageTag), data)
+}
+
+func (writer *BundleArchiveFileWriter) writeImageArchive(imageTag, fileName string, data []byte) error {
 	now := time.Now()
 	buf := bytes.NewBuffer(nil)
 	zipper := gzip.NewWriter(buf)
@@ -133,7 +142,7 @@ func (writer *BundleArchiveFileWriter) WriteImageArchive(imageTag string, data [
 	}
 
 	header := &tar.Header{
-		Name:       fmt.Sprintf("images/%s.tar.gz", imageTag),
+		Name:       fileName,
 		Typeflag:   tar.TypeReg,
 		Mode:       0o777,
 		Size:       int64(len(buf.Bytes())),
@@ -147,7 +156,7 @@ func (writer *BundleArchiveFileWriter) WriteImageArchive(imageTag string, data [
 	}
 
 	if _, err := writer.tmpArchiveWriter.Write(buf.Bytes()); err != nil {
-		return fmt.Errorf("unable to write chart.tar.gz data: %w", err)
+		return fmt.Errorf("unable to write image %q data: %w", imageTag, err)
 	}
 
 	return nil
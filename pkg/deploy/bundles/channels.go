@@ -0,0 +1,222 @@
+package bundles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/docker_registry"
+)
+
+const promoteMaxAttempts = 5
+
+var errChannelsManifestChanged = errors.New("channels manifest has been changed concurrently")
+
+const (
+	// ChannelsManifestTag is a tag in the bundle repo, which contains the release channels manifest.
+	ChannelsManifestTag       = "werf-bundle-channels"
+	ChannelsManifestMediaType = "application/vnd.werf.bundle.channels.v1+yaml"
+)
+
+// ChannelsManifest maps release channels (alpha, beta, stable, etc.) to bundle tags,
+// same as trdl_channels.yaml maps werf release channels to werf versions.
+type ChannelsManifest struct {
+	Channels []*Channel `json:"channels"`
+}
+
+type Channel struct {
+	Name string `json:"name"`
+	Tag  string `json:"tag"`
+}
+
+func (manifest *ChannelsManifest) GetChannelTag(name string) (string, bool) {
+	for _, ch := range manifest.Channels {
+		if ch.Name == name {
+			return ch.Tag, true
+		}
+	}
+	return "", false
+}
+
+func (manifest *ChannelsManifest) SetChannelTag(name, tag string) {
+	for _, ch := range manifest.Channels {
+		if ch.Name == name {
+			ch.Tag = tag
+			return
+		}
+	}
+
+	manifest.Channels = append(manifest.Channels, &Channel{Name: name, Tag: tag})
+	sort.Slice(manifest.Channels, func(i, j int) bool {
+		return manifest.Channels[i].Name < manifest.Channels[j].Name
+	})
+}
+
+func getChannelsManifestReference(repo string) string {
+	return fmt.Sprintf("%s:%s", repo, ChannelsManifestTag)
+}
+
+// ReadChannelsManifest reads release channels manifest of the bundle repo, empty manifest is returned if there is no one.
+func ReadChannelsManifest(ctx context.Context, registryClient docker_registry.Interface, repo string) (*ChannelsManifest, error) {
+	manifest, _, err := readChannelsManifest(ctx, registryClient, repo)
+	return manifest, err
+}
+
+// readChannelsManifest returns the release channels manifest and the digest of its image, the digest is empty if there is no manifest.
+func readChannelsManifest(ctx context.Context, registryClient docker_registry.Interface, repo string) (*ChannelsManifest, string, error) {
+	ref := getChannelsManifestReference(repo)
+
+	img, err := registryClient.PullImage(ctx, ref)
+	if err != nil {
+		if docker_registry.IsImageNotFoundError(err) || docker_registry.IsStatusNotFoundErr(err) {
+			return &ChannelsManifest{}, "", nil
+		}
+		return nil, "", fmt.Errorf("unable to pull channels manifest %q: %w", ref, err)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to get channels manifest %q digest: %w", ref, err)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to get channels manifest %q layers: %w", ref, err)
+	}
+	if len(layers) != 1 {
+		return nil, "", fmt.Errorf("unexpected channels manifest %q: expected 1 layer, got %d", ref, len(layers))
+	}
+
+	rc, err := layers[0].Compressed()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read channels manifest %q: %w", ref, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read channels manifest %q: %w", ref, err)
+	}
+
+	manifest := &ChannelsManifest{}
+	if err := yaml.UnmarshalStrict(data, manifest); err != nil {
+		return nil, "", fmt.Errorf("unable to parse channels manifest %q: %w", ref, err)
+	}
+
+	return manifest, digest.String(), nil
+}
+
+func WriteChannelsManifest(ctx context.Context, registryClient docker_registry.Interface, repo string, manifest *ChannelsManifest) error {
+	ref := getChannelsManifestReference(repo)
+
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("unable to marshal channels manifest: %w", err)
+	}
+
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: static.NewLayer(data, ChannelsManifestMediaType)})
+	if err != nil {
+		return fmt.Errorf("unable to construct channels manifest image: %w", err)
+	}
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, types.OCIConfigJSON)
+
+	if err := registryClient.PushImageFrom(ctx, img, ref); err != nil {
+		return fmt.Errorf("unable to push channels manifest %q: %w", ref, err)
+	}
+
+	return nil
+}
+
+// ResolveChannelTag returns bundle tag of the release channel.
+func ResolveChannelTag(ctx context.Context, registryClient docker_registry.Interface, repo, channel string) (string, error) {
+	manifest, err := ReadChannelsManifest(ctx, registryClient, repo)
+	if err != nil {
+		return "", err
+	}
+
+	tag, ok := manifest.GetChannelTag(channel)
+	if !ok {
+		return "", fmt.Errorf("release channel %q not found in the bundle repo %q", channel, repo)
+	}
+
+	return tag, nil
+}
+
+type PromoteOptions struct {
+	// Either FromChannel or Tag should be specified.
+	FromChannel string
+	Tag         string
+	ToChannel   string
+}
+
+// Promote sets the ToChannel release channel of the bundle repo to the specified bundle tag or to the tag of the FromChannel release channel.
+//
+// Container registries do not support conditional manifest updates, so the channels manifest is updated optimistically:
+// the update is retried if the manifest has been changed by a concurrent promote before the push or if the promoted
+// channel has been overwritten right after the push.
+func Promote(ctx context.Context, registryClient docker_registry.Interface, repo string, opts PromoteOptions) error {
+	for attempt := 1; ; attempt++ {
+		err := promote(ctx, registryClient, repo, opts)
+		if !errors.Is(err, errChannelsManifestChanged) || attempt == promoteMaxAttempts {
+			return err
+		}
+
+		logboek.Context(ctx).Warn().LogF("WARNING: %s, retrying (%d/%d)\n", err, attempt, promoteMaxAttempts)
+	}
+}
+
+func promote(ctx context.Context, registryClient docker_registry.Interface, repo string, opts PromoteOptions) error {
+	manifest, digest, err := readChannelsManifest(ctx, registryClient, repo)
+	if err != nil {
+		return err
+	}
+
+	tag := opts.Tag
+	if opts.FromChannel != "" {
+		var ok bool
+		if tag, ok = manifest.GetChannelTag(opts.FromChannel); !ok {
+			return fmt.Errorf("release channel %q not found in the bundle repo %q", opts.FromChannel, repo)
+		}
+	}
+
+	if prevTag, ok := manifest.GetChannelTag(opts.ToChannel); ok && prevTag == tag {
+		logboek.Context(ctx).Default().LogF("Release channel %q is already at %s:%s\n", opts.ToChannel, repo, tag)
+		return nil
+	}
+
+	if _, err := registryClient.PullImage(ctx, fmt.Sprintf("%s:%s", repo, tag)); err != nil {
+		return fmt.Errorf("unable to get bundle %s:%s: %w", repo, tag, err)
+	}
+
+	manifest.SetChannelTag(opts.ToChannel, tag)
+
+	if _, currentDigest, err := readChannelsManifest(ctx, registryClient, repo); err != nil {
+		return err
+	} else if currentDigest != digest {
+		return fmt.Errorf("%w: digest %q changed to %q", errChannelsManifestChanged, digest, currentDigest)
+	}
+
+	if err := WriteChannelsManifest(ctx, registryClient, repo, manifest); err != nil {
+		return err
+	}
+
+	if writtenManifest, _, err := readChannelsManifest(ctx, registryClient, repo); err != nil {
+		return err
+	} else if writtenTag, _ := writtenManifest.GetChannelTag(opts.ToChannel); writtenTag != tag {
+		return fmt.Errorf("%w: release channel %q has been overwritten with tag %q", errChannelsManifestChanged, opts.ToChannel, writtenTag)
+	}
+
+	logboek.Context(ctx).Default().LogF("Release channel %q promoted to %s:%s\n", opts.ToChannel, repo, tag)
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package bundles
+
+import (
+	"context"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/v2/pkg/logging"
+)
+
+var _ = Describe("Bundle release channels", func() {
+	var ctx context.Context
+
+	BeforeEach(func(ctx0 context.Context) {
+		ctx = logging.WithLogger(ctx0)
+	})
+
+	It("should promote bundle tag through release channels", func() {
+		registryClient := NewDockerRegistryStub()
+		repo := "registry.example.com/group/testproject"
+
+		for _, tag := range []string{"1.0.0", "1.1.0"} {
+			img, err := random.Image(16, 1)
+			Expect(err).NotTo(HaveOccurred())
+			registryClient.V1ImagesByReference[repo+":"+tag] = img
+		}
+
+		manifest, err := ReadChannelsManifest(ctx, registryClient, repo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Channels).To(BeEmpty())
+
+		_, err = ResolveChannelTag(ctx, registryClient, repo, "stable")
+		Expect(err).To(MatchError(ContainSubstring(`release channel "stable" not found`)))
+
+		Expect(Promote(ctx, registryClient, repo, PromoteOptions{Tag: "1.0.0", ToChannel: "beta"})).To(Succeed())
+		Expect(Promote(ctx, registryClient, repo, PromoteOptions{FromChannel: "beta", ToChannel: "stable"})).To(Succeed())
+		Expect(Promote(ctx, registryClient, repo, PromoteOptions{Tag: "1.1.0", ToChannel: "beta"})).To(Succeed())
+
+		Expect(ResolveChannelTag(ctx, registryClient, repo, "stable")).To(Equal("1.0.0"))
+		Expect(ResolveChannelTag(ctx, registryClient, repo, "beta")).To(Equal("1.1.0"))
+
+		manifest, err = ReadChannelsManifest(ctx, registryClient, repo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Channels).To(Equal([]*Channel{{Name: "beta", Tag: "1.1.0"}, {Name: "stable", Tag: "1.0.0"}}))
+
+		Expect(Promote(ctx, registryClient, repo, PromoteOptions{FromChannel: "alpha", ToChannel: "beta"})).To(MatchError(ContainSubstring(`release channel "alpha" not found`)))
+		Expect(Promote(ctx, registryClient, repo, PromoteOptions{Tag: "2.0.0", ToChannel: "alpha"})).To(MatchError(ContainSubstring("unable to get bundle")))
+	})
+
+	It("should retry promote if channels manifest has been changed concurrently", func() {
+		stub := NewDockerRegistryStub()
+		repo := "registry.example.com/group/testproject"
+
+		for _, tag := range []string{"1.0.0", "1.1.0"} {
+			img, err := random.Image(16, 1)
+			Expect(err).NotTo(HaveOccurred())
+			stub.V1ImagesByReference[repo+":"+tag] = img
+		}
+
+		registryClient := &racingDockerRegistryStub{DockerRegistryStub: stub}
+		registryClient.onPull = func(reference string) {
+			if reference != getChannelsManifestReference(repo) {
+				return
+			}
+
+			// Concurrent promote changes the manifest after it has been read by the first promote.
+			registryClient.onPull = nil
+			Expect(Promote(ctx, stub, repo, PromoteOptions{Tag: "1.0.0", ToChannel: "stable"})).To(Succeed())
+		}
+
+		Expect(Promote(ctx, registryClient, repo, PromoteOptions{Tag: "1.1.0", ToChannel: "beta"})).To(Succeed())
+
+		manifest, err := ReadChannelsManifest(ctx, stub, repo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Channels).To(Equal([]*Channel{{Name: "beta", Tag: "1.1.0"}, {Name: "stable", Tag: "1.0.0"}}))
+	})
+})
+
+type racingDockerRegistryStub struct {
+	*DockerRegistryStub
+
+	onPull func(reference string)
+}
+
+func (registry *racingDockerRegistryStub) PullImage(ctx context.Context, reference string) (v1.Image, error) {
+	img, err := registry.DockerRegistryStub.PullImage(ctx, reference)
+	if registry.onPull != nil {
+		registry.onPull(reference)
+	}
+	return img, err
+}
@@ -58,3 +58,109 @@ func SaveChartValues(ctx context.Context, ch *chart.Chart) error {
 
 	return nil
 }
+
+// GetChartImages returns image references from the .Values.werf.image of the bundle chart by image name.
+func GetChartImages(ch *chart.Chart) (map[string]string, error) {
+	res := make(map[string]string)
+
+	werfVals, ok := ch.Values["werf"].(map[string]interface{})
+	if !ok {
+		return res, nil
+	}
+
+	imageVals, ok := werfVals["image"].(map[string]interface{})
+	if !ok {
+		return res, nil
+	}
+
+	for imageName, v := range imageVals {
+		imageRef, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value .Values.werf.image.%s=%v", imageName, v)
+		}
+		res[imageName] = imageRef
+	}
+
+	return res, nil
+}
+
+// getImageDigestValue returns .Values.werf.digest of the image, which is set by the werf service values.
+func getImageDigestValue(werfVals map[string]interface{}, imageName string) (string, bool) {
+	digestVals, ok := werfVals["digest"].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+
+	digest, ok := digestVals[imageName].(string)
+	return digest, ok && digest != ""
+}
+
+// setImageDigestValues sets .Values.werf.digest and .Values.werf.image_digest_ref of the image copied into the repo.
+func setImageDigestValues(werfVals map[string]interface{}, imageName, repo, digest string) {
+	if digestVals, ok := werfVals["digest"].(map[string]interface{}); ok {
+		digestVals[imageName] = digest
+	}
+	if digestRefVals, ok := werfVals["image_digest_ref"].(map[string]interface{}); ok {
+		digestRefVals[imageName] = fmt.Sprintf("%s@%s", repo, digest)
+	}
+}
+
+// GetChartThirdPartyImages returns sorted references of the third-party images copied into the bundle repo by
+// werf bundle publish, these are the values referencing .Values.werf.repo besides the .Values.werf service values.
+func GetChartThirdPartyImages(ch *chart.Chart) []string {
+	return getValuesThirdPartyImages(ch.Values)
+}
+
+func getValuesThirdPartyImages(values map[string]interface{}) []string {
+	werfVals, ok := values["werf"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	repo, _ := werfVals["repo"].(string)
+	if repo == "" {
+		return nil
+	}
+
+	images := make(map[string]bool)
+	for key, value := range values {
+		if key == "werf" {
+			continue
+		}
+		collectRepoImages(value, repo, images)
+	}
+
+	return sortedKeys(images)
+}
+
+// getChartImageReferences returns references of all images stored with the bundle: werf images and third-party images.
+func getChartImageReferences(ch *chart.Chart) ([]string, error) {
+	images, err := GetChartImages(ch)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(images))
+	for _, imageName := range sortedKeys(images) {
+		res = append(res, images[imageName])
+	}
+
+	return append(res, GetChartThirdPartyImages(ch)...), nil
+}
+
+func collectRepoImages(obj interface{}, repo string, images map[string]bool) {
+	switch v := obj.(type) {
+	case string:
+		if ref := splitImageReference(v); ref.Repo == repo && ref.Tag != "" {
+			images[v] = true
+		}
+	case map[string]interface{}:
+		for _, value := range v {
+			collectRepoImages(value, repo, images)
+		}
+	case []interface{}:
+		for _, value := range v {
+			collectRepoImages(value, repo, images)
+		}
+	}
+}
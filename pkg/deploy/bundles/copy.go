@@ -2,6 +2,8 @@ package bundles
 
 import (
 	"context"
+	"crypto"
+	"fmt"
 
 	"github.com/werf/3p-helm/pkg/werf/helmopts"
 	"github.com/werf/werf/v2/pkg/docker_registry"
@@ -13,6 +15,14 @@ type CopyOptions struct {
 	HelmCompatibleChart                  bool
 	RenameChart                          string
 	HelmOptions                          helmopts.HelmOptions
+
+	// DeltaBase is an address of the bundle destination repo or of a previously transferred bundle archive.
+	// When specified, the destination archive will contain only blobs missing at the delta base.
+	DeltaBase               *Addr
+	DeltaBaseRegistryClient docker_registry.Interface
+
+	// VerifyKey is used to verify the source bundle signature before copying.
+	VerifyKey crypto.PublicKey
 }
 
 func Copy(ctx context.Context, fromAddr, toAddr *Addr, opts CopyOptions) error {
@@ -25,5 +35,24 @@ func Copy(ctx context.Context, fromAddr, toAddr *Addr, opts CopyOptions) error {
 		RegistryClient:        opts.ToRegistryClient,
 	})
 
-	return fromBundle.CopyTo(ctx, toBundle, copyToOptions{HelmCompatibleChart: opts.HelmCompatibleChart, RenameChart: opts.RenameChart, HelmOptions: opts.HelmOptions})
+	copyOpts := copyToOptions{HelmCompatibleChart: opts.HelmCompatibleChart, RenameChart: opts.RenameChart, HelmOptions: opts.HelmOptions}
+
+	if opts.DeltaBase != nil {
+		if toAddr.ArchiveAddress == nil {
+			return fmt.Errorf("delta base could be used only with the bundle archive destination, got %q", toAddr.String())
+		}
+
+		copyOpts.DeltaBase = NewDeltaBase(opts.DeltaBase, DeltaBaseOptions{
+			RegistryClient: opts.DeltaBaseRegistryClient,
+			HelmOptions:    opts.HelmOptions,
+		})
+	}
+
+	if opts.VerifyKey != nil {
+		if err := VerifyBundleSignature(ctx, fromBundle, opts.VerifyKey, opts.HelmOptions); err != nil {
+			return err
+		}
+	}
+
+	return fromBundle.CopyTo(ctx, toBundle, copyOpts)
 }
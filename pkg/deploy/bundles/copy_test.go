@@ -3,10 +3,14 @@ package bundles
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"sigs.k8s.io/yaml"
@@ -15,6 +19,7 @@ import (
 	"github.com/werf/3p-helm/pkg/werf/helmopts"
 	bundles_registry "github.com/werf/werf/v2/pkg/deploy/bundles/registry"
 	"github.com/werf/werf/v2/pkg/docker_registry"
+	"github.com/werf/werf/v2/pkg/image"
 	"github.com/werf/werf/v2/pkg/logging"
 )
 
@@ -442,11 +447,102 @@ werf:
 			Expect(registryClient.ImagesByReference["registry2.example.com/group2/testproject2:tag-3"]).To(Equal([]byte(`image-3-bytes`)))
 		}
 	})
+	It("should relocate third-party images copied into the bundle repo", func() {
+		digest := "sha256:" + strings.Repeat("1", 64)
+		newChart := func(repo string) *chart.Chart {
+			values := map[string]interface{}{
+				"werf": map[string]interface{}{
+					"image": map[string]interface{}{"image-1": repo + ":tag-1"},
+					"repo":  repo,
+				},
+				"redis": map[string]interface{}{
+					"image": repo + ":docker.io-library-redis-7@" + digest,
+				},
+				"sidecars": []interface{}{"docker.io/library/nginx:1.25"},
+			}
+			valuesRaw, err := yaml.Marshal(values)
+			Expect(err).NotTo(HaveOccurred())
+
+			return &chart.Chart{
+				Metadata: &chart.Metadata{APIVersion: "v2", Name: "testproject", Version: "1.2.3", Type: "application"},
+				Values:   values,
+				Raw:      []*chart.File{{Name: "values.yaml", Data: valuesRaw}},
+			}
+		}
+
+		By("remote to remote")
+		{
+			bundlesRegistryClient := NewBundlesRegistryClientStub()
+			registryClient := NewDockerRegistryStub()
+
+			fromAddr, err := ParseAddr("registry.example.com/group/testproject:1.2.3")
+			Expect(err).NotTo(HaveOccurred())
+			from := NewRemoteBundle(fromAddr.RegistryAddress, bundlesRegistryClient, registryClient)
+			bundlesRegistryClient.StubCharts[fromAddr.RegistryAddress.FullName()] = newChart("registry.example.com/group/testproject")
+			registryClient.ImagesByReference["registry.example.com/group/testproject:tag-1"] = []byte(`image-1-bytes`)
+			registryClient.ImagesByReference["registry.example.com/group/testproject:docker.io-library-redis-7"] = []byte(`redis-bytes`)
+
+			toAddr, err := ParseAddr("registry2.example.com/group2/testproject2:4.5.6")
+			Expect(err).NotTo(HaveOccurred())
+			to := NewRemoteBundle(toAddr.RegistryAddress, bundlesRegistryClient, registryClient)
+
+			Expect(from.CopyTo(ctx, to, copyToOptions{})).To(Succeed())
+
+			newCh := bundlesRegistryClient.StubCharts[toAddr.RegistryAddress.FullName()]
+			Expect(newCh).NotTo(BeNil())
+			Expect(newCh.Values["redis"]).To(Equal(map[string]interface{}{"image": "registry2.example.com/group2/testproject2:docker.io-library-redis-7@" + digest}))
+			Expect(newCh.Values["sidecars"]).To(Equal([]interface{}{"docker.io/library/nginx:1.25"}))
+			Expect(registryClient.ImagesByReference["registry2.example.com/group2/testproject2:docker.io-library-redis-7"]).To(Equal([]byte(`redis-bytes`)))
+		}
+
+		By("archive to remote")
+		{
+			images := map[string][]byte{
+				"tag-1":                     []byte(`image-1-bytes`),
+				"docker.io-library-redis-7": []byte(`redis-bytes`),
+			}
+			from := NewBundleArchive(NewBundleArchiveStubReader(newChart("repo"), images), NewBundleArchiveStubWriter())
+
+			addr, err := ParseAddr("registry.example.com/group/testproject:1.2.3")
+			Expect(err).NotTo(HaveOccurred())
+			bundlesRegistryClient := NewBundlesRegistryClientStub()
+			registryClient := NewDockerRegistryStub()
+			to := NewRemoteBundle(addr.RegistryAddress, bundlesRegistryClient, registryClient)
+
+			Expect(from.CopyTo(ctx, to, copyToOptions{})).To(Succeed())
+
+			newCh := bundlesRegistryClient.StubCharts[addr.RegistryAddress.FullName()]
+			Expect(newCh).NotTo(BeNil())
+			// Image manifest is rebuilt from the archive, so the digest is taken from the destination.
+			Expect(newCh.Values["redis"]).To(Equal(map[string]interface{}{"image": fmt.Sprintf("registry.example.com/group/testproject:docker.io-library-redis-7@sha256:%x", sha256.Sum256([]byte(`redis-bytes`)))}))
+			Expect(registryClient.ImagesByReference["registry.example.com/group/testproject:docker.io-library-redis-7"]).To(Equal([]byte(`redis-bytes`)))
+		}
+
+		By("remote to archive")
+		{
+			bundlesRegistryClient := NewBundlesRegistryClientStub()
+			registryClient := NewDockerRegistryStub()
+
+			addr, err := ParseAddr("registry.example.com/group/testproject:1.2.3")
+			Expect(err).NotTo(HaveOccurred())
+			from := NewRemoteBundle(addr.RegistryAddress, bundlesRegistryClient, registryClient)
+			bundlesRegistryClient.StubCharts[addr.RegistryAddress.FullName()] = newChart("registry.example.com/group/testproject")
+			registryClient.ImagesByReference["registry.example.com/group/testproject:tag-1"] = []byte(`image-1-bytes`)
+			registryClient.ImagesByReference["registry.example.com/group/testproject:docker.io-library-redis-7@"+digest] = []byte(`redis-bytes`)
+
+			toArchiveWriterStub := NewBundleArchiveStubWriter()
+			to := NewBundleArchive(NewBundleArchiveStubReader(nil, nil), toArchiveWriterStub)
+
+			Expect(from.CopyTo(ctx, to, copyToOptions{})).To(Succeed())
+			Expect(toArchiveWriterStub.ImagesByTag["docker.io-library-redis-7"]).To(Equal([]byte(`redis-bytes`)))
+		}
+	})
 })
 
 type BundleArchiveStubReader struct {
-	StubChart   *chart.Chart
-	ImagesByTag map[string][]byte
+	StubChart          *chart.Chart
+	ImagesByTag        map[string][]byte
+	PartialImagesByTag map[string][]byte
 }
 
 func NewBundleArchiveStubReader(stubChart *chart.Chart, imagesByTag map[string][]byte) *BundleArchiveStubReader {
@@ -464,18 +560,27 @@ func (reader *BundleArchiveStubReader) ReadChartArchive() ([]byte, error) {
 func (reader *BundleArchiveStubReader) ReadImageArchive(imageTag string) (*ImageArchiveReadCloser, error) {
 	data, hasTag := reader.ImagesByTag[imageTag]
 	if !hasTag {
-		return nil, fmt.Errorf("no image found by tag %q", imageTag)
+		return nil, fmt.Errorf("no image found by tag %q: %w", imageTag, ErrImageArchiveNotFound)
+	}
+	return NewImageArchiveReadCloser(bytes.NewReader(data), func() error { return nil }), nil
+}
+
+func (reader *BundleArchiveStubReader) ReadPartialImageArchive(imageTag string) (*ImageArchiveReadCloser, error) {
+	data, hasTag := reader.PartialImagesByTag[imageTag]
+	if !hasTag {
+		return nil, fmt.Errorf("no partial image found by tag %q: %w", imageTag, ErrImageArchiveNotFound)
 	}
 	return NewImageArchiveReadCloser(bytes.NewReader(data), func() error { return nil }), nil
 }
 
 type BundleArchiveStubWriter struct {
-	StubChart   *chart.Chart
-	ImagesByTag map[string][]byte
+	StubChart          *chart.Chart
+	ImagesByTag        map[string][]byte
+	PartialImagesByTag map[string][]byte
 }
 
 func NewBundleArchiveStubWriter() *BundleArchiveStubWriter {
-	return &BundleArchiveStubWriter{ImagesByTag: make(map[string][]byte)}
+	return &BundleArchiveStubWriter{ImagesByTag: make(map[string][]byte), PartialImagesByTag: make(map[string][]byte)}
 }
 
 func (writer *BundleArchiveStubWriter) Open() error { return nil }
@@ -496,6 +601,11 @@ func (writer *BundleArchiveStubWriter) WriteImageArchive(imageTag string, data [
 	return nil
 }
 
+func (writer *BundleArchiveStubWriter) WritePartialImageArchive(imageTag string, data []byte) error {
+	writer.PartialImagesByTag[imageTag] = data
+	return nil
+}
+
 func (writer *BundleArchiveStubWriter) Save() error { return nil }
 
 type BundlesRegistryClientStub struct {
@@ -531,12 +641,16 @@ func (client *BundlesRegistryClientStub) PushChart(ctx context.Context, ref *bun
 type DockerRegistryStub struct {
 	docker_registry.Interface
 
-	ImagesByReference map[string][]byte
+	ImagesByReference   map[string][]byte
+	V1ImagesByReference map[string]v1.Image
+	BlobsByRepo         map[string]map[string]bool
 }
 
 func NewDockerRegistryStub() *DockerRegistryStub {
 	return &DockerRegistryStub{
-		ImagesByReference: make(map[string][]byte),
+		ImagesByReference:   make(map[string][]byte),
+		V1ImagesByReference: make(map[string]v1.Image),
+		BlobsByRepo:         make(map[string]map[string]bool),
 	}
 }
 
@@ -583,6 +697,72 @@ func (registry *DockerRegistryStub) CopyImage(_ context.Context, sourceReference
 	return nil
 }
 
+func (registry *DockerRegistryStub) PullImage(_ context.Context, reference string) (v1.Image, error) {
+	img, hasImage := registry.V1ImagesByReference[reference]
+	if !hasImage {
+		return nil, fmt.Errorf("%s: image not found", transport.ManifestUnknownErrorCode)
+	}
+	return img, nil
+}
+
+func (registry *DockerRegistryStub) PushImageFrom(_ context.Context, img v1.Image, reference string) error {
+	repo, _ := image.ParseRepositoryAndTag(reference)
+	if registry.BlobsByRepo[repo] == nil {
+		registry.BlobsByRepo[repo] = make(map[string]bool)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return err
+	}
+
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return err
+		}
+
+		if registry.BlobsByRepo[repo][digest.String()] {
+			continue
+		}
+
+		rc, err := layer.Compressed()
+		if err != nil {
+			return err
+		}
+		if err := rc.Close(); err != nil {
+			return err
+		}
+
+		registry.BlobsByRepo[repo][digest.String()] = true
+	}
+
+	registry.V1ImagesByReference[reference] = img
+	return nil
+}
+
+func (registry *DockerRegistryStub) GetRepoImage(_ context.Context, reference string) (*image.Info, error) {
+	repo, _ := image.ParseRepositoryAndTag(reference)
+
+	if img, hasImage := registry.V1ImagesByReference[reference]; hasImage {
+		digest, err := img.Digest()
+		if err != nil {
+			return nil, err
+		}
+		return &image.Info{Name: reference, Repository: repo, RepoDigest: fmt.Sprintf("%s@%s", repo, digest)}, nil
+	}
+
+	if data, hasImage := registry.ImagesByReference[reference]; hasImage {
+		return &image.Info{Name: reference, Repository: repo, RepoDigest: fmt.Sprintf("%s@sha256:%x", repo, sha256.Sum256(data))}, nil
+	}
+
+	return nil, fmt.Errorf("%s: image not found", transport.ManifestUnknownErrorCode)
+}
+
+func (registry *DockerRegistryStub) IsBlobExist(_ context.Context, repository, digest string) (bool, error) {
+	return registry.BlobsByRepo[repository][digest], nil
+}
+
 type VerifyChartOptions struct {
 	ExpectedName    string
 	ExpectedVersion string
@@ -0,0 +1,128 @@
+package bundles
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/werf/3p-helm/pkg/werf/helmopts"
+	"github.com/werf/werf/v2/pkg/docker_registry"
+)
+
+// DeltaBase is a set of blobs already available at the bundle destination.
+// Delta bundle archive includes only blobs missing at the delta base.
+type DeltaBase interface {
+	String() string
+	HasBlob(ctx context.Context, digest v1.Hash) (bool, error)
+}
+
+type DeltaBaseOptions struct {
+	RegistryClient docker_registry.Interface
+	HelmOptions    helmopts.HelmOptions
+}
+
+func NewDeltaBase(addr *Addr, opts DeltaBaseOptions) DeltaBase {
+	switch {
+	case addr.RegistryAddress != nil:
+		return NewRegistryDeltaBase(addr.RegistryAddress.Repo, opts.RegistryClient)
+	case addr.ArchiveAddress != nil:
+		return NewArchiveDeltaBase(NewBundleArchive(NewBundleArchiveFileReader(addr.ArchiveAddress.Path), nil), opts.HelmOptions)
+	default:
+		panic(fmt.Sprintf("invalid address given %#v", addr))
+	}
+}
+
+// RegistryDeltaBase checks blobs existence directly in the destination repository.
+type RegistryDeltaBase struct {
+	Repo           string
+	RegistryClient docker_registry.Interface
+
+	existingBlobs map[v1.Hash]bool
+}
+
+func NewRegistryDeltaBase(repo string, registryClient docker_registry.Interface) *RegistryDeltaBase {
+	return &RegistryDeltaBase{Repo: repo, RegistryClient: registryClient, existingBlobs: make(map[v1.Hash]bool)}
+}
+
+func (base *RegistryDeltaBase) String() string {
+	return base.Repo
+}
+
+func (base *RegistryDeltaBase) HasBlob(ctx context.Context, digest v1.Hash) (bool, error) {
+	if exists, ok := base.existingBlobs[digest]; ok {
+		return exists, nil
+	}
+
+	exists, err := base.RegistryClient.IsBlobExist(ctx, base.Repo, digest.String())
+	if err != nil {
+		return false, err
+	}
+	base.existingBlobs[digest] = exists
+
+	return exists, nil
+}
+
+// ArchiveDeltaBase is a previously transferred bundle archive (full or delta):
+// all blobs referenced by the images of this bundle are considered available at the destination.
+type ArchiveDeltaBase struct {
+	Archive     *BundleArchive
+	HelmOptions helmopts.HelmOptions
+
+	blobs map[v1.Hash]bool
+}
+
+func NewArchiveDeltaBase(archive *BundleArchive, helmOpts helmopts.HelmOptions) *ArchiveDeltaBase {
+	return &ArchiveDeltaBase{Archive: archive, HelmOptions: helmOpts}
+}
+
+func (base *ArchiveDeltaBase) String() string {
+	return base.Archive.Reader.String()
+}
+
+func (base *ArchiveDeltaBase) HasBlob(ctx context.Context, digest v1.Hash) (bool, error) {
+	if base.blobs == nil {
+		blobs, err := base.loadBlobs(ctx)
+		if err != nil {
+			return false, fmt.Errorf("unable to load blobs list from the bundle archive %q: %w", base.String(), err)
+		}
+		base.blobs = blobs
+	}
+
+	return base.blobs[digest], nil
+}
+
+func (base *ArchiveDeltaBase) loadBlobs(ctx context.Context) (map[v1.Hash]bool, error) {
+	ch, err := base.Archive.ReadChart(ctx, base.HelmOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := getChartImageReferences(ch)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make(map[v1.Hash]bool)
+
+	for _, imageRef := range images {
+		tag := getImageReferenceTag(imageRef)
+
+		img, err := base.Archive.ReadImage(tag)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image %q: %w", imageRef, err)
+		}
+
+		manifest, err := img.Manifest()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get image %q manifest: %w", imageRef, err)
+		}
+
+		blobs[manifest.Config.Digest] = true
+		for _, desc := range manifest.Layers {
+			blobs[desc.Digest] = true
+		}
+	}
+
+	return blobs, nil
+}
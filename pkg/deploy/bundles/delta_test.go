@@ -0,0 +1,153 @@
+package bundles
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/werf/helmopts"
+	"github.com/werf/werf/v2/pkg/logging"
+)
+
+var _ = Describe("Bundle delta copy", func() {
+	var ctx context.Context
+
+	var baseImg, newImg v1.Image
+	var newLayerDigest v1.Hash
+
+	BeforeEach(func(ctx0 context.Context) {
+		ctx = logging.WithLogger(ctx0)
+
+		var err error
+		baseImg, err = random.Image(1024, 2)
+		Expect(err).NotTo(HaveOccurred())
+
+		newLayer, err := random.Layer(1024, types.DockerLayer)
+		Expect(err).NotTo(HaveOccurred())
+		newLayerDigest, err = newLayer.Digest()
+		Expect(err).NotTo(HaveOccurred())
+
+		newImg, err = mutate.AppendLayers(baseImg, newLayer)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	newChart := func(imageRef string) *chart.Chart {
+		return &chart.Chart{
+			Metadata: &chart.Metadata{
+				APIVersion: "v2",
+				Name:       "testproject",
+				Version:    "1.2.3",
+				Type:       "application",
+			},
+			Values: map[string]interface{}{
+				"werf": map[string]interface{}{
+					"image": map[string]interface{}{"image-1": imageRef},
+					"repo":  "registry.example.com/group/testproject",
+				},
+			},
+			Raw: []*chart.File{
+				{
+					Name: "values.yaml",
+					Data: []byte("werf:\n  image:\n    image-1: " + imageRef + "\n  repo: registry.example.com/group/testproject\n"),
+				},
+			},
+		}
+	}
+
+	It("should copy remote to delta archive containing only missing layers and apply it to remote", func() {
+		baseImageBytes := bytes.NewBuffer(nil)
+		baseRef, err := name.NewTag("registry.example.com/group/testproject:tag-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tarball.Write(baseRef, baseImg, baseImageBytes)).To(Succeed())
+
+		baseArchiveReaderStub := NewBundleArchiveStubReader(newChart("registry.example.com/group/testproject:tag-1"), map[string][]byte{"tag-1": baseImageBytes.Bytes()})
+		deltaBase := NewArchiveDeltaBase(NewBundleArchive(baseArchiveReaderStub, nil), helmopts.HelmOptions{})
+
+		addr, err := ParseAddr("registry.example.com/group/testproject:1.2.4")
+		Expect(err).NotTo(HaveOccurred())
+		bundlesRegistryClient := NewBundlesRegistryClientStub()
+		registryClient := NewDockerRegistryStub()
+		from := NewRemoteBundle(addr.RegistryAddress, bundlesRegistryClient, registryClient)
+
+		ch := newChart("registry.example.com/group/testproject:tag-2")
+		bundlesRegistryClient.StubCharts[addr.RegistryAddress.FullName()] = ch
+		registryClient.V1ImagesByReference["registry.example.com/group/testproject:tag-2"] = newImg
+
+		deltaArchiveWriterStub := NewBundleArchiveStubWriter()
+		delta := NewBundleArchive(NewBundleArchiveStubReader(ch, nil), deltaArchiveWriterStub)
+
+		Expect(from.CopyTo(ctx, delta, copyToOptions{DeltaBase: deltaBase})).To(Succeed())
+
+		Expect(deltaArchiveWriterStub.ImagesByTag).To(BeEmpty())
+		Expect(deltaArchiveWriterStub.PartialImagesByTag).To(HaveKey("tag-2"))
+
+		partialImg, err := ReadPartialImageArchive(bytes.NewReader(deltaArchiveWriterStub.PartialImagesByTag["tag-2"]))
+		Expect(err).NotTo(HaveOccurred())
+
+		expectedDigest, err := newImg.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(partialImg.Digest()).To(Equal(expectedDigest))
+
+		layers, err := partialImg.Layers()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(layers).To(HaveLen(3))
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = layer.Compressed()
+			if digest == newLayerDigest {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		}
+
+		deltaArchiveReaderStub := NewBundleArchiveStubReader(deltaArchiveWriterStub.StubChart, nil)
+		deltaArchiveReaderStub.PartialImagesByTag = deltaArchiveWriterStub.PartialImagesByTag
+		deltaArchive := NewBundleArchive(deltaArchiveReaderStub, nil)
+
+		{
+			toAddr, err := ParseAddr("registry2.example.com/group2/testproject2:1.2.4")
+			Expect(err).NotTo(HaveOccurred())
+			toRegistryClient := NewDockerRegistryStub()
+			to := NewRemoteBundle(toAddr.RegistryAddress, NewBundlesRegistryClientStub(), toRegistryClient)
+
+			Expect(deltaArchive.CopyTo(ctx, to, copyToOptions{})).NotTo(Succeed())
+		}
+
+		{
+			toAddr, err := ParseAddr("registry2.example.com/group2/testproject2:1.2.4")
+			Expect(err).NotTo(HaveOccurred())
+			toRegistryClient := NewDockerRegistryStub()
+			to := NewRemoteBundle(toAddr.RegistryAddress, NewBundlesRegistryClientStub(), toRegistryClient)
+			Expect(toRegistryClient.PushImageFrom(ctx, baseImg, "registry2.example.com/group2/testproject2:tag-1")).To(Succeed())
+
+			Expect(deltaArchive.CopyTo(ctx, to, copyToOptions{})).To(Succeed())
+
+			pushedImg := toRegistryClient.V1ImagesByReference["registry2.example.com/group2/testproject2:tag-2"]
+			Expect(pushedImg).NotTo(BeNil())
+			Expect(pushedImg.Digest()).To(Equal(expectedDigest))
+		}
+	})
+
+	It("should skip layers existing in the destination registry", func() {
+		registryClient := NewDockerRegistryStub()
+		Expect(registryClient.PushImageFrom(ctx, baseImg, "registry.example.com/group/testproject:tag-1")).To(Succeed())
+
+		buf := bytes.NewBuffer(nil)
+		stats, err := WritePartialImageArchive(ctx, buf, newImg, NewRegistryDeltaBase("registry.example.com/group/testproject", registryClient))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.IncludedLayers).To(Equal(1))
+		Expect(stats.SkippedLayers).To(Equal(2))
+	})
+})
@@ -0,0 +1,400 @@
+package bundles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/chart"
+)
+
+type DiffChange string
+
+const (
+	DiffChangeAdded   DiffChange = "added"
+	DiffChangeRemoved DiffChange = "removed"
+	DiffChangeChanged DiffChange = "changed"
+)
+
+// BundleDiff is a structured difference between two bundle versions.
+type BundleDiff struct {
+	FromVersion string `json:"fromVersion"`
+	ToVersion   string `json:"toVersion"`
+
+	Images       []ImageDiff      `json:"images,omitempty"`
+	Dependencies []DependencyDiff `json:"dependencies,omitempty"`
+	Values       []ValueDiff      `json:"values,omitempty"`
+	Templates    []FileDiff       `json:"templates,omitempty"`
+	Manifests    []FileDiff       `json:"manifests,omitempty"`
+}
+
+type ImageDiff struct {
+	Name          string     `json:"name"`
+	Change        DiffChange `json:"change"`
+	FromReference string     `json:"fromReference,omitempty"`
+	ToReference   string     `json:"toReference,omitempty"`
+	FromDigest    string     `json:"fromDigest,omitempty"`
+	ToDigest      string     `json:"toDigest,omitempty"`
+	DigestChanged bool       `json:"digestChanged"`
+}
+
+type DependencyDiff struct {
+	Name           string     `json:"name"`
+	Change         DiffChange `json:"change"`
+	FromVersion    string     `json:"fromVersion,omitempty"`
+	ToVersion      string     `json:"toVersion,omitempty"`
+	FromRepository string     `json:"fromRepository,omitempty"`
+	ToRepository   string     `json:"toRepository,omitempty"`
+}
+
+type ValueDiff struct {
+	Path   string      `json:"path"`
+	Change DiffChange  `json:"change"`
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+}
+
+type FileDiff struct {
+	Name   string     `json:"name"`
+	Change DiffChange `json:"change"`
+	Diff   string     `json:"diff,omitempty"`
+}
+
+type DiffChartsOptions struct {
+	// GetImageDigest is used to detect whether image digest has changed, digests are not compared if not set.
+	GetImageDigest func(ctx context.Context, reference string) (string, error)
+	ContextLines   int
+}
+
+func DiffCharts(ctx context.Context, from, to *chart.Chart, opts DiffChartsOptions) (*BundleDiff, error) {
+	diff := &BundleDiff{
+		FromVersion: from.Metadata.Version,
+		ToVersion:   to.Metadata.Version,
+	}
+
+	images, err := diffImages(ctx, from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+	diff.Images = images
+
+	diff.Dependencies = diffDependencies(from.Metadata.Dependencies, to.Metadata.Dependencies)
+	diff.Values = diffValues("", from.Values, to.Values)
+	diff.Templates = diffFiles(chartFilesMap(from.Templates), chartFilesMap(to.Templates), opts.ContextLines)
+
+	return diff, nil
+}
+
+// SetManifests sets difference of rendered manifests given by resource id.
+func (diff *BundleDiff) SetManifests(from, to map[string]string, contextLines int) {
+	diff.Manifests = diffFiles(from, to, contextLines)
+}
+
+func (diff *BundleDiff) HasChanges() bool {
+	return len(diff.Images) > 0 || len(diff.Dependencies) > 0 || len(diff.Values) > 0 || len(diff.Templates) > 0 || len(diff.Manifests) > 0
+}
+
+func (diff *BundleDiff) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Bundle %s -> %s\n", diff.FromVersion, diff.ToVersion)
+
+	if !diff.HasChanges() {
+		b.WriteString("\nNo changes\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	if len(diff.Images) > 0 {
+		b.WriteString("\nImages:\n")
+		for _, img := range diff.Images {
+			switch img.Change {
+			case DiffChangeAdded:
+				fmt.Fprintf(&b, "  + %s: %s%s\n", img.Name, img.ToReference, digestSuffix(img.ToDigest))
+			case DiffChangeRemoved:
+				fmt.Fprintf(&b, "  - %s: %s%s\n", img.Name, img.FromReference, digestSuffix(img.FromDigest))
+			default:
+				fmt.Fprintf(&b, "  ~ %s: %s -> %s", img.Name, img.FromReference, img.ToReference)
+				switch {
+				case img.DigestChanged:
+					fmt.Fprintf(&b, " (digest changed: %s -> %s)", img.FromDigest, img.ToDigest)
+				case img.FromDigest != "":
+					fmt.Fprintf(&b, " (digest unchanged: %s)", img.FromDigest)
+				}
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	if len(diff.Dependencies) > 0 {
+		b.WriteString("\nDependencies:\n")
+		for _, dep := range diff.Dependencies {
+			switch dep.Change {
+			case DiffChangeAdded:
+				fmt.Fprintf(&b, "  + %s %s (%s)\n", dep.Name, dep.ToVersion, dep.ToRepository)
+			case DiffChangeRemoved:
+				fmt.Fprintf(&b, "  - %s %s (%s)\n", dep.Name, dep.FromVersion, dep.FromRepository)
+			default:
+				fmt.Fprintf(&b, "  ~ %s %s (%s) -> %s (%s)\n", dep.Name, dep.FromVersion, dep.FromRepository, dep.ToVersion, dep.ToRepository)
+			}
+		}
+	}
+
+	if len(diff.Values) > 0 {
+		b.WriteString("\nDefault values:\n")
+		for _, v := range diff.Values {
+			switch v.Change {
+			case DiffChangeAdded:
+				fmt.Fprintf(&b, "  + %s: %v\n", v.Path, formatValue(v.To))
+			case DiffChangeRemoved:
+				fmt.Fprintf(&b, "  - %s: %v\n", v.Path, formatValue(v.From))
+			default:
+				fmt.Fprintf(&b, "  ~ %s: %v -> %v\n", v.Path, formatValue(v.From), formatValue(v.To))
+			}
+		}
+	}
+
+	writeFileDiffs(&b, "Templates", diff.Templates)
+	writeFileDiffs(&b, "Rendered manifests", diff.Manifests)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFileDiffs(b *strings.Builder, title string, diffs []FileDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n%s:\n", title)
+	for _, f := range diffs {
+		switch f.Change {
+		case DiffChangeAdded:
+			fmt.Fprintf(b, "  + %s\n", f.Name)
+		case DiffChangeRemoved:
+			fmt.Fprintf(b, "  - %s\n", f.Name)
+		default:
+			fmt.Fprintf(b, "  ~ %s\n", f.Name)
+		}
+		if f.Diff != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(f.Diff, "\n"), "\n") {
+				fmt.Fprintf(b, "      %s\n", line)
+			}
+		}
+	}
+}
+
+func digestSuffix(digest string) string {
+	if digest == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", digest)
+}
+
+func formatValue(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := yaml.Marshal(v)
+		if err == nil {
+			return strings.TrimSpace(strings.ReplaceAll(string(data), "\n", " "))
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func diffImages(ctx context.Context, from, to *chart.Chart, opts DiffChartsOptions) ([]ImageDiff, error) {
+	fromImages, err := GetChartImages(from)
+	if err != nil {
+		return nil, err
+	}
+	toImages, err := GetChartImages(to)
+	if err != nil {
+		return nil, err
+	}
+
+	getDigest := func(reference string) (string, error) {
+		if opts.GetImageDigest == nil || reference == "" {
+			return "", nil
+		}
+		digest, err := opts.GetImageDigest(ctx, reference)
+		if err != nil {
+			return "", fmt.Errorf("unable to get image %s digest: %w", reference, err)
+		}
+		return digest, nil
+	}
+
+	var res []ImageDiff
+	for _, name := range sortedKeys(fromImages, toImages) {
+		fromRef, toRef := fromImages[name], toImages[name]
+
+		d := ImageDiff{Name: name, FromReference: fromRef, ToReference: toRef}
+		switch {
+		case fromRef == "":
+			d.Change = DiffChangeAdded
+		case toRef == "":
+			d.Change = DiffChangeRemoved
+		default:
+			d.Change = DiffChangeChanged
+		}
+
+		if d.FromDigest, err = getDigest(fromRef); err != nil {
+			return nil, err
+		}
+		if d.ToDigest, err = getDigest(toRef); err != nil {
+			return nil, err
+		}
+		d.DigestChanged = d.Change == DiffChangeChanged && d.FromDigest != d.ToDigest
+
+		if d.Change == DiffChangeChanged && fromRef == toRef && !d.DigestChanged {
+			continue
+		}
+
+		res = append(res, d)
+	}
+
+	return res, nil
+}
+
+func diffDependencies(from, to []*chart.Dependency) []DependencyDiff {
+	fromDeps := make(map[string]*chart.Dependency)
+	for _, dep := range from {
+		fromDeps[dependencyKey(dep)] = dep
+	}
+	toDeps := make(map[string]*chart.Dependency)
+	for _, dep := range to {
+		toDeps[dependencyKey(dep)] = dep
+	}
+
+	var res []DependencyDiff
+	for _, key := range sortedKeys(fromDeps, toDeps) {
+		fromDep, toDep := fromDeps[key], toDeps[key]
+
+		d := DependencyDiff{Name: key}
+		if fromDep != nil {
+			d.FromVersion, d.FromRepository = fromDep.Version, fromDep.Repository
+		}
+		if toDep != nil {
+			d.ToVersion, d.ToRepository = toDep.Version, toDep.Repository
+		}
+
+		switch {
+		case fromDep == nil:
+			d.Change = DiffChangeAdded
+		case toDep == nil:
+			d.Change = DiffChangeRemoved
+		case d.FromVersion != d.ToVersion || d.FromRepository != d.ToRepository:
+			d.Change = DiffChangeChanged
+		default:
+			continue
+		}
+
+		res = append(res, d)
+	}
+
+	return res
+}
+
+func dependencyKey(dep *chart.Dependency) string {
+	if dep.Alias != "" {
+		return dep.Alias
+	}
+	return dep.Name
+}
+
+// Image references in .Values.werf.image are compared separately.
+func diffValues(prefix string, from, to map[string]interface{}) []ValueDiff {
+	var res []ValueDiff
+
+	for _, key := range sortedKeys(from, to) {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		if path == "werf.image" {
+			continue
+		}
+
+		fromVal, hasFrom := from[key]
+		toVal, hasTo := to[key]
+
+		switch {
+		case !hasFrom:
+			res = append(res, ValueDiff{Path: path, Change: DiffChangeAdded, To: toVal})
+		case !hasTo:
+			res = append(res, ValueDiff{Path: path, Change: DiffChangeRemoved, From: fromVal})
+		default:
+			fromMap, fromIsMap := fromVal.(map[string]interface{})
+			toMap, toIsMap := toVal.(map[string]interface{})
+			if fromIsMap && toIsMap {
+				res = append(res, diffValues(path, fromMap, toMap)...)
+			} else if !reflect.DeepEqual(fromVal, toVal) {
+				res = append(res, ValueDiff{Path: path, Change: DiffChangeChanged, From: fromVal, To: toVal})
+			}
+		}
+	}
+
+	return res
+}
+
+func chartFilesMap(files []*chart.File) map[string]string {
+	res := make(map[string]string)
+	for _, f := range files {
+		res[f.Name] = string(f.Data)
+	}
+	return res
+}
+
+func diffFiles(from, to map[string]string, contextLines int) []FileDiff {
+	var res []FileDiff
+
+	for _, name := range sortedKeys(from, to) {
+		fromData, hasFrom := from[name]
+		toData, hasTo := to[name]
+
+		d := FileDiff{Name: name}
+		switch {
+		case !hasFrom:
+			d.Change = DiffChangeAdded
+		case !hasTo:
+			d.Change = DiffChangeRemoved
+		case fromData != toData:
+			d.Change = DiffChangeChanged
+		default:
+			continue
+		}
+
+		d.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:       difflib.SplitLines(fromData),
+			B:       difflib.SplitLines(toData),
+			Context: contextLines,
+		})
+
+		res = append(res, d)
+	}
+
+	return res
+}
+
+func sortedKeys[V any](maps ...map[string]V) []string {
+	keys := make(map[string]struct{})
+	for _, m := range maps {
+		for k := range m {
+			keys[k] = struct{}{}
+		}
+	}
+
+	res := make([]string, 0, len(keys))
+	for k := range keys {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
+}
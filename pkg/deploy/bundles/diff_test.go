@@ -0,0 +1,108 @@
+package bundles
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/3p-helm/pkg/chart"
+)
+
+var _ = Describe("Bundle diff", func() {
+	newChart := func(version string, values map[string]interface{}, deps []*chart.Dependency, templates map[string]string) *chart.Chart {
+		ch := &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: "v2", Name: "testproject", Version: version, Dependencies: deps},
+			Values:   values,
+		}
+		for name, data := range templates {
+			ch.Templates = append(ch.Templates, &chart.File{Name: name, Data: []byte(data)})
+		}
+		return ch
+	}
+
+	It("should report changed images, dependencies, values and templates", func(ctx context.Context) {
+		from := newChart("1.0.0",
+			map[string]interface{}{
+				"werf": map[string]interface{}{
+					"image": map[string]interface{}{
+						"backend":  "registry.example.com/app:tag-1",
+						"frontend": "registry.example.com/app:tag-2",
+						"worker":   "registry.example.com/app:tag-3",
+					},
+				},
+				"replicas": 1,
+				"ingress":  map[string]interface{}{"enabled": false},
+			},
+			[]*chart.Dependency{{Name: "redis", Version: "17.0.0", Repository: "oci://charts"}},
+			map[string]string{"templates/deployment.yaml": "replicas: 1\n", "templates/old.yaml": "old\n"},
+		)
+		to := newChart("1.1.0",
+			map[string]interface{}{
+				"werf": map[string]interface{}{
+					"image": map[string]interface{}{
+						"backend":  "registry.example.com/app:tag-1",
+						"frontend": "registry.example.com/app:tag-4",
+						"migrator": "registry.example.com/app:tag-5",
+					},
+				},
+				"replicas": 2,
+				"ingress":  map[string]interface{}{"enabled": false, "host": "example.com"},
+			},
+			[]*chart.Dependency{{Name: "redis", Version: "18.0.0", Repository: "oci://charts"}, {Name: "postgresql", Version: "1.0.0", Repository: "oci://charts"}},
+			map[string]string{"templates/deployment.yaml": "replicas: 2\n"},
+		)
+
+		digests := map[string]string{
+			"registry.example.com/app:tag-1": "sha256:aaa",
+			"registry.example.com/app:tag-2": "sha256:bbb",
+			"registry.example.com/app:tag-3": "sha256:ccc",
+			"registry.example.com/app:tag-4": "sha256:bbb",
+			"registry.example.com/app:tag-5": "sha256:ddd",
+		}
+
+		diff, err := DiffCharts(ctx, from, to, DiffChartsOptions{
+			GetImageDigest: func(_ context.Context, reference string) (string, error) { return digests[reference], nil },
+			ContextLines:   3,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.HasChanges()).To(BeTrue())
+
+		Expect(diff.Images).To(Equal([]ImageDiff{
+			{Name: "frontend", Change: DiffChangeChanged, FromReference: "registry.example.com/app:tag-2", ToReference: "registry.example.com/app:tag-4", FromDigest: "sha256:bbb", ToDigest: "sha256:bbb"},
+			{Name: "migrator", Change: DiffChangeAdded, ToReference: "registry.example.com/app:tag-5", ToDigest: "sha256:ddd"},
+			{Name: "worker", Change: DiffChangeRemoved, FromReference: "registry.example.com/app:tag-3", FromDigest: "sha256:ccc"},
+		}))
+
+		Expect(diff.Dependencies).To(Equal([]DependencyDiff{
+			{Name: "postgresql", Change: DiffChangeAdded, ToVersion: "1.0.0", ToRepository: "oci://charts"},
+			{Name: "redis", Change: DiffChangeChanged, FromVersion: "17.0.0", ToVersion: "18.0.0", FromRepository: "oci://charts", ToRepository: "oci://charts"},
+		}))
+
+		Expect(diff.Values).To(Equal([]ValueDiff{
+			{Path: "ingress.host", Change: DiffChangeAdded, To: "example.com"},
+			{Path: "replicas", Change: DiffChangeChanged, From: 1, To: 2},
+		}))
+
+		Expect(diff.Templates).To(HaveLen(2))
+		Expect(diff.Templates[0].Name).To(Equal("templates/deployment.yaml"))
+		Expect(diff.Templates[0].Change).To(Equal(DiffChangeChanged))
+		Expect(diff.Templates[0].Diff).To(ContainSubstring("-replicas: 1"))
+		Expect(diff.Templates[0].Diff).To(ContainSubstring("+replicas: 2"))
+		Expect(diff.Templates[1].Name).To(Equal("templates/old.yaml"))
+		Expect(diff.Templates[1].Change).To(Equal(DiffChangeRemoved))
+
+		buf := bytes.NewBuffer(nil)
+		Expect(diff.WriteText(buf)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring("~ frontend: registry.example.com/app:tag-2 -> registry.example.com/app:tag-4 (digest unchanged: sha256:bbb)"))
+	})
+
+	It("should report no changes for the same bundle", func(ctx context.Context) {
+		ch := newChart("1.0.0", map[string]interface{}{"werf": map[string]interface{}{"image": map[string]interface{}{"app": "registry.example.com/app:tag-1"}}}, nil, map[string]string{"templates/a.yaml": "a\n"})
+
+		diff, err := DiffCharts(ctx, ch, ch, DiffChartsOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.HasChanges()).To(BeFalse())
+	})
+})
@@ -0,0 +1,62 @@
+package bundles
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// ImageReference is an image reference of the bundle values: REPO:TAG or REPO:TAG@DIGEST for the pinned image.
+// Tag is kept in the pinned reference, because bundle archive stores images by tag.
+type ImageReference struct {
+	Repo   string
+	Tag    string
+	Digest string
+}
+
+func ParseImageReference(ref string) (*ImageReference, error) {
+	if _, err := name.ParseReference(ref, name.WeakValidation); err != nil {
+		return nil, fmt.Errorf("invalid image reference %q: %w", ref, err)
+	}
+	return splitImageReference(ref), nil
+}
+
+func splitImageReference(ref string) *ImageReference {
+	res := &ImageReference{Repo: ref}
+
+	if i := strings.Index(res.Repo, "@"); i >= 0 {
+		res.Repo, res.Digest = res.Repo[:i], res.Repo[i+1:]
+	}
+
+	// Colon before the last slash belongs to the registry address with port (registry:5000/repo).
+	if i := strings.LastIndex(res.Repo, ":"); i > strings.LastIndex(res.Repo, "/") {
+		res.Repo, res.Tag = res.Repo[:i], res.Repo[i+1:]
+	}
+
+	return res
+}
+
+// getImageReferenceTag returns tag of the image reference, which is the key of the image in the bundle archive.
+func getImageReferenceTag(ref string) string {
+	return splitImageReference(ref).Tag
+}
+
+func (ref *ImageReference) IsPinned() bool {
+	return ref.Digest != ""
+}
+
+// TaggedName returns REPO:TAG reference without digest.
+func (ref *ImageReference) TaggedName() string {
+	if ref.Tag == "" {
+		return ref.Repo
+	}
+	return fmt.Sprintf("%s:%s", ref.Repo, ref.Tag)
+}
+
+func (ref *ImageReference) String() string {
+	if ref.Digest == "" {
+		return ref.TaggedName()
+	}
+	return fmt.Sprintf("%s@%s", ref.TaggedName(), ref.Digest)
+}
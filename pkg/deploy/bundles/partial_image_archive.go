@@ -0,0 +1,261 @@
+package bundles
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/werf/logboek"
+)
+
+const (
+	partialImageManifestFileName = "manifest.json"
+	partialImageBlobsDir         = "blobs"
+)
+
+func imageArchiveFileName(imageTag string) string {
+	return fmt.Sprintf("images/%s.tar.gz", imageTag)
+}
+
+// Partial image archive contains image manifest, image config and only those layers which are missing at the destination.
+func partialImageArchiveFileName(imageTag string) string {
+	return fmt.Sprintf("partial-images/%s.tar.gz", imageTag)
+}
+
+func partialImageBlobFileName(digest v1.Hash) string {
+	return fmt.Sprintf("%s/%s/%s", partialImageBlobsDir, digest.Algorithm, digest.Hex)
+}
+
+type PartialImageArchiveStats struct {
+	IncludedLayers, SkippedLayers int
+	IncludedBytes, SkippedBytes   int64
+}
+
+// WritePartialImageArchive dumps image into the partial image archive, image layers existing at the delta base are skipped.
+func WritePartialImageArchive(ctx context.Context, w io.Writer, img v1.Image, base DeltaBase) (*PartialImageArchiveStats, error) {
+	twriter := tar.NewWriter(w)
+	stats := &PartialImageArchiveStats{}
+
+	manifest, err := img.RawManifest()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image manifest: %w", err)
+	}
+	if err := writePartialImageArchiveEntry(twriter, partialImageManifestFileName, int64(len(manifest)), bytes.NewReader(manifest)); err != nil {
+		return nil, err
+	}
+
+	configName, err := img.ConfigName()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image config digest: %w", err)
+	}
+	config, err := img.RawConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image config: %w", err)
+	}
+	if err := writePartialImageArchiveEntry(twriter, partialImageBlobFileName(configName), int64(len(config)), bytes.NewReader(config)); err != nil {
+		return nil, err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image layers: %w", err)
+	}
+
+	written := make(map[v1.Hash]bool)
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get layer digest: %w", err)
+		}
+		size, err := layer.Size()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get layer %s size: %w", digest, err)
+		}
+
+		if written[digest] {
+			continue
+		}
+
+		exists, err := base.HasBlob(ctx, digest)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check layer %s at %s: %w", digest, base.String(), err)
+		}
+
+		if exists {
+			logboek.Context(ctx).Debug().LogF("Skip layer %s: exists at %s\n", digest, base.String())
+			stats.SkippedLayers++
+			stats.SkippedBytes += size
+			continue
+		}
+
+		if err := func() error {
+			rc, err := layer.Compressed()
+			if err != nil {
+				return fmt.Errorf("unable to read layer %s: %w", digest, err)
+			}
+			defer rc.Close()
+
+			return writePartialImageArchiveEntry(twriter, partialImageBlobFileName(digest), size, rc)
+		}(); err != nil {
+			return nil, err
+		}
+
+		written[digest] = true
+		stats.IncludedLayers++
+		stats.IncludedBytes += size
+	}
+
+	if err := twriter.Close(); err != nil {
+		return nil, fmt.Errorf("unable to close partial image archive: %w", err)
+	}
+
+	return stats, nil
+}
+
+func writePartialImageArchiveEntry(twriter *tar.Writer, name string, size int64, r io.Reader) error {
+	now := time.Now()
+	header := &tar.Header{
+		Name:       name,
+		Typeflag:   tar.TypeReg,
+		Mode:       0o644,
+		Size:       size,
+		ModTime:    now,
+		AccessTime: now,
+		ChangeTime: now,
+	}
+
+	if err := twriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("unable to write %q header: %w", name, err)
+	}
+
+	if _, err := io.Copy(twriter, r); err != nil {
+		return fmt.Errorf("unable to write %q data: %w", name, err)
+	}
+
+	return nil
+}
+
+// ReadPartialImageArchive loads image from the partial image archive.
+// Layers which are not included into the archive could not be read, but their digests, sizes and media types are known,
+// which is enough to push such image into the registry which already contains these layers.
+func ReadPartialImageArchive(r io.Reader) (v1.Image, error) {
+	core := &partialArchiveImageCore{blobs: make(map[v1.Hash][]byte)}
+
+	treader := tar.NewReader(r)
+	for {
+		header, err := treader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading partial image archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(treader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %q from partial image archive: %w", header.Name, err)
+		}
+
+		if header.Name == partialImageManifestFileName {
+			core.manifest = data
+			continue
+		}
+
+		digest, err := v1.NewHash(partialImageBlobDigest(header.Name))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected file %q in partial image archive: %w", header.Name, err)
+		}
+		core.blobs[digest] = data
+	}
+
+	if core.manifest == nil {
+		return nil, fmt.Errorf("no %s found in partial image archive", partialImageManifestFileName)
+	}
+
+	m, err := v1.ParseManifest(bytes.NewReader(core.manifest))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse partial image manifest: %w", err)
+	}
+	core.parsedManifest = m
+
+	if _, hasConfig := core.blobs[m.Config.Digest]; !hasConfig {
+		return nil, fmt.Errorf("no image config %s found in partial image archive", m.Config.Digest)
+	}
+
+	return partial.CompressedToImage(core)
+}
+
+func partialImageBlobDigest(fileName string) string {
+	return strings.Replace(strings.TrimPrefix(fileName, partialImageBlobsDir+"/"), "/", ":", 1)
+}
+
+type partialArchiveImageCore struct {
+	manifest       []byte
+	parsedManifest *v1.Manifest
+	blobs          map[v1.Hash][]byte
+}
+
+func (core *partialArchiveImageCore) RawConfigFile() ([]byte, error) {
+	return core.blobs[core.parsedManifest.Config.Digest], nil
+}
+
+func (core *partialArchiveImageCore) MediaType() (types.MediaType, error) {
+	if core.parsedManifest.MediaType != "" {
+		return core.parsedManifest.MediaType, nil
+	}
+	return types.OCIManifestSchema1, nil
+}
+
+func (core *partialArchiveImageCore) RawManifest() ([]byte, error) {
+	return core.manifest, nil
+}
+
+func (core *partialArchiveImageCore) LayerByDigest(digest v1.Hash) (partial.CompressedLayer, error) {
+	if digest == core.parsedManifest.Config.Digest {
+		return &partialArchiveLayer{desc: core.parsedManifest.Config, data: core.blobs[digest]}, nil
+	}
+
+	for _, desc := range core.parsedManifest.Layers {
+		if desc.Digest == digest {
+			return &partialArchiveLayer{desc: desc, data: core.blobs[digest]}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("layer %s not found in the image manifest", digest)
+}
+
+type partialArchiveLayer struct {
+	desc v1.Descriptor
+	data []byte
+}
+
+func (layer *partialArchiveLayer) Digest() (v1.Hash, error) {
+	return layer.desc.Digest, nil
+}
+
+func (layer *partialArchiveLayer) Compressed() (io.ReadCloser, error) {
+	if layer.data == nil {
+		return nil, fmt.Errorf("layer %s is not included into the partial image archive and should already exist at the destination", layer.desc.Digest)
+	}
+	return io.NopCloser(bytes.NewReader(layer.data)), nil
+}
+
+func (layer *partialArchiveLayer) Size() (int64, error) {
+	return layer.desc.Size, nil
+}
+
+func (layer *partialArchiveLayer) MediaType() (types.MediaType, error) {
+	return layer.desc.MediaType, nil
+}
@@ -0,0 +1,188 @@
+package bundles
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/docker_registry"
+	"github.com/werf/werf/v2/pkg/slug"
+)
+
+type PinImageDigestsOptions struct {
+	// ThirdPartyImages are image references used by the rendered bundle manifests, see GetManifestsImages.
+	ThirdPartyImages []string
+	// CopyThirdPartyImagesToRepo is a repo to copy third-party images into before pinning,
+	// third-party images are pinned in their own repos if not specified.
+	CopyThirdPartyImagesToRepo string
+}
+
+// PinImageDigests rewrites .Values.werf.image references and values equal to the third-party image references
+// to the immutable REPO:TAG@DIGEST references, so the bundle deploys the same images even if the tags are moved later.
+func PinImageDigests(ctx context.Context, registryClient docker_registry.Interface, values map[string]interface{}, opts PinImageDigestsOptions) error {
+	werfImages := make(map[string]bool)
+
+	if werfVals, ok := values["werf"].(map[string]interface{}); ok {
+		if imageVals, ok := werfVals["image"].(map[string]interface{}); ok {
+			for _, imageName := range sortedKeys(imageVals) {
+				imageRef, ok := imageVals[imageName].(string)
+				if !ok {
+					return fmt.Errorf("unexpected value .Values.werf.image.%s=%v", imageName, imageVals[imageName])
+				}
+				werfImages[imageRef] = true
+
+				ref, err := ParseImageReference(imageRef)
+				if err != nil {
+					return err
+				}
+
+				if !ref.IsPinned() {
+					if ref.Digest, err = GetImageDigest(ctx, registryClient, ref.TaggedName()); err != nil {
+						return err
+					}
+				}
+
+				logboek.Context(ctx).Default().LogFDetails("Image %s: %s\n", imageName, ref.String())
+				imageVals[imageName] = ref.String()
+			}
+		}
+	}
+
+	pinnedThirdPartyImages := make(map[string]string)
+
+	for _, imageRef := range opts.ThirdPartyImages {
+		if _, ok := pinnedThirdPartyImages[imageRef]; ok || werfImages[imageRef] {
+			continue
+		}
+
+		ref, err := ParseImageReference(imageRef)
+		if err != nil {
+			return err
+		}
+
+		if ref.IsPinned() {
+			continue
+		}
+
+		if opts.CopyThirdPartyImagesToRepo != "" {
+			dest := &ImageReference{Repo: opts.CopyThirdPartyImagesToRepo, Tag: GetThirdPartyImageTag(imageRef)}
+
+			logboek.Context(ctx).Default().LogFDetails("Copy third-party image %s into %s\n", imageRef, dest.TaggedName())
+
+			if err := registryClient.CopyImage(ctx, imageRef, dest.TaggedName(), docker_registry.CopyImageOptions{}); err != nil {
+				return fmt.Errorf("error copying image %s into %s: %w", imageRef, dest.TaggedName(), err)
+			}
+
+			ref = dest
+		}
+
+		if ref.Digest, err = GetImageDigest(ctx, registryClient, ref.TaggedName()); err != nil {
+			return err
+		}
+
+		logboek.Context(ctx).Default().LogFDetails("Third-party image %s: %s\n", imageRef, ref.String())
+		pinnedThirdPartyImages[imageRef] = ref.String()
+	}
+
+	replaced := make(map[string]bool)
+	replaceValuesStrings(values, pinnedThirdPartyImages, replaced)
+
+	for _, imageRef := range sortedKeys(pinnedThirdPartyImages) {
+		if !replaced[imageRef] {
+			logboek.Context(ctx).Warn().LogF("WARNING: Image %s is not set in the bundle values and cannot be pinned, specify it in the values.yaml to pin it\n", imageRef)
+		}
+	}
+
+	return nil
+}
+
+// GetImageDigest returns digest of the image manifest or of the image index for the multi-platform image.
+func GetImageDigest(ctx context.Context, registryClient docker_registry.Interface, imageRef string) (string, error) {
+	info, err := registryClient.GetRepoImage(ctx, imageRef)
+	if err != nil {
+		return "", fmt.Errorf("unable to get image %s: %w", imageRef, err)
+	}
+
+	digest := info.GetDigest()
+	if digest == "" {
+		return "", fmt.Errorf("unable to get image %s digest", imageRef)
+	}
+
+	return digest, nil
+}
+
+// GetThirdPartyImageTag returns tag of the third-party image copied into the bundle repo.
+func GetThirdPartyImageTag(imageRef string) string {
+	return slug.DockerTag(strings.NewReplacer("/", "-", ":", "-", "@", "-").Replace(imageRef))
+}
+
+// GetManifestsImages returns sorted unique images of the containers, init containers and ephemeral containers of the manifests.
+func GetManifestsImages(manifests []map[string]interface{}) []string {
+	images := make(map[string]bool)
+	for _, manifest := range manifests {
+		collectContainersImages(manifest, images)
+	}
+
+	res := make([]string, 0, len(images))
+	for imageRef := range images {
+		res = append(res, imageRef)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func collectContainersImages(obj interface{}, images map[string]bool) {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			switch key {
+			case "containers", "initContainers", "ephemeralContainers":
+				if containers, ok := value.([]interface{}); ok {
+					for _, container := range containers {
+						if c, ok := container.(map[string]interface{}); ok {
+							if imageRef, ok := c["image"].(string); ok && imageRef != "" {
+								images[imageRef] = true
+							}
+						}
+					}
+				}
+			default:
+				collectContainersImages(value, images)
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			collectContainersImages(value, images)
+		}
+	}
+}
+
+func replaceValuesStrings(obj interface{}, replacements map[string]string, replaced map[string]bool) {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok {
+				if newValue, ok := replacements[s]; ok {
+					v[key] = newValue
+					replaced[s] = true
+				}
+				continue
+			}
+			replaceValuesStrings(value, replacements, replaced)
+		}
+	case []interface{}:
+		for i, value := range v {
+			if s, ok := value.(string); ok {
+				if newValue, ok := replacements[s]; ok {
+					v[i] = newValue
+					replaced[s] = true
+				}
+				continue
+			}
+			replaceValuesStrings(value, replacements, replaced)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package bundles
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/werf/v2/pkg/logging"
+)
+
+var _ = Describe("Bundle image digests pinning", func() {
+	var ctx context.Context
+
+	BeforeEach(func(ctx0 context.Context) {
+		ctx = logging.WithLogger(ctx0)
+	})
+
+	DescribeTable("should parse image reference",
+		func(ref string, expected ImageReference) {
+			parsed, err := ParseImageReference(ref)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*parsed).To(Equal(expected))
+			Expect(parsed.String()).To(Equal(ref))
+		},
+		Entry("repo without tag", "nginx", ImageReference{Repo: "nginx"}),
+		Entry("repo with tag", "registry.example.com/group/testproject:tag-1", ImageReference{Repo: "registry.example.com/group/testproject", Tag: "tag-1"}),
+		Entry("registry with port without tag", "registry.example.com:5000/testproject", ImageReference{Repo: "registry.example.com:5000/testproject"}),
+		Entry("pinned repo with tag", "registry.example.com:5000/testproject:tag-1@sha256:"+fmt.Sprintf("%x", sha256.Sum256(nil)), ImageReference{
+			Repo:   "registry.example.com:5000/testproject",
+			Tag:    "tag-1",
+			Digest: fmt.Sprintf("sha256:%x", sha256.Sum256(nil)),
+		}),
+	)
+
+	It("should find images of the rendered manifests", func() {
+		manifests := []map[string]interface{}{
+			{
+				"kind": "Deployment",
+				"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+					"initContainers": []interface{}{map[string]interface{}{"name": "init", "image": "busybox:1.36"}},
+					"containers":     []interface{}{map[string]interface{}{"name": "app", "image": "nginx:1.25"}},
+				}}},
+			},
+			{
+				"kind": "CronJob",
+				"spec": map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"name": "job", "image": "nginx:1.25"}},
+				}}}}},
+			},
+		}
+
+		Expect(GetManifestsImages(manifests)).To(Equal([]string{"busybox:1.36", "nginx:1.25"}))
+	})
+
+	It("should pin werf images and third-party images found in values", func() {
+		registryClient := NewDockerRegistryStub()
+
+		img, err := random.Image(16, 1)
+		Expect(err).NotTo(HaveOccurred())
+		imgDigest, err := img.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		registryClient.V1ImagesByReference["registry.example.com/group/testproject:tag-1"] = img
+		registryClient.ImagesByReference["nginx:1.25"] = []byte("nginx-bytes")
+		registryClient.ImagesByReference["busybox:1.36"] = []byte("busybox-bytes")
+
+		values := map[string]interface{}{
+			"werf": map[string]interface{}{
+				"image": map[string]interface{}{"image-1": "registry.example.com/group/testproject:tag-1"},
+				"repo":  "registry.example.com/group/testproject",
+			},
+			"proxy": map[string]interface{}{"image": "nginx:1.25"},
+			"jobs":  []interface{}{"nginx:1.25"},
+		}
+
+		Expect(PinImageDigests(ctx, registryClient, values, PinImageDigestsOptions{
+			ThirdPartyImages:           []string{"busybox:1.36", "nginx:1.25", "registry.example.com/group/testproject:tag-1"},
+			CopyThirdPartyImagesToRepo: "registry.example.com/group/testproject",
+		})).To(Succeed())
+
+		pinnedNginx := fmt.Sprintf("registry.example.com/group/testproject:nginx-1.25@sha256:%x", sha256.Sum256([]byte("nginx-bytes")))
+		Expect(values).To(Equal(map[string]interface{}{
+			"werf": map[string]interface{}{
+				"image": map[string]interface{}{"image-1": "registry.example.com/group/testproject:tag-1@" + imgDigest.String()},
+				"repo":  "registry.example.com/group/testproject",
+			},
+			"proxy": map[string]interface{}{"image": pinnedNginx},
+			"jobs":  []interface{}{pinnedNginx},
+		}))
+		Expect(registryClient.ImagesByReference["registry.example.com/group/testproject:nginx-1.25"]).To(Equal([]byte("nginx-bytes")))
+	})
+
+	It("should copy pinned images of the remote bundle keeping digests", func() {
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("image-1-bytes")))
+		pinnedRef := "registry.example.com/group/testproject:tag-1@" + digest
+
+		ch := &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: "v2", Name: "testproject", Version: "1.2.3"},
+			Values: map[string]interface{}{
+				"werf": map[string]interface{}{
+					"image": map[string]interface{}{"image-1": pinnedRef},
+					"repo":  "registry.example.com/group/testproject",
+				},
+			},
+			Raw: []*chart.File{{Name: "values.yaml", Data: []byte("werf:\n  image:\n    image-1: " + pinnedRef + "\n  repo: registry.example.com/group/testproject\n")}},
+		}
+
+		bundlesRegistryClient := NewBundlesRegistryClientStub()
+		registryClient := NewDockerRegistryStub()
+
+		fromAddr, err := ParseAddr("registry.example.com/group/testproject:1.2.3")
+		Expect(err).NotTo(HaveOccurred())
+		from := NewRemoteBundle(fromAddr.RegistryAddress, bundlesRegistryClient, registryClient)
+		bundlesRegistryClient.StubCharts[fromAddr.RegistryAddress.FullName()] = ch
+		registryClient.ImagesByReference[pinnedRef] = []byte("image-1-bytes")
+
+		toAddr, err := ParseAddr("registry2.example.com/group2/testproject2:4.5.6")
+		Expect(err).NotTo(HaveOccurred())
+		to := NewRemoteBundle(toAddr.RegistryAddress, bundlesRegistryClient, registryClient)
+
+		Expect(from.CopyTo(ctx, to, copyToOptions{})).To(Succeed())
+
+		VerifyChart(ctx, bundlesRegistryClient.StubCharts[toAddr.RegistryAddress.FullName()], VerifyChartOptions{
+			ExpectedName:    "testproject2",
+			ExpectedVersion: "4.5.6",
+			ExpectedRepo:    "registry2.example.com/group2/testproject2",
+			ExpectedImages:  map[string]string{"image-1": "registry2.example.com/group2/testproject2:tag-1@" + digest},
+		})
+		Expect(registryClient.ImagesByReference["registry2.example.com/group2/testproject2:tag-1"]).To(Equal([]byte("image-1-bytes")))
+	})
+})
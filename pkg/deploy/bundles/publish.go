@@ -2,6 +2,7 @@ package bundles
 
 import (
 	"context"
+	"crypto"
 	"fmt"
 	"path/filepath"
 
@@ -15,6 +16,10 @@ type PublishOptions struct {
 	HelmCompatibleChart bool
 	RenameChart         string
 	HelmOptions         helmopts.HelmOptions
+
+	// SignKey is used to sign the bundle when specified, GetImageID is required to sign bundle images.
+	SignKey    crypto.Signer
+	GetImageID ImageIDGetter
 }
 
 func Publish(ctx context.Context, bundleDir, bundleRef string, bundlesRegistryClient *registry.Client, opts PublishOptions) error {
@@ -38,6 +43,14 @@ func Publish(ctx context.Context, bundleDir, bundleRef string, bundlesRegistryCl
 			ch.Metadata.Name = *nameOverwrite
 		}
 
+		if opts.SignKey != nil {
+			if err := logboek.Context(ctx).Default().LogProcess("Signing bundle").DoError(func() error {
+				return SignChart(ctx, ch, opts.SignKey, opts.GetImageID)
+			}); err != nil {
+				return err
+			}
+		}
+
 		if err := bundlesRegistryClient.SaveChart(ctx, ch, r, opts.HelmOptions); err != nil {
 			return fmt.Errorf("unable to save bundle to the local chart helm cache: %w", err)
 		}
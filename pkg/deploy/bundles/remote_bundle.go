@@ -2,6 +2,7 @@ package bundles
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,7 +13,6 @@ import (
 	"github.com/werf/3p-helm/pkg/werf/helmopts"
 	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/logboek"
-	bundles_registry "github.com/werf/werf/v2/pkg/deploy/bundles/registry"
 	"github.com/werf/werf/v2/pkg/docker_registry"
 )
 
@@ -90,29 +90,66 @@ func (bundle *RemoteBundle) CopyFromArchive(ctx context.Context, fromArchive *Bu
 	}
 
 	if err := logboek.Context(ctx).LogProcess("Copy images from bundle archive").DoError(func() error {
+		relocatedImages := make(map[string]string)
+		for _, imageRef := range GetChartThirdPartyImages(ch) {
+			ref := splitImageReference(imageRef)
+			ref.Repo = bundle.RegistryAddress.Repo
+
+			logboek.Context(ctx).Default().LogFDetails("Third-party image: %s\n", ref.TaggedName())
+
+			if err := bundle.pushImageFromArchive(ctx, fromArchive, ref.Tag, ref.TaggedName()); err != nil {
+				return err
+			}
+
+			// Image manifest is rebuilt from the archive, so digest should be taken from the destination.
+			if ref.IsPinned() {
+				digest, err := GetImageDigest(ctx, bundle.RegistryClient, ref.TaggedName())
+				if err != nil {
+					return err
+				}
+				ref.Digest = digest
+			}
+
+			relocatedImages[imageRef] = ref.String()
+		}
+		replaceValuesStrings(ch.Values, relocatedImages, make(map[string]bool))
+
 		if werfVals, ok := ch.Values["werf"].(map[string]interface{}); ok {
 			if imageVals, ok := werfVals["image"].(map[string]interface{}); ok {
 				newImageVals := make(map[string]interface{})
 
 				for imageName, v := range imageVals {
 					if imageRef, ok := v.(string); ok {
-						ref, err := bundles_registry.ParseReference(imageRef)
+						ref, err := ParseImageReference(imageRef)
 						if err != nil {
 							return err
 						}
 						ref.Repo = bundle.RegistryAddress.Repo
 
-						if imageRef != ref.FullName() {
-							logboek.Context(ctx).Default().LogFDetails("Image: %s\n", ref.FullName())
+						if imageRef != ref.String() {
+							logboek.Context(ctx).Default().LogFDetails("Image: %s\n", ref.TaggedName())
 
-							imageArchiveOpener := fromArchive.GetImageArchiveOpener(ref.Tag)
+							if err := bundle.pushImageFromArchive(ctx, fromArchive, ref.Tag, ref.TaggedName()); err != nil {
+								return err
+							}
 
-							if err := bundle.RegistryClient.PushImageArchive(ctx, imageArchiveOpener, ref.FullName()); err != nil {
-								return fmt.Errorf("error copying image from bundle archive %q into %q: %w", fromArchive.Reader.String(), ref.FullName(), err)
+							// Image manifest is rebuilt from the archive, so digests should be taken from the destination.
+							if _, hasDigestValue := getImageDigestValue(werfVals, imageName); ref.IsPinned() || hasDigestValue {
+								digest, err := GetImageDigest(ctx, bundle.RegistryClient, ref.TaggedName())
+								if err != nil {
+									return err
+								}
+
+								if ref.IsPinned() {
+									ref.Digest = digest
+								}
+								if hasDigestValue {
+									setImageDigestValues(werfVals, imageName, ref.Repo, digest)
+								}
 							}
 						}
 
-						newImageVals[imageName] = ref.FullName()
+						newImageVals[imageName] = ref.String()
 					} else {
 						return fmt.Errorf("unexpected value .Values.werf.image.%s=%v", imageName, v)
 					}
@@ -150,6 +187,25 @@ func (bundle *RemoteBundle) CopyFromArchive(ctx context.Context, fromArchive *Bu
 	return nil
 }
 
+// pushImageFromArchive pushes the image stored in the bundle archive either as a full or as a partial image archive.
+func (bundle *RemoteBundle) pushImageFromArchive(ctx context.Context, fromArchive *BundleArchive, imageTag, destRef string) error {
+	img, err := fromArchive.ReadPartialImage(imageTag)
+	if err == nil {
+		if err := bundle.RegistryClient.PushImageFrom(ctx, img, destRef); err != nil {
+			return fmt.Errorf("error copying partial image from bundle archive %q into %q: %w", fromArchive.Reader.String(), destRef, err)
+		}
+		return nil
+	} else if !errors.Is(err, ErrImageArchiveNotFound) {
+		return err
+	}
+
+	if err := bundle.RegistryClient.PushImageArchive(ctx, fromArchive.GetImageArchiveOpener(imageTag), destRef); err != nil {
+		return fmt.Errorf("error copying image from bundle archive %q into %q: %w", fromArchive.Reader.String(), destRef, err)
+	}
+
+	return nil
+}
+
 func (bundle *RemoteBundle) CopyFromRemote(ctx context.Context, fromRemote *RemoteBundle, opts copyToOptions) error {
 	ch, err := fromRemote.ReadChart(ctx, opts.HelmOptions)
 	if err != nil {
@@ -162,13 +218,35 @@ func (bundle *RemoteBundle) CopyFromRemote(ctx context.Context, fromRemote *Remo
 	}
 
 	if err := logboek.Context(ctx).LogProcess("Copy images from remote bundle").DoError(func() error {
+		relocatedImages := make(map[string]string)
+		for _, imageRef := range GetChartThirdPartyImages(ch) {
+			ref := splitImageReference(imageRef)
+			ref.Repo = bundle.RegistryAddress.Repo
+			if imageRef == ref.String() {
+				continue
+			}
+
+			source := splitImageReference(imageRef).TaggedName()
+
+			logboek.Context(ctx).Default().LogFDetails("Source: %s\n", source)
+			logboek.Context(ctx).Default().LogFDetails("Destination: %s\n", ref.TaggedName())
+
+			// Pinned digest is kept as is, because the image manifest is copied unchanged.
+			if err := fromRemote.RegistryClient.CopyImage(ctx, source, ref.TaggedName(), docker_registry.CopyImageOptions{}); err != nil {
+				return fmt.Errorf("error copying image %s into %s: %w", source, ref.TaggedName(), err)
+			}
+
+			relocatedImages[imageRef] = ref.String()
+		}
+		replaceValuesStrings(ch.Values, relocatedImages, make(map[string]bool))
+
 		if werfVals, ok := ch.Values["werf"].(map[string]interface{}); ok {
 			if imageVals, ok := werfVals["image"].(map[string]interface{}); ok {
 				newImageVals := make(map[string]interface{})
 
 				for imageName, v := range imageVals {
 					if image, ok := v.(string); ok {
-						ref, err := bundles_registry.ParseReference(image)
+						ref, err := ParseImageReference(image)
 						if err != nil {
 							return err
 						}
@@ -176,16 +254,21 @@ func (bundle *RemoteBundle) CopyFromRemote(ctx context.Context, fromRemote *Remo
 						ref.Repo = bundle.RegistryAddress.Repo
 
 						// TODO: copy images in parallel
-						if image != ref.FullName() {
+						// Pinned digest is kept as is, because the image manifest is copied unchanged.
+						if image != ref.String() {
 							logboek.Context(ctx).Default().LogFDetails("Source: %s\n", image)
-							logboek.Context(ctx).Default().LogFDetails("Destination: %s\n", ref.FullName())
+							logboek.Context(ctx).Default().LogFDetails("Destination: %s\n", ref.TaggedName())
+
+							if err := fromRemote.RegistryClient.CopyImage(ctx, image, ref.TaggedName(), docker_registry.CopyImageOptions{}); err != nil {
+								return fmt.Errorf("error copying image %s into %s: %w", image, ref.TaggedName(), err)
+							}
 
-							if err := fromRemote.RegistryClient.CopyImage(ctx, image, ref.FullName(), docker_registry.CopyImageOptions{}); err != nil {
-								return fmt.Errorf("error copying image %s into %s: %w", image, ref.FullName(), err)
+							if digest, ok := getImageDigestValue(werfVals, imageName); ok {
+								setImageDigestValues(werfVals, imageName, ref.Repo, digest)
 							}
 						}
 
-						newImageVals[imageName] = ref.FullName()
+						newImageVals[imageName] = ref.String()
 					} else {
 						return fmt.Errorf("unexpected value .Values.werf.image.%s=%v", imageName, v)
 					}
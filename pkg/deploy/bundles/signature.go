@@ -0,0 +1,401 @@
+package bundles
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/chartutil"
+	"github.com/werf/3p-helm/pkg/werf/helmopts"
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/docker_registry"
+)
+
+// BundleSignatureFileName is a chart file containing the bundle signature.
+const BundleSignatureFileName = "bundle_signature.json"
+
+const werfHelpersTemplateName = "templates/_werf_helpers.tpl"
+
+var ErrBundleSignatureNotFound = errors.New("bundle signature not found")
+
+// BundleSignaturePayload is the signed content of the bundle.
+//
+// ChartDigest does not depend on the registry location of the bundle (chart name, version, .Values.werf.repo,
+// .Values.werf.image and references of the third-party images copied into the bundle repo are excluded), and Images
+// contains image ids (config digests) by image name, so the signature remains valid after the bundle is copied
+// into another registry or into the archive.
+type BundleSignaturePayload struct {
+	ChartDigest string            `json:"chartDigest"`
+	Images      map[string]string `json:"images"`
+}
+
+type BundleSignature struct {
+	Payload   BundleSignaturePayload `json:"payload"`
+	Signature []byte                 `json:"signature"`
+}
+
+// ImageIDGetter returns image id (config digest) of the image referenced in the bundle values.
+// Manifest digests are not signed, because the manifest is rebuilt when the image is pushed from the bundle archive.
+type ImageIDGetter func(ctx context.Context, imageRef string) (string, error)
+
+// GetRemoteImageIDGetter returns ImageIDGetter which queries the container registry.
+func GetRemoteImageIDGetter(registryClient docker_registry.Interface) ImageIDGetter {
+	return func(ctx context.Context, imageRef string) (string, error) {
+		info, err := registryClient.GetRepoImage(ctx, imageRef)
+		if err != nil {
+			return "", err
+		}
+		return info.ID, nil
+	}
+}
+
+// GetArchiveImageIDGetter returns ImageIDGetter which reads images from the bundle archive and does not require network access.
+func GetArchiveImageIDGetter(archive *BundleArchive) ImageIDGetter {
+	return func(ctx context.Context, imageRef string) (string, error) {
+		tag := getImageReferenceTag(imageRef)
+
+		img, err := archive.ReadImage(tag)
+		if err != nil {
+			return "", err
+		}
+
+		id, err := img.ConfigName()
+		if err != nil {
+			return "", fmt.Errorf("unable to get image config digest: %w", err)
+		}
+
+		return id.String(), nil
+	}
+}
+
+func NewBundleSignaturePayload(ctx context.Context, ch *chart.Chart, getImageID ImageIDGetter) (*BundleSignaturePayload, error) {
+	chartDigest, err := GetChartContentDigest(ch)
+	if err != nil {
+		return nil, fmt.Errorf("unable to calculate chart digest: %w", err)
+	}
+
+	images, err := GetChartImages(ch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ref := range GetChartThirdPartyImages(ch) {
+		images[getThirdPartyImageSignatureName(ref)] = ref
+	}
+
+	payload := &BundleSignaturePayload{ChartDigest: chartDigest, Images: make(map[string]string)}
+	for name, ref := range images {
+		id, err := getImageID(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get image %q id: %w", ref, err)
+		}
+		payload.Images[name] = id
+	}
+
+	return payload, nil
+}
+
+// SignChart signs bundle chart and saves the signature into the chart files replacing the existing one.
+func SignChart(ctx context.Context, ch *chart.Chart, key crypto.Signer, getImageID ImageIDGetter) error {
+	payload, err := NewBundleSignaturePayload(ctx, ch, getImageID)
+	if err != nil {
+		return err
+	}
+
+	payloadData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal bundle signature payload: %w", err)
+	}
+
+	signature, err := signData(key, payloadData)
+	if err != nil {
+		return fmt.Errorf("unable to sign bundle: %w", err)
+	}
+
+	data, err := json.MarshalIndent(BundleSignature{Payload: *payload, Signature: signature}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal bundle signature: %w", err)
+	}
+
+	removeChartFile(ch, BundleSignatureFileName)
+	ch.Files = append(ch.Files, &chart.File{Name: BundleSignatureFileName, Data: append(data, '\n')})
+
+	logboek.Context(ctx).Default().LogFDetails("Chart digest: %s\n", payload.ChartDigest)
+	for _, name := range sortedKeys(payload.Images) {
+		logboek.Context(ctx).Default().LogFDetails("Image %s: %s\n", name, payload.Images[name])
+	}
+
+	return nil
+}
+
+// VerifyChartSignature checks the bundle chart signature against the actual chart content and the actual images.
+// Images referenced by .Values.werf.image_digest_ref are also checked against the signed image ids.
+func VerifyChartSignature(ctx context.Context, ch *chart.Chart, key crypto.PublicKey, getImageID ImageIDGetter) error {
+	return verifyChartSignature(ctx, ch, key, getImageID, true)
+}
+
+func verifyChartSignature(ctx context.Context, ch *chart.Chart, key crypto.PublicKey, getImageID ImageIDGetter, verifyDigestRefs bool) error {
+	sig, err := GetChartSignature(ch)
+	if err != nil {
+		return err
+	}
+
+	payload, err := NewBundleSignaturePayload(ctx, ch, getImageID)
+	if err != nil {
+		return err
+	}
+
+	payloadData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal bundle signature payload: %w", err)
+	}
+
+	if err := verifyData(key, payloadData, sig.Signature); err != nil {
+		if reason := describePayloadMismatch(&sig.Payload, payload); reason != "" {
+			return fmt.Errorf("bundle signature verification failed: %s", reason)
+		}
+		return fmt.Errorf("bundle signature verification failed: %w", err)
+	}
+
+	if verifyDigestRefs {
+		if err := verifyImageDigestRefs(ctx, ch, &sig.Payload, getImageID); err != nil {
+			return fmt.Errorf("bundle signature verification failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// verifyImageDigestRefs checks that .Values.werf.digest and .Values.werf.image_digest_ref, which are excluded
+// from the chart digest, point to the signed images.
+func verifyImageDigestRefs(ctx context.Context, ch *chart.Chart, signed *BundleSignaturePayload, getImageID ImageIDGetter) error {
+	werfVals, ok := ch.Values["werf"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	digestRefVals, _ := werfVals["image_digest_ref"].(map[string]interface{})
+	digestVals, _ := werfVals["digest"].(map[string]interface{})
+
+	for _, name := range sortedKeys(digestRefVals, digestVals) {
+		signedID, isSigned := signed.Images[name]
+		if !isSigned {
+			return fmt.Errorf("image %q is not signed", name)
+		}
+
+		digestRef, ok := digestRefVals[name].(string)
+		if !ok || digestRef == "" {
+			return fmt.Errorf("unexpected value .Values.werf.image_digest_ref.%s=%v", name, digestRefVals[name])
+		}
+
+		ref, err := ParseImageReference(digestRef)
+		if err != nil {
+			return err
+		}
+
+		if digest, _ := getImageDigestValue(werfVals, name); !ref.IsPinned() || ref.Digest != digest {
+			return fmt.Errorf("image %q digest ref %s does not match digest %q", name, digestRef, digest)
+		}
+
+		id, err := getImageID(ctx, digestRef)
+		if err != nil {
+			return fmt.Errorf("unable to get image %q id: %w", digestRef, err)
+		}
+		if id != signedID {
+			return fmt.Errorf("image %q digest ref %s id %s does not match signed image id %s", name, digestRef, id, signedID)
+		}
+	}
+
+	return nil
+}
+
+func GetChartSignature(ch *chart.Chart) (*BundleSignature, error) {
+	for _, f := range ch.Files {
+		if f.Name != BundleSignatureFileName {
+			continue
+		}
+
+		sig := &BundleSignature{}
+		if err := json.Unmarshal(f.Data, sig); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", BundleSignatureFileName, err)
+		}
+		return sig, nil
+	}
+
+	return nil, ErrBundleSignatureNotFound
+}
+
+// GetChartContentDigest calculates digest of the chart content, which does not depend on the bundle location.
+func GetChartContentDigest(ch *chart.Chart) (string, error) {
+	content, err := getChartDigestContent(ch, true)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data)), nil
+}
+
+type chartDigestContent struct {
+	Metadata     *chart.Metadata        `json:"metadata"`
+	Lock         *chart.Lock            `json:"lock,omitempty"`
+	Values       map[string]interface{} `json:"values"`
+	Schema       []byte                 `json:"schema,omitempty"`
+	Templates    map[string]string      `json:"templates"`
+	Files        map[string]string      `json:"files"`
+	Dependencies map[string]string      `json:"dependencies"`
+}
+
+func getChartDigestContent(ch *chart.Chart, isRoot bool) (*chartDigestContent, error) {
+	content := &chartDigestContent{
+		Lock:         ch.Lock,
+		Schema:       ch.Schema,
+		Templates:    make(map[string]string),
+		Files:        make(map[string]string),
+		Dependencies: make(map[string]string),
+	}
+
+	if ch.Metadata != nil {
+		metadata := *ch.Metadata
+		if isRoot {
+			metadata.Name = ""
+			metadata.Version = ""
+		}
+		content.Metadata = &metadata
+	}
+
+	// Use raw values.yaml, because loaded values could be disabled or changed by the chart load options.
+	for _, f := range ch.Raw {
+		if f.Name != chartutil.ValuesfileName {
+			continue
+		}
+
+		if err := yaml.Unmarshal(f.Data, &content.Values); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", chartutil.ValuesfileName, err)
+		}
+	}
+
+	if isRoot {
+		// Third-party images copied into the bundle repo are relocated along with the bundle, their image ids are signed instead.
+		thirdPartyImages := make(map[string]string)
+		for _, ref := range getValuesThirdPartyImages(content.Values) {
+			thirdPartyImages[ref] = getThirdPartyImageSignatureName(ref)
+		}
+		replaceValuesStrings(content.Values, thirdPartyImages, make(map[string]bool))
+
+		if werfVals, ok := content.Values["werf"].(map[string]interface{}); ok {
+			delete(werfVals, "image")
+			delete(werfVals, "repo")
+			// Image digests could change when the image is pushed from the bundle archive, image ids are signed instead
+			// and digest refs are verified against the signed image ids.
+			delete(werfVals, "digest")
+			delete(werfVals, "image_digest_ref")
+		}
+	}
+
+	for _, f := range ch.Templates {
+		// Skip empty helpers template injected by the chart loader depending on the chart type, helpers template
+		// with the content is signed as any other template.
+		if f.Name == werfHelpersTemplateName && len(f.Data) == 0 {
+			continue
+		}
+		content.Templates[f.Name] = fmt.Sprintf("sha256:%x", sha256.Sum256(f.Data))
+	}
+
+	for _, f := range ch.Files {
+		if isRoot && f.Name == BundleSignatureFileName {
+			continue
+		}
+		content.Files[f.Name] = fmt.Sprintf("sha256:%x", sha256.Sum256(f.Data))
+	}
+
+	for _, dep := range ch.Dependencies() {
+		depContent, err := getChartDigestContent(dep, false)
+		if err != nil {
+			return nil, fmt.Errorf("subchart %q: %w", dep.Name(), err)
+		}
+
+		data, err := json.Marshal(depContent)
+		if err != nil {
+			return nil, err
+		}
+
+		content.Dependencies[dep.Name()] = fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	}
+
+	return content, nil
+}
+
+// getThirdPartyImageSignatureName returns the name of the third-party image copied into the bundle repo in the signature payload.
+func getThirdPartyImageSignatureName(ref string) string {
+	return "third-party:" + getImageReferenceTag(ref)
+}
+
+func describePayloadMismatch(signed, actual *BundleSignaturePayload) string {
+	if signed.ChartDigest != actual.ChartDigest {
+		return fmt.Sprintf("chart digest %s does not match signed chart digest %s", actual.ChartDigest, signed.ChartDigest)
+	}
+
+	for _, name := range sortedKeys(signed.Images, actual.Images) {
+		signedID, isSigned := signed.Images[name]
+		actualID, isActual := actual.Images[name]
+
+		switch {
+		case !isSigned:
+			return fmt.Sprintf("image %q is not signed", name)
+		case !isActual:
+			return fmt.Sprintf("signed image %q not found in the bundle", name)
+		case signedID != actualID:
+			return fmt.Sprintf("image %q id %s does not match signed image id %s", name, actualID, signedID)
+		}
+	}
+
+	return ""
+}
+
+func removeChartFile(ch *chart.Chart, name string) {
+	var files []*chart.File
+	for _, f := range ch.Files {
+		if f.Name != name {
+			files = append(files, f)
+		}
+	}
+	ch.Files = files
+}
+
+// VerifyBundleSignature reads the bundle chart and verifies its signature.
+// Images of the bundle archive are read from the archive itself, so archive verification does not require network access.
+func VerifyBundleSignature(ctx context.Context, bundle BundleAccessor, key crypto.PublicKey, opts helmopts.HelmOptions) error {
+	ch, err := bundle.ReadChart(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("unable to read bundle chart: %w", err)
+	}
+
+	var getImageID ImageIDGetter
+	switch b := bundle.(type) {
+	case *RemoteBundle:
+		getImageID = GetRemoteImageIDGetter(b.RegistryClient)
+	case *BundleArchive:
+		getImageID = GetArchiveImageIDGetter(b)
+	default:
+		panic(fmt.Sprintf("unexpected bundle accessor %T", bundle))
+	}
+
+	// Digest refs of the bundle archive point to the source registry and are replaced when the archive is copied
+	// into the registry, so they are verified only for the remote bundle.
+	_, verifyDigestRefs := bundle.(*RemoteBundle)
+
+	return logboek.Context(ctx).LogProcess("Verifying bundle signature").DoError(func() error {
+		return verifyChartSignature(ctx, ch, key, getImageID, verifyDigestRefs)
+	})
+}
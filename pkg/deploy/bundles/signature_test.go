@@ -0,0 +1,236 @@
+package bundles
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/3p-helm/pkg/chart"
+	"github.com/werf/3p-helm/pkg/werf/helmopts"
+	"github.com/werf/werf/v2/pkg/logging"
+)
+
+var _ = Describe("Bundle signature", func() {
+	var ctx context.Context
+	var signKeyPath, verifyKeyPath string
+
+	BeforeEach(func(ctx0 context.Context) {
+		ctx = logging.WithLogger(ctx0)
+
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		privData, err := x509.MarshalPKCS8PrivateKey(priv)
+		Expect(err).NotTo(HaveOccurred())
+		pubData, err := x509.MarshalPKIXPublicKey(pub)
+		Expect(err).NotTo(HaveOccurred())
+
+		dir := GinkgoT().TempDir()
+		signKeyPath = filepath.Join(dir, "key.pem")
+		verifyKeyPath = filepath.Join(dir, "key.pub")
+		Expect(os.WriteFile(signKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privData}), 0o600)).To(Succeed())
+		Expect(os.WriteFile(verifyKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubData}), 0o644)).To(Succeed())
+	})
+
+	newChart := func(repo string) *chart.Chart {
+		ch := &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: "v2", Name: "testproject", Version: "1.2.3"},
+			Values: map[string]interface{}{
+				"werf": map[string]interface{}{
+					"image": map[string]interface{}{"image-1": repo + ":tag-1"},
+					"repo":  repo,
+				},
+				"replicas": 1,
+			},
+			Templates: []*chart.File{{Name: "templates/deployment.yaml", Data: []byte("kind: Deployment\n")}},
+		}
+		ch.Raw = []*chart.File{{Name: "values.yaml", Data: []byte("werf:\n  image:\n    image-1: " + repo + ":tag-1\n  repo: " + repo + "\nreplicas: 1\n")}}
+		return ch
+	}
+
+	imageIDs := map[string]string{
+		"registry.example.com/group/testproject:tag-1":   "sha256:aaa",
+		"registry2.example.com/group2/testproject:tag-1": "sha256:aaa",
+	}
+	getImageID := func(_ context.Context, ref string) (string, error) { return imageIDs[ref], nil }
+
+	It("should verify signed bundle after it has been copied into another repo", func() {
+		signKey, err := LoadSigningKey(signKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+		verifyKey, err := LoadVerificationKey(verifyKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		ch := newChart("registry.example.com/group/testproject")
+		Expect(SignChart(ctx, ch, signKey, getImageID)).To(Succeed())
+
+		sig, err := GetChartSignature(ch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sig.Payload.Images).To(Equal(map[string]string{"image-1": "sha256:aaa"}))
+
+		Expect(VerifyChartSignature(ctx, ch, verifyKey, getImageID)).To(Succeed())
+
+		copied := newChart("registry2.example.com/group2/testproject")
+		copied.Metadata.Name = "testproject2"
+		copied.Metadata.Version = "0.0.0-1"
+		copied.Files = ch.Files
+		Expect(VerifyChartSignature(ctx, copied, verifyKey, getImageID)).To(Succeed())
+	})
+
+	It("should verify signed bundle with third-party images after it has been copied into another repo", func() {
+		signKey, err := LoadSigningKey(signKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+		verifyKey, err := LoadVerificationKey(verifyKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		withThirdPartyImage := func(ch *chart.Chart, repo string) *chart.Chart {
+			ch.Values["redis"] = map[string]interface{}{"image": repo + ":docker.io-library-redis-7"}
+			ch.Raw[0].Data = append(ch.Raw[0].Data, []byte("redis:\n  image: "+repo+":docker.io-library-redis-7\n")...)
+			return ch
+		}
+
+		thirdPartyImageIDs := map[string]string{
+			"registry.example.com/group/testproject:docker.io-library-redis-7":   "sha256:ccc",
+			"registry2.example.com/group2/testproject:docker.io-library-redis-7": "sha256:ccc",
+		}
+		getImageID := func(ctx context.Context, ref string) (string, error) {
+			if id, ok := thirdPartyImageIDs[ref]; ok {
+				return id, nil
+			}
+			return getImageID(ctx, ref)
+		}
+
+		ch := withThirdPartyImage(newChart("registry.example.com/group/testproject"), "registry.example.com/group/testproject")
+		Expect(SignChart(ctx, ch, signKey, getImageID)).To(Succeed())
+
+		sig, err := GetChartSignature(ch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sig.Payload.Images).To(Equal(map[string]string{"image-1": "sha256:aaa", "third-party:docker.io-library-redis-7": "sha256:ccc"}))
+
+		copied := withThirdPartyImage(newChart("registry2.example.com/group2/testproject"), "registry2.example.com/group2/testproject")
+		copied.Files = ch.Files
+		Expect(VerifyChartSignature(ctx, copied, verifyKey, getImageID)).To(Succeed())
+
+		thirdPartyImageIDs["registry2.example.com/group2/testproject:docker.io-library-redis-7"] = "sha256:ddd"
+		Expect(VerifyChartSignature(ctx, copied, verifyKey, getImageID)).To(MatchError(ContainSubstring(`image "third-party:docker.io-library-redis-7" id sha256:ddd does not match signed image id sha256:ccc`)))
+	})
+
+	It("should refuse tampered bundle", func() {
+		signKey, err := LoadSigningKey(signKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+		verifyKey, err := LoadVerificationKey(verifyKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		ch := newChart("registry.example.com/group/testproject")
+		Expect(VerifyChartSignature(ctx, ch, verifyKey, getImageID)).To(MatchError(ErrBundleSignatureNotFound))
+
+		Expect(SignChart(ctx, ch, signKey, getImageID)).To(Succeed())
+
+		ch.Templates[0].Data = []byte("kind: DaemonSet\n")
+		Expect(VerifyChartSignature(ctx, ch, verifyKey, getImageID)).To(MatchError(ContainSubstring("chart digest")))
+
+		ch.Templates[0].Data = []byte("kind: Deployment\n")
+		Expect(VerifyChartSignature(ctx, ch, verifyKey, func(_ context.Context, _ string) (string, error) {
+			return "sha256:bbb", nil
+		})).To(MatchError(ContainSubstring(`image "image-1" id sha256:bbb does not match signed image id sha256:aaa`)))
+
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(VerifyChartSignature(ctx, ch, &otherKey.PublicKey, getImageID)).To(MatchError(ContainSubstring("invalid signature")))
+	})
+
+	It("should refuse bundle with tampered werf helpers template", func() {
+		signKey, err := LoadSigningKey(signKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+		verifyKey, err := LoadVerificationKey(verifyKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		ch := newChart("registry.example.com/group/testproject")
+		Expect(SignChart(ctx, ch, signKey, getImageID)).To(Succeed())
+
+		ch.Templates = append(ch.Templates, &chart.File{Name: werfHelpersTemplateName})
+		Expect(VerifyChartSignature(ctx, ch, verifyKey, getImageID)).To(Succeed())
+
+		ch.Templates[len(ch.Templates)-1].Data = []byte("{{- define \"injected\" }}kind: Pod{{ end }}\n")
+		Expect(VerifyChartSignature(ctx, ch, verifyKey, getImageID)).To(MatchError(ContainSubstring("chart digest")))
+	})
+
+	It("should refuse bundle with repointed image digest ref", func() {
+		signKey, err := LoadSigningKey(signKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+		verifyKey, err := LoadVerificationKey(verifyKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		digest1 := "sha256:" + strings.Repeat("1", 64)
+		digest2 := "sha256:" + strings.Repeat("2", 64)
+
+		imageIDs := map[string]string{
+			"registry.example.com/group/testproject:tag-1":      "sha256:aaa",
+			"registry.example.com/group/testproject@" + digest1: "sha256:aaa",
+			"registry.example.com/group/testproject@" + digest2: "sha256:bbb",
+		}
+		getImageID := func(_ context.Context, ref string) (string, error) { return imageIDs[ref], nil }
+
+		setDigest := func(ch *chart.Chart, digest string) {
+			werfVals := ch.Values["werf"].(map[string]interface{})
+			werfVals["digest"] = map[string]interface{}{"image-1": digest}
+			werfVals["image_digest_ref"] = map[string]interface{}{"image-1": "registry.example.com/group/testproject@" + digest}
+		}
+
+		ch := newChart("registry.example.com/group/testproject")
+		setDigest(ch, digest1)
+		Expect(SignChart(ctx, ch, signKey, getImageID)).To(Succeed())
+		Expect(VerifyChartSignature(ctx, ch, verifyKey, getImageID)).To(Succeed())
+
+		setDigest(ch, digest2)
+		Expect(VerifyChartSignature(ctx, ch, verifyKey, getImageID)).To(MatchError(ContainSubstring(`image "image-1" digest ref registry.example.com/group/testproject@` + digest2 + ` id sha256:bbb does not match signed image id sha256:aaa`)))
+
+		ch.Values["werf"].(map[string]interface{})["digest"] = map[string]interface{}{"image-1": digest1}
+		Expect(VerifyChartSignature(ctx, ch, verifyKey, getImageID)).To(MatchError(ContainSubstring(fmt.Sprintf("does not match digest %q", digest1))))
+	})
+
+	It("should verify bundle archive offline", func() {
+		signKey, err := LoadSigningKey(signKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+		verifyKey, err := LoadVerificationKey(verifyKeyPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		img, err := random.Image(1024, 2)
+		Expect(err).NotTo(HaveOccurred())
+		imgID, err := img.ConfigName()
+		Expect(err).NotTo(HaveOccurred())
+
+		imgRef, err := name.NewTag("registry.example.com/group/testproject:tag-1")
+		Expect(err).NotTo(HaveOccurred())
+		imgBytes := bytes.NewBuffer(nil)
+		Expect(tarball.Write(imgRef, img, imgBytes)).To(Succeed())
+
+		ch := newChart("registry.example.com/group/testproject")
+		Expect(SignChart(ctx, ch, signKey, func(_ context.Context, _ string) (string, error) { return imgID.String(), nil })).To(Succeed())
+
+		archive := NewBundleArchive(NewBundleArchiveStubReader(ch, map[string][]byte{"tag-1": imgBytes.Bytes()}), nil)
+		Expect(VerifyBundleSignature(ctx, archive, verifyKey, helmopts.HelmOptions{})).To(Succeed())
+
+		otherImg, err := random.Image(1024, 1)
+		Expect(err).NotTo(HaveOccurred())
+		otherImgBytes := bytes.NewBuffer(nil)
+		Expect(tarball.Write(imgRef, otherImg, otherImgBytes)).To(Succeed())
+
+		tamperedArchive := NewBundleArchive(NewBundleArchiveStubReader(ch, map[string][]byte{"tag-1": otherImgBytes.Bytes()}), nil)
+		Expect(VerifyBundleSignature(ctx, tamperedArchive, verifyKey, helmopts.HelmOptions{})).To(MatchError(ContainSubstring(`image "image-1" id`)))
+	})
+})
@@ -0,0 +1,466 @@
+package drift
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type ChangeType string
+
+const (
+	// ChangeChanged is the field of the manifest with the different value in the cluster.
+	ChangeChanged ChangeType = "changed"
+	// ChangeRemoved is the field of the manifest, which is missing in the cluster.
+	ChangeRemoved ChangeType = "removed"
+	// ChangeAdded is the field, which is not in the manifest and is added manually in the cluster.
+	ChangeAdded ChangeType = "added"
+)
+
+// ManualFieldManagerPrefix is the prefix of the field managers of manual changes: kubectl-edit, kubectl-patch, kubectl-annotate, etc.
+const ManualFieldManagerPrefix = "kubectl"
+
+const (
+	hookAnnoName              = "helm.sh/hook"
+	lastAppliedConfigAnnoName = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+const redactedValue = "<redacted>"
+
+// ReleaseDrift is the drift of the live release resources from the rendered chart.
+type ReleaseDrift struct {
+	Resources []ResourceDrift `json:"resources,omitempty"`
+}
+
+type ResourceDrift struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Namespace  string       `json:"namespace,omitempty"`
+	Name       string       `json:"name"`
+	Missing    bool         `json:"missing,omitempty"`
+	Fields     []FieldDrift `json:"fields,omitempty"`
+}
+
+type FieldDrift struct {
+	Path    string      `json:"path"`
+	Change  ChangeType  `json:"change"`
+	Desired interface{} `json:"desired,omitempty"`
+	Live    interface{} `json:"live,omitempty"`
+	Manager string      `json:"manager,omitempty"`
+	// Redacted is set for the Secret data fields, values of which are not reported.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// GetLiveFunc returns the live object of the rendered resource or nil if the resource does not exist in the cluster.
+type GetLiveFunc func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+
+// Detect compares the rendered resources with the live objects. Hooks are skipped, because they are recreated on each deploy.
+func Detect(ctx context.Context, resources []map[string]interface{}, getLive GetLiveFunc) (*ReleaseDrift, error) {
+	releaseDrift := &ReleaseDrift{}
+
+	for _, res := range resources {
+		desired := &unstructured.Unstructured{Object: res}
+		if _, ok := desired.GetAnnotations()[hookAnnoName]; ok {
+			continue
+		}
+
+		live, err := getLive(ctx, desired)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get %s/%s from the cluster: %w", desired.GetKind(), desired.GetName(), err)
+		}
+
+		drift := ResourceDrift{
+			APIVersion: desired.GetAPIVersion(),
+			Kind:       desired.GetKind(),
+			Namespace:  desired.GetNamespace(),
+			Name:       desired.GetName(),
+		}
+
+		if live == nil {
+			drift.Missing = true
+		} else {
+			drift.Namespace = live.GetNamespace()
+			drift.Fields = CompareResource(desired, live)
+		}
+
+		if drift.Missing || len(drift.Fields) > 0 {
+			releaseDrift.Resources = append(releaseDrift.Resources, drift)
+		}
+	}
+
+	return releaseDrift, nil
+}
+
+// CompareResource returns the fields of the manifest, which differ in the live object, and the fields added to the live object manually.
+// Only fields of the manifest are compared, so the defaults and the fields populated by the server are ignored.
+// Secret stringData is compared as the data stored by the server and values of the Secret data are redacted.
+func CompareResource(desired, live *unstructured.Unstructured) []FieldDrift {
+	secret := isSecret(desired)
+	if secret {
+		desired = normalizeSecret(desired)
+	}
+
+	fields := compareResource(desired, live)
+
+	if secret {
+		for i := range fields {
+			redactSecretField(&fields[i])
+		}
+	}
+
+	return fields
+}
+
+func compareResource(desired, live *unstructured.Unstructured) []FieldDrift {
+	var fields []FieldDrift
+
+	for _, key := range sortedKeys(desired.Object) {
+		if key == "status" {
+			continue
+		}
+		fields = append(fields, compareValues(formatPath("", key), desired.Object[key], live.Object[key], keyExists(live.Object, key))...)
+	}
+
+	for _, entry := range live.GetManagedFields() {
+		if !strings.HasPrefix(entry.Manager, ManualFieldManagerPrefix) || entry.Subresource == "status" || entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fieldSet map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fieldSet); err != nil {
+			continue
+		}
+
+		for _, field := range addedFields("", fieldSet, desired.Object, live.Object) {
+			field.Manager = entry.Manager
+			fields = append(fields, field)
+		}
+	}
+
+	return dedupFields(fields)
+}
+
+func isSecret(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == "Secret" && obj.GetAPIVersion() == "v1"
+}
+
+// normalizeSecret moves stringData to data the way the server does: values are base64 encoded
+// and override the data values with the same keys.
+func normalizeSecret(secret *unstructured.Unstructured) *unstructured.Unstructured {
+	stringData, ok := secret.Object["stringData"].(map[string]interface{})
+	if !ok {
+		return secret
+	}
+
+	secret = secret.DeepCopy()
+	delete(secret.Object, "stringData")
+
+	data, _ := secret.Object["data"].(map[string]interface{})
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	for key, value := range stringData {
+		data[key] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(value)))
+	}
+	secret.Object["data"] = data
+
+	return secret
+}
+
+// redactSecretField hides values of the Secret data fields and the last applied configuration of kubectl apply,
+// which contains the Secret data too.
+func redactSecretField(field *FieldDrift) {
+	annotationsPath := formatPath("metadata", "annotations")
+
+	switch {
+	case field.Path == "data" || strings.HasPrefix(field.Path, "data.") || strings.HasPrefix(field.Path, "data["),
+		field.Path == formatPath(annotationsPath, lastAppliedConfigAnnoName):
+		field.Desired, field.Live, field.Redacted = nil, nil, true
+	case field.Path == annotationsPath:
+		if annotations, ok := field.Live.(map[string]interface{}); ok && keyExists(annotations, lastAppliedConfigAnnoName) {
+			annotations = maps.Clone(annotations)
+			annotations[lastAppliedConfigAnnoName] = redactedValue
+			field.Live = annotations
+		}
+	}
+}
+
+func compareValues(path string, desired, live interface{}, liveExists bool) []FieldDrift {
+	if isEmpty(desired) {
+		return nil
+	}
+
+	if !liveExists {
+		return []FieldDrift{{Path: path, Change: ChangeRemoved, Desired: desired}}
+	}
+
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return []FieldDrift{{Path: path, Change: ChangeChanged, Desired: desired, Live: live}}
+		}
+
+		var fields []FieldDrift
+		for _, key := range sortedKeys(d) {
+			fields = append(fields, compareValues(formatPath(path, key), d[key], l[key], keyExists(l, key))...)
+		}
+		return fields
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return []FieldDrift{{Path: path, Change: ChangeChanged, Desired: desired, Live: live}}
+		}
+
+		var fields []FieldDrift
+		for i := range d {
+			fields = append(fields, compareValues(fmt.Sprintf("%s[%d]", path, i), d[i], l[i], true)...)
+		}
+		return fields
+	default:
+		if !scalarsEqual(desired, live) {
+			return []FieldDrift{{Path: path, Change: ChangeChanged, Desired: desired, Live: live}}
+		}
+		return nil
+	}
+}
+
+// addedFields walks the managed fields set (FieldsV1) and returns the topmost fields, which are not in the manifest.
+func addedFields(path string, fieldSet map[string]interface{}, desired, live interface{}) []FieldDrift {
+	var fields []FieldDrift
+
+	for _, key := range sortedKeys(fieldSet) {
+		subSet, _ := fieldSet[key].(map[string]interface{})
+
+		var fieldPath string
+		var desiredValue, liveValue interface{}
+		var found bool
+
+		switch {
+		case strings.HasPrefix(key, "f:"):
+			name := strings.TrimPrefix(key, "f:")
+			fieldPath = formatPath(path, name)
+			if m, ok := desired.(map[string]interface{}); ok {
+				desiredValue, found = m[name]
+			}
+			if m, ok := live.(map[string]interface{}); ok {
+				liveValue = m[name]
+			}
+		case strings.HasPrefix(key, "k:"):
+			var itemKey map[string]interface{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(key, "k:")), &itemKey); err != nil {
+				continue
+			}
+			fieldPath = fmt.Sprintf("%s[%s]", path, formatItemKey(itemKey))
+			desiredValue, found = findItemByKey(desired, itemKey, true)
+			liveValue, _ = findItemByKey(live, itemKey, false)
+		case strings.HasPrefix(key, "v:"):
+			var value interface{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(key, "v:")), &value); err != nil {
+				continue
+			}
+			fieldPath = fmt.Sprintf("%s[%s]", path, formatValue(value))
+			desiredValue, found = findItemByValue(desired, value)
+			liveValue = value
+		default:
+			continue
+		}
+
+		if !found {
+			fields = append(fields, FieldDrift{Path: fieldPath, Change: ChangeAdded, Live: liveValue})
+			continue
+		}
+
+		fields = append(fields, addedFields(fieldPath, subSet, desiredValue, liveValue)...)
+	}
+
+	return fields
+}
+
+// findItemByKey finds the list item by the associative list key. Key fields missing in the manifest item are defaulted by the server,
+// so they are ignored when the manifest is searched.
+func findItemByKey(list interface{}, itemKey map[string]interface{}, ignoreMissingKeyFields bool) (interface{}, bool) {
+	items, _ := list.([]interface{})
+
+Items:
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var matched int
+		for k, v := range itemKey {
+			itemValue, ok := m[k]
+			switch {
+			case !ok && ignoreMissingKeyFields:
+				continue
+			case !ok || fmt.Sprint(itemValue) != fmt.Sprint(v):
+				continue Items
+			}
+			matched++
+		}
+
+		if matched > 0 {
+			return item, true
+		}
+	}
+
+	return nil, false
+}
+
+func findItemByValue(list interface{}, value interface{}) (interface{}, bool) {
+	items, _ := list.([]interface{})
+	for _, item := range items {
+		if scalarsEqual(item, value) {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// scalarsEqual compares scalars of the manifest and the live object, numbers are compared regardless of their type,
+// resource quantities are compared by the value (e.g. 1000m and 1).
+func scalarsEqual(a, b interface{}) bool {
+	if fmt.Sprint(a) == fmt.Sprint(b) || reflect.DeepEqual(a, b) {
+		return true
+	}
+
+	qa, err := resource.ParseQuantity(fmt.Sprint(a))
+	if err != nil {
+		return false
+	}
+	qb, err := resource.ParseQuantity(fmt.Sprint(b))
+	if err != nil {
+		return false
+	}
+	return qa.Cmp(qb) == 0
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func dedupFields(fields []FieldDrift) []FieldDrift {
+	seen := map[string]bool{}
+	var result []FieldDrift
+	for _, field := range fields {
+		if seen[field.Path] {
+			continue
+		}
+		seen[field.Path] = true
+		result = append(result, field)
+	}
+	return result
+}
+
+func keyExists(m map[string]interface{}, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatPath joins the path with the field name, names with dots or slashes (e.g. annotations) are quoted.
+func formatPath(path, name string) string {
+	if strings.ContainsAny(name, "./") {
+		return fmt.Sprintf("%s[%q]", path, name)
+	}
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func formatItemKey(itemKey map[string]interface{}) string {
+	var parts []string
+	for _, k := range sortedKeys(itemKey) {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, itemKey[k]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatValue(value interface{}) string {
+	var b strings.Builder
+
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (releaseDrift *ReleaseDrift) HasDrift() bool {
+	return len(releaseDrift.Resources) > 0
+}
+
+func (releaseDrift *ReleaseDrift) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	if !releaseDrift.HasDrift() {
+		b.WriteString("No drift\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	for i, res := range releaseDrift.Resources {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		name := fmt.Sprintf("%s/%s", res.Kind, res.Name)
+		if res.Namespace != "" {
+			name = fmt.Sprintf("%s (namespace %s)", name, res.Namespace)
+		}
+
+		if res.Missing {
+			fmt.Fprintf(&b, "%s: missing in the cluster\n", name)
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s:\n", name)
+		for _, field := range res.Fields {
+			desired, live := formatValue(field.Desired), formatValue(field.Live)
+			if field.Redacted {
+				desired, live = redactedValue, redactedValue
+			}
+
+			switch field.Change {
+			case ChangeAdded:
+				fmt.Fprintf(&b, "  + %s: %s (by %s)\n", field.Path, live, field.Manager)
+			case ChangeRemoved:
+				fmt.Fprintf(&b, "  - %s: %s\n", field.Path, desired)
+			default:
+				fmt.Fprintf(&b, "  ~ %s: %s -> %s\n", field.Path, desired, live)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,214 @@
+package drift
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newDesiredDeployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"name":      "app",
+						"image":     "app:v1",
+						"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": int64(1)}},
+						"ports":     []interface{}{map[string]interface{}{"containerPort": int64(80)}},
+					}},
+				},
+			},
+		},
+	}}
+}
+
+func newLiveDeployment() *unstructured.Unstructured {
+	live := newDesiredDeployment().DeepCopy()
+	live.SetNamespace("default")
+	live.SetResourceVersion("100")
+	live.SetUID("uid")
+	live.Object["status"] = map[string]interface{}{"replicas": int64(2)}
+
+	containers, _, _ := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+	container := containers[0].(map[string]interface{})
+	container["terminationMessagePath"] = "/dev/termination-log"
+	container["resources"] = map[string]interface{}{"limits": map[string]interface{}{"cpu": "1000m"}}
+	container["ports"] = []interface{}{map[string]interface{}{"containerPort": int64(80), "protocol": "TCP"}}
+	Expect(unstructured.SetNestedSlice(live.Object, containers, "spec", "template", "spec", "containers")).To(Succeed())
+
+	return live
+}
+
+var _ = Describe("CompareResource", func() {
+	It("should ignore defaults and fields populated by the server", func() {
+		Expect(CompareResource(newDesiredDeployment(), newLiveDeployment())).To(BeEmpty())
+	})
+
+	It("should report changed and removed fields of the manifest", func() {
+		live := newLiveDeployment()
+		Expect(unstructured.SetNestedField(live.Object, int64(5), "spec", "replicas")).To(Succeed())
+		containers, _, _ := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+		containers[0].(map[string]interface{})["image"] = "app:debug"
+		delete(containers[0].(map[string]interface{}), "resources")
+		Expect(unstructured.SetNestedSlice(live.Object, containers, "spec", "template", "spec", "containers")).To(Succeed())
+
+		Expect(CompareResource(newDesiredDeployment(), live)).To(Equal([]FieldDrift{
+			{Path: "spec.replicas", Change: ChangeChanged, Desired: int64(2), Live: int64(5)},
+			{Path: "spec.template.spec.containers[0].image", Change: ChangeChanged, Desired: "app:v1", Live: "app:debug"},
+			{Path: "spec.template.spec.containers[0].resources", Change: ChangeRemoved, Desired: map[string]interface{}{"limits": map[string]interface{}{"cpu": int64(1)}}},
+		}))
+	})
+
+	It("should report fields added manually with kubectl", func() {
+		live := newLiveDeployment()
+		Expect(unstructured.SetNestedField(live.Object, "true", "metadata", "annotations", "debug.example.com/enabled")).To(Succeed())
+		containers, _, _ := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+		containers[0].(map[string]interface{})["env"] = []interface{}{map[string]interface{}{"name": "DEBUG", "value": "1"}}
+		Expect(unstructured.SetNestedSlice(live.Object, containers, "spec", "template", "spec", "containers")).To(Succeed())
+
+		live.SetManagedFields([]metav1.ManagedFieldsEntry{
+			{
+				Manager:  "helm",
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+			},
+			{
+				Manager: "kubectl-edit",
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{
+					"f:metadata":{"f:annotations":{".":{},"f:debug.example.com/enabled":{}}},
+					"f:spec":{"f:template":{"f:spec":{"f:containers":{
+						"k:{\"name\":\"app\"}":{"f:env":{".":{},"k:{\"name\":\"DEBUG\"}":{".":{},"f:name":{},"f:value":{}}}}
+					}}}}
+				}`)},
+			},
+		})
+
+		Expect(CompareResource(newDesiredDeployment(), live)).To(Equal([]FieldDrift{
+			{Path: "metadata.annotations", Change: ChangeAdded, Live: map[string]interface{}{"debug.example.com/enabled": "true"}, Manager: "kubectl-edit"},
+			{Path: "spec.template.spec.containers[name=app].env", Change: ChangeAdded, Live: []interface{}{map[string]interface{}{"name": "DEBUG", "value": "1"}}, Manager: "kubectl-edit"},
+		}))
+	})
+})
+
+var _ = Describe("CompareResource of Secret", func() {
+	newDesiredSecret := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "app"},
+			"data":       map[string]interface{}{"username": base64.StdEncoding.EncodeToString([]byte("admin"))},
+			"stringData": map[string]interface{}{"password": "desired-password"},
+		}}
+	}
+
+	newLiveSecret := func(password string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "app", "namespace": "default"},
+			"data": map[string]interface{}{
+				"username": base64.StdEncoding.EncodeToString([]byte("admin")),
+				"password": base64.StdEncoding.EncodeToString([]byte(password)),
+			},
+		}}
+	}
+
+	It("should compare stringData with the data stored by the server", func() {
+		Expect(CompareResource(newDesiredSecret(), newLiveSecret("desired-password"))).To(BeEmpty())
+	})
+
+	It("should not report values of the Secret data", func() {
+		live := newLiveSecret("live-password")
+		Expect(unstructured.SetNestedField(live.Object, base64.StdEncoding.EncodeToString([]byte("live-token")), "data", "token")).To(Succeed())
+		Expect(unstructured.SetNestedField(live.Object, `{"data":{"token":"bGl2ZS10b2tlbg=="}}`, "metadata", "annotations", lastAppliedConfigAnnoName)).To(Succeed())
+		live.SetManagedFields([]metav1.ManagedFieldsEntry{
+			{
+				Manager: "kubectl-client-side-apply",
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{
+					"f:data":{"f:token":{}},
+					"f:metadata":{"f:annotations":{".":{},"f:kubectl.kubernetes.io/last-applied-configuration":{}}}
+				}`)},
+			},
+		})
+
+		report := &ReleaseDrift{Resources: []ResourceDrift{{APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "app", Fields: CompareResource(newDesiredSecret(), live)}}}
+		Expect(report.Resources[0].Fields).To(Equal([]FieldDrift{
+			{Path: "data.password", Change: ChangeChanged, Redacted: true},
+			{Path: "data.token", Change: ChangeAdded, Manager: "kubectl-client-side-apply", Redacted: true},
+			{Path: "metadata.annotations", Change: ChangeAdded, Live: map[string]interface{}{lastAppliedConfigAnnoName: redactedValue}, Manager: "kubectl-client-side-apply"},
+		}))
+
+		var buf bytes.Buffer
+		Expect(report.WriteText(&buf)).To(Succeed())
+		Expect(buf.String()).To(Equal(`Secret/app (namespace default):
+  ~ data.password: <redacted> -> <redacted>
+  + data.token: <redacted> (by kubectl-client-side-apply)
+  + metadata.annotations: {"kubectl.kubernetes.io/last-applied-configuration":"<redacted>"} (by kubectl-client-side-apply)
+`))
+
+		data, err := json.Marshal(report)
+		Expect(err).To(Succeed())
+		for _, value := range []string{"desired-password", "live-password", "live-token"} {
+			Expect(string(data)).NotTo(ContainSubstring(base64.StdEncoding.EncodeToString([]byte(value))))
+			Expect(buf.String()).NotTo(ContainSubstring(base64.StdEncoding.EncodeToString([]byte(value))))
+		}
+	})
+})
+
+var _ = Describe("Detect", func() {
+	It("should report missing and drifted resources and skip hooks", func() {
+		hook := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata":   map[string]interface{}{"name": "migrate", "annotations": map[string]interface{}{"helm.sh/hook": "pre-install"}},
+		}}
+		service := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"name": "app"},
+		}}
+
+		live := newLiveDeployment()
+		Expect(unstructured.SetNestedField(live.Object, int64(5), "spec", "replicas")).To(Succeed())
+
+		report, err := Detect(context.Background(), []map[string]interface{}{newDesiredDeployment().Object, service.Object, hook.Object}, func(_ context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			Expect(obj.GetKind()).NotTo(Equal("Job"))
+			if obj.GetKind() == "Deployment" {
+				return live, nil
+			}
+			return nil, nil
+		})
+		Expect(err).To(Succeed())
+		Expect(report.HasDrift()).To(BeTrue())
+
+		var buf bytes.Buffer
+		Expect(report.WriteText(&buf)).To(Succeed())
+		Expect(buf.String()).To(Equal(`Deployment/app (namespace default):
+  ~ spec.replicas: 2 -> 5
+
+Service/app: missing in the cluster
+`))
+	})
+
+	It("should report no drift", func() {
+		report, err := Detect(context.Background(), []map[string]interface{}{newDesiredDeployment().Object}, func(context.Context, *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			return newLiveDeployment(), nil
+		})
+		Expect(err).To(Succeed())
+		Expect(report.HasDrift()).To(BeFalse())
+
+		var buf bytes.Buffer
+		Expect(report.WriteText(&buf)).To(Succeed())
+		Expect(buf.String()).To(Equal("No drift\n"))
+	})
+})
@@ -0,0 +1,42 @@
+package drift
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// NewGetLiveFunc returns GetLiveFunc, which gets the live objects with the dynamic client.
+// Namespaced resources without namespace in the manifest are searched in the release namespace.
+func NewGetLiveFunc(dynamicClient dynamic.Interface, mapper meta.RESTMapper, releaseNamespace string) GetLiveFunc {
+	return func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, fmt.Errorf("unable to map %s: %w", gvk, err)
+		}
+
+		var client dynamic.ResourceInterface
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			namespace := obj.GetNamespace()
+			if namespace == "" {
+				namespace = releaseNamespace
+			}
+			client = dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+		} else {
+			client = dynamicClient.Resource(mapping.Resource)
+		}
+
+		live, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return live, err
+	}
+}
@@ -26,4 +26,6 @@ const (
 
 	ExternalDependencyResourceAnnoName  = "external-dependency.werf.io/resource"
 	ExternalDependencyNamespaceAnnoName = "external-dependency.werf.io/namespace"
+
+	ReleaseExpiresAtAnnoName = "werf.io/release-expires-at"
 )
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"sigs.k8s.io/yaml"
@@ -45,6 +46,12 @@ func GetServiceValues(ctx context.Context, projectName, repo string, imageInfoGe
 		"repo":    repo,
 		"image":   map[string]interface{}{},
 		"tag":     map[string]interface{}{},
+		// digest, image_digest_ref, platforms, labels and size of werf images by image name.
+		"digest":           map[string]interface{}{},
+		"image_digest_ref": map[string]interface{}{},
+		"platforms":        map[string]interface{}{},
+		"labels":           map[string]interface{}{},
+		"size":             map[string]interface{}{},
 		"commit": map[string]interface{}{
 			"hash": opts.CommitHash,
 			"date": map[string]interface{}{
@@ -69,12 +76,20 @@ func GetServiceValues(ctx context.Context, projectName, repo string, imageInfoGe
 	if opts.IsStub {
 		stubTag := "TAG"
 		stubImage := fmt.Sprintf("%s:%s", repo, stubTag)
+		stubDigest := "sha256:" + strings.Repeat("0", 64)
+		stubImageDigestRef := fmt.Sprintf("%s@%s", repo, stubDigest)
 
 		werfInfo["is_stub"] = true
 		werfInfo["stub_image"] = stubImage
+		werfInfo["stub_digest"] = stubDigest
 		for _, name := range opts.StubImageNameList {
 			werfInfo["image"].(map[string]interface{})[name] = stubImage
 			werfInfo["tag"].(map[string]interface{})[name] = stubTag
+			werfInfo["digest"].(map[string]interface{})[name] = stubDigest
+			werfInfo["image_digest_ref"].(map[string]interface{})[name] = stubImageDigestRef
+			werfInfo["platforms"].(map[string]interface{})[name] = []interface{}{}
+			werfInfo["labels"].(map[string]interface{})[name] = map[string]interface{}{}
+			werfInfo["size"].(map[string]interface{})[name] = int64(0)
 		}
 	}
 
@@ -86,8 +101,29 @@ func GetServiceValues(ctx context.Context, projectName, repo string, imageInfoGe
 			werfInfo["is_nameless_image"] = true
 			werfInfo["nameless_image"] = image
 		} else {
-			werfInfo["image"].(map[string]interface{})[imageInfoGetter.GetWerfImageName()] = image
-			werfInfo["tag"].(map[string]interface{})[imageInfoGetter.GetWerfImageName()] = tag
+			name := imageInfoGetter.GetWerfImageName()
+
+			werfInfo["image"].(map[string]interface{})[name] = image
+			werfInfo["tag"].(map[string]interface{})[name] = tag
+
+			if imageInfoGetter.Digest != "" {
+				werfInfo["digest"].(map[string]interface{})[name] = imageInfoGetter.Digest
+				werfInfo["image_digest_ref"].(map[string]interface{})[name] = imageInfoGetter.GetDigestRef()
+			}
+
+			platforms := []interface{}{}
+			for _, platform := range imageInfoGetter.Platforms {
+				platforms = append(platforms, platform)
+			}
+			werfInfo["platforms"].(map[string]interface{})[name] = platforms
+
+			labels := map[string]interface{}{}
+			for key, value := range imageInfoGetter.Labels {
+				labels[key] = value
+			}
+			werfInfo["labels"].(map[string]interface{})[name] = labels
+
+			werfInfo["size"].(map[string]interface{})[name] = imageInfoGetter.Size
 		}
 	}
 
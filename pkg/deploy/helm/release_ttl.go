@@ -0,0 +1,127 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/3p-helm/pkg/release"
+	"github.com/werf/3p-helm/pkg/storage/driver"
+)
+
+type ExpiredRelease struct {
+	Name      string
+	Namespace string
+	ExpiresAt time.Time
+}
+
+// GetReleaseExpiresAt returns the value of the release info annotation for the release with the TTL.
+func GetReleaseExpiresAt(ttl time.Duration, now time.Time) string {
+	return now.Add(ttl).UTC().Format(time.RFC3339)
+}
+
+type GetExpiredReleasesOptions struct {
+	// RestrictionNamespace limits the scan to the single namespace, all namespaces are scanned if empty.
+	RestrictionNamespace string
+	NamespaceSelector    string
+	ReleaseStorageDriver string
+	Now                  time.Time
+}
+
+// GetExpiredReleases returns releases, the latest revision of which has the expiry annotation in the past.
+// Uninstalled releases, which history is kept, are skipped.
+func GetExpiredReleases(ctx context.Context, kubernetesClient kubernetes.Interface, opts GetExpiredReleasesOptions) ([]*ExpiredRelease, error) {
+	namespaces, err := getReleaseNamespaces(ctx, kubernetesClient, opts.RestrictionNamespace, opts.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*ExpiredRelease
+	for _, namespace := range namespaces {
+		releaseDriver, err := newReleaseStorageDriver(kubernetesClient, namespace, opts.ReleaseStorageDriver)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions, err := releaseDriver.List(func(*release.Release) bool { return true })
+		if err != nil {
+			return nil, fmt.Errorf("unable to list releases in namespace %q: %w", namespace, err)
+		}
+
+		latestRevisions := map[string]*release.Release{}
+		for _, rev := range revisions {
+			if prev, ok := latestRevisions[rev.Name]; !ok || prev.Version < rev.Version {
+				latestRevisions[rev.Name] = rev
+			}
+		}
+
+		for _, rel := range latestRevisions {
+			if rel.Info == nil || rel.Info.Status == release.StatusUninstalled {
+				continue
+			}
+
+			value, ok := rel.Info.Annotations[ReleaseExpiresAtAnnoName]
+			if !ok {
+				continue
+			}
+
+			expiresAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid release %q in namespace %q annotation %s=%q: %w", rel.Name, namespace, ReleaseExpiresAtAnnoName, value, err)
+			}
+
+			if expiresAt.Before(opts.Now) {
+				res = append(res, &ExpiredRelease{Name: rel.Name, Namespace: namespace, ExpiresAt: expiresAt})
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+func getReleaseNamespaces(ctx context.Context, kubernetesClient kubernetes.Interface, restrictionNamespace, namespaceSelector string) ([]string, error) {
+	listOptions := metav1.ListOptions{LabelSelector: namespaceSelector}
+	if restrictionNamespace != "" {
+		listOptions.FieldSelector = fields.OneTermEqualSelector("metadata.name", restrictionNamespace).String()
+	}
+
+	list, err := kubernetesClient.CoreV1().Namespaces().List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list namespaces: %w", err)
+	}
+
+	var res []string
+	for _, ns := range list.Items {
+		if ns.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+		res = append(res, ns.Name)
+	}
+
+	return res, nil
+}
+
+func newReleaseStorageDriver(kubernetesClient kubernetes.Interface, namespace, driverName string) (driver.Driver, error) {
+	switch strings.ToLower(driverName) {
+	case "", "secret", "secrets":
+		return driver.NewSecrets(kubernetesClient.CoreV1().Secrets(namespace)), nil
+	case "configmap", "configmaps":
+		return driver.NewConfigMaps(kubernetesClient.CoreV1().ConfigMaps(namespace)), nil
+	default:
+		return nil, fmt.Errorf("release storage driver %q is not supported, only secret and configmap drivers can be scanned", driverName)
+	}
+}
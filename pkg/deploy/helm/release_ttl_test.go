@@ -0,0 +1,84 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/werf/3p-helm/pkg/release"
+	"github.com/werf/3p-helm/pkg/storage/driver"
+)
+
+var _ = Describe("Release TTL", func() {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	expired := GetReleaseExpiresAt(-time.Hour, now)
+	notExpired := GetReleaseExpiresAt(time.Hour, now)
+
+	newNamespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	createRelease := func(client *fake.Clientset, namespace, name string, version int, status release.Status, expiresAt string) {
+		rel := &release.Release{
+			Name:      name,
+			Namespace: namespace,
+			Version:   version,
+			Info:      &release.Info{Status: status},
+		}
+		if expiresAt != "" {
+			rel.Info.Annotations = map[string]string{ReleaseExpiresAtAnnoName: expiresAt}
+		}
+
+		key := fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, version)
+		Expect(driver.NewSecrets(client.CoreV1().Secrets(namespace)).Create(key, rel)).To(Succeed())
+	}
+
+	It("should return releases with the expiry annotation in the past", func(ctx context.Context) {
+		client := fake.NewSimpleClientset(
+			newNamespace("review-1", map[string]string{"env": "review"}),
+			newNamespace("review-2", map[string]string{"env": "review"}),
+			newNamespace("production", map[string]string{"env": "production"}),
+		)
+
+		createRelease(client, "review-1", "app-review-1", 1, release.StatusDeployed, expired)
+		createRelease(client, "review-2", "app-review-2", 1, release.StatusSuperseded, expired)
+		createRelease(client, "review-2", "app-review-2", 2, release.StatusDeployed, notExpired)
+		createRelease(client, "review-2", "other", 1, release.StatusDeployed, "")
+		createRelease(client, "production", "app-production", 1, release.StatusDeployed, expired)
+
+		releases, err := GetExpiredReleases(ctx, client, GetExpiredReleasesOptions{
+			NamespaceSelector: "env=review",
+			Now:               now,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(releases).To(HaveLen(1))
+		Expect(releases[0].Name).To(Equal("app-review-1"))
+		Expect(releases[0].Namespace).To(Equal("review-1"))
+	})
+
+	It("should skip uninstalled releases", func(ctx context.Context) {
+		client := fake.NewSimpleClientset(newNamespace("review-1", nil))
+
+		createRelease(client, "review-1", "app-review-1", 1, release.StatusDeployed, expired)
+		createRelease(client, "review-1", "app-review-1", 2, release.StatusUninstalled, expired)
+
+		releases, err := GetExpiredReleases(ctx, client, GetExpiredReleasesOptions{Now: now})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(releases).To(BeEmpty())
+	})
+
+	It("should fail on the invalid expiry annotation", func(ctx context.Context) {
+		client := fake.NewSimpleClientset(newNamespace("review-1", nil))
+
+		createRelease(client, "review-1", "app-review-1", 1, release.StatusDeployed, "tomorrow")
+
+		_, err := GetExpiredReleases(ctx, client, GetExpiredReleasesOptions{Now: now})
+		Expect(err).To(HaveOccurred())
+	})
+})
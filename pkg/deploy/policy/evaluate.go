@@ -0,0 +1,175 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/werf/logboek"
+)
+
+type Violation struct {
+	Rule     string
+	Action   Action
+	Resource string
+	Path     string
+	Message  string
+}
+
+func (v *Violation) String() string {
+	if v.Path == "" {
+		return fmt.Sprintf("%s: rule %q: %s", v.Resource, v.Rule, v.Message)
+	}
+	return fmt.Sprintf("%s: %s: rule %q: %s", v.Resource, v.Path, v.Rule, v.Message)
+}
+
+// Evaluate checks the rendered resources against the policy rules.
+// The expression, which cannot be evaluated for the resource (e.g. because of the missing key), is a violation of the rule.
+func (policy *Policy) Evaluate(resources []map[string]interface{}) []*Violation {
+	var violations []*Violation
+
+	for _, obj := range resources {
+		kind, _ := obj["kind"].(string)
+		resource := resourceID(obj)
+
+		for _, rule := range policy.Rules {
+			if !rule.matchKind(kind) {
+				continue
+			}
+
+			newViolation := func(path, message string) {
+				if rule.Message != "" {
+					message = rule.Message
+				}
+				violations = append(violations, &Violation{Rule: rule.Name, Action: rule.Action, Resource: resource, Path: path, Message: message})
+			}
+
+			switch rule.Check {
+			case CheckRequireResourceLimits:
+				walkContainers(obj, "", func(path string, container map[string]interface{}) {
+					limits, _ := getMap(container, "resources", "limits")
+					var missed []string
+					for _, name := range []string{"cpu", "memory"} {
+						if _, ok := limits[name]; !ok {
+							missed = append(missed, name)
+						}
+					}
+					if len(missed) > 0 {
+						newViolation(path+".resources.limits", fmt.Sprintf("%s limits required", strings.Join(missed, " and ")))
+					}
+				})
+			case CheckForbidLatestTag:
+				walkContainers(obj, "", func(path string, container map[string]interface{}) {
+					imageRef, _ := container["image"].(string)
+					if isLatestImage(imageRef) {
+						newViolation(path+".image", fmt.Sprintf("image %q should have a tag other than latest", imageRef))
+					}
+				})
+			case CheckRequireAnnotation:
+				annotations, _ := getMap(obj, "metadata", "annotations")
+				if _, ok := annotations[rule.Annotation]; !ok {
+					newViolation("metadata.annotations", fmt.Sprintf("annotation %s required", rule.Annotation))
+				}
+			default:
+				ok, err := rule.evaluateExpression(obj)
+				if err != nil {
+					violations = append(violations, &Violation{Rule: rule.Name, Action: rule.Action, Resource: resource, Message: fmt.Sprintf("unable to evaluate expression %s: %s", strings.TrimSpace(rule.Expression), err)})
+					continue
+				}
+				if !ok {
+					newViolation("", fmt.Sprintf("expression %s is not satisfied", strings.TrimSpace(rule.Expression)))
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// ReportViolations logs the violations and returns error if there are violations of the rules with deny action.
+func ReportViolations(ctx context.Context, violations []*Violation) error {
+	var denied int
+	for _, v := range violations {
+		if v.Action == ActionDeny {
+			denied++
+			logboek.Context(ctx).Error().LogF("Policy violation (deny): %s\n", v)
+		} else {
+			logboek.Context(ctx).Warn().LogF("Policy violation (warn): %s\n", v)
+		}
+	}
+
+	if denied > 0 {
+		return fmt.Errorf("%d policy violation(s) with deny action found", denied)
+	}
+
+	return nil
+}
+
+func resourceID(obj map[string]interface{}) string {
+	kind, _ := obj["kind"].(string)
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+
+	if namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", namespace, kind, name)
+	}
+	return fmt.Sprintf("%s/%s", kind, name)
+}
+
+// walkContainers calls the function for each container, init container and ephemeral container found in the resource.
+func walkContainers(obj interface{}, path string, f func(path string, container map[string]interface{})) {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+
+			switch key {
+			case "containers", "initContainers", "ephemeralContainers":
+				if containers, ok := v[key].([]interface{}); ok {
+					for i, container := range containers {
+						if c, ok := container.(map[string]interface{}); ok {
+							f(fmt.Sprintf("%s[%d]", keyPath, i), c)
+						}
+					}
+				}
+			default:
+				walkContainers(v[key], keyPath, f)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			walkContainers(value, fmt.Sprintf("%s[%d]", path, i), f)
+		}
+	}
+}
+
+func getMap(obj map[string]interface{}, keys ...string) (map[string]interface{}, bool) {
+	for _, key := range keys {
+		next, ok := obj[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		obj = next
+	}
+	return obj, true
+}
+
+func isLatestImage(imageRef string) bool {
+	if imageRef == "" || strings.Contains(imageRef, "@") {
+		return false
+	}
+
+	// Colon before the last slash belongs to the registry address with port (registry:5000/repo).
+	i := strings.LastIndex(imageRef, ":")
+	if i < 0 || i < strings.LastIndex(imageRef, "/") {
+		return true
+	}
+
+	return imageRef[i+1:] == "latest"
+}
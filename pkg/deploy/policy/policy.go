@@ -0,0 +1,214 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"sigs.k8s.io/yaml"
+)
+
+type Action string
+
+const (
+	ActionDeny Action = "deny"
+	ActionWarn Action = "warn"
+)
+
+const (
+	CheckRequireResourceLimits = "requireResourceLimits"
+	CheckForbidLatestTag       = "forbidLatestTag"
+	CheckRequireAnnotation     = "requireAnnotation"
+)
+
+type Policy struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// Rule is either the built-in check or the CEL expression, which should return true for the compliant resource.
+// The resource is available in the expression as the object variable.
+type Rule struct {
+	Name       string   `json:"name"`
+	Action     Action   `json:"action,omitempty"`
+	Kinds      []string `json:"kinds,omitempty"`
+	Check      string   `json:"check,omitempty"`
+	Annotation string   `json:"annotation,omitempty"`
+	Expression string   `json:"expression,omitempty"`
+	Message    string   `json:"message,omitempty"`
+
+	// File is the policy file path inside the policies directory.
+	File string `json:"-"`
+
+	expressionProgram cel.Program
+}
+
+// FileReader reads the policy files, giterminism_manager.FileReader is used for the project policies.
+type FileReader interface {
+	ReadPolicyFiles(ctx context.Context, customDirRelPath string, handleFileFunc func(pathInsideDir string, data []byte, err error) error) error
+}
+
+// LoadPolicy reads and validates rules of all policy files of the directory.
+func LoadPolicy(ctx context.Context, fileReader FileReader, dir string) (*Policy, error) {
+	policy := &Policy{}
+
+	if err := fileReader.ReadPolicyFiles(ctx, dir, func(pathInsideDir string, data []byte, err error) error {
+		if err != nil {
+			return err
+		}
+
+		filePolicy, err := ParsePolicy(data)
+		if err != nil {
+			return fmt.Errorf("unable to parse policy file %q: %w", pathInsideDir, err)
+		}
+
+		for _, rule := range filePolicy.Rules {
+			rule.File = pathInsideDir
+		}
+		policy.Rules = append(policy.Rules, filePolicy.Rules...)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for _, rule := range policy.Rules {
+		if file, ok := names[rule.Name]; ok {
+			return nil, fmt.Errorf("rule %q is defined in policy files %q and %q, rule names should be unique", rule.Name, file, rule.File)
+		}
+		names[rule.Name] = rule.File
+	}
+
+	return policy, nil
+}
+
+func ParsePolicy(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range policy.Rules {
+		if err := rule.init(); err != nil {
+			return nil, fmt.Errorf("invalid rule #%d %q: %w", i, rule.Name, err)
+		}
+	}
+
+	return policy, nil
+}
+
+func (rule *Rule) init() error {
+	if rule.Name == "" {
+		return fmt.Errorf("name required")
+	}
+
+	switch rule.Action {
+	case "":
+		rule.Action = ActionDeny
+	case ActionDeny, ActionWarn:
+	default:
+		return fmt.Errorf("unknown action %q, expected %q or %q", rule.Action, ActionDeny, ActionWarn)
+	}
+
+	switch {
+	case rule.Check != "" && rule.Expression != "":
+		return fmt.Errorf("check and expression cannot be used together")
+	case rule.Check == "" && rule.Expression == "":
+		return fmt.Errorf("check or expression required")
+	case rule.Expression != "":
+		prg, err := compileExpression(rule.Expression)
+		if err != nil {
+			return fmt.Errorf("unable to parse expression: %w", err)
+		}
+		rule.expressionProgram = prg
+	}
+
+	switch rule.Check {
+	case "", CheckRequireResourceLimits, CheckForbidLatestTag:
+	case CheckRequireAnnotation:
+		if rule.Annotation == "" {
+			return fmt.Errorf("annotation required for the check %s", CheckRequireAnnotation)
+		}
+	default:
+		return fmt.Errorf("unknown check %q, expected one of %s", rule.Check, strings.Join([]string{CheckRequireResourceLimits, CheckForbidLatestTag, CheckRequireAnnotation}, ", "))
+	}
+
+	return nil
+}
+
+func (rule *Rule) matchKind(kind string) bool {
+	return len(rule.Kinds) == 0 || slices.Contains(rule.Kinds, kind)
+}
+
+// getCelEnv returns the environment of the rule expressions, numbers of the parsed manifests are doubles,
+// so they can be compared with integer literals.
+var getCelEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.CrossTypeNumericComparisons(true),
+	)
+})
+
+func compileExpression(expression string) (cel.Program, error) {
+	env, err := getCelEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if outputType := ast.OutputType(); !outputType.IsExactType(cel.BoolType) && !outputType.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression should return bool, got %s", outputType)
+	}
+
+	return env.Program(ast)
+}
+
+func (rule *Rule) evaluateExpression(obj map[string]interface{}) (bool, error) {
+	out, _, err := rule.expressionProgram.Eval(map[string]interface{}{"object": obj})
+	if err != nil {
+		return false, err
+	}
+
+	res, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %v, expected bool", out.Value())
+	}
+	return res, nil
+}
+
+type localFileReader struct{}
+
+// NewLocalFileReader returns FileReader reading policy files from the local directory without giterminism,
+// it is used when there is no project git repository (e.g. werf bundle apply).
+func NewLocalFileReader() FileReader {
+	return localFileReader{}
+}
+
+func (localFileReader) ReadPolicyFiles(_ context.Context, dir string, handleFileFunc func(pathInsideDir string, data []byte, err error) error) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || (filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml") {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		return handleFileFunc(filepath.ToSlash(relPath), data, err)
+	})
+}
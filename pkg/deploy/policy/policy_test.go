@@ -0,0 +1,141 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+const testPolicy = `
+rules:
+- name: require-resource-limits
+  kinds: [Deployment]
+  check: requireResourceLimits
+- name: forbid-latest-tag
+  action: warn
+  check: forbidLatestTag
+- name: require-job-weight
+  kinds: [Job]
+  check: requireAnnotation
+  annotation: werf.io/weight
+- name: require-replicas
+  kinds: [Deployment]
+  expression: has(object.spec.replicas) && object.spec.replicas >= 2
+  message: at least 2 replicas required
+- name: require-seed-weight
+  kinds: [Job]
+  action: warn
+  expression: object.metadata.annotations["werf.io/weight"] != ""
+`
+
+const testResources = `
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: app
+    namespace: prod
+  spec:
+    replicas: 1
+    template:
+      spec:
+        initContainers:
+        - name: init
+          image: registry.example.com:5000/init
+          resources:
+            limits: {cpu: 100m, memory: 64Mi}
+        containers:
+        - name: app
+          image: registry.example.com/app:v1
+          resources:
+            limits: {memory: 128Mi}
+- apiVersion: batch/v1
+  kind: Job
+  metadata:
+    name: migrate
+  spec:
+    template:
+      spec:
+        containers:
+        - name: migrate
+          image: registry.example.com/app@sha256:0000000000000000000000000000000000000000000000000000000000000000
+- apiVersion: batch/v1
+  kind: Job
+  metadata:
+    name: seed
+    annotations:
+      werf.io/weight: "10"
+  spec:
+    template:
+      spec:
+        containers:
+        - name: seed
+          image: registry.example.com/app:latest
+`
+
+var _ = Describe("Policy", func() {
+	It("should report violations of the built-in checks and expressions", func() {
+		policy, err := ParsePolicy([]byte(testPolicy))
+		Expect(err).NotTo(HaveOccurred())
+
+		var resources []map[string]interface{}
+		Expect(yaml.Unmarshal([]byte(testResources), &resources)).To(Succeed())
+
+		violations := policy.Evaluate(resources)
+
+		var got []string
+		for _, v := range violations {
+			got = append(got, string(v.Action)+" "+v.String())
+		}
+
+		Expect(got).To(Equal([]string{
+			`deny prod/Deployment/app: spec.template.spec.containers[0].resources.limits: rule "require-resource-limits": cpu limits required`,
+			`warn prod/Deployment/app: spec.template.spec.initContainers[0].image: rule "forbid-latest-tag": image "registry.example.com:5000/init" should have a tag other than latest`,
+			`deny prod/Deployment/app: rule "require-replicas": at least 2 replicas required`,
+			`deny Job/migrate: metadata.annotations: rule "require-job-weight": annotation werf.io/weight required`,
+			`warn Job/migrate: rule "require-seed-weight": unable to evaluate expression object.metadata.annotations["werf.io/weight"] != "": no such key: annotations`,
+			`warn Job/seed: spec.template.spec.containers[0].image: rule "forbid-latest-tag": image "registry.example.com/app:latest" should have a tag other than latest`,
+		}))
+
+		Expect(ReportViolations(context.Background(), violations)).To(MatchError("3 policy violation(s) with deny action found"))
+	})
+
+	DescribeTable("should validate rules",
+		func(policyData, expectedErr string) {
+			_, err := ParsePolicy([]byte(policyData))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(expectedErr))
+		},
+		Entry("without name", "rules: [{check: forbidLatestTag}]", "name required"),
+		Entry("unknown action", "rules: [{name: r, action: fail, check: forbidLatestTag}]", `unknown action "fail"`),
+		Entry("unknown check", "rules: [{name: r, check: requireProbes}]", `unknown check "requireProbes"`),
+		Entry("without check and expression", "rules: [{name: r}]", "check or expression required"),
+		Entry("with check and expression", "rules: [{name: r, check: forbidLatestTag, expression: 'true'}]", "cannot be used together"),
+		Entry("annotation check without annotation", "rules: [{name: r, check: requireAnnotation}]", "annotation required"),
+		Entry("invalid expression", "rules: [{name: r, expression: 'object.spec.'}]", "unable to parse expression"),
+		Entry("not bool expression", "rules: [{name: r, expression: 'size(object.metadata.name)'}]", "expression should return bool"),
+		Entry("unknown field", "rules: [{name: r, check: forbidLatestTag, severity: high}]", "unknown field"),
+	)
+
+	It("should load policy files of the local directory", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(dir, "jobs"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "images.yaml"), []byte("rules: [{name: forbid-latest-tag, check: forbidLatestTag}]"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "jobs", "weight.yml"), []byte("rules: [{name: forbid-latest-tag, check: requireAnnotation, annotation: werf.io/weight}]"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "README.md"), []byte("docs"), 0o644)).To(Succeed())
+
+		_, err := LoadPolicy(context.Background(), NewLocalFileReader(), dir)
+		Expect(err).To(MatchError(`rule "forbid-latest-tag" is defined in policy files "images.yaml" and "jobs/weight.yml", rule names should be unique`))
+
+		Expect(os.WriteFile(filepath.Join(dir, "jobs", "weight.yml"), []byte("rules: [{name: require-job-weight, check: requireAnnotation, annotation: werf.io/weight}]"), 0o644)).To(Succeed())
+
+		policy, err := LoadPolicy(context.Background(), NewLocalFileReader(), dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy.Rules).To(HaveLen(2))
+		Expect(policy.Rules[1].File).To(Equal("jobs/weight.yml"))
+		Expect(policy.Rules[1].Action).To(Equal(ActionDeny))
+	})
+})
@@ -0,0 +1,200 @@
+package rollout
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	StrategyAnnoName    = "werf.io/rollout-strategy"
+	StepsAnnoName       = "werf.io/rollout-steps"
+	StepPauseAnnoName   = "werf.io/rollout-step-pause"
+	StepTimeoutAnnoName = "werf.io/rollout-step-timeout"
+	FailModeAnnoName    = "werf.io/fail-mode"
+
+	// TemplateHashAnnoName is set on the live Deployment after the successful rollout,
+	// the next rollout is started only if the rendered pod template has changed.
+	TemplateHashAnnoName = "werf.io/rollout-template-hash"
+	// TrackLabelName distinguishes pods of the canary (or green) Deployment from the stable ones.
+	TrackLabelName = "werf.io/rollout-track"
+
+	FailModeIgnoreAndContinueDeployProcess = "IgnoreAndContinueDeployProcess"
+)
+
+type Strategy string
+
+const (
+	StrategyCanary    Strategy = "canary"
+	StrategyBlueGreen Strategy = "blue-green"
+)
+
+var (
+	DefaultCanarySteps = []int{25, 50, 100}
+	DefaultStepPause   = 30 * time.Second
+	DefaultStepTimeout = 5 * time.Minute
+)
+
+// Rollout is the progressive rollout of the Deployment from the rendered chart.
+// The new pod template is rolled out with the paired Deployment, which shares the stable Deployment selector labels,
+// so the Service traffic is weighted by the ratio of the paired and the stable Deployment replicas.
+// Selectors of the Deployments are kept disjoint with the werf.io/rollout-track label.
+type Rollout struct {
+	Name          string
+	Namespace     string
+	Strategy      Strategy
+	Steps         []int
+	StepPause     time.Duration
+	StepTimeout   time.Duration
+	IgnoreFailure bool
+
+	Deployment   *appsv1.Deployment
+	TemplateHash string
+
+	// State of the rollout in the cluster.
+	started        bool
+	stableReplicas int32
+}
+
+// NewRollouts returns rollouts of the Deployments annotated with werf.io/rollout-strategy.
+func NewRollouts(resources []map[string]interface{}, defaultNamespace string) ([]*Rollout, error) {
+	var rollouts []*Rollout
+	for _, obj := range resources {
+		if obj["kind"] != "Deployment" {
+			continue
+		}
+
+		deployment := &appsv1.Deployment{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, deployment); err != nil {
+			return nil, fmt.Errorf("unable to convert Deployment: %w", err)
+		}
+
+		if _, ok := deployment.Annotations[StrategyAnnoName]; !ok {
+			continue
+		}
+
+		if deployment.Namespace == "" {
+			deployment.Namespace = defaultNamespace
+		}
+
+		r, err := newRollout(deployment)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rollout of Deployment %s/%s: %w", deployment.Namespace, deployment.Name, err)
+		}
+		rollouts = append(rollouts, r)
+	}
+
+	return rollouts, nil
+}
+
+func newRollout(deployment *appsv1.Deployment) (*Rollout, error) {
+	r := &Rollout{
+		Name:          deployment.Name,
+		Namespace:     deployment.Namespace,
+		Strategy:      Strategy(deployment.Annotations[StrategyAnnoName]),
+		StepPause:     DefaultStepPause,
+		StepTimeout:   DefaultStepTimeout,
+		IgnoreFailure: deployment.Annotations[FailModeAnnoName] == FailModeIgnoreAndContinueDeployProcess,
+		Deployment:    deployment,
+	}
+
+	steps := deployment.Annotations[StepsAnnoName]
+	switch r.Strategy {
+	case StrategyCanary:
+		r.Steps = DefaultCanarySteps
+		if steps != "" {
+			var err error
+			if r.Steps, err = parseSteps(steps); err != nil {
+				return nil, fmt.Errorf("invalid %s annotation: %w", StepsAnnoName, err)
+			}
+		}
+	case StrategyBlueGreen:
+		if steps != "" {
+			return nil, fmt.Errorf("%s annotation is not supported for %s strategy", StepsAnnoName, StrategyBlueGreen)
+		}
+		r.Steps = []int{100}
+	default:
+		return nil, fmt.Errorf("unknown %s %q, expected %q or %q", StrategyAnnoName, r.Strategy, StrategyCanary, StrategyBlueGreen)
+	}
+
+	for annoName, value := range map[string]*time.Duration{StepPauseAnnoName: &r.StepPause, StepTimeoutAnnoName: &r.StepTimeout} {
+		if s, ok := deployment.Annotations[annoName]; ok {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid %s annotation %q: non-negative duration expected", annoName, s)
+			}
+			*value = d
+		}
+	}
+
+	templateData, err := json.Marshal(deployment.Spec.Template)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal pod template: %w", err)
+	}
+	sum := sha256.Sum256(templateData)
+	r.TemplateHash = hex.EncodeToString(sum[:])
+
+	// Stable Deployment should not adopt the paired pods, its selector is immutable, so it is not patched by werf.
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	if selector.Matches(labels.Set(r.newPaired(0).Spec.Template.Labels)) {
+		return nil, fmt.Errorf("selector matches pods of the paired Deployment %s: add %s label (e.g. %s: stable) to the selector and the pod template or exclude the paired pods with the %q selector expression", r.PairedName(), TrackLabelName, TrackLabelName, metav1.LabelSelectorOpDoesNotExist)
+	}
+
+	return r, nil
+}
+
+// parseSteps parses comma separated weights of the paired Deployment in percents, e.g. 10,50,100.
+func parseSteps(s string) ([]int, error) {
+	var steps []int
+	for _, part := range strings.Split(s, ",") {
+		weight, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("weight %q is not a number", part)
+		}
+
+		switch {
+		case weight <= 0 || weight > 100:
+			return nil, fmt.Errorf("weight %d should be in range 1-100", weight)
+		case len(steps) > 0 && weight <= steps[len(steps)-1]:
+			return nil, fmt.Errorf("weights should increase")
+		}
+
+		steps = append(steps, weight)
+	}
+
+	if steps[len(steps)-1] != 100 {
+		steps = append(steps, 100)
+	}
+
+	return steps, nil
+}
+
+// PairedName is the name of the Deployment with the new pod template.
+func (r *Rollout) PairedName() string {
+	if r.Strategy == StrategyBlueGreen {
+		return r.Name + "-green"
+	}
+	return r.Name + "-canary"
+}
+
+// getPairedReplicas returns the number of replicas of the paired Deployment for the step weight,
+// at least one replica is used for each step.
+func getPairedReplicas(total int32, weight int) int32 {
+	if total <= 0 {
+		total = 1
+	}
+	replicas := (int64(total)*int64(weight) + 99) / 100
+	return int32(max(replicas, 1))
+}
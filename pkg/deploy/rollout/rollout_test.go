@@ -0,0 +1,122 @@
+package rollout
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDeploymentObject(name string, annotations map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":        name,
+			"annotations": annotations,
+		},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": name, TrackLabelName: "stable"}},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": name, TrackLabelName: "stable"}},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"name": "app", "image": "app:v2"}},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("NewRollouts", func() {
+	It("should return rollouts of the annotated Deployments only", func() {
+		rollouts, err := NewRollouts([]map[string]interface{}{
+			newDeploymentObject("canary", map[string]interface{}{
+				StrategyAnnoName:    "canary",
+				StepsAnnoName:       "10, 50",
+				StepPauseAnnoName:   "1m",
+				StepTimeoutAnnoName: "10m",
+				FailModeAnnoName:    FailModeIgnoreAndContinueDeployProcess,
+			}),
+			newDeploymentObject("blue-green", map[string]interface{}{StrategyAnnoName: "blue-green"}),
+			newDeploymentObject("regular", nil),
+			{"apiVersion": "v1", "kind": "Service", "metadata": map[string]interface{}{"name": "svc"}},
+		}, "ns")
+		Expect(err).To(Succeed())
+		Expect(rollouts).To(HaveLen(2))
+
+		Expect(rollouts[0].Name).To(Equal("canary"))
+		Expect(rollouts[0].Namespace).To(Equal("ns"))
+		Expect(rollouts[0].Strategy).To(Equal(StrategyCanary))
+		Expect(rollouts[0].Steps).To(Equal([]int{10, 50, 100}))
+		Expect(rollouts[0].StepPause).To(Equal(time.Minute))
+		Expect(rollouts[0].StepTimeout).To(Equal(10 * time.Minute))
+		Expect(rollouts[0].IgnoreFailure).To(BeTrue())
+		Expect(rollouts[0].PairedName()).To(Equal("canary-canary"))
+		Expect(rollouts[0].TemplateHash).NotTo(BeEmpty())
+
+		Expect(rollouts[1].Strategy).To(Equal(StrategyBlueGreen))
+		Expect(rollouts[1].Steps).To(Equal([]int{100}))
+		Expect(rollouts[1].StepPause).To(Equal(DefaultStepPause))
+		Expect(rollouts[1].IgnoreFailure).To(BeFalse())
+		Expect(rollouts[1].PairedName()).To(Equal("blue-green-green"))
+	})
+
+	It("should change template hash with the pod template only", func() {
+		v1 := newDeploymentObject("app", map[string]interface{}{StrategyAnnoName: "canary"})
+		v1Scaled := newDeploymentObject("app", map[string]interface{}{StrategyAnnoName: "canary"})
+		v1Scaled["spec"].(map[string]interface{})["replicas"] = int64(5)
+		v2 := newDeploymentObject("app", map[string]interface{}{StrategyAnnoName: "canary"})
+		v2["spec"].(map[string]interface{})["template"].(map[string]interface{})["metadata"].(map[string]interface{})["labels"].(map[string]interface{})["version"] = "v2"
+
+		rollouts, err := NewRollouts([]map[string]interface{}{v1, v1Scaled, v2}, "ns")
+		Expect(err).To(Succeed())
+		Expect(rollouts[0].TemplateHash).To(Equal(rollouts[1].TemplateHash))
+		Expect(rollouts[0].TemplateHash).NotTo(Equal(rollouts[2].TemplateHash))
+	})
+
+	It("should allow paired pods to be excluded from the selector with the expression", func() {
+		obj := newDeploymentObject("app", map[string]interface{}{StrategyAnnoName: "canary"})
+		obj["spec"].(map[string]interface{})["selector"] = map[string]interface{}{
+			"matchLabels":      map[string]interface{}{"app": "app"},
+			"matchExpressions": []interface{}{map[string]interface{}{"key": TrackLabelName, "operator": "DoesNotExist"}},
+		}
+
+		rollouts, err := NewRollouts([]map[string]interface{}{obj}, "ns")
+		Expect(err).To(Succeed())
+		Expect(rollouts[0].newPaired(1).Spec.Selector).To(Equal(&metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": "app", TrackLabelName: "canary"},
+		}))
+	})
+
+	It("should fail if the selector matches the paired pods", func() {
+		obj := newDeploymentObject("app", map[string]interface{}{StrategyAnnoName: "canary"})
+		obj["spec"].(map[string]interface{})["selector"] = map[string]interface{}{"matchLabels": map[string]interface{}{"app": "app"}}
+
+		_, err := NewRollouts([]map[string]interface{}{obj}, "ns")
+		Expect(err).To(MatchError(ContainSubstring("selector matches pods of the paired Deployment app-canary")))
+	})
+
+	DescribeTable("should fail on invalid annotations",
+		func(annotations map[string]interface{}, expectedErr string) {
+			_, err := NewRollouts([]map[string]interface{}{newDeploymentObject("app", annotations)}, "ns")
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("unknown strategy", map[string]interface{}{StrategyAnnoName: "linear"}, `unknown werf.io/rollout-strategy "linear"`),
+		Entry("not a number weight", map[string]interface{}{StrategyAnnoName: "canary", StepsAnnoName: "10,half"}, `weight "half" is not a number`),
+		Entry("weight out of range", map[string]interface{}{StrategyAnnoName: "canary", StepsAnnoName: "0,50"}, "should be in range 1-100"),
+		Entry("decreasing weights", map[string]interface{}{StrategyAnnoName: "canary", StepsAnnoName: "50,20"}, "weights should increase"),
+		Entry("steps of blue-green", map[string]interface{}{StrategyAnnoName: "blue-green", StepsAnnoName: "50"}, "is not supported for blue-green strategy"),
+		Entry("invalid pause", map[string]interface{}{StrategyAnnoName: "canary", StepPauseAnnoName: "soon"}, "invalid werf.io/rollout-step-pause annotation"),
+	)
+})
+
+var _ = DescribeTable("getPairedReplicas",
+	func(total int32, weight int, expected int32) {
+		Expect(getPairedReplicas(total, weight)).To(Equal(expected))
+	},
+	Entry("rounds up", int32(10), 25, int32(3)),
+	Entry("at least one replica", int32(3), 10, int32(1)),
+	Entry("all replicas", int32(4), 100, int32(4)),
+	Entry("scaled to zero stable", int32(0), 50, int32(1)),
+)
@@ -0,0 +1,336 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/werf/logboek"
+	nelmcommon "github.com/werf/nelm/pkg/common"
+)
+
+var DefaultPollInterval = 2 * time.Second
+
+type RunOptions struct {
+	PollInterval time.Duration
+}
+
+// Run rolls out the new pod template step by step with the paired Deployment before the release is deployed.
+// Nothing is done on the first deploy or if the pod template has not changed since the last rollout.
+// Failed rollout is aborted and fails unless werf.io/fail-mode is IgnoreAndContinueDeployProcess.
+func (r *Rollout) Run(ctx context.Context, client kubernetes.Interface, opts RunOptions) error {
+	stable, err := client.AppsV1().Deployments(r.Namespace).Get(ctx, r.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		logboek.Context(ctx).Default().LogF("Deployment %s/%s does not exist yet, rollout skipped\n", r.Namespace, r.Name)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to get Deployment %s/%s: %w", r.Namespace, r.Name, err)
+	}
+
+	// The Deployment without the hash has not been rolled out with werf yet and is rolled out step by step too.
+	if stable.Annotations[TemplateHashAnnoName] == r.TemplateHash {
+		logboek.Context(ctx).Default().LogF("Deployment %s/%s pod template has not changed, rollout skipped\n", r.Namespace, r.Name)
+		return nil
+	}
+
+	r.started = true
+	r.stableReplicas = getReplicas(stable.Spec.Replicas)
+
+	if err := r.runSteps(ctx, client, opts); err != nil {
+		if abortErr := r.Abort(ctx, client); abortErr != nil {
+			return fmt.Errorf("%w, abort failed: %w", err, abortErr)
+		}
+
+		if r.IgnoreFailure {
+			logboek.Context(ctx).Warn().LogF("WARNING: Deployment %s/%s %s rollout failed and aborted: %s\n", r.Namespace, r.Name, r.Strategy, err)
+			return nil
+		}
+
+		return fmt.Errorf("Deployment %s/%s %s rollout failed and aborted: %w", r.Namespace, r.Name, r.Strategy, err)
+	}
+
+	return nil
+}
+
+func (r *Rollout) runSteps(ctx context.Context, client kubernetes.Interface, opts RunOptions) error {
+	for i, weight := range r.Steps {
+		pairedReplicas := getPairedReplicas(r.stableReplicas, weight)
+
+		if err := logboek.Context(ctx).Default().LogProcess("Rollout %s/%s step %d/%d: weight %d (%d of %d replicas) to %s", r.Namespace, r.Name, i+1, len(r.Steps), weight, pairedReplicas, r.stableReplicas, r.PairedName()).DoError(func() error {
+			if err := r.applyPaired(ctx, client, pairedReplicas); err != nil {
+				return err
+			}
+
+			if err := waitReady(ctx, client, r.Namespace, r.PairedName(), r.StepTimeout, opts); err != nil {
+				return err
+			}
+
+			if err := scale(ctx, client, r.Namespace, r.Name, max(r.stableReplicas-pairedReplicas, 0)); err != nil {
+				return err
+			}
+
+			return r.analyze(ctx, client, opts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Finish completes the rollout after the release is deployed: restores the stable Deployment replicas,
+// deletes the paired Deployment and records the pod template hash.
+func (r *Rollout) Finish(ctx context.Context, client kubernetes.Interface, opts RunOptions) error {
+	if r.started {
+		// Replicas are set by the release if specified in the chart.
+		if r.Deployment.Spec.Replicas == nil {
+			if err := scale(ctx, client, r.Namespace, r.Name, r.stableReplicas); err != nil {
+				return err
+			}
+		}
+
+		if err := waitReady(ctx, client, r.Namespace, r.Name, r.StepTimeout, opts); err != nil {
+			return err
+		}
+
+		if err := deletePaired(ctx, client, r.Namespace, r.PairedName()); err != nil {
+			return err
+		}
+
+		r.started = false
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, TemplateHashAnnoName, r.TemplateHash)
+	if _, err := client.AppsV1().Deployments(r.Namespace).Patch(ctx, r.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("unable to record Deployment %s/%s pod template hash: %w", r.Namespace, r.Name, err)
+	}
+
+	return nil
+}
+
+// Abort restores the stable Deployment replicas and deletes the paired Deployment.
+func (r *Rollout) Abort(ctx context.Context, client kubernetes.Interface) error {
+	if !r.started {
+		return nil
+	}
+
+	if err := scale(ctx, client, r.Namespace, r.Name, r.stableReplicas); err != nil {
+		return err
+	}
+
+	if err := deletePaired(ctx, client, r.Namespace, r.PairedName()); err != nil {
+		return err
+	}
+
+	r.started = false
+
+	return nil
+}
+
+func (r *Rollout) applyPaired(ctx context.Context, client kubernetes.Interface, replicas int32) error {
+	deployments := client.AppsV1().Deployments(r.Namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		paired, err := deployments.Get(ctx, r.PairedName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err := deployments.Create(ctx, r.newPaired(replicas), metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to create Deployment %s/%s: %w", r.Namespace, r.PairedName(), err)
+			}
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to get Deployment %s/%s: %w", r.Namespace, r.PairedName(), err)
+		}
+
+		paired.Spec = r.newPaired(replicas).Spec
+		if _, err := deployments.Update(ctx, paired, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("unable to update Deployment %s/%s: %w", r.Namespace, r.PairedName(), err)
+		}
+		return nil
+	})
+}
+
+// newPaired returns the Deployment with the rendered pod template. The pods have the stable Deployment labels
+// to receive the Service traffic and the track label to keep the Deployments selectors from overlapping.
+func (r *Rollout) newPaired(replicas int32) *appsv1.Deployment {
+	spec := *r.Deployment.Spec.DeepCopy()
+	spec.Replicas = &replicas
+
+	spec.Selector = spec.Selector.DeepCopy()
+	if spec.Selector == nil {
+		spec.Selector = &metav1.LabelSelector{}
+	}
+	spec.Selector.MatchLabels = maps.Clone(spec.Selector.MatchLabels)
+	if spec.Selector.MatchLabels == nil {
+		spec.Selector.MatchLabels = map[string]string{}
+	}
+	spec.Selector.MatchLabels[TrackLabelName] = string(r.Strategy)
+
+	// Expressions excluding the paired pods from the stable Deployment selector.
+	var matchExpressions []metav1.LabelSelectorRequirement
+	for _, expr := range spec.Selector.MatchExpressions {
+		if expr.Key != TrackLabelName {
+			matchExpressions = append(matchExpressions, expr)
+		}
+	}
+	spec.Selector.MatchExpressions = matchExpressions
+
+	spec.Template.Labels = maps.Clone(spec.Template.Labels)
+	if spec.Template.Labels == nil {
+		spec.Template.Labels = map[string]string{}
+	}
+	spec.Template.Labels[TrackLabelName] = string(r.Strategy)
+
+	deploymentLabels := maps.Clone(r.Deployment.Labels)
+	if deploymentLabels == nil {
+		deploymentLabels = map[string]string{}
+	}
+	deploymentLabels[TrackLabelName] = string(r.Strategy)
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.PairedName(),
+			Namespace: r.Namespace,
+			Labels:    deploymentLabels,
+		},
+		Spec: spec,
+	}
+}
+
+// analyze checks that the paired Deployment stays ready and its containers are not restarted during the step pause.
+func (r *Rollout) analyze(ctx context.Context, client kubernetes.Interface, opts RunOptions) error {
+	if r.StepPause == 0 {
+		return nil
+	}
+
+	baseRestarts, err := r.getPairedRestarts(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(r.StepPause)
+	for {
+		paired, err := client.AppsV1().Deployments(r.Namespace).Get(ctx, r.PairedName(), metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get Deployment %s/%s: %w", r.Namespace, r.PairedName(), err)
+		}
+		if !isReady(paired) {
+			return fmt.Errorf("Deployment %s/%s is not ready: %d of %d replicas available", r.Namespace, r.PairedName(), paired.Status.AvailableReplicas, getReplicas(paired.Spec.Replicas))
+		}
+
+		restarts, err := r.getPairedRestarts(ctx, client)
+		if err != nil {
+			return err
+		}
+		if restarts > baseRestarts {
+			return fmt.Errorf("Deployment %s/%s containers have been restarted %d time(s)", r.Namespace, r.PairedName(), restarts-baseRestarts)
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(getPollInterval(opts), time.Until(deadline))):
+		}
+	}
+}
+
+func (r *Rollout) getPairedRestarts(ctx context.Context, client kubernetes.Interface) (int32, error) {
+	selector := labels.SelectorFromSet(r.newPaired(0).Spec.Selector.MatchLabels)
+
+	pods, err := client.CoreV1().Pods(r.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return 0, fmt.Errorf("unable to list Deployment %s/%s pods: %w", r.Namespace, r.PairedName(), err)
+	}
+
+	var restarts int32
+	for _, pod := range pods.Items {
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				restarts += status.RestartCount
+			}
+		}
+	}
+
+	return restarts, nil
+}
+
+func waitReady(ctx context.Context, client kubernetes.Interface, namespace, name string, timeout time.Duration, opts RunOptions) error {
+	var last *appsv1.Deployment
+	if err := wait.PollUntilContextTimeout(ctx, getPollInterval(opts), timeout, true, func(ctx context.Context) (bool, error) {
+		deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("unable to get Deployment %s/%s: %w", namespace, name, err)
+		}
+		last = deployment
+
+		for _, cond := range deployment.Status.Conditions {
+			if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+				return false, fmt.Errorf("Deployment %s/%s progress deadline exceeded: %s", namespace, name, cond.Message)
+			}
+		}
+
+		return isReady(deployment), nil
+	}); err != nil {
+		if wait.Interrupted(err) && last != nil {
+			return fmt.Errorf("Deployment %s/%s is not ready after %s: %d of %d replicas available", namespace, name, timeout, last.Status.AvailableReplicas, getReplicas(last.Spec.Replicas))
+		}
+		return err
+	}
+
+	return nil
+}
+
+func isReady(deployment *appsv1.Deployment) bool {
+	replicas := getReplicas(deployment.Spec.Replicas)
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas >= replicas &&
+		deployment.Status.AvailableReplicas >= replicas
+}
+
+// scale patches the replicas with the field manager of nelm, which owns the field after the server-side apply
+// of the release, so the next release apply does not conflict with the rollout.
+func scale(ctx context.Context, client kubernetes.Interface, namespace, name string, replicas int32) error {
+	patch := fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas)
+	if _, err := client.AppsV1().Deployments(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{FieldManager: nelmcommon.DefaultFieldManager}); err != nil {
+		return fmt.Errorf("unable to scale Deployment %s/%s to %d replicas: %w", namespace, name, replicas, err)
+	}
+
+	return nil
+}
+
+func deletePaired(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+	propagation := metav1.DeletePropagationForeground
+	if err := client.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete Deployment %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+func getReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+func getPollInterval(opts RunOptions) time.Duration {
+	if opts.PollInterval > 0 {
+		return opts.PollInterval
+	}
+	return DefaultPollInterval
+}
@@ -0,0 +1,224 @@
+package rollout
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Rollout", func() {
+	const namespace = "ns"
+
+	var client *fake.Clientset
+	var readyPaired bool
+	var stableReplicasHistory []int32
+
+	ctx := context.Background()
+	opts := RunOptions{PollInterval: time.Millisecond}
+
+	newRollout := func(annotations map[string]interface{}) *Rollout {
+		rollouts, err := NewRollouts([]map[string]interface{}{newDeploymentObject("app", annotations)}, namespace)
+		Expect(err).To(Succeed())
+		Expect(rollouts).To(HaveLen(1))
+		return rollouts[0]
+	}
+
+	getDeployment := func(name string) (*appsv1.Deployment, error) {
+		return client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+
+	BeforeEach(func() {
+		replicas := int32(4)
+		client = fake.NewSimpleClientset(&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "app",
+				Namespace:   namespace,
+				Annotations: map[string]string{TemplateHashAnnoName: "previous"},
+			},
+			Spec:   appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{UpdatedReplicas: replicas, AvailableReplicas: replicas},
+		})
+
+		readyPaired = true
+		stableReplicasHistory = nil
+
+		// There is no deployment controller, so the status is updated along with the spec.
+		updateStatus := func(deployment *appsv1.Deployment) {
+			if deployment.Name == "app" {
+				stableReplicasHistory = append(stableReplicasHistory, *deployment.Spec.Replicas)
+			}
+			if deployment.Name != "app" && !readyPaired {
+				return
+			}
+
+			deployment.Status.UpdatedReplicas = *deployment.Spec.Replicas
+			deployment.Status.AvailableReplicas = *deployment.Spec.Replicas
+		}
+
+		client.PrependReactor("*", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if objAction, ok := action.(interface{ GetObject() runtime.Object }); ok {
+				updateStatus(objAction.GetObject().(*appsv1.Deployment))
+			}
+			return false, nil, nil
+		})
+
+		client.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			patchAction := action.(k8stesting.PatchAction)
+			if !strings.Contains(string(patchAction.GetPatch()), "replicas") {
+				return false, nil, nil
+			}
+
+			_, obj, err := k8stesting.ObjectReaction(client.Tracker())(action)
+			if err != nil {
+				return true, nil, err
+			}
+
+			deployment := obj.(*appsv1.Deployment)
+			updateStatus(deployment)
+			return true, deployment, client.Tracker().Update(action.GetResource(), deployment, action.GetNamespace())
+		})
+	})
+
+	It("should roll out canary step by step and finish after the release is deployed", func() {
+		r := newRollout(map[string]interface{}{StrategyAnnoName: "canary", StepsAnnoName: "25,50", StepPauseAnnoName: "5ms"})
+
+		Expect(r.Run(ctx, client, opts)).To(Succeed())
+		Expect(stableReplicasHistory).To(Equal([]int32{3, 2, 0}))
+
+		paired, err := getDeployment("app-canary")
+		Expect(err).To(Succeed())
+		Expect(*paired.Spec.Replicas).To(Equal(int32(4)))
+		Expect(paired.Labels).To(HaveKeyWithValue(TrackLabelName, "canary"))
+		Expect(paired.Spec.Selector.MatchLabels).To(Equal(map[string]string{"app": "app", TrackLabelName: "canary"}))
+		Expect(paired.Spec.Template.Labels).To(Equal(map[string]string{"app": "app", TrackLabelName: "canary"}))
+		Expect(paired.Spec.Template.Spec.Containers[0].Image).To(Equal("app:v2"))
+
+		Expect(r.Finish(ctx, client, opts)).To(Succeed())
+		Expect(stableReplicasHistory).To(Equal([]int32{3, 2, 0, 4}))
+
+		_, err = getDeployment("app-canary")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		stable, err := getDeployment("app")
+		Expect(err).To(Succeed())
+		Expect(stable.Annotations).To(HaveKeyWithValue(TemplateHashAnnoName, r.TemplateHash))
+	})
+
+	It("should switch all replicas to green with blue-green strategy", func() {
+		r := newRollout(map[string]interface{}{StrategyAnnoName: "blue-green", StepPauseAnnoName: "0s"})
+
+		Expect(r.Run(ctx, client, opts)).To(Succeed())
+		Expect(stableReplicasHistory).To(Equal([]int32{0}))
+
+		paired, err := getDeployment("app-green")
+		Expect(err).To(Succeed())
+		Expect(*paired.Spec.Replicas).To(Equal(int32(4)))
+	})
+
+	It("should abort and fail if canary is not ready", func() {
+		readyPaired = false
+		r := newRollout(map[string]interface{}{StrategyAnnoName: "canary", StepTimeoutAnnoName: "20ms"})
+
+		Expect(r.Run(ctx, client, opts)).To(MatchError(ContainSubstring("Deployment ns/app-canary is not ready after 20ms")))
+		Expect(stableReplicasHistory).To(Equal([]int32{4}))
+
+		_, err := getDeployment("app-canary")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should abort and fail if canary containers are restarted", func() {
+		r := newRollout(map[string]interface{}{StrategyAnnoName: "canary", StepsAnnoName: "50", StepPauseAnnoName: "50ms"})
+
+		restarted := false
+		client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			var restarts int32
+			if restarted {
+				restarts = 1
+			}
+			restarted = true
+
+			return true, &corev1.PodList{Items: []corev1.Pod{{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "app", TrackLabelName: "canary"}},
+				Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{RestartCount: restarts}}},
+			}}}, nil
+		})
+
+		Expect(r.Run(ctx, client, opts)).To(MatchError(ContainSubstring("containers have been restarted 1 time(s)")))
+		Expect(stableReplicasHistory).To(Equal([]int32{2, 4}))
+	})
+
+	It("should abort and continue with IgnoreAndContinueDeployProcess fail mode", func() {
+		readyPaired = false
+		r := newRollout(map[string]interface{}{StrategyAnnoName: "canary", StepTimeoutAnnoName: "20ms", FailModeAnnoName: FailModeIgnoreAndContinueDeployProcess})
+
+		Expect(r.Run(ctx, client, opts)).To(Succeed())
+
+		_, err := getDeployment("app-canary")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(r.Finish(ctx, client, opts)).To(Succeed())
+		Expect(stableReplicasHistory).To(Equal([]int32{4}))
+	})
+
+	DescribeTable("should skip rollout",
+		func(setup func(), expectedStableReplicasHistory []int32) {
+			r := newRollout(map[string]interface{}{StrategyAnnoName: "canary"})
+			if setup != nil {
+				setup()
+			}
+
+			Expect(r.Run(ctx, client, opts)).To(Succeed())
+			Expect(r.Finish(ctx, client, opts)).To(Succeed())
+
+			_, err := getDeployment("app-canary")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		},
+		Entry("if the pod template has not changed", func() {
+			r := newRollout(map[string]interface{}{StrategyAnnoName: "canary"})
+			Expect(r.Finish(ctx, client, opts)).To(Succeed())
+		}, nil),
+	)
+
+	It("should roll out canary if the Deployment has not been rolled out with werf yet", func() {
+		stable, err := getDeployment("app")
+		Expect(err).To(Succeed())
+		stable.Annotations = nil
+		_, err = client.AppsV1().Deployments(namespace).Update(ctx, stable, metav1.UpdateOptions{})
+		Expect(err).To(Succeed())
+		stableReplicasHistory = nil
+
+		r := newRollout(map[string]interface{}{StrategyAnnoName: "canary", StepsAnnoName: "50", StepPauseAnnoName: "5ms"})
+		Expect(r.Run(ctx, client, opts)).To(Succeed())
+		Expect(stableReplicasHistory).To(Equal([]int32{2, 0}))
+
+		_, err = getDeployment("app-canary")
+		Expect(err).To(Succeed())
+
+		Expect(r.Finish(ctx, client, opts)).To(Succeed())
+		Expect(stableReplicasHistory).To(Equal([]int32{2, 0, 4}))
+
+		stable, err = getDeployment("app")
+		Expect(err).To(Succeed())
+		Expect(stable.Annotations).To(HaveKeyWithValue(TemplateHashAnnoName, r.TemplateHash))
+	})
+
+	It("should skip rollout on the first deploy", func() {
+		Expect(client.AppsV1().Deployments(namespace).Delete(ctx, "app", metav1.DeleteOptions{})).To(Succeed())
+
+		r := newRollout(map[string]interface{}{StrategyAnnoName: "canary"})
+		Expect(r.Run(ctx, client, opts)).To(Succeed())
+
+		_, err := getDeployment("app-canary")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
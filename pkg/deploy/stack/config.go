@@ -0,0 +1,322 @@
+package stack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/werf/werf/v2/pkg/git_repo"
+)
+
+const DefaultConfigFileName = "werf-stack.yaml"
+
+var projectNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Config is the werf-stack.yaml: werf projects deployed together in the order of their dependencies.
+type Config struct {
+	// Values are shared by all projects.
+	Values map[string]interface{} `yaml:"values,omitempty"`
+	// ValuesFiles are paths relative to the stack config, shared by all projects.
+	ValuesFiles []string   `yaml:"valuesFiles,omitempty"`
+	Projects    []*Project `yaml:"projects"`
+
+	// Dir is the directory of the stack config, relative paths of the config are resolved against it.
+	Dir string `yaml:"-"`
+}
+
+// Project is the werf project of the stack. The project source is the local directory (dir),
+// the remote git repository (git with branch, tag or commit and optional dir inside the repository) or the bundle.
+type Project struct {
+	Name string `yaml:"name"`
+
+	Dir       string                         `yaml:"dir,omitempty"`
+	Git       string                         `yaml:"git,omitempty"`
+	BasicAuth *git_repo.BasicAuthCredentials `yaml:"basicAuth,omitempty"`
+	Branch    string                         `yaml:"branch,omitempty"`
+	Tag       string                         `yaml:"tag,omitempty"`
+	Commit    string                         `yaml:"commit,omitempty"`
+	Bundle    string                         `yaml:"bundle,omitempty"`
+
+	// Repo is the container registry repo of the project images (not used for bundles).
+	Repo      string `yaml:"repo,omitempty"`
+	Release   string `yaml:"release,omitempty"`
+	Namespace string `yaml:"namespace,omitempty"`
+
+	DependsOn []string `yaml:"dependsOn,omitempty"`
+
+	// Values and ValuesFiles override the shared values of the stack.
+	Values      map[string]interface{} `yaml:"values,omitempty"`
+	ValuesFiles []string               `yaml:"valuesFiles,omitempty"`
+}
+
+func (p *Project) IsBundle() bool {
+	return p.Bundle != ""
+}
+
+func (p *Project) IsGit() bool {
+	return p.Git != ""
+}
+
+// Source is the human-readable source of the project.
+func (p *Project) Source() string {
+	switch {
+	case p.IsBundle():
+		if p.Tag != "" {
+			return fmt.Sprintf("bundle %s:%s", p.Bundle, p.Tag)
+		}
+		return "bundle " + p.Bundle
+	case p.IsGit():
+		source := fmt.Sprintf("git %s@%s", p.Git, p.gitRef())
+		if p.Dir != "" {
+			source += " dir " + p.Dir
+		}
+		return source
+	default:
+		return "dir " + p.Dir
+	}
+}
+
+func (p *Project) gitRef() string {
+	switch {
+	case p.Commit != "":
+		return p.Commit
+	case p.Tag != "":
+		return p.Tag
+	default:
+		return p.Branch
+	}
+}
+
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read stack config: %w", err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get absolute path of %q: %w", path, err)
+	}
+
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stack config %q: %w", path, err)
+	}
+	config.Dir = filepath.Dir(absPath)
+
+	return config, nil
+}
+
+func ParseConfig(data []byte) (*Config, error) {
+	config := &Config{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to parse: %w", err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Projects) == 0 {
+		return fmt.Errorf("no projects specified")
+	}
+
+	names := map[string]bool{}
+	for _, p := range c.Projects {
+		if !projectNameRegexp.MatchString(p.Name) {
+			return fmt.Errorf("project name %q should consist of lower case alphanumeric characters and '-'", p.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("project %q is specified more than once", p.Name)
+		}
+		names[p.Name] = true
+
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("project %q: %w", p.Name, err)
+		}
+	}
+
+	for _, p := range c.Projects {
+		for _, dep := range p.DependsOn {
+			if !names[dep] {
+				return fmt.Errorf("project %q: unknown project %q in `dependsOn`", p.Name, dep)
+			}
+			if dep == p.Name {
+				return fmt.Errorf("project %q: project cannot depend on itself", p.Name)
+			}
+		}
+	}
+
+	if cycle := c.findCycle(); cycle != nil {
+		return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+func (p *Project) validate() error {
+	refsCount := countNonEmpty(p.Branch, p.Tag, p.Commit)
+
+	switch {
+	case countNonEmpty(p.Git, p.Bundle) > 1:
+		return fmt.Errorf("specify only `git` or `bundle`")
+	case p.IsBundle():
+		if p.Dir != "" || p.Branch != "" || p.Commit != "" || p.Repo != "" || p.BasicAuth != nil {
+			return fmt.Errorf("only `tag`, `release` and `namespace` can be specified for the bundle")
+		}
+		if p.Release == "" || p.Namespace == "" {
+			return fmt.Errorf("`release` and `namespace` are required for the bundle")
+		}
+	case p.IsGit():
+		if refsCount != 1 {
+			return fmt.Errorf("specify only `branch` or `tag` or `commit`")
+		}
+		if filepath.IsAbs(p.Dir) {
+			return fmt.Errorf("`dir` must be a relative path inside the git repository")
+		}
+		if p.BasicAuth != nil && p.BasicAuth.Username == "" {
+			return fmt.Errorf("username should be specified when using git basic auth")
+		}
+	default:
+		if p.Dir == "" {
+			return fmt.Errorf("specify the project source: `dir`, `git` or `bundle`")
+		}
+		if refsCount > 0 || p.BasicAuth != nil {
+			return fmt.Errorf("`branch`, `tag`, `commit` and `basicAuth` can be specified only for `git`")
+		}
+	}
+
+	return nil
+}
+
+// findCycle returns the projects of the first found dependency cycle or nil.
+func (c *Config) findCycle() []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	deps := c.dependencies()
+	state := map[string]int{}
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, p := range c.Projects {
+		if cycle := visit(p.Name); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) dependencies() map[string][]string {
+	deps := map[string][]string{}
+	for _, p := range c.Projects {
+		deps[p.Name] = p.DependsOn
+	}
+	return deps
+}
+
+// ProjectValues returns the values of the project: the shared values files, the shared values,
+// the project values files and the project values merged in this order.
+func (c *Config) ProjectValues(p *Project) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	for _, layer := range []struct {
+		files  []string
+		values map[string]interface{}
+	}{
+		{files: c.ValuesFiles, values: c.Values},
+		{files: p.ValuesFiles, values: p.Values},
+	} {
+		for _, path := range layer.files {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(c.Dir, path)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read values file: %w", err)
+			}
+
+			fileValues := map[string]interface{}{}
+			if err := yaml.Unmarshal(data, &fileValues); err != nil {
+				return nil, fmt.Errorf("unable to parse values file %q: %w", path, err)
+			}
+
+			mergeValues(values, fileValues)
+		}
+
+		mergeValues(values, layer.values)
+	}
+
+	return values, nil
+}
+
+func mergeValues(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		switch {
+		case srcIsMap && dstIsMap:
+			mergeValues(dstMap, srcMap)
+		case srcIsMap:
+			// Copy to keep the values of the config unchanged by the following merges.
+			copied := map[string]interface{}{}
+			mergeValues(copied, srcMap)
+			dst[k] = copied
+		default:
+			dst[k] = v
+		}
+	}
+}
+
+func countNonEmpty(values ...string) int {
+	var n int
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,134 @@
+package stack
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseConfig", func() {
+	It("should parse projects of all sources", func() {
+		config, err := ParseConfig([]byte(`
+projects:
+- name: database
+  bundle: registry.example.com/database
+  tag: v1.2.0
+  release: database
+  namespace: infra
+- name: backend
+  git: https://example.com/backend.git
+  tag: v2.0.0
+  dir: deploy
+  dependsOn: [database]
+- name: frontend
+  dir: ../frontend
+  dependsOn: [backend]
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Projects).To(HaveLen(3))
+		Expect(config.Projects[0].Source()).To(Equal("bundle registry.example.com/database:v1.2.0"))
+		Expect(config.Projects[1].Source()).To(Equal("git https://example.com/backend.git@v2.0.0 dir deploy"))
+		Expect(config.Projects[2].Source()).To(Equal("dir ../frontend"))
+	})
+
+	DescribeTable("should reject the invalid config",
+		func(data, expectedErr string) {
+			_, err := ParseConfig([]byte(data))
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("no projects", `projects: []`, "no projects specified"),
+		Entry("unknown field", `
+projects:
+- name: app
+  dir: .
+  unknown: true
+`, "field unknown not found"),
+		Entry("invalid name", `
+projects:
+- name: App
+  dir: .
+`, "should consist of lower case alphanumeric characters"),
+		Entry("duplicated name", `
+projects:
+- name: app
+  dir: .
+- name: app
+  dir: app
+`, `project "app" is specified more than once`),
+		Entry("no source", `
+projects:
+- name: app
+`, "specify the project source"),
+		Entry("git without ref", `
+projects:
+- name: app
+  git: https://example.com/app.git
+`, "specify only `branch` or `tag` or `commit`"),
+		Entry("bundle without release", `
+projects:
+- name: app
+  bundle: registry.example.com/app
+`, "`release` and `namespace` are required for the bundle"),
+		Entry("unknown dependency", `
+projects:
+- name: app
+  dir: .
+  dependsOn: [db]
+`, `unknown project "db"`),
+		Entry("dependency cycle", `
+projects:
+- name: a
+  dir: a
+  dependsOn: [c]
+- name: b
+  dir: b
+  dependsOn: [a]
+- name: c
+  dir: c
+  dependsOn: [b]
+`, "dependency cycle: a -> c -> b -> a"),
+	)
+})
+
+var _ = Describe("Config.ProjectValues", func() {
+	It("should merge the shared and the project values in order", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "shared.yaml"), []byte("global:\n  domain: example.com\n  env: dev\nreplicas: 1\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "app.yaml"), []byte("replicas: 2\n"), 0o644)).To(Succeed())
+
+		config, err := ParseConfig([]byte(`
+valuesFiles: [shared.yaml]
+values:
+  global:
+    env: production
+projects:
+- name: app
+  dir: app
+  valuesFiles: [app.yaml]
+  values:
+    replicas: 3
+    global:
+      tier: web
+- name: other
+  dir: other
+`))
+		Expect(err).NotTo(HaveOccurred())
+		config.Dir = dir
+
+		values, err := config.ProjectValues(config.Projects[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal(map[string]interface{}{
+			"global":   map[string]interface{}{"domain": "example.com", "env": "production", "tier": "web"},
+			"replicas": 3,
+		}))
+
+		values, err = config.ProjectValues(config.Projects[1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal(map[string]interface{}{
+			"global":   map[string]interface{}{"domain": "example.com", "env": "production"},
+			"replicas": 1,
+		}))
+	})
+})
@@ -0,0 +1,181 @@
+package stack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// StatusSkipped is the status of the project, which is not processed, because the stack is failed earlier.
+	StatusSkipped Status = "skipped"
+)
+
+// ErrProjectsFailed is returned if any project of the stack is failed.
+var ErrProjectsFailed = errors.New("stack projects failed")
+
+type ProjectResult struct {
+	Name           string          `json:"name"`
+	Source         string          `json:"source"`
+	Status         Status          `json:"status"`
+	Duration       string          `json:"duration,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	ChangesPlanned bool            `json:"changesPlanned,omitempty"`
+	DeployReport   json.RawMessage `json:"deployReport,omitempty"`
+}
+
+// Result is the combined result of the projects in the processing order.
+type Result struct {
+	Projects []*ProjectResult `json:"projects"`
+}
+
+// RunProjectFunc processes the project and fills in the result: the planned changes and the deploy report.
+type RunProjectFunc func(ctx context.Context, project *Project, result *ProjectResult) error
+
+type RunOptions struct {
+	// Reverse processes the projects after their dependents, e.g. on dismiss.
+	Reverse bool
+	// ParallelTasksLimit is the max number of projects processed at the same time, no limit if less than 1.
+	ParallelTasksLimit int
+}
+
+// Run processes the projects of the config in the order of their dependencies, independent projects are processed in parallel.
+// New projects are not started after the first failure, they are reported as skipped.
+func Run(ctx context.Context, config *Config, opts RunOptions, runProject RunProjectFunc) *Result {
+	waitFor := map[string][]string{}
+	for _, p := range config.Projects {
+		if opts.Reverse {
+			for _, dep := range p.DependsOn {
+				waitFor[dep] = append(waitFor[dep], p.Name)
+			}
+		} else {
+			waitFor[p.Name] = p.DependsOn
+		}
+	}
+
+	limit := opts.ParallelTasksLimit
+	if limit < 1 {
+		limit = len(config.Projects)
+	}
+
+	type done struct {
+		project *Project
+		result  *ProjectResult
+	}
+
+	result := &Result{}
+	finished := map[string]bool{}
+	started := map[string]bool{}
+	doneCh := make(chan done)
+	var running int
+	var failed bool
+
+	isReady := func(p *Project) bool {
+		for _, name := range waitFor[p.Name] {
+			if !finished[name] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for {
+		for _, p := range config.Projects {
+			if failed || running >= limit {
+				break
+			}
+			if started[p.Name] || !isReady(p) {
+				continue
+			}
+
+			started[p.Name] = true
+			running++
+
+			go func(p *Project) {
+				projectResult := &ProjectResult{Name: p.Name, Source: p.Source()}
+				startTime := time.Now()
+				err := runProject(ctx, p, projectResult)
+				projectResult.Duration = time.Since(startTime).Round(time.Millisecond).String()
+
+				if err != nil {
+					projectResult.Status = StatusFailed
+					projectResult.Error = err.Error()
+				} else {
+					projectResult.Status = StatusSucceeded
+				}
+
+				doneCh <- done{project: p, result: projectResult}
+			}(p)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		d := <-doneCh
+		running--
+		result.Projects = append(result.Projects, d.result)
+		if d.result.Status == StatusFailed {
+			failed = true
+		} else {
+			finished[d.project.Name] = true
+		}
+	}
+
+	for _, p := range config.Projects {
+		if !started[p.Name] {
+			result.Projects = append(result.Projects, &ProjectResult{Name: p.Name, Source: p.Source(), Status: StatusSkipped})
+		}
+	}
+
+	return result
+}
+
+func (r *Result) Err() error {
+	var errs []error
+	for _, p := range r.Projects {
+		if p.Status == StatusFailed {
+			errs = append(errs, fmt.Errorf("project %q: %s", p.Name, p.Error))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrProjectsFailed, errors.Join(errs...))
+}
+
+func (r *Result) ChangesPlanned() bool {
+	for _, p := range r.Projects {
+		if p.ChangesPlanned {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Result) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal stack report: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create dir for %q: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("unable to write stack report %q: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package stack
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	var config *Config
+
+	BeforeEach(func() {
+		var err error
+		config, err = ParseConfig([]byte(`
+projects:
+- name: frontend
+  dir: frontend
+  dependsOn: [backend]
+- name: backend
+  dir: backend
+  dependsOn: [database, cache]
+- name: database
+  dir: database
+- name: cache
+  dir: cache
+`))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	recordOrder := func(fail string) (*[]string, RunProjectFunc) {
+		var mux sync.Mutex
+		var order []string
+		return &order, func(_ context.Context, p *Project, _ *ProjectResult) error {
+			mux.Lock()
+			order = append(order, p.Name)
+			mux.Unlock()
+
+			if p.Name == fail {
+				return errors.New("boom")
+			}
+			return nil
+		}
+	}
+
+	It("should process the projects after their dependencies", func() {
+		order, runProject := recordOrder("")
+		result := Run(context.Background(), config, RunOptions{}, runProject)
+
+		Expect(result.Err()).NotTo(HaveOccurred())
+		Expect((*order)[:2]).To(ConsistOf("database", "cache"))
+		Expect((*order)[2:]).To(Equal([]string{"backend", "frontend"}))
+		Expect(result.Projects).To(HaveLen(4))
+	})
+
+	It("should process the projects before their dependencies in reverse mode", func() {
+		order, runProject := recordOrder("")
+		result := Run(context.Background(), config, RunOptions{Reverse: true, ParallelTasksLimit: 1}, runProject)
+
+		Expect(result.Err()).NotTo(HaveOccurred())
+		Expect((*order)[:2]).To(Equal([]string{"frontend", "backend"}))
+		Expect((*order)[2:]).To(ConsistOf("database", "cache"))
+	})
+
+	It("should skip the projects not started before the failure", func() {
+		_, runProject := recordOrder("database")
+		result := Run(context.Background(), config, RunOptions{ParallelTasksLimit: 1}, runProject)
+
+		Expect(result.Err()).To(MatchError(ErrProjectsFailed))
+		Expect(result.Err()).To(MatchError(ContainSubstring(`project "database": boom`)))
+
+		statuses := map[string]Status{}
+		for _, p := range result.Projects {
+			statuses[p.Name] = p.Status
+		}
+		Expect(statuses).To(Equal(map[string]Status{
+			"database": StatusFailed,
+			"cache":    StatusSkipped,
+			"backend":  StatusSkipped,
+			"frontend": StatusSkipped,
+		}))
+	})
+})
+
+var _ = Describe("Executor.Args", func() {
+	var config *Config
+
+	BeforeEach(func() {
+		var err error
+		config, err = ParseConfig([]byte(`
+values:
+  global.domain: example.com
+projects:
+- name: app
+  dir: app
+  repo: registry.example.com/app
+  namespace: app
+- name: db
+  bundle: registry.example.com/db
+  tag: v1
+  release: db
+  namespace: infra
+`))
+		Expect(err).NotTo(HaveOccurred())
+		config.Dir = "/stack"
+	})
+
+	It("should build converge args", func() {
+		e := &Executor{Config: config, Action: ActionConverge, Environment: "production", ReportDir: "/reports"}
+
+		args, err := e.Args(config.Projects[0], "/stack/app")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{
+			"converge", "--save-deploy-report", "--deploy-report-path", "/reports/app.json",
+			"--dir", "/stack/app", "--repo", "registry.example.com/app", "--env", "production", "--namespace", "app",
+			"--set-json", `global\.domain="example.com"`,
+		}))
+
+		args, err = e.Args(config.Projects[1], "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{
+			"bundle", "apply", "--save-deploy-report", "--deploy-report-path", "/reports/db.json",
+			"--repo", "registry.example.com/db", "--tag", "v1", "--env", "production", "--release", "db", "--namespace", "infra",
+			"--set-json", `global\.domain="example.com"`,
+		}))
+	})
+
+	It("should build plan and dismiss args", func() {
+		e := &Executor{Config: config, Action: ActionPlan}
+		args, err := e.Args(config.Projects[1], "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args[:3]).To(Equal([]string{"bundle", "plan", "--exit-code"}))
+
+		e = &Executor{Config: config, Action: ActionDismiss, Environment: "production"}
+		args, err = e.Args(config.Projects[0], "/stack/app")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{"dismiss", "--dir", "/stack/app", "--env", "production", "--namespace", "app"}))
+
+		args, err = e.Args(config.Projects[1], "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{"dismiss", "--release", "db", "--namespace", "infra"}))
+	})
+})
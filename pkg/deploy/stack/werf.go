@@ -0,0 +1,309 @@
+package stack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/git_repo"
+	"github.com/werf/werf/v2/pkg/true_git"
+	werfExec "github.com/werf/werf/v2/pkg/werf/exec"
+)
+
+type Action string
+
+const (
+	ActionConverge Action = "converge"
+	ActionPlan     Action = "plan"
+	ActionDismiss  Action = "dismiss"
+)
+
+// planChangesExitCode is the exit code of werf plan --exit-code if any changes planned.
+const planChangesExitCode = 2
+
+// Executor processes each project of the stack with the separate werf process: werf converge or werf bundle apply,
+// werf plan or werf bundle plan, werf dismiss.
+type Executor struct {
+	Config *Config
+	Action Action
+	// WerfPath is the werf binary to run.
+	WerfPath    string
+	Environment string
+	// ReportDir is the directory for the deploy reports of the projects on converge.
+	ReportDir string
+	// Out receives the output of all projects, each line is prefixed with the project name.
+	Out io.Writer
+
+	outMux sync.Mutex
+	gitMux sync.Mutex
+}
+
+func (e *Executor) RunProject(ctx context.Context, project *Project, result *ProjectResult) error {
+	return e.withProjectDir(ctx, project, func(dir string) error {
+		args, err := e.Args(project, dir)
+		if err != nil {
+			return err
+		}
+
+		out := &prefixWriter{w: e.Out, mux: &e.outMux, prefix: project.Name + " | "}
+		defer out.Flush()
+
+		cmd := werfExec.CommandContextCancellation(ctx, e.WerfPath, args...)
+		cmd.Stdout = out
+		cmd.Stderr = out
+
+		err = cmd.Run()
+		switch {
+		case e.Action == ActionPlan && werfExec.ExitCode(err) == planChangesExitCode:
+			result.ChangesPlanned = true
+			return nil
+		case err != nil:
+			return fmt.Errorf("werf %s: %w", strings.Join(args[:e.subcommandLen(project)], " "), err)
+		}
+
+		if e.Action == ActionConverge {
+			report, err := os.ReadFile(e.reportPath(project))
+			if err != nil {
+				return fmt.Errorf("unable to read deploy report: %w", err)
+			}
+			result.DeployReport = json.RawMessage(bytes.TrimSpace(report))
+		}
+
+		return nil
+	})
+}
+
+// Args returns the arguments of werf to process the project located in the dir (empty for the bundle).
+func (e *Executor) Args(project *Project, dir string) ([]string, error) {
+	var args []string
+
+	switch e.Action {
+	case ActionDismiss:
+		args = append(args, "dismiss")
+		if project.IsBundle() || (project.Release != "" && project.Namespace != "") {
+			return append(args, "--release", project.Release, "--namespace", project.Namespace), nil
+		}
+	case ActionPlan:
+		if project.IsBundle() {
+			args = append(args, "bundle", "plan")
+		} else {
+			args = append(args, "plan")
+		}
+		args = append(args, "--exit-code")
+	default:
+		if project.IsBundle() {
+			args = append(args, "bundle", "apply")
+		} else {
+			args = append(args, "converge")
+		}
+		args = append(args, "--save-deploy-report", "--deploy-report-path", e.reportPath(project))
+	}
+
+	if project.IsBundle() {
+		args = append(args, "--repo", project.Bundle)
+		if project.Tag != "" {
+			args = append(args, "--tag", project.Tag)
+		}
+	} else {
+		args = append(args, "--dir", dir)
+		if project.Repo != "" && e.Action != ActionDismiss {
+			args = append(args, "--repo", project.Repo)
+		}
+	}
+
+	if e.Environment != "" {
+		args = append(args, "--env", e.Environment)
+	}
+	if project.Release != "" {
+		args = append(args, "--release", project.Release)
+	}
+	if project.Namespace != "" {
+		args = append(args, "--namespace", project.Namespace)
+	}
+
+	if e.Action == ActionDismiss {
+		return args, nil
+	}
+
+	values, err := e.Config.ProjectValues(project)
+	if err != nil {
+		return nil, err
+	}
+
+	setJSON, err := valuesToSetJSON(values)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range setJSON {
+		args = append(args, "--set-json", s)
+	}
+
+	return args, nil
+}
+
+func (e *Executor) subcommandLen(project *Project) int {
+	if project.IsBundle() && e.Action != ActionDismiss {
+		return 2
+	}
+	return 1
+}
+
+func (e *Executor) reportPath(project *Project) string {
+	return filepath.Join(e.ReportDir, project.Name+".json")
+}
+
+// withProjectDir calls f with the local directory of the project or the work tree of the git commit.
+func (e *Executor) withProjectDir(ctx context.Context, project *Project, f func(dir string) error) error {
+	switch {
+	case project.IsBundle():
+		return f("")
+	case project.IsGit():
+		repo, commit, err := e.prepareGitRepo(ctx, project)
+		if err != nil {
+			return err
+		}
+
+		rawRepo, err := repo.PlainOpen()
+		if err != nil {
+			return fmt.Errorf("unable to open git repository %s: %w", project.Git, err)
+		}
+
+		commitObj, err := rawRepo.CommitObject(plumbing.NewHash(commit))
+		if err != nil {
+			return fmt.Errorf("unable to get commit %q of %s: %w", commit, project.Git, err)
+		}
+
+		hasSubmodules, err := git_repo.HasSubmodulesInCommit(commitObj)
+		if err != nil {
+			return err
+		}
+
+		workTreeCacheDir := filepath.Join(git_repo.GetWorkTreeCacheDir(), "stack", util.Sha256Hash(project.Git, project.Name))
+		return true_git.WithWorkTree(ctx, repo.GetClonePath(), workTreeCacheDir, commit, true_git.WithWorkTreeOptions{HasSubmodules: hasSubmodules}, func(workTreeDir string) error {
+			return f(filepath.Join(workTreeDir, project.Dir))
+		})
+	default:
+		dir := project.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(e.Config.Dir, dir)
+		}
+		return f(dir)
+	}
+}
+
+// prepareGitRepo clones or fetches the remote repository and resolves the commit of the project ref.
+// Projects from the same repository are prepared one by one.
+func (e *Executor) prepareGitRepo(ctx context.Context, project *Project) (*git_repo.Remote, string, error) {
+	e.gitMux.Lock()
+	defer e.gitMux.Unlock()
+
+	repo, err := git_repo.OpenRemoteRepo(project.Git, project.Git, project.BasicAuth)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to open git repository %s: %w", project.Git, err)
+	}
+
+	if err := logboek.Context(ctx).Info().LogProcess("Fetching git repository %s of project %s", project.Git, project.Name).DoError(func() error {
+		return repo.CloneAndFetch(ctx)
+	}); err != nil {
+		return nil, "", err
+	}
+
+	var commit string
+	switch {
+	case project.Branch != "":
+		commit, err = repo.LatestBranchCommit(ctx, project.Branch)
+	case project.Tag != "":
+		commit, err = repo.TagCommit(ctx, project.Tag)
+	default:
+		commit = project.Commit
+		var exists bool
+		if exists, err = repo.IsCommitExists(ctx, commit); err == nil && !exists {
+			err = fmt.Errorf("commit %q not found in %s", commit, project.Git)
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return repo, commit, nil
+}
+
+// valuesToSetJSON returns --set-json values for each top-level key: shared values are passed as options,
+// since werf reads values files only from the project git repository.
+func valuesToSetJSON(values map[string]interface{}) ([]string, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var result []string
+	for _, k := range keys {
+		data, err := json.Marshal(values[k])
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal value %q: %w", k, err)
+		}
+		result = append(result, fmt.Sprintf("%s=%s", strings.ReplaceAll(k, ".", `\.`), data))
+	}
+
+	return result, nil
+}
+
+// prefixWriter writes complete lines with the prefix, so the output of the projects processed in parallel is not mixed.
+type prefixWriter struct {
+	w      io.Writer
+	mux    *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+
+	if err := w.writeLines(w.buf[:i+1]); err != nil {
+		return 0, err
+	}
+	w.buf = append(w.buf[:0], w.buf[i+1:]...)
+
+	return len(p), nil
+}
+
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		_ = w.writeLines(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLines(data []byte) error {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(data, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		out.WriteString(w.prefix)
+		out.Write(line)
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	_, err := w.w.Write(out.Bytes())
+	return err
+}
@@ -584,6 +584,70 @@ func (api *api) PullImageArchive(ctx context.Context, archiveWriter io.Writer, r
 	return nil
 }
 
+// PullImage returns remote image, image blobs are fetched lazily on access.
+func (api *api) PullImage(ctx context.Context, reference string) (v1.Image, error) {
+	desc, _, err := api.getImageDesc(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve image manifest for reference %q: %w", reference, err)
+	}
+
+	return img, nil
+}
+
+// PushImageFrom writes image into the registry, blobs already existing in the destination repository are not read from the image.
+func (api *api) PushImageFrom(ctx context.Context, img v1.Image, reference string) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("unable to parse reference %q: %w", reference, err)
+	}
+
+	return api.pushWithRetry(ctx, func() error {
+		if err := api.writeToRemote(ctx, ref, img); err != nil {
+			return fmt.Errorf("write to the remote %s have failed: %w", ref.String(), err)
+		}
+		return nil
+	})
+}
+
+// GetRepoImageDescriptor returns the descriptor of the manifest or the manifest list without resolving the platform.
+func (api *api) GetRepoImageDescriptor(ctx context.Context, reference string) (*v1.Descriptor, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse reference %q: %w", reference, err)
+	}
+
+	desc, err := remote.Head(ref, api.defaultRemoteOptions(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get descriptor of %s: %w", ref, err)
+	}
+
+	return desc, nil
+}
+
+func (api *api) IsBlobExist(ctx context.Context, repository, digest string) (bool, error) {
+	ref, err := name.NewDigest(fmt.Sprintf("%s@%s", repository, digest), api.parseReferenceOptions()...)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse blob reference %s@%s: %w", repository, digest, err)
+	}
+
+	layer, err := remote.Layer(ref, api.defaultRemoteOptions(ctx)...)
+	if err != nil {
+		return false, fmt.Errorf("unable to get blob %s: %w", ref, err)
+	}
+
+	exists, err := partial.Exists(layer)
+	if err != nil {
+		return false, fmt.Errorf("unable to check blob %s existence: %w", ref, err)
+	}
+
+	return exists, nil
+}
+
 func (api *api) PushManifestList(ctx context.Context, reference string, opts ManifestListOptions) error {
 	if len(opts.Manifests) == 0 {
 		panic("unexpected empty manifests list")
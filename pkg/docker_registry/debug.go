@@ -122,6 +122,42 @@ func (r *DockerRegistryTracer) PullImageArchive(ctx context.Context, archiveWrit
 	return
 }
 
+func (r *DockerRegistryTracer) PullImage(ctx context.Context, reference string) (res v1.Image, err error) {
+	logboek.Context(ctx).Default().LogProcess("DockerRegistryTracer.PullImage %q", reference).Do(func() {
+		if r.DockerRegistry != nil {
+			res, err = r.DockerRegistry.PullImage(ctx, reference)
+		} else {
+			res, err = r.DockerRegistryApi.PullImage(ctx, reference)
+		}
+	})
+	return
+}
+
+func (r *DockerRegistryTracer) PushImageFrom(ctx context.Context, img v1.Image, reference string) (err error) {
+	logboek.Context(ctx).Default().LogProcess("DockerRegistryTracer.PushImageFrom %q", reference).Do(func() {
+		err = r.DockerRegistry.PushImageFrom(ctx, img, reference)
+	})
+	return
+}
+
+func (r *DockerRegistryTracer) IsBlobExist(ctx context.Context, repository, digest string) (res bool, err error) {
+	logboek.Context(ctx).Default().LogProcess("DockerRegistryTracer.IsBlobExist %q %q", repository, digest).Do(func() {
+		res, err = r.DockerRegistry.IsBlobExist(ctx, repository, digest)
+	})
+	return
+}
+
+func (r *DockerRegistryTracer) GetRepoImageDescriptor(ctx context.Context, reference string) (res *v1.Descriptor, err error) {
+	logboek.Context(ctx).Default().LogProcess("DockerRegistryTracer.GetRepoImageDescriptor %q", reference).Do(func() {
+		if r.DockerRegistry != nil {
+			res, err = r.DockerRegistry.GetRepoImageDescriptor(ctx, reference)
+		} else {
+			res, err = r.DockerRegistryApi.GetRepoImageDescriptor(ctx, reference)
+		}
+	})
+	return
+}
+
 func (r *DockerRegistryTracer) PushManifestList(ctx context.Context, reference string, opts ManifestListOptions) (err error) {
 	logboek.Context(ctx).Default().LogProcess("DockerRegistryTracer.PushManifestList %q", reference).Do(func() {
 		err = r.DockerRegistry.PushManifestList(ctx, reference, opts)
@@ -83,6 +83,32 @@ func (api *genericApi) GetRepoImage(ctx context.Context, reference string) (*ima
 	return api.commonApi.GetRepoImage(ctx, reference)
 }
 
+func (api *genericApi) GetRepoImageDescriptor(ctx context.Context, reference string) (*v1.Descriptor, error) {
+	mirrorReferenceList, err := api.mirrorReferenceList(ctx, reference)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare mirror reference list: %w", err)
+	}
+
+	for _, mirrorReference := range mirrorReferenceList {
+		desc, err := api.commonApi.GetRepoImageDescriptor(ctx, mirrorReference)
+		if err != nil {
+			if IsStatusNotFoundErr(err) || IsImageNotFoundError(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		return desc, nil
+	}
+
+	return api.commonApi.GetRepoImageDescriptor(ctx, reference)
+}
+
+func (api *genericApi) PullImage(ctx context.Context, reference string) (v1.Image, error) {
+	return api.commonApi.PullImage(ctx, reference)
+}
+
 func (api *genericApi) mirrorReferenceList(ctx context.Context, reference string) ([]string, error) {
 	var referenceList []string
 
@@ -30,6 +30,10 @@ type Interface interface {
 
 	PushImageArchive(ctx context.Context, archiveOpener ArchiveOpener, reference string) error
 	PullImageArchive(ctx context.Context, archiveWriter io.Writer, reference string) error
+	PullImage(ctx context.Context, reference string) (v1.Image, error)
+	PushImageFrom(ctx context.Context, img v1.Image, reference string) error
+	IsBlobExist(ctx context.Context, repository, digest string) (bool, error)
+	GetRepoImageDescriptor(ctx context.Context, reference string) (*v1.Descriptor, error)
 	PushManifestList(ctx context.Context, reference string, opts ManifestListOptions) error
 
 	String() string
@@ -41,6 +45,8 @@ type GenericApiInterface interface {
 	commonInterface
 
 	GetRepoImageConfigFile(ctx context.Context, reference string) (*v1.ConfigFile, error)
+	GetRepoImageDescriptor(ctx context.Context, reference string) (*v1.Descriptor, error)
+	PullImage(ctx context.Context, reference string) (v1.Image, error)
 }
 
 type ArchiveOpener interface {
@@ -41,7 +41,13 @@ func Init(ctx context.Context, insecureRegistry, skipTlsVerifyRegistry bool, reg
 	return nil
 }
 
+// API returns the api configured with Init or the api with the default options for the commands,
+// which do not initialize the docker registry (e.g. werf config render with the OCI includes).
 func API() GenericApiInterface {
+	if generic == nil {
+		generic, _ = newGenericApi(context.Background(), apiOptions{})
+	}
+
 	if debugDockerRegistry() {
 		return NewDockerRegistryTracer(nil, generic)
 	}
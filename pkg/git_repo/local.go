@@ -301,6 +301,22 @@ func (repo *Local) TagsList(_ context.Context) ([]string, error) {
 	return repo.tagsList(repo.WorkTreeDir)
 }
 
+func (repo *Local) HeadBranch(_ context.Context) (string, error) {
+	return repo.headBranch(repo.WorkTreeDir)
+}
+
+func (repo *Local) CommitTags(_ context.Context, commit string) ([]string, error) {
+	return repo.commitTags(repo.WorkTreeDir, commit)
+}
+
+func (repo *Local) NearestSemverTag(_ context.Context, commit string) (string, error) {
+	return repo.nearestSemverTag(repo.WorkTreeDir, commit)
+}
+
+func (repo *Local) ChangedFiles(_ context.Context, fromRef, commit string) ([]string, error) {
+	return repo.changedFiles(repo.WorkTreeDir, fromRef, commit)
+}
+
 func (repo *Local) RemoteBranchesList(_ context.Context) ([]string, error) {
 	return repo.remoteBranchesList(repo.WorkTreeDir)
 }
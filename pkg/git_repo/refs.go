@@ -0,0 +1,206 @@
+package git_repo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// headBranch returns the branch checked out in the work tree or empty string for the detached HEAD.
+func (repo *Base) headBranch(repoPath string) (string, error) {
+	repository, err := repo.PlainOpen(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot open repo %q: %w", repoPath, err)
+	}
+
+	ref, err := repository.Head()
+	if err != nil {
+		return "", fmt.Errorf("cannot get HEAD: %w", err)
+	}
+
+	if !ref.Name().IsBranch() {
+		return "", nil
+	}
+	return ref.Name().Short(), nil
+}
+
+// commitTags returns the sorted tags pointing to the commit.
+func (repo *Base) commitTags(repoPath, commit string) ([]string, error) {
+	repository, err := repo.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open repo %q: %w", repoPath, err)
+	}
+
+	tagsByCommit, err := tagsByCommit(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	res := tagsByCommit[plumbing.NewHash(commit)]
+	if res == nil {
+		res = []string{}
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// nearestSemverTag returns the semver tag of the closest commit reachable from the commit.
+// The highest version is returned if there are several such tags, empty string is returned if there are none.
+// The commits beyond the shallow clone boundary are not walked.
+func (repo *Base) nearestSemverTag(repoPath, commit string) (string, error) {
+	repository, err := repo.PlainOpen(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot open repo %q: %w", repoPath, err)
+	}
+
+	tagsByCommit, err := tagsByCommit(repository)
+	if err != nil {
+		return "", err
+	}
+
+	level := []plumbing.Hash{plumbing.NewHash(commit)}
+	visited := map[plumbing.Hash]bool{level[0]: true}
+	for len(level) > 0 {
+		var tags []string
+		for _, h := range level {
+			tags = append(tags, tagsByCommit[h]...)
+		}
+		if tag := HighestSemverTag(tags); tag != "" {
+			return tag, nil
+		}
+
+		var next []plumbing.Hash
+		for _, h := range level {
+			c, err := repository.CommitObject(h)
+			if err == plumbing.ErrObjectNotFound {
+				// The parent is beyond the shallow clone boundary
+				continue
+			} else if err != nil {
+				return "", fmt.Errorf("cannot get commit %q: %w", h, err)
+			}
+			for _, p := range c.ParentHashes {
+				if !visited[p] {
+					visited[p] = true
+					next = append(next, p)
+				}
+			}
+		}
+		level = next
+	}
+
+	return "", nil
+}
+
+// changedFiles returns the sorted paths of the files changed between the ref and the commit.
+func (repo *Base) changedFiles(repoPath, fromRef, commit string) ([]string, error) {
+	repository, err := repo.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open repo %q: %w", repoPath, err)
+	}
+
+	fromHash, err := repository.ResolveRevision(plumbing.Revision(fromRef))
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %q: %w", fromRef, err)
+	}
+
+	fromTree, err := commitTree(repository, *fromHash)
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := commitTree(repository, plumbing.NewHash(commit))
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, fmt.Errorf("cannot diff %q and %q: %w", fromRef, commit, err)
+	}
+
+	paths := make(map[string]bool)
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" {
+				paths[name] = true
+			}
+		}
+	}
+
+	res := make([]string, 0, len(paths))
+	for p := range paths {
+		res = append(res, p)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+func commitTree(repository *git.Repository, hash plumbing.Hash) (*object.Tree, error) {
+	c, err := repository.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get commit %q: %w", hash, err)
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get commit %q tree: %w", hash, err)
+	}
+	return tree, nil
+}
+
+// tagsByCommit returns the tags by the commits they point to, the annotated tags are peeled.
+func tagsByCommit(repository *git.Repository) (map[plumbing.Hash][]string, error) {
+	tags, err := repository.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[plumbing.Hash][]string)
+	if err := tags.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		hash := ref.Hash()
+
+		obj, err := repository.TagObject(hash)
+		switch err {
+		case nil:
+			c, err := obj.Commit()
+			if err != nil {
+				// The tag does not point to a commit
+				return nil
+			}
+			hash = c.Hash
+		case plumbing.ErrObjectNotFound:
+		default:
+			return err
+		}
+
+		res[hash] = append(res[hash], name)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// HighestSemverTag returns the tag with the highest version, the tags can be prefixed with "v".
+func HighestSemverTag(tags []string) string {
+	var res string
+	var resVersion *semver.Version
+	for _, tag := range tags {
+		v, err := semver.StrictNewVersion(strings.TrimPrefix(tag, "v"))
+		if err != nil {
+			continue
+		}
+		if resVersion == nil || v.GreaterThan(resVersion) {
+			res, resVersion = tag, v
+		}
+	}
+	return res
+}
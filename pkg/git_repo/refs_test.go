@@ -0,0 +1,99 @@
+package git_repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighestSemverTag(t *testing.T) {
+	assert.Equal(t, "v1.10.0", HighestSemverTag([]string{"v1.2.0", "latest", "v1.10.0", "1.9.0"}))
+	assert.Equal(t, "2.0.0-rc.1", HighestSemverTag([]string{"1.9.9", "2.0.0-rc.1"}))
+	assert.Equal(t, "", HighestSemverTag([]string{"latest", "2024", "v1"}))
+	assert.Equal(t, "", HighestSemverTag(nil))
+}
+
+func TestRefs(t *testing.T) {
+	dir := t.TempDir()
+	repository, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	first := testCommit(t, repository, dir, map[string]string{"werf.yaml": "project: test", "app/main.go": "package main"})
+	_, err = repository.CreateTag("v1.0.0", first, nil)
+	require.NoError(t, err)
+
+	second := testCommit(t, repository, dir, map[string]string{"app/main.go": "package main // v2"})
+	_, err = repository.CreateTag("v1.1.0-rc.1", second, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		Message: "rc",
+	})
+	require.NoError(t, err)
+	_, err = repository.CreateTag("staging", second, nil)
+	require.NoError(t, err)
+
+	third := testCommit(t, repository, dir, map[string]string{"docs/README.md": "docs"})
+
+	repo := &Base{}
+
+	t.Run("headBranch", func(t *testing.T) {
+		branch, err := repo.headBranch(dir)
+		require.NoError(t, err)
+		assert.Equal(t, "master", branch)
+	})
+
+	t.Run("commitTags", func(t *testing.T) {
+		tags, err := repo.commitTags(dir, second.String())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"staging", "v1.1.0-rc.1"}, tags)
+
+		tags, err = repo.commitTags(dir, third.String())
+		require.NoError(t, err)
+		assert.Empty(t, tags)
+	})
+
+	t.Run("nearestSemverTag", func(t *testing.T) {
+		tag, err := repo.nearestSemverTag(dir, third.String())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.1.0-rc.1", tag)
+
+		tag, err = repo.nearestSemverTag(dir, first.String())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.0.0", tag)
+	})
+
+	t.Run("changedFiles", func(t *testing.T) {
+		files, err := repo.changedFiles(dir, "v1.0.0", third.String())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app/main.go", "docs/README.md"}, files)
+
+		_, err = repo.changedFiles(dir, "unknown", third.String())
+		assert.Error(t, err)
+	})
+}
+
+func testCommit(t *testing.T, repository *git.Repository, dir string, files map[string]string) plumbing.Hash {
+	t.Helper()
+
+	worktree, err := repository.Worktree()
+	require.NoError(t, err)
+
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), os.ModePerm))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0o644))
+		_, err := worktree.Add(path)
+		require.NoError(t, err)
+	}
+
+	hash, err := worktree.Commit("commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	return hash
+}
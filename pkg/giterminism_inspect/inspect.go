@@ -0,0 +1,254 @@
+package giterminism_inspect
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/werf/common-go/pkg/util"
+	"github.com/werf/werf/v2/pkg/base_images"
+	"github.com/werf/werf/v2/pkg/build/stage"
+	"github.com/werf/werf/v2/pkg/config"
+	"github.com/werf/werf/v2/pkg/includes"
+)
+
+type Kind string
+
+const (
+	KindEnv             Kind = "env"
+	KindSecret          Kind = "secret"
+	KindContextAddFile  Kind = "contextAddFile"
+	KindUncommittedFile Kind = "uncommittedFile"
+	KindMount           Kind = "mount"
+	KindBaseImage       Kind = "baseImage"
+	KindFromLatest      Kind = "fromLatest"
+	KindGitBranch       Kind = "gitBranch"
+	KindInclude         Kind = "include"
+	KindGitRef          Kind = "gitRef"
+)
+
+const (
+	RuleLooseGiterminism = "--loose-giterminism"
+	RuleDev              = "--dev"
+	RuleIncludesLock     = "werf-includes.lock"
+
+	defaultGitRemoteRef = "default branch"
+)
+
+// Input is an input of the build outside of the git repository commit.
+type Input struct {
+	Kind  Kind   `json:"kind"`
+	Name  string `json:"name"`
+	Image string `json:"image,omitempty"`
+	// Rule is the werf-giterminism.yaml directive, the lock file or the option permitting the input, empty if the input is not permitted.
+	Rule      string `json:"rule,omitempty"`
+	Permitted bool   `json:"permitted"`
+}
+
+type Rules interface {
+	ConfigGoTemplateRenderingEnvRule(envName string) (string, error)
+	ConfigGoTemplateRenderingGitRefsRule() string
+	ConfigSecretEnvRule(name string) string
+	ConfigSecretSrcRule(path string) string
+	ConfigSecretValueRule(id string) string
+	ConfigStapelFromLatestRule() string
+	ConfigStapelGitBranchRule() string
+	ConfigStapelMountBuildDirRule() string
+	ConfigStapelMountFromPathRule(fromPath string) string
+	ConfigDockerfileContextAddFileRule(relPath string) string
+	UnlockedBaseImageRule(reference string) string
+	UncommittedConfigRule() string
+	UncommittedFileRule(relPath string) string
+	IncludesAllowUpdateRule() string
+}
+
+// Collector collects the inputs of the project and the werf-giterminism.yaml rules permitting them.
+type Collector struct {
+	Rules            Rules
+	LooseGiterminism bool
+	Dev              bool
+	// BaseImagesLock is the werf-base-images.lock of the project, nil if the project has no lock file.
+	BaseImagesLock *base_images.Lock
+	ReadDockerfile base_images.ReadDockerfileFunc
+
+	inputs []Input
+}
+
+func (c *Collector) Inputs() []Input {
+	return c.inputs
+}
+
+func (c *Collector) AddEnv(envName string) error {
+	rule, err := c.Rules.ConfigGoTemplateRenderingEnvRule(envName)
+	if err != nil {
+		return err
+	}
+
+	c.add(Input{Kind: KindEnv, Name: envName}, rule)
+	return nil
+}
+
+// AddGitRef adds the werf config template function, which depends on the git branches or tags.
+func (c *Collector) AddGitRef(funcName string) {
+	c.add(Input{Kind: KindGitRef, Name: funcName}, c.Rules.ConfigGoTemplateRenderingGitRefsRule())
+}
+
+func (c *Collector) AddImages(ctx context.Context, werfConfig *config.WerfConfig) error {
+	for _, img := range werfConfig.Images(false) {
+		switch typedImg := img.(type) {
+		case config.StapelImageInterface:
+			c.addStapelImage(werfConfig, typedImg.ImageBaseConfig())
+		case *config.ImageFromDockerfile:
+			if err := c.addDockerfileImage(ctx, typedImg); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Collector) addStapelImage(werfConfig *config.WerfConfig, img *config.StapelImageBase) {
+	if base_images.IsStapelFromExternal(werfConfig, img) {
+		if img.FromLatest {
+			c.add(Input{Kind: KindFromLatest, Name: img.From, Image: img.Name}, c.Rules.ConfigStapelFromLatestRule())
+		} else {
+			c.addBaseImage(img.Name, img.From)
+		}
+	}
+
+	for _, secret := range img.Secrets {
+		c.addSecret(img.Name, secret)
+	}
+
+	for _, mount := range img.Mount {
+		switch mount.Type {
+		case "custom_dir":
+			c.add(Input{Kind: KindMount, Name: mount.From, Image: img.Name}, c.Rules.ConfigStapelMountFromPathRule(mount.From))
+		case "build_dir":
+			c.add(Input{Kind: KindMount, Name: mount.Type, Image: img.Name}, c.Rules.ConfigStapelMountBuildDirRule())
+		}
+	}
+
+	if img.Git != nil {
+		for _, remote := range img.Git.Remote {
+			if remote.Commit != "" || remote.Tag != "" {
+				continue
+			}
+
+			branch := remote.Branch
+			if branch == "" {
+				branch = defaultGitRemoteRef
+			}
+			c.add(Input{Kind: KindGitBranch, Name: fmt.Sprintf("%s (%s)", remote.Url, branch), Image: img.Name}, c.Rules.ConfigStapelGitBranchRule())
+		}
+	}
+}
+
+func (c *Collector) addDockerfileImage(ctx context.Context, img *config.ImageFromDockerfile) error {
+	relDockerfilePath := filepath.Join(img.Context, img.Dockerfile)
+	data, err := c.ReadDockerfile(ctx, relDockerfilePath)
+	if err != nil {
+		return fmt.Errorf("unable to read dockerfile %s: %w", relDockerfilePath, err)
+	}
+
+	references, err := base_images.DockerfileReferences(data, util.MapStringInterfaceToMapStringString(img.Args), stage.GetDependenciesArgsKeys(img.Dependencies))
+	if err != nil {
+		return fmt.Errorf("image %q: dockerfile %s: %w", img.Name, relDockerfilePath, err)
+	}
+
+	for _, reference := range references {
+		c.addBaseImage(img.Name, reference)
+	}
+
+	for _, secret := range img.Secrets {
+		c.addSecret(img.Name, secret)
+	}
+
+	for _, contextAddFile := range img.ContextAddFiles {
+		relPath := filepath.Join(img.Context, contextAddFile)
+		c.add(Input{Kind: KindContextAddFile, Name: relPath, Image: img.Name}, c.Rules.ConfigDockerfileContextAddFileRule(relPath))
+	}
+
+	return nil
+}
+
+// addBaseImage adds the base image, which is not pinned to the digest in werf.yaml, Dockerfile or werf-base-images.lock.
+func (c *Collector) addBaseImage(imageName, reference string) {
+	if !base_images.IsLockable(reference) {
+		return
+	}
+
+	if c.BaseImagesLock != nil {
+		if _, ok := c.BaseImagesLock.Digest(reference); ok {
+			return
+		}
+	}
+
+	c.add(Input{Kind: KindBaseImage, Name: reference, Image: imageName}, c.Rules.UnlockedBaseImageRule(reference))
+}
+
+func (c *Collector) addSecret(imageName string, secret config.Secret) {
+	switch {
+	case secret.ValueFromEnv != "":
+		c.add(Input{Kind: KindSecret, Name: "env " + secret.ValueFromEnv, Image: imageName}, c.Rules.ConfigSecretEnvRule(secret.ValueFromEnv))
+	case secret.ValueFromSrc != "":
+		c.add(Input{Kind: KindSecret, Name: "src " + secret.ValueFromSrc, Image: imageName}, c.Rules.ConfigSecretSrcRule(secret.ValueFromSrc))
+	case secret.ValueFromPlain != "":
+		c.add(Input{Kind: KindSecret, Name: "value " + secret.Id, Image: imageName}, c.Rules.ConfigSecretValueRule(secret.Id))
+	}
+}
+
+// AddUncommittedFiles adds the uncommitted and untracked files of the project directory,
+// which are read instead of the committed ones. The paths are relative to the project directory.
+func (c *Collector) AddUncommittedFiles(werfConfigRelPath string, relPaths []string) {
+	for _, relPath := range relPaths {
+		var rule string
+		switch {
+		case c.Dev:
+			rule = RuleDev
+		case filepath.Clean(relPath) == filepath.Clean(werfConfigRelPath):
+			rule = c.Rules.UncommittedConfigRule()
+		default:
+			rule = c.Rules.UncommittedFileRule(relPath)
+		}
+
+		if rule == "" && !c.LooseGiterminism {
+			continue
+		}
+
+		c.add(Input{Kind: KindUncommittedFile, Name: relPath}, rule)
+	}
+}
+
+// AddIncludes adds the includes with branch refs, which are pinned by werf-includes.lock unless the update is allowed.
+func (c *Collector) AddIncludes(includesConfig includes.Config, allowUpdate bool) {
+	for _, include := range includesConfig.Includes {
+		if include.Branch == "" {
+			continue
+		}
+
+		rule := RuleIncludesLock
+		if allowUpdate {
+			rule = c.Rules.IncludesAllowUpdateRule()
+		}
+
+		c.add(Input{Kind: KindInclude, Name: fmt.Sprintf("%s (%s)", include.Git, include.Branch)}, rule)
+	}
+}
+
+func (c *Collector) add(input Input, rule string) {
+	if rule == "" && c.LooseGiterminism {
+		rule = RuleLooseGiterminism
+	}
+	input.Rule = rule
+	input.Permitted = rule != ""
+
+	for _, existing := range c.inputs {
+		if existing == input {
+			return
+		}
+	}
+
+	c.inputs = append(c.inputs, input)
+}
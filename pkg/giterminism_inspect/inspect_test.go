@@ -0,0 +1,197 @@
+package giterminism_inspect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/v2/pkg/base_images"
+	"github.com/werf/werf/v2/pkg/config"
+	giterminism_config "github.com/werf/werf/v2/pkg/giterminism_manager/config"
+	"github.com/werf/werf/v2/pkg/includes"
+)
+
+func newRules(data string) giterminism_config.Config {
+	var rules giterminism_config.Config
+	Expect(json.Unmarshal([]byte(data), &rules)).To(Succeed())
+	return rules
+}
+
+var _ = Describe("Collector", func() {
+	ctx := context.Background()
+
+	var werfConfig *config.WerfConfig
+	var dockerfiles map[string]string
+
+	readDockerfile := func(_ context.Context, relPath string) ([]byte, error) {
+		return []byte(dockerfiles[relPath]), nil
+	}
+
+	BeforeEach(func() {
+		dockerfiles = map[string]string{
+			"web/Dockerfile": "FROM node:20 AS build\nFROM build\nFROM nginx@sha256:" + strings.Repeat("a", 64) + "\n",
+		}
+
+		werfConfig = config.NewWerfConfig(nil, []config.ImageInterface{
+			&config.StapelImage{StapelImageBase: &config.StapelImageBase{
+				Name: "base",
+				From: "alpine:3.20",
+				Mount: []*config.Mount{
+					{Type: "custom_dir", From: "/var/cache/apt", To: "/var/cache/apt"},
+					{Type: "build_dir", To: "/build"},
+					{Type: "tmp_dir", To: "/tmp"},
+				},
+				Secrets: []config.Secret{{Id: "token", ValueFromEnv: "NPM_TOKEN"}},
+				Git: &config.GitManager{Remote: []*config.GitRemote{
+					{Url: "https://example.com/lib.git", GitRemoteExport: &config.GitRemoteExport{}},
+					{Url: "https://example.com/pinned.git", GitRemoteExport: &config.GitRemoteExport{Tag: "v1"}},
+				}},
+			}},
+			&config.StapelImage{StapelImageBase: &config.StapelImageBase{Name: "app", From: "base"}},
+			&config.StapelImage{StapelImageBase: &config.StapelImageBase{Name: "latest", From: "debian:12", FromLatest: true}},
+			&config.ImageFromDockerfile{
+				Name:            "web",
+				Context:         "web",
+				Dockerfile:      "Dockerfile",
+				ContextAddFiles: []string{"local.conf"},
+				Secrets:         []config.Secret{{Id: "ca", ValueFromSrc: "/etc/ssl/ca.pem"}},
+			},
+		})
+	})
+
+	It("should collect the inputs of the images with the permitting rules", func() {
+		collector := &Collector{
+			Rules: newRules(`{"config": {
+				"stapel": {"mount": {"allowFromPaths": ["/var/cache/**"]}, "git": {"allowBranch": true}},
+				"dockerfile": {"allowContextAddFiles": ["web/local.conf"]}
+			}}`),
+			ReadDockerfile: readDockerfile,
+		}
+
+		Expect(collector.AddImages(ctx, werfConfig)).To(Succeed())
+		Expect(collector.Inputs()).To(Equal([]Input{
+			{Kind: KindBaseImage, Name: "alpine:3.20", Image: "base"},
+			{Kind: KindSecret, Name: "env NPM_TOKEN", Image: "base"},
+			{Kind: KindMount, Name: "/var/cache/apt", Image: "base", Rule: "config.stapel.mount.allowFromPaths: /var/cache/**", Permitted: true},
+			{Kind: KindMount, Name: "build_dir", Image: "base"},
+			{Kind: KindGitBranch, Name: "https://example.com/lib.git (default branch)", Image: "base", Rule: "config.stapel.git.allowBranch: true", Permitted: true},
+			{Kind: KindFromLatest, Name: "debian:12", Image: "latest"},
+			{Kind: KindBaseImage, Name: "node:20", Image: "web"},
+			{Kind: KindSecret, Name: "src /etc/ssl/ca.pem", Image: "web"},
+			{Kind: KindContextAddFile, Name: "web/local.conf", Image: "web", Rule: "config.dockerfile.allowContextAddFiles: web/local.conf", Permitted: true},
+		}))
+	})
+
+	It("should check the base images by werf-base-images.lock", func() {
+		lock := base_images.NewLock()
+		lock.Set("alpine:3.20", "sha256:"+strings.Repeat("b", 64))
+
+		collector := &Collector{
+			Rules:          newRules(`{"config": {"allowUnlockedBaseImages": ["node:*"]}}`),
+			BaseImagesLock: lock,
+			ReadDockerfile: readDockerfile,
+		}
+
+		Expect(collector.AddImages(ctx, werfConfig)).To(Succeed())
+
+		var baseImages []Input
+		for _, input := range collector.Inputs() {
+			if input.Kind == KindBaseImage {
+				baseImages = append(baseImages, input)
+			}
+		}
+		Expect(baseImages).To(Equal([]Input{
+			{Kind: KindBaseImage, Name: "node:20", Image: "web", Rule: "config.allowUnlockedBaseImages: node:*", Permitted: true},
+		}))
+	})
+
+	It("should permit all inputs with loose giterminism", func() {
+		collector := &Collector{Rules: newRules(`{}`), LooseGiterminism: true, ReadDockerfile: readDockerfile}
+
+		Expect(collector.AddImages(ctx, werfConfig)).To(Succeed())
+		for _, input := range collector.Inputs() {
+			Expect(input.Permitted).To(BeTrue(), "input %s %s", input.Kind, input.Name)
+		}
+	})
+
+	It("should collect the env variables", func() {
+		collector := &Collector{Rules: newRules(`{"config": {"goTemplateRendering": {"allowEnvVariables": ["/CI_.*/"]}}}`)}
+		inspector := collector.Inspector(nil)
+
+		Expect(inspector.InspectConfigGoTemplateRenderingEnv(ctx, "CI_COMMIT_TAG")).To(Succeed())
+		Expect(inspector.InspectConfigGoTemplateRenderingEnv(ctx, "HOME")).To(Succeed())
+		Expect(inspector.InspectConfigGoTemplateRenderingEnv(ctx, "HOME")).To(Succeed())
+		Expect(inspector.InspectConfigStapelMountBuildDir()).To(Succeed())
+
+		Expect(collector.Inputs()).To(Equal([]Input{
+			{Kind: KindEnv, Name: "CI_COMMIT_TAG", Rule: "config.goTemplateRendering.allowEnvVariables: /CI_.*/", Permitted: true},
+			{Kind: KindEnv, Name: "HOME"},
+		}))
+	})
+
+	It("should collect the git refs used in the werf config templates", func() {
+		collector := &Collector{Rules: newRules(`{}`)}
+		inspector := collector.Inspector(nil)
+
+		Expect(inspector.InspectConfigGoTemplateRenderingGitRefs("gitBranch")).To(Succeed())
+		Expect(inspector.InspectConfigGoTemplateRenderingGitRefs("gitBranch")).To(Succeed())
+
+		allowedCollector := &Collector{Rules: newRules(`{"config": {"goTemplateRendering": {"allowGitRefs": true}}}`)}
+		Expect(allowedCollector.Inspector(nil).InspectConfigGoTemplateRenderingGitRefs(".Commit.Tags")).To(Succeed())
+
+		Expect(collector.Inputs()).To(Equal([]Input{{Kind: KindGitRef, Name: "gitBranch"}}))
+		Expect(allowedCollector.Inputs()).To(Equal([]Input{
+			{Kind: KindGitRef, Name: ".Commit.Tags", Rule: "config.goTemplateRendering.allowGitRefs: true", Permitted: true},
+		}))
+	})
+
+	It("should collect the uncommitted files read due to the rules", func() {
+		collector := &Collector{Rules: newRules(`{
+			"config": {"allowUncommitted": true, "dockerfile": {"allowUncommitted": ["web/Dockerfile"]}},
+			"helm": {"allowUncommittedFiles": [".helm/values.yaml"]}
+		}`)}
+
+		collector.AddUncommittedFiles("werf.yaml", []string{"werf.yaml", "web/Dockerfile", ".helm/values.yaml", "README.md"})
+		Expect(collector.Inputs()).To(Equal([]Input{
+			{Kind: KindUncommittedFile, Name: "werf.yaml", Rule: "config.allowUncommitted: true", Permitted: true},
+			{Kind: KindUncommittedFile, Name: "web/Dockerfile", Rule: "config.dockerfile.allowUncommitted: web/Dockerfile", Permitted: true},
+			{Kind: KindUncommittedFile, Name: ".helm/values.yaml", Rule: "helm.allowUncommittedFiles: .helm/values.yaml", Permitted: true},
+		}))
+	})
+
+	It("should collect the includes with branch refs", func() {
+		var includesConfig includes.Config
+		Expect(json.Unmarshal([]byte(`{"Includes": [
+			{"Git": "https://example.com/common.git", "Branch": "main"},
+			{"Git": "https://example.com/pinned.git", "Tag": "v1"}
+		]}`), &includesConfig)).To(Succeed())
+
+		collector := &Collector{Rules: newRules(`{"includes": {"allowIncludesUpdate": true}}`)}
+		collector.AddIncludes(includesConfig, false)
+		collector.AddIncludes(includesConfig, true)
+
+		Expect(collector.Inputs()).To(Equal([]Input{
+			{Kind: KindInclude, Name: "https://example.com/common.git (main)", Rule: RuleIncludesLock, Permitted: true},
+			{Kind: KindInclude, Name: "https://example.com/common.git (main)", Rule: "includes.allowIncludesUpdate: true", Permitted: true},
+		}))
+	})
+})
+
+var _ = Describe("WriteTable", func() {
+	It("should write the inputs", func() {
+		var buf bytes.Buffer
+		Expect(WriteTable(&buf, []Input{
+			{Kind: KindEnv, Name: "HOME"},
+			{Kind: KindMount, Name: "/var/cache", Image: "app", Rule: "config.stapel.mount.allowFromPaths: /var/cache", Permitted: true},
+		})).To(Succeed())
+
+		Expect(buf.String()).To(Equal(`KIND   NAME        IMAGE  PERMITTED  RULE
+env    HOME        -      no         -
+mount  /var/cache  app    yes        config.stapel.mount.allowFromPaths: /var/cache
+`))
+	})
+})
@@ -0,0 +1,59 @@
+package giterminism_inspect
+
+import (
+	"context"
+
+	"github.com/werf/werf/v2/pkg/giterminism_manager"
+)
+
+// Inspector returns the giterminism inspector, which collects the env variables and the git refs used in the werf config templates
+// and does not fail the werf config parsing, the other inputs are collected with AddImages.
+func (c *Collector) Inspector(inspector giterminism_manager.Inspector) giterminism_manager.Inspector {
+	return collectingInspector{Inspector: inspector, collector: c}
+}
+
+type collectingInspector struct {
+	giterminism_manager.Inspector
+	collector *Collector
+}
+
+func (i collectingInspector) InspectConfigGoTemplateRenderingEnv(_ context.Context, envName string) error {
+	return i.collector.AddEnv(envName)
+}
+
+func (i collectingInspector) InspectConfigGoTemplateRenderingGitRefs(funcName string) error {
+	i.collector.AddGitRef(funcName)
+	return nil
+}
+
+func (i collectingInspector) InspectConfigStapelFromLatest() error {
+	return nil
+}
+
+func (i collectingInspector) InspectConfigStapelGitBranch() error {
+	return nil
+}
+
+func (i collectingInspector) InspectConfigStapelMountBuildDir() error {
+	return nil
+}
+
+func (i collectingInspector) InspectConfigStapelMountFromPath(_ string) error {
+	return nil
+}
+
+func (i collectingInspector) InspectConfigDockerfileContextAddFile(_ string) error {
+	return nil
+}
+
+func (i collectingInspector) InspectConfigSecretEnvAccepted(_ string) error {
+	return nil
+}
+
+func (i collectingInspector) InspectConfigSecretSrcAccepted(_ string) error {
+	return nil
+}
+
+func (i collectingInspector) InspectConfigSecretValueAccepted(_ string) error {
+	return nil
+}
@@ -0,0 +1,44 @@
+package giterminism_inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+const tableHeader = "KIND\tNAME\tIMAGE\tPERMITTED\tRULE"
+
+func WriteTable(w io.Writer, inputs []Input) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, tableHeader)
+	for _, input := range inputs {
+		permitted := "no"
+		if input.Permitted {
+			permitted = "yes"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", input.Kind, input.Name, valueOrDash(input.Image), permitted, valueOrDash(input.Rule))
+	}
+
+	return tw.Flush()
+}
+
+func WriteJSON(w io.Writer, inputs []Input) error {
+	if inputs == nil {
+		inputs = []Input{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Inputs []Input `json:"inputs"`
+	}{Inputs: inputs})
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
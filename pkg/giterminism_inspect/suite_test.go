@@ -0,0 +1,13 @@
+package giterminism_inspect
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGiterminismInspect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "giterminism_inspect suite")
+}
@@ -8,6 +8,8 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/bmatcuk/doublestar/v4"
+
 	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/werf/v2/pkg/path_matcher"
 )
@@ -45,10 +47,11 @@ type fileReader interface {
 }
 
 type Config struct {
-	Cli      cli      `json:"cli"`
-	Config   config   `json:"config"`
-	Helm     helm     `json:"helm"`
-	Includes includes `json:"includes"`
+	Cli              cli              `json:"cli"`
+	Config           config           `json:"config"`
+	Helm             helm             `json:"helm"`
+	Includes         includes         `json:"includes"`
+	CommitSignatures commitSignatures `json:"commitSignatures"`
 }
 
 func (c Config) IsCustomTagsAccepted() bool {
@@ -71,6 +74,14 @@ func (c Config) IsConfigGoTemplateRenderingEnvNameAccepted(envName string) (bool
 	return c.Config.GoTemplateRendering.IsEnvNameAccepted(envName)
 }
 
+func (c Config) IsConfigGoTemplateRenderingGitRefsAccepted() bool {
+	return c.Config.GoTemplateRendering.AllowGitRefs
+}
+
+func (c Config) IsUnlockedBaseImageAccepted(reference string) bool {
+	return c.Config.IsUnlockedBaseImageAccepted(reference)
+}
+
 func (c Config) IsConfigStapelFromLatestAccepted() bool {
 	return c.Config.Stapel.AllowFromLatest
 }
@@ -119,6 +130,14 @@ func (c Config) IsUpdateIncludesAccepted() bool {
 	return c.Includes.IsAllowIncludesUpdate()
 }
 
+func (c Config) CommitSignaturesTrustedKeys() []string {
+	return c.CommitSignatures.TrustedKeys
+}
+
+func (c Config) IsIncludesCommitSignaturesVerificationEnabled() bool {
+	return c.CommitSignatures.VerifyIncludes
+}
+
 type cli struct {
 	AllowCustomTags bool `json:"allowCustomTags"`
 }
@@ -126,6 +145,7 @@ type cli struct {
 type config struct {
 	AllowUncommitted          bool                `json:"allowUncommitted"`
 	AllowUncommittedTemplates []string            `json:"allowUncommittedTemplates"`
+	AllowUnlockedBaseImages   []string            `json:"allowUnlockedBaseImages"`
 	GoTemplateRendering       goTemplateRendering `json:"goTemplateRendering"`
 	Secrets                   secrets             `json:"secrets"`
 	Stapel                    stapel              `json:"stapel"`
@@ -136,12 +156,28 @@ func (c config) UncommittedTemplateFilePathMatcher() path_matcher.PathMatcher {
 	return pathMatcher(c.AllowUncommittedTemplates)
 }
 
+func (c config) IsUnlockedBaseImageAccepted(reference string) bool {
+	for _, pattern := range c.AllowUnlockedBaseImages {
+		if matched, err := doublestar.Match(pattern, reference); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
 type goTemplateRendering struct {
 	AllowEnvVariables     []string `json:"allowEnvVariables"`
 	AllowUncommittedFiles []string `json:"allowUncommittedFiles"`
+	AllowGitRefs          bool     `json:"allowGitRefs"`
 }
 
 func (r goTemplateRendering) IsEnvNameAccepted(name string) (bool, error) {
+	pattern, err := r.AcceptedEnvNamePattern(name)
+	return pattern != "", err
+}
+
+// AcceptedEnvNamePattern returns the first pattern of allowEnvVariables matching the name or an empty string.
+func (r goTemplateRendering) AcceptedEnvNamePattern(name string) (string, error) {
 	for _, pattern := range r.AllowEnvVariables {
 		match, err := func() (bool, error) {
 			if strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
@@ -157,15 +193,15 @@ func (r goTemplateRendering) IsEnvNameAccepted(name string) (bool, error) {
 			}
 		}()
 		if err != nil {
-			return false, err
+			return "", err
 		}
 
 		if match {
-			return true, nil
+			return pattern, nil
 		}
 	}
 
-	return false, nil
+	return "", nil
 }
 
 func (r goTemplateRendering) UncommittedFilePathMatcher() path_matcher.PathMatcher {
@@ -270,3 +306,8 @@ type includes struct {
 func (i *includes) IsAllowIncludesUpdate() bool {
 	return i.AllowIncludesUpdate
 }
+
+type commitSignatures struct {
+	TrustedKeys    []string `json:"trustedKeys"`
+	VerifyIncludes bool     `json:"verifyIncludes"`
+}
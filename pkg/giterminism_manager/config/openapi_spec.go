@@ -30,6 +30,8 @@ properties:
     $ref: '#/definitions/Config'
   helm:
     $ref: '#/definitions/Helm'
+  commitSignatures:
+    $ref: '#/definitions/CommitSignatures'
 definitions:
   CLI:
     type: object
@@ -47,6 +49,10 @@ definitions:
         type: array
         items:
           type: string
+      allowUnlockedBaseImages:
+        type: array
+        items:
+          type: string
       goTemplateRendering:
         $ref: '#/definitions/ConfigGoTemplateRendering'
       stapel:
@@ -65,6 +71,8 @@ definitions:
         type: array
         items:
           type: string
+      allowGitRefs:
+        type: boolean
   ConfigStapel:
     type: object
     additionalProperties: {}
@@ -107,6 +115,16 @@ definitions:
         type: array
         items:
           type: string
+  CommitSignatures:
+    type: object
+    additionalProperties: {}
+    properties:
+      trustedKeys:
+        type: array
+        items:
+          type: string
+      verifyIncludes:
+        type: boolean
   Helm:
     type: object
     additionalProperties: {}
@@ -142,6 +160,19 @@ func openAPISchema() *spec.Schema {
 	return schema
 }
 
+// JSONSchema returns the JSON Schema of werf-giterminism.yaml.
+func JSONSchema() map[string]interface{} {
+	schema := map[string]interface{}{}
+	if err := yaml.UnmarshalStrict([]byte(schemaYaml), &schema); err != nil {
+		panic(fmt.Sprint("unexpected error: ", err))
+	}
+
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "werf-giterminism.yaml"
+
+	return schema
+}
+
 func processWithOpenAPISchema(dataObj *[]byte) error {
 	validator := validate.NewSchemaValidator(openAPISchema(), nil, "", strfmt.Default)
 
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/werf/common-go/pkg/util"
+)
+
+// The *Rule methods return the directive of the giterminism config permitting the input,
+// e.g. "config.stapel.mount.allowFromPaths: /var/cache/**", or an empty string if the input is not permitted.
+
+func (c Config) ConfigGoTemplateRenderingEnvRule(envName string) (string, error) {
+	pattern, err := c.Config.GoTemplateRendering.AcceptedEnvNamePattern(envName)
+	if err != nil || pattern == "" {
+		return "", err
+	}
+	return rule("config.goTemplateRendering.allowEnvVariables", pattern), nil
+}
+
+func (c Config) ConfigGoTemplateRenderingGitRefsRule() string {
+	return boolRule("config.goTemplateRendering.allowGitRefs", c.Config.GoTemplateRendering.AllowGitRefs)
+}
+
+func (c Config) ConfigSecretEnvRule(name string) string {
+	if slices.Contains(c.Config.Secrets.AllowEnvVariables, name) {
+		return rule("config.secrets.allowEnvVariables", name)
+	}
+	return ""
+}
+
+func (c Config) ConfigSecretSrcRule(path string) string {
+	absPath, err := util.ExpandPath(path)
+	if err != nil {
+		return ""
+	}
+
+	for _, pattern := range c.Config.Secrets.AllowFiles {
+		if isAbsPathMatched([]string{pattern}, absPath) {
+			return rule("config.secrets.allowFiles", pattern)
+		}
+	}
+	return ""
+}
+
+func (c Config) ConfigSecretValueRule(id string) string {
+	if slices.Contains(c.Config.Secrets.AllowValueIds, id) {
+		return rule("config.secrets.allowValueIds", id)
+	}
+	return ""
+}
+
+func (c Config) ConfigStapelFromLatestRule() string {
+	return boolRule("config.stapel.allowFromLatest", c.Config.Stapel.AllowFromLatest)
+}
+
+func (c Config) ConfigStapelGitBranchRule() string {
+	return boolRule("config.stapel.git.allowBranch", c.Config.Stapel.Git.AllowBranch)
+}
+
+func (c Config) ConfigStapelMountBuildDirRule() string {
+	return boolRule("config.stapel.mount.allowBuildDir", c.Config.Stapel.Mount.AllowBuildDir)
+}
+
+func (c Config) ConfigStapelMountFromPathRule(fromPath string) string {
+	return pathRule("config.stapel.mount.allowFromPaths", c.Config.Stapel.Mount.AllowFromPaths, fromPath)
+}
+
+func (c Config) ConfigDockerfileContextAddFileRule(relPath string) string {
+	return pathRule("config.dockerfile.allowContextAddFiles", c.Config.Dockerfile.AllowContextAddFiles, relPath)
+}
+
+func (c Config) UnlockedBaseImageRule(reference string) string {
+	for _, pattern := range c.Config.AllowUnlockedBaseImages {
+		if matched, err := doublestar.Match(pattern, reference); err == nil && matched {
+			return rule("config.allowUnlockedBaseImages", pattern)
+		}
+	}
+	return ""
+}
+
+func (c Config) UncommittedConfigRule() string {
+	return boolRule("config.allowUncommitted", c.Config.AllowUncommitted)
+}
+
+// UncommittedFileRule returns the first allowUncommitted* directive matching the path relative to the project directory.
+func (c Config) UncommittedFileRule(relPath string) string {
+	for _, directive := range []struct {
+		name     string
+		patterns []string
+	}{
+		{"config.allowUncommittedTemplates", c.Config.AllowUncommittedTemplates},
+		{"config.goTemplateRendering.allowUncommittedFiles", c.Config.GoTemplateRendering.AllowUncommittedFiles},
+		{"config.dockerfile.allowUncommitted", c.Config.Dockerfile.AllowUncommitted},
+		{"config.dockerfile.allowUncommittedDockerignoreFiles", c.Config.Dockerfile.AllowUncommittedDockerignoreFiles},
+		{"helm.allowUncommittedFiles", c.Helm.AllowUncommittedFiles},
+	} {
+		if r := pathRule(directive.name, directive.patterns, relPath); r != "" {
+			return r
+		}
+	}
+	return ""
+}
+
+func (c Config) IncludesAllowUpdateRule() string {
+	return boolRule("includes.allowIncludesUpdate", c.Includes.AllowIncludesUpdate)
+}
+
+func pathRule(directive string, patterns []string, p string) string {
+	for _, pattern := range patterns {
+		if isPathMatched([]string{pattern}, p) {
+			return rule(directive, pattern)
+		}
+	}
+	return ""
+}
+
+func boolRule(directive string, value bool) string {
+	if value {
+		return rule(directive, "true")
+	}
+	return ""
+}
+
+func rule(directive, value string) string {
+	return fmt.Sprintf("%s: %s", directive, value)
+}
@@ -57,8 +57,9 @@ type NewFileManagerOptions struct {
 	ProjectDir             string
 	FileReader             FileReader
 	Inspector              inspector.Inspector
-	CreateIncludesLockFile bool
+	SkipIncludesInit       bool
 	AllowIncludesUpdate    bool
+	IncludesCommitVerifier includes.CommitVerifier
 }
 
 func NewFileManager(ctx context.Context, opts NewFileManagerOptions) (*FileManager, error) {
@@ -67,18 +68,23 @@ func NewFileManager(ctx context.Context, opts NewFileManagerOptions) (*FileManag
 			return nil, err
 		}
 	}
-	includes, err := includes.Init(ctx, includes.InitIncludesOptions{
-		FileReader:             opts.FileReader,
-		CreateOrUpdateLockFile: opts.CreateIncludesLockFile,
-		UseLatestVersion:       opts.AllowIncludesUpdate,
-		ProjectDir:             opts.ProjectDir,
-	})
-	if err != nil {
-		return nil, err
+	var includesList []*includes.Include
+	if !opts.SkipIncludesInit {
+		var err error
+		includesList, err = includes.Init(ctx, includes.InitIncludesOptions{
+			FileReader:       opts.FileReader,
+			UseLatestVersion: opts.AllowIncludesUpdate,
+			ProjectDir:       opts.ProjectDir,
+			CommitVerifier:   opts.IncludesCommitVerifier,
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
+
 	return &FileManager{
 		fileReader: opts.FileReader,
-		includes:   includes,
+		includes:   includesList,
 		caches: &caches{
 			dockerFiles: make(map[string][]byte),
 		},
@@ -101,6 +107,31 @@ func (f *FileManager) ReadConfig(ctx context.Context, relPath string) (string, [
 	return f.fileReader.ReadConfig(ctx, relPath)
 }
 
+// ReadConfigOverlay reads the optional werf config overlay from the project directory or from the includes.
+// Empty path is returned if the overlay does not exist.
+func (f *FileManager) ReadConfigOverlay(ctx context.Context, relPath string) (string, []byte, error) {
+	exists, err := f.fileReader.IsConfigExistAnywhere(ctx, relPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if exists {
+		return f.fileReader.ReadConfig(ctx, relPath)
+	}
+
+	if len(f.includes) > 0 {
+		overlayPath, overlayData, err := includes.FindWerfConfig(ctx, f.includes, []string{relPath})
+		if errors.Is(err, includes.ErrConfigFileNotFound) {
+			return "", nil, nil
+		} else if err != nil {
+			return "", nil, fmt.Errorf("unable to read config overlay %q from includes: %w", relPath, err)
+		}
+		return overlayPath, overlayData, nil
+	}
+
+	return "", nil, nil
+}
+
 func (f *FileManager) ReadConfigTemplateFiles(ctx context.Context, customRelDirPath string, tmplFunc func(templateName, content string) error) error {
 	werfTemplatesCache := make(map[string]struct{})
 	err := f.fileReader.ReadConfigTemplateFiles(ctx, customRelDirPath, func(templatePathInsideDir string, data []byte, err error) error {
@@ -448,6 +479,11 @@ func (f *FileManager) ChartIsDir(relPath string) (bool, error) {
 	return false, fmt.Errorf("check chart is dir error: path %q not found on local filesystem or includes", relPath)
 }
 
+// IsProjectFileExist returns true if the regular file exists in the project, such file overrides the file of the includes.
+func (f *FileManager) IsProjectFileExist(ctx context.Context, relPath string) (bool, error) {
+	return f.fileReader.IsRegularFileExist(ctx, relPath)
+}
+
 const (
 	fromFsSource = "local"
 )
@@ -0,0 +1,58 @@
+package file_reader
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/path_matcher"
+)
+
+var DefaultPoliciesDirRelPath = ".werf/policies"
+
+func (r FileReader) ReadPolicyFiles(ctx context.Context, customDirRelPath string, handleFileFunc func(pathInsideDir string, data []byte, err error) error) (err error) {
+	logboek.Context(ctx).Debug().
+		LogBlock("ReadPolicyFiles %q", customDirRelPath).
+		Options(applyDebugToLogboek).
+		Do(func() {
+			err = r.readPolicyFiles(ctx, customDirRelPath, handleFileFunc)
+
+			if debug() {
+				logboek.Context(ctx).Debug().LogF("err: %q\n", err)
+			}
+		})
+
+	if err != nil {
+		return fmt.Errorf("unable to read werf policies: %w", err)
+	}
+
+	return nil
+}
+
+func (r FileReader) readPolicyFiles(ctx context.Context, customDirRelPath string, handleFileFunc func(pathInsideDir string, data []byte, err error) error) error {
+	dirRelPath := PoliciesDirRelPath(customDirRelPath)
+
+	for _, glob := range []string{"**/*.yaml", "**/*.yml"} {
+		if err := r.WalkConfigurationFilesWithGlob(
+			ctx,
+			dirRelPath,
+			glob,
+			path_matcher.NewFalsePathMatcher(),
+			func(relativeToDirNotResolvedPath string, data []byte, err error) error {
+				return handleFileFunc(filepath.ToSlash(relativeToDirNotResolvedPath), data, err)
+			},
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func PoliciesDirRelPath(customDirRelPath string) string {
+	if customDirRelPath != "" {
+		return customDirRelPath
+	}
+	return DefaultPoliciesDirRelPath
+}
@@ -0,0 +1,15 @@
+package inspector
+
+import "fmt"
+
+func (i Inspector) InspectUnlockedBaseImage(reference string) error {
+	if i.sharedOptions.LooseGiterminism() || i.giterminismConfig.IsUnlockedBaseImageAccepted(reference) {
+		return nil
+	}
+
+	return NewExternalDependencyFoundError(fmt.Sprintf(`base image %q not locked by giterminism
+
+The base image tag might be moved to another image in the registry at any moment. Thus, the same commit might be built into different images, and the stage digests do not reflect the change of the base image.
+
+Lock the digests of the base images with the "werf base-images update" command and commit werf-base-images.lock, or allow the unlocked base image with config.allowUnlockedBaseImages in werf-giterminism.yaml.`, reference))
+}
@@ -20,3 +20,13 @@ func (i Inspector) InspectConfigGoTemplateRenderingEnv(ctx context.Context, envN
 
 The use of the function env complicates the sharing and reproducibility of the configuration in CI jobs and among developers, because the value of the environment variable affects the final digest of built images.`, envName))
 }
+
+func (i Inspector) InspectConfigGoTemplateRenderingGitRefs(funcName string) error {
+	if i.sharedOptions.LooseGiterminism() || i.giterminismConfig.IsConfigGoTemplateRenderingGitRefsAccepted() {
+		return nil
+	}
+
+	return NewExternalDependencyFoundError(fmt.Sprintf(`%s not allowed by giterminism
+
+The branches and tags are not fixed by the commit and can be moved at any moment, so the same commit might be rendered into different configurations. Allow the git refs with config.goTemplateRendering.allowGitRefs in werf-giterminism.yaml.`, funcName))
+}
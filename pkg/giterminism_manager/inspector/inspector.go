@@ -21,6 +21,8 @@ func NewInspector(giterminismConfig giterminismConfig, fileReader fileReader, sh
 type giterminismConfig interface {
 	IsCustomTagsAccepted() bool
 	IsConfigGoTemplateRenderingEnvNameAccepted(envName string) (bool, error)
+	IsConfigGoTemplateRenderingGitRefsAccepted() bool
+	IsUnlockedBaseImageAccepted(reference string) bool
 	IsConfigStapelFromLatestAccepted() bool
 	IsConfigStapelGitBranchAccepted() bool
 	IsConfigStapelMountBuildDirAccepted() bool
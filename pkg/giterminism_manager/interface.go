@@ -35,12 +35,18 @@ type FileReader interface {
 	ReadIncludesConfig(ctx context.Context, relPath string) ([]byte, error)
 	ReadIncludesLockFile(ctx context.Context, relPath string) (data []byte, err error)
 
+	IsBaseImagesLockFileExistAnywhere(ctx context.Context, relPath string) (bool, error)
+	ReadBaseImagesLockFile(ctx context.Context, relPath string) ([]byte, error)
+
+	ReadPolicyFiles(ctx context.Context, customDirRelPath string, handleFileFunc func(pathInsideDir string, data []byte, err error) error) error
+
 	file.ChartFileReaderInterface
 }
 
 type Inspector interface {
 	InspectCustomTags() error
 	InspectConfigGoTemplateRenderingEnv(ctx context.Context, envName string) error
+	InspectConfigGoTemplateRenderingGitRefs(funcName string) error
 	InspectConfigStapelFromLatest() error
 	InspectConfigStapelGitBranch() error
 	InspectConfigStapelMountBuildDir() error
@@ -51,4 +57,5 @@ type Inspector interface {
 	InspectConfigSecretSrcAccepted(secret string) error
 	InspectConfigSecretValueAccepted(secret string) error
 	InspectIncludesAllowUpdate() error
+	InspectUnlockedBaseImage(reference string) error
 }
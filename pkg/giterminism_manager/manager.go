@@ -2,9 +2,13 @@ package giterminism_manager
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing"
 
 	"github.com/werf/common-go/pkg/util"
 	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/commit_signature"
 	"github.com/werf/werf/v2/pkg/git_repo"
 	"github.com/werf/werf/v2/pkg/giterminism_manager/config"
 	"github.com/werf/werf/v2/pkg/giterminism_manager/errors"
@@ -14,13 +18,30 @@ import (
 )
 
 type NewManagerOptions struct {
-	LooseGiterminism       bool
-	Dev                    bool
-	CreateIncludesLockFile bool
-	AllowIncludesUpdate    bool
+	LooseGiterminism    bool
+	Dev                 bool
+	SkipIncludesInit    bool
+	AllowIncludesUpdate bool
+
+	// VerifyProjectHeadCommitSignature enables the verification of the project HEAD commit signature with ProjectHeadCommitTrustedKeys.
+	// The keys are not read from werf-giterminism.yaml, because the file is a part of the verified commit.
+	VerifyProjectHeadCommitSignature bool
+	ProjectHeadCommitTrustedKeys     []string
 }
 
 func NewManager(ctx context.Context, configRelPath, projectDir string, localGitRepo *git_repo.Local, headCommit string, options NewManagerOptions) (*Manager, error) {
+	// The HEAD commit is verified before werf-giterminism.yaml and other files of the commit are read.
+	if options.VerifyProjectHeadCommitSignature {
+		verifier, err := commit_signature.NewVerifier(options.ProjectHeadCommitTrustedKeys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted keys of the project HEAD commit signature: %w", err)
+		}
+
+		if err := verifyProjectHeadCommitSignature(ctx, localGitRepo, headCommit, verifier); err != nil {
+			return nil, err
+		}
+	}
+
 	sharedOptions := &sharedOptions{
 		projectDir:       projectDir,
 		localGitRepo:     localGitRepo,
@@ -48,17 +69,26 @@ func NewManager(ctx context.Context, configRelPath, projectDir string, localGitR
 
 	m := &Manager{
 		sharedOptions: sharedOptions,
+		config:        c,
 		fileReader:    fr,
 		inspector:     i,
 	}
 
-	m.FileManager, err = filemanager.NewFileManager(ctx, filemanager.NewFileManagerOptions{
-		ProjectDir:             m.ProjectDir(),
-		FileReader:             fr,
-		Inspector:              i,
-		CreateIncludesLockFile: options.CreateIncludesLockFile,
-		AllowIncludesUpdate:    options.AllowIncludesUpdate,
-	})
+	fileManagerOptions := filemanager.NewFileManagerOptions{
+		ProjectDir:          m.ProjectDir(),
+		FileReader:          fr,
+		Inspector:           i,
+		SkipIncludesInit:    options.SkipIncludesInit,
+		AllowIncludesUpdate: options.AllowIncludesUpdate,
+	}
+	if c.IsIncludesCommitSignaturesVerificationEnabled() {
+		fileManagerOptions.IncludesCommitVerifier, err = commit_signature.NewVerifier(c.CommitSignaturesTrustedKeys())
+		if err != nil {
+			return nil, fmt.Errorf("invalid commitSignatures.trustedKeys in the giterminism config: %w", err)
+		}
+	}
+
+	m.FileManager, err = filemanager.NewFileManager(ctx, fileManagerOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +99,7 @@ func NewManager(ctx context.Context, configRelPath, projectDir string, localGitR
 }
 
 type Manager struct {
+	config     config.Config
 	fileReader FileReader
 	inspector  Inspector
 
@@ -85,6 +116,37 @@ func (m Manager) Inspector() Inspector {
 	return m.inspector
 }
 
+// SetInspector replaces the inspector used by the werf config parser, e.g. to collect the inspections instead of failing.
+func (m *Manager) SetInspector(inspector Inspector) {
+	m.inspector = inspector
+}
+
+func (m Manager) Config() config.Config {
+	return m.config
+}
+
+// verifyProjectHeadCommitSignature checks that the project HEAD commit is signed with the trusted key.
+func verifyProjectHeadCommitSignature(ctx context.Context, localGitRepo *git_repo.Local, headCommit string, verifier *commit_signature.Verifier) error {
+	repo, err := localGitRepo.PlainOpen()
+	if err != nil {
+		return fmt.Errorf("unable to open project repository: %w", err)
+	}
+
+	commit, err := repo.CommitObject(plumbing.NewHash(headCommit))
+	if err != nil {
+		return fmt.Errorf("unable to get project HEAD commit %s: %w", headCommit, err)
+	}
+
+	fingerprint, err := verifier.Verify(commit)
+	if err != nil {
+		return fmt.Errorf("project HEAD %w", err)
+	}
+
+	logboek.Context(ctx).Info().LogF("Project HEAD commit %s is signed with trusted key %s\n", headCommit, fingerprint)
+
+	return nil
+}
+
 type sharedOptions struct {
 	projectDir       string
 	headCommit       string
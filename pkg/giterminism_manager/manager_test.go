@@ -0,0 +1,99 @@
+package giterminism_manager
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/commit_signature"
+	"github.com/werf/werf/v2/pkg/git_repo"
+)
+
+var _ = Describe("Project HEAD commit signature", func() {
+	var ctx context.Context
+	var trustedEntity, untrustedEntity *openpgp.Entity
+	var workTreeDir string
+
+	BeforeEach(func() {
+		ctx = logboek.NewContext(context.Background(), logboek.DefaultLogger())
+
+		var err error
+		trustedEntity, err = openpgp.NewEntity("Platform Team", "", "platform@example.com", nil)
+		Expect(err).To(Succeed())
+		untrustedEntity, err = openpgp.NewEntity("Attacker", "", "attacker@example.com", nil)
+		Expect(err).To(Succeed())
+
+		workTreeDir = GinkgoT().TempDir()
+	})
+
+	// commit commits werf-giterminism.yaml trusting the key of the signer.
+	commit := func(signer *openpgp.Entity) string {
+		repo, err := git.PlainInit(workTreeDir, false)
+		Expect(err).To(Succeed())
+
+		giterminismConfig := "giterminismConfigVersion: 1\ncommitSignatures:\n  trustedKeys:\n    - |\n"
+		for _, line := range bytes.Split(bytes.TrimSpace([]byte(armoredPublicKey(signer))), []byte("\n")) {
+			giterminismConfig += "      " + string(line) + "\n"
+		}
+		giterminismConfig += "  verifyIncludes: true\n"
+		Expect(os.WriteFile(filepath.Join(workTreeDir, "werf-giterminism.yaml"), []byte(giterminismConfig), 0o644)).To(Succeed())
+
+		worktree, err := repo.Worktree()
+		Expect(err).To(Succeed())
+		Expect(worktree.Add("werf-giterminism.yaml")).Error().To(Succeed())
+
+		hash, err := worktree.Commit("Trust my key", &git.CommitOptions{
+			Author:  &object.Signature{Name: "Developer", Email: "dev@example.com", When: time.Now()},
+			SignKey: signer,
+		})
+		Expect(err).To(Succeed())
+
+		return hash.String()
+	}
+
+	It("should reject the HEAD commit adding its own key to werf-giterminism.yaml", func() {
+		headCommit := commit(untrustedEntity)
+
+		_, err := NewManager(ctx, "", workTreeDir, &git_repo.Local{WorkTreeDir: workTreeDir}, headCommit, NewManagerOptions{
+			VerifyProjectHeadCommitSignature: true,
+			ProjectHeadCommitTrustedKeys:     []string{armoredPublicKey(trustedEntity)},
+		})
+		Expect(err).To(MatchError(ContainSubstring("signature is not made by trusted GPG key")))
+	})
+
+	It("should require the trusted keys to verify the HEAD commit", func() {
+		headCommit := commit(untrustedEntity)
+
+		_, err := NewManager(ctx, "", workTreeDir, &git_repo.Local{WorkTreeDir: workTreeDir}, headCommit, NewManagerOptions{
+			VerifyProjectHeadCommitSignature: true,
+		})
+		Expect(err).To(MatchError(ContainSubstring("no trusted keys specified")))
+	})
+
+	It("should accept the HEAD commit signed with the trusted key", func() {
+		headCommit := commit(trustedEntity)
+
+		verifier, err := commit_signature.NewVerifier([]string{armoredPublicKey(trustedEntity)})
+		Expect(err).To(Succeed())
+		Expect(verifyProjectHeadCommitSignature(ctx, &git_repo.Local{WorkTreeDir: workTreeDir}, headCommit, verifier)).To(Succeed())
+	})
+})
+
+func armoredPublicKey(entity *openpgp.Entity) string {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	Expect(err).To(Succeed())
+	Expect(entity.Serialize(w)).To(Succeed())
+	Expect(w.Close()).To(Succeed())
+	return buf.String()
+}
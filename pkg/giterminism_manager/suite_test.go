@@ -0,0 +1,13 @@
+package giterminism_manager
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGiterminismManager(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "giterminism_manager suite")
+}
@@ -1,6 +1,12 @@
 package image
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// OCIAnnotationsLabelPrefix is a prefix of the OCI pre-defined annotation keys, such labels are exposed in the werf service values.
+const OCIAnnotationsLabelPrefix = "org.opencontainers.image."
 
 type (
 	CustomTagFunc func(string, string) string
@@ -12,6 +18,12 @@ type InfoGetter struct {
 	Repo          string
 	Tag           string
 
+	// Digest of the image manifest or of the image index for the multi-platform image, empty if the image is not in the container registry.
+	Digest    string
+	Platforms []string
+	Labels    map[string]string
+	Size      int64
+
 	InfoGetterOptions
 }
 
@@ -48,3 +60,35 @@ func (d *InfoGetter) GetTag() string {
 	}
 	return d.Tag
 }
+
+// SetStageInfo sets digest, OCI labels and size of the image from the stage info.
+func (d *InfoGetter) SetStageInfo(info *Info) {
+	d.Digest = info.GetDigest()
+	d.Size = info.Size
+
+	labels := info.Labels
+	if info.IsIndex {
+		d.Size = 0
+		for _, platformInfo := range info.Index {
+			d.Size += platformInfo.Size
+			if labels == nil {
+				labels = platformInfo.Labels
+			}
+		}
+	}
+
+	d.Labels = make(map[string]string)
+	for key, value := range labels {
+		if strings.HasPrefix(key, OCIAnnotationsLabelPrefix) {
+			d.Labels[key] = value
+		}
+	}
+}
+
+// GetDigestRef returns REPO@DIGEST reference, empty if the digest is unknown.
+func (d *InfoGetter) GetDigestRef() string {
+	if d.Digest == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s@%s", d.Repo, d.Digest)
+}
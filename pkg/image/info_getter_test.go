@@ -57,6 +57,48 @@ var _ = Describe("InfoGetter", func() {
 	)
 })
 
+var _ = Describe("InfoGetter stage info", func() {
+	It("should set digest, OCI labels and size of the image", func() {
+		getter := NewInfoGetter("backend", "myregistry.domain.com/group/project:abcd", InfoGetterOptions{})
+		getter.SetStageInfo(&Info{
+			RepoDigest: "myregistry.domain.com/group/project@sha256:1234",
+			Labels: map[string]string{
+				"org.opencontainers.image.source": "https://github.com/werf/werf",
+				"werf-stage-content-digest":       "5678",
+			},
+			Size: 100,
+		})
+
+		Expect(getter.Digest).To(Equal("sha256:1234"))
+		Expect(getter.GetDigestRef()).To(Equal("myregistry.domain.com/group/project@sha256:1234"))
+		Expect(getter.Labels).To(Equal(map[string]string{"org.opencontainers.image.source": "https://github.com/werf/werf"}))
+		Expect(getter.Size).To(Equal(int64(100)))
+	})
+
+	It("should sum platform images size of the image index", func() {
+		getter := NewInfoGetter("backend", "myregistry.domain.com/group/project:abcd", InfoGetterOptions{})
+		getter.SetStageInfo(&Info{
+			RepoDigest: "myregistry.domain.com/group/project@sha256:1234",
+			IsIndex:    true,
+			Index: []*Info{
+				{Size: 100, Labels: map[string]string{"org.opencontainers.image.version": "1.0.0"}},
+				{Size: 200},
+			},
+		})
+
+		Expect(getter.Size).To(Equal(int64(300)))
+		Expect(getter.Labels).To(Equal(map[string]string{"org.opencontainers.image.version": "1.0.0"}))
+	})
+
+	It("should not set digest reference of the local image", func() {
+		getter := NewInfoGetter("backend", "project:abcd", InfoGetterOptions{})
+		getter.SetStageInfo(&Info{})
+
+		Expect(getter.Digest).To(BeEmpty())
+		Expect(getter.GetDigestRef()).To(BeEmpty())
+	})
+})
+
 type TestInfoGetter struct {
 	ImageName string
 	Ref       string
@@ -0,0 +1,61 @@
+package includes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/werf/common-go/pkg/util"
+)
+
+// archiveAbsPath returns the path of the archive, the relative path is relative to the project directory.
+func archiveAbsPath(projectDir, archive string) (string, error) {
+	p, err := util.ReplaceTildeWithHome(archive)
+	if err != nil {
+		return "", fmt.Errorf("unable to expand path %q: %w", archive, err)
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(projectDir, p)
+	}
+	return p, nil
+}
+
+func readArchive(projectDir, archive string) ([]byte, string, error) {
+	p, err := archiveAbsPath(projectDir, archive)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read archive: %w", err)
+	}
+
+	return data, fmt.Sprintf("sha256:%x", sha256.Sum256(data)), nil
+}
+
+func getArchiveDigest(projectDir, archive string) (string, error) {
+	_, digest, err := readArchive(projectDir, archive)
+	return digest, err
+}
+
+// newArchiveSource returns the files of the tar or the gzipped tar archive, the archive must match the locked digest.
+func newArchiveSource(projectDir, archive, digest string) (*filesSource, error) {
+	data, archiveDigest, err := readArchive(projectDir, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	if archiveDigest != digest {
+		return nil, fmt.Errorf("archive digest %s does not match %s in %s.\n\nUpdate lock file using `werf includes update` command", archiveDigest, digest, GetWerfIncludesLockConfigRelPath())
+	}
+
+	src := newFilesSource(archive, digest)
+	if err := src.addTar(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("unable to read archive: %w", err)
+	}
+
+	return src, nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
@@ -21,6 +20,8 @@ type Config struct {
 
 type includeConf struct {
 	Git          string                         `yaml:"git"`
+	OCI          string                         `yaml:"oci"`
+	Archive      string                         `yaml:"archive"`
 	BasicAuth    *git_repo.BasicAuthCredentials `yaml:"basicAuth,omitempty"`
 	Branch       string                         `yaml:"branch"`
 	Tag          string                         `yaml:"tag"`
@@ -32,11 +33,20 @@ type includeConf struct {
 }
 
 func (i *includeConf) Ref() (string, error) {
-	return ref(i.Git, i.Commit, i.Tag, i.Branch)
+	if i.Archive != "" {
+		return "", nil
+	}
+	return ref(i.sourceName(), i.Commit, i.Tag, i.Branch)
+}
+
+// sourceName returns the git repository, the OCI repository or the archive path of the include.
+func (i *includeConf) sourceName() string {
+	return sourceName(i.Git, i.OCI, i.Archive)
 }
 
 type LockInfo struct {
 	includeToCommitMapper map[string]string
+	includeToDigestMapper map[string]string
 }
 
 type lockConfig struct {
@@ -44,10 +54,13 @@ type lockConfig struct {
 }
 
 type includeLockConf struct {
-	Git    string `yaml:"git"`
-	Branch string `yaml:"branch,omitempty"`
-	Tag    string `yaml:"tag,omitempty"`
-	Commit string `yaml:"commit"`
+	Git     string `yaml:"git,omitempty"`
+	OCI     string `yaml:"oci,omitempty"`
+	Archive string `yaml:"archive,omitempty"`
+	Branch  string `yaml:"branch,omitempty"`
+	Tag     string `yaml:"tag,omitempty"`
+	Commit  string `yaml:"commit,omitempty"`
+	Digest  string `yaml:"digest,omitempty"`
 }
 
 func NewConfig(ctx context.Context, fileReader GiterminismManagerFileReader, configRelPath string, createLockConfig bool) (Config, error) {
@@ -91,11 +104,17 @@ func parseConfig(ctx context.Context, fileReader GiterminismManagerFileReader, c
 
 func validate(config Config) error {
 	for _, include := range config.Includes {
-		if include.Git == "" {
-			return fmt.Errorf("`git` field is required")
+		if !exactlyOne([]bool{include.Git != "", include.OCI != "", include.Archive != ""}) {
+			return fmt.Errorf("specify only `git` or `oci` or `archive` field for include")
 		}
 
+		name := include.sourceName()
+
 		if include.BasicAuth != nil {
+			if include.Git == "" {
+				return fmt.Errorf("include %s: `basicAuth` can be used only with `git`", name)
+			}
+
 			if include.BasicAuth.Username == "" {
 				return fmt.Errorf("username should be specified when using git basic auth")
 			}
@@ -107,33 +126,44 @@ func validate(config Config) error {
 		}
 
 		if include.Add == "" {
-			return fmt.Errorf("include %s: `add` field is required", include.Git)
+			return fmt.Errorf("include %s: `add` field is required", name)
 		}
 		if !strings.HasPrefix(include.Add, "/") {
-			return fmt.Errorf("include %s: `add` must be an absolute path relative to the repository root", include.Git)
+			return fmt.Errorf("include %s: `add` must be an absolute path relative to the repository root", name)
 		}
 		if include.To == "" {
-			return fmt.Errorf("include %s: `to` field is required", include.Git)
+			return fmt.Errorf("include %s: `to` field is required", name)
 		}
 		if !strings.HasPrefix(include.To, "/") {
-			return fmt.Errorf("include %s: `to` must be an absolute path relative to the repository root", include.Git)
+			return fmt.Errorf("include %s: `to` must be an absolute path relative to the repository root", name)
 		}
 
 		for _, path := range include.IncludePaths {
 			if strings.HasPrefix(path, "/") {
-				return fmt.Errorf("include %s: `includePaths` must be relative paths to the repository root", include.Git)
+				return fmt.Errorf("include %s: `includePaths` must be relative paths to the repository root", name)
 			}
 		}
 
 		for _, path := range include.ExcludePaths {
 			if strings.HasPrefix(path, "/") {
-				return fmt.Errorf("include %s: `excludePaths` must be relative paths to the repository root", include.Git)
+				return fmt.Errorf("include %s: `excludePaths` must be relative paths to the repository root", name)
 			}
 		}
 
-		if !exactlyOne([]bool{include.Branch != "", include.Commit != "", include.Tag != ""}) {
-			err := fmt.Errorf("include %s: specify only `branch` or `tag` or `commit`", include.Git)
-			return err
+		switch {
+		case include.Git != "":
+			if !exactlyOne([]bool{include.Branch != "", include.Commit != "", include.Tag != ""}) {
+				err := fmt.Errorf("include %s: specify only `branch` or `tag` or `commit`", name)
+				return err
+			}
+		case include.OCI != "":
+			if include.Tag == "" || include.Branch != "" || include.Commit != "" {
+				return fmt.Errorf("include %s: specify only `tag` for `oci`", name)
+			}
+		case include.Archive != "":
+			if include.Branch != "" || include.Tag != "" || include.Commit != "" {
+				return fmt.Errorf("include %s: `branch`, `tag` and `commit` cannot be used with `archive`", name)
+			}
 		}
 	}
 
@@ -141,18 +171,19 @@ func validate(config Config) error {
 }
 
 type getLockInfoOptions struct {
-	includesConfig         Config
-	createOrUpdateLockFile bool
-	useLatestVersion       bool
-	remoteRepos            *gitRepositoriesWithCache
-	lockConfig             *lockConfig
+	projectDir       string
+	includesConfig   Config
+	useLatestVersion bool
+	remoteRepos      *gitRepositoriesWithCache
+	lockConfig       *lockConfig
 }
 
-func getLockInfo(opts getLockInfoOptions) (*LockInfo, error) {
+func getLockInfo(ctx context.Context, opts getLockInfoOptions) (*LockInfo, error) {
 	var lockConf *lockConfig
 
 	if opts.useLatestVersion {
-		cfg, err := createLockConfig(createLockConfigOptions{
+		cfg, err := createLockConfig(ctx, createLockConfigOptions{
+			projectDir:     opts.projectDir,
 			includesConfig: opts.includesConfig,
 			remoteRepos:    opts.remoteRepos,
 		})
@@ -169,7 +200,7 @@ func getLockInfo(opts getLockInfoOptions) (*LockInfo, error) {
 		return nil, fmt.Errorf("unable to read include lock info: %w", err)
 	}
 
-	if len(lockInfo.includeToCommitMapper) == 0 {
+	if len(lockInfo.includeToCommitMapper) == 0 && len(lockInfo.includeToDigestMapper) == 0 {
 		return nil, fmt.Errorf("no includes found in werf-includes.lock")
 	}
 
@@ -179,14 +210,20 @@ func getLockInfo(opts getLockInfoOptions) (*LockInfo, error) {
 func readLockInfo(lockConf *lockConfig) (*LockInfo, error) {
 	lockInfo := &LockInfo{
 		includeToCommitMapper: make(map[string]string),
+		includeToDigestMapper: make(map[string]string),
 	}
 
 	for _, l := range lockConf.IncludeLock {
 		ref, err := l.Ref()
 		if err != nil {
-			return nil, fmt.Errorf("unable to get ref for include %s: %w", l.Git, err)
+			return nil, fmt.Errorf("unable to get ref for include %s: %w", l.sourceName(), err)
+		}
+
+		if l.Git != "" {
+			lockInfo.includeToCommitMapper[lockId(l.Git, ref)] = l.Commit
+		} else {
+			lockInfo.includeToDigestMapper[lockId(l.sourceName(), ref)] = l.Digest
 		}
-		lockInfo.includeToCommitMapper[lockId(l.Git, ref)] = l.Commit
 	}
 
 	return lockInfo, nil
@@ -206,52 +243,18 @@ func parseLockConfig(ctx context.Context, fileReader GiterminismManagerFileReade
 }
 
 type createLockConfigOptions struct {
-	projectDir       string
-	includesConfig   Config
-	includesLockPath string
-	remoteRepos      *gitRepositoriesWithCache
-}
-
-func CreateOrUpdateLockConfig(ctx context.Context, opts createLockConfigOptions) error {
-	err := CreateLockConfig(ctx, opts)
-	if err != nil {
-		return fmt.Errorf("error create includes lock file: %w", err)
-	}
-	logboek.Context(ctx).Info().LogF("Successfully created %q file\n", opts.includesLockPath)
-	return nil
+	projectDir     string
+	includesConfig Config
+	remoteRepos    *gitRepositoriesWithCache
 }
 
-func CreateLockConfig(_ context.Context, opts createLockConfigOptions) error {
-	locksConf, err := createLockConfig(opts)
+func createLockConfig(ctx context.Context, opts createLockConfigOptions) (lockConfig, error) {
+	lockConfs, err := lockEntries(opts.includesConfig)
 	if err != nil {
-		return fmt.Errorf("create lock config: %w", err)
-	}
-
-	includesLockPathAbs := filepath.Join(opts.projectDir, opts.includesLockPath)
-	return writeLockConfig(locksConf, includesLockPathAbs)
-}
-
-func createLockConfig(opts createLockConfigOptions) (lockConfig, error) {
-	includesMap := make(map[string]bool)
-	var lockConfs []includeLockConf
-	for _, c := range opts.includesConfig.Includes {
-		ref, err := c.Ref()
-		if err != nil {
-			return lockConfig{}, fmt.Errorf("get ref for include %s: %w", c.Git, err)
-		}
-		lockId := lockId(c.Git, ref)
-		if !includesMap[lockId] {
-			lockConfs = append(lockConfs, includeLockConf{
-				Git:    c.Git,
-				Branch: c.Branch,
-				Tag:    c.Tag,
-				Commit: c.Commit,
-			})
-			includesMap[lockId] = true
-		}
+		return lockConfig{}, err
 	}
 
-	newLockConfig, err := newLockConfig(lockConfs, opts.remoteRepos)
+	newLockConfig, err := newLockConfig(ctx, lockConfs, opts.remoteRepos, opts.projectDir)
 	if err != nil {
 		return lockConfig{}, fmt.Errorf("unable to update lock config: %w", err)
 	}
@@ -259,13 +262,13 @@ func createLockConfig(opts createLockConfigOptions) (lockConfig, error) {
 	return newLockConfig, nil
 }
 
-func newLockConfig(cfg []includeLockConf, remoteRepos *gitRepositoriesWithCache) (lockConfig, error) {
+func newLockConfig(ctx context.Context, cfg []includeLockConf, remoteRepos *gitRepositoriesWithCache, projectDir string) (lockConfig, error) {
 	newLockConfig := lockConfig{
 		IncludeLock: make([]includeLockConf, 0, len(cfg)),
 	}
 
 	for _, c := range cfg {
-		updated, err := c.updateCommit(remoteRepos)
+		updated, err := c.update(ctx, remoteRepos, projectDir)
 		if err != nil {
 			return newLockConfig, err
 		}
@@ -275,6 +278,31 @@ func newLockConfig(cfg []includeLockConf, remoteRepos *gitRepositoriesWithCache)
 	return newLockConfig, nil
 }
 
+// lockEntries returns the unlocked entry for each unique source and ref of the includes config.
+func lockEntries(cfg Config) ([]includeLockConf, error) {
+	includesMap := make(map[string]bool)
+	var lockConfs []includeLockConf
+	for _, c := range cfg.Includes {
+		ref, err := c.Ref()
+		if err != nil {
+			return nil, fmt.Errorf("get ref for include %s: %w", c.sourceName(), err)
+		}
+		lockId := lockId(c.sourceName(), ref)
+		if !includesMap[lockId] {
+			lockConfs = append(lockConfs, includeLockConf{
+				Git:     c.Git,
+				OCI:     c.OCI,
+				Archive: c.Archive,
+				Branch:  c.Branch,
+				Tag:     c.Tag,
+				Commit:  c.Commit,
+			})
+			includesMap[lockId] = true
+		}
+	}
+	return lockConfs, nil
+}
+
 func (l *LockInfo) GetCommit(git, ref string) (string, error) {
 	commit, ok := l.includeToCommitMapper[lockId(git, ref)]
 	if !ok {
@@ -283,8 +311,23 @@ func (l *LockInfo) GetCommit(git, ref string) (string, error) {
 	return commit, nil
 }
 
+func (l *LockInfo) GetDigest(source, ref string) (string, error) {
+	digest, ok := l.includeToDigestMapper[lockId(source, ref)]
+	if !ok {
+		return "", fmt.Errorf("lock config not found for %s.\n\nUpdate lock file using `werf includes update` command", source)
+	}
+	return digest, nil
+}
+
 func (i *includeLockConf) Ref() (string, error) {
-	return ref(i.Git, i.Tag, i.Branch, i.Commit)
+	if i.Archive != "" {
+		return "", nil
+	}
+	return ref(i.sourceName(), i.Tag, i.Branch, i.Commit)
+}
+
+func (i *includeLockConf) sourceName() string {
+	return sourceName(i.Git, i.OCI, i.Archive)
 }
 
 func (i *includeLockConf) getCommit(r *git.Repository) (*object.Commit, error) {
@@ -304,6 +347,18 @@ func writeLockConfig(inputConfs lockConfig, configAbsPath string) error {
 	return nil
 }
 
+// update resolves the latest commit or digest of the entry.
+func (c *includeLockConf) update(ctx context.Context, remoteRepos *gitRepositoriesWithCache, projectDir string) (*includeLockConf, error) {
+	switch {
+	case c.OCI != "":
+		return c.updateOCIDigest(ctx)
+	case c.Archive != "":
+		return c.updateArchiveDigest(projectDir)
+	default:
+		return c.updateCommit(remoteRepos)
+	}
+}
+
 func (c *includeLockConf) updateCommit(remoteRepos *gitRepositoriesWithCache) (*includeLockConf, error) {
 	r, err := remoteRepos.getRepository(c.Git)
 	if err != nil {
@@ -328,6 +383,31 @@ func (c *includeLockConf) updateCommit(remoteRepos *gitRepositoriesWithCache) (*
 	}, nil
 }
 
+func (c *includeLockConf) updateOCIDigest(ctx context.Context) (*includeLockConf, error) {
+	digest, err := getOCIDigest(ctx, c.OCI, c.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &includeLockConf{
+		OCI:    c.OCI,
+		Tag:    c.Tag,
+		Digest: digest,
+	}, nil
+}
+
+func (c *includeLockConf) updateArchiveDigest(projectDir string) (*includeLockConf, error) {
+	digest, err := getArchiveDigest(projectDir, c.Archive)
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", c.Archive, err)
+	}
+
+	return &includeLockConf{
+		Archive: c.Archive,
+		Digest:  digest,
+	}, nil
+}
+
 func exactlyOne(conditions []bool) bool {
 	count := 0
 	for _, c := range conditions {
@@ -338,6 +418,17 @@ func exactlyOne(conditions []bool) bool {
 	return count == 1
 }
 
-func lockId(git, ref string) string {
-	return fmt.Sprintf("%s@%s", git, ref)
+func lockId(source, ref string) string {
+	return fmt.Sprintf("%s@%s", source, ref)
+}
+
+func sourceName(git, oci, archive string) string {
+	switch {
+	case oci != "":
+		return oci
+	case archive != "":
+		return archive
+	default:
+		return git
+	}
 }
@@ -23,12 +23,12 @@ type GiterminismManagerFileReader interface {
 }
 
 type Include struct {
-	repo       GitRepository
-	commitHash string
+	// `source` is the git repository commit, the OCI artifact or the archive
+	source source
 	// `objects` is a map of destination path to original path
-	// where the file was found in the remote repository
+	// where the file was found in the source
 	// e.g. /path/to/file.txt (desired mount path) -> /path/to/remote/file.txt (original path in remote repo)
-	// This is used to read the file from the remote repository
+	// This is used to read the file from the source
 	objects map[string]string
 }
 
@@ -40,22 +40,27 @@ func GetWerfIncludesLockConfigRelPath() string {
 	return defaultIncludesLockConfigFileName
 }
 
+// CommitVerifier checks the commits of the git includes, e.g. their signatures.
+type CommitVerifier interface {
+	Verify(commit *object.Commit) (string, error)
+}
+
 type InitIncludesOptions struct {
-	FileReader             GiterminismManagerFileReader
-	ProjectDir             string
-	CreateOrUpdateLockFile bool
-	UseLatestVersion       bool
+	FileReader       GiterminismManagerFileReader
+	ProjectDir       string
+	UseLatestVersion bool
+	CommitVerifier   CommitVerifier
 }
 
 func Init(ctx context.Context, opts InitIncludesOptions) ([]*Include, error) {
-	config, err := NewConfig(ctx, opts.FileReader, GetWerfIncludesConfigRelPath(), opts.CreateOrUpdateLockFile)
+	config, err := NewConfig(ctx, opts.FileReader, GetWerfIncludesConfigRelPath(), false)
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize includes: %w", err)
 	}
 
 	if len(config.Includes) > 0 {
 		var lockConfig *lockConfig
-		if !opts.CreateOrUpdateLockFile && !opts.UseLatestVersion {
+		if !opts.UseLatestVersion {
 			lockConfig, err = parseLockConfig(ctx, opts.FileReader, GetWerfIncludesLockConfigRelPath())
 			if err != nil {
 				return nil, err
@@ -67,30 +72,18 @@ func Init(ctx context.Context, opts InitIncludesOptions) ([]*Include, error) {
 			return nil, fmt.Errorf("unable to initialize remote repositories: %w", err)
 		}
 
-		if opts.CreateOrUpdateLockFile {
-			if err := CreateOrUpdateLockConfig(ctx, createLockConfigOptions{
-				projectDir:       opts.ProjectDir,
-				includesConfig:   config,
-				includesLockPath: GetWerfIncludesLockConfigRelPath(),
-				remoteRepos:      remoteRepos,
-			}); err != nil {
-				return nil, fmt.Errorf("create or update werf-includes.lock: %w", err)
-			}
-			return nil, nil
-		}
-
-		lockInfo, err := getLockInfo(getLockInfoOptions{
-			includesConfig:         config,
-			createOrUpdateLockFile: opts.CreateOrUpdateLockFile,
-			useLatestVersion:       opts.UseLatestVersion,
-			remoteRepos:            remoteRepos,
-			lockConfig:             lockConfig,
+		lockInfo, err := getLockInfo(ctx, getLockInfoOptions{
+			projectDir:       opts.ProjectDir,
+			includesConfig:   config,
+			useLatestVersion: opts.UseLatestVersion,
+			remoteRepos:      remoteRepos,
+			lockConfig:       lockConfig,
 		})
 		if err != nil {
 			return nil, err
 		}
 
-		includes, err := GetIncludes(ctx, config, lockInfo, remoteRepos)
+		includes, err := GetIncludes(ctx, config, lockInfo, remoteRepos, opts.ProjectDir, opts.CommitVerifier)
 		if err != nil {
 			return nil, fmt.Errorf("unable to get includes: %w", err)
 		}
@@ -100,41 +93,27 @@ func Init(ctx context.Context, opts InitIncludesOptions) ([]*Include, error) {
 	return []*Include{}, nil
 }
 
-func GetIncludes(ctx context.Context, cfg Config, lockInfo *LockInfo, remoteRepos *gitRepositoriesWithCache) ([]*Include, error) {
+func GetIncludes(ctx context.Context, cfg Config, lockInfo *LockInfo, remoteRepos *gitRepositoriesWithCache, projectDir string, commitVerifier CommitVerifier) ([]*Include, error) {
 	includes := []*Include{}
 	err := logboek.Context(ctx).Default().LogBlock("Initializing includes").DoError(func() error {
 		for i := len(cfg.Includes) - 1; i >= 0; i-- {
 			// Reverse order to prioritize the last include in the list
 			inc := cfg.Includes[i]
-			r, err := remoteRepos.getRepository(inc.Git)
-			if err != nil {
-				return fmt.Errorf("unable to find remote repository %s: %w", inc.Git, err)
-			}
 
 			ref, err := inc.Ref()
 			if err != nil {
 				return err
 			}
 
-			err = logboek.Context(ctx).Default().LogProcess(fmt.Sprintf("Processing include %s with ref %s", inc.Git, ref)).DoError(func() error {
-				commitFromLockInfo, err := lockInfo.GetCommit(inc.Git, ref)
-				if err != nil {
-					return fmt.Errorf("unable to get commit from lock info: %w", err)
-				}
-
-				repo, err := r.repo.PlainOpen()
-				if err != nil {
-					return fmt.Errorf("failed to open repository: %w", err)
-				}
-
-				commit, err := repo.CommitObject(plumbing.NewHash(commitFromLockInfo))
-				if err != nil {
-					return fmt.Errorf("failed to get commit object: %w", err)
-				}
+			processMsg := fmt.Sprintf("Processing include %s", inc.sourceName())
+			if ref != "" {
+				processMsg += fmt.Sprintf(" with ref %s", ref)
+			}
 
-				tree, err := commit.Tree()
+			err = logboek.Context(ctx).Default().LogProcess(processMsg).DoError(func() error {
+				src, err := newSource(ctx, inc, ref, lockInfo, remoteRepos, projectDir, commitVerifier)
 				if err != nil {
-					return fmt.Errorf("failed to get tree: %w", err)
+					return err
 				}
 
 				pm := path_matcher.NewPathMatcher(path_matcher.PathMatcherOptions{
@@ -146,10 +125,10 @@ func GetIncludes(ctx context.Context, cfg Config, lockInfo *LockInfo, remoteRepo
 				logboek.Context(ctx).Debug().LogF("Using path matcher: basePath=%s, includeGlobs=%v, excludeGlobs=%v\n", inc.Add, inc.IncludePaths, inc.ExcludePaths)
 
 				matchedMap := map[string]string{}
-				err = tree.Files().ForEach(func(f *object.File) error {
-					if pm.IsPathMatched(f.Name) {
-						newPath := prepareRelPath(f.Name, inc.Add, inc.To)
-						matchedMap[newPath] = f.Name
+				err = src.WalkFiles(func(path string) error {
+					if pm.IsPathMatched(path) {
+						newPath := prepareRelPath(path, inc.Add, inc.To)
+						matchedMap[newPath] = path
 					}
 					return nil
 				})
@@ -158,18 +137,20 @@ func GetIncludes(ctx context.Context, cfg Config, lockInfo *LockInfo, remoteRepo
 				}
 
 				if len(matchedMap) == 0 {
-					return fmt.Errorf("no files matched for include %s with ref %s", inc.Git, ref)
+					if ref != "" {
+						return fmt.Errorf("no files matched for include %s with ref %s", inc.sourceName(), ref)
+					}
+					return fmt.Errorf("no files matched for include %s", inc.sourceName())
 				}
 
 				include := &Include{
-					repo:       r.repo,
-					commitHash: commit.Hash.String(),
-					objects:    matchedMap,
+					source:  src,
+					objects: matchedMap,
 				}
 
 				includes = append(includes, include)
 
-				logboek.Context(ctx).Debug().LogF("Include initialized: repo: %s commit: %s\n", include.repo, include.commitHash)
+				logboek.Context(ctx).Debug().LogF("Include initialized: %s\n", src)
 				return nil
 			})
 			if err != nil {
@@ -185,11 +166,71 @@ func GetIncludes(ctx context.Context, cfg Config, lockInfo *LockInfo, remoteRepo
 	return includes, nil
 }
 
+func newSource(ctx context.Context, inc includeConf, ref string, lockInfo *LockInfo, remoteRepos *gitRepositoriesWithCache, projectDir string, commitVerifier CommitVerifier) (source, error) {
+	switch {
+	case inc.OCI != "":
+		digest, err := lockInfo.GetDigest(inc.OCI, ref)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get digest from lock info: %w", err)
+		}
+		return newOCISource(ctx, inc.OCI, inc.Tag, digest)
+	case inc.Archive != "":
+		digest, err := lockInfo.GetDigest(inc.Archive, ref)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get digest from lock info: %w", err)
+		}
+		return newArchiveSource(projectDir, inc.Archive, digest)
+	default:
+		return newGitSource(ctx, inc, ref, lockInfo, remoteRepos, commitVerifier)
+	}
+}
+
+func newGitSource(ctx context.Context, inc includeConf, ref string, lockInfo *LockInfo, remoteRepos *gitRepositoriesWithCache, commitVerifier CommitVerifier) (*gitSource, error) {
+	r, err := remoteRepos.getRepository(inc.Git)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find remote repository %s: %w", inc.Git, err)
+	}
+
+	commitFromLockInfo, err := lockInfo.GetCommit(inc.Git, ref)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get commit from lock info: %w", err)
+	}
+
+	repo, err := r.repo.PlainOpen()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	commit, err := repo.CommitObject(plumbing.NewHash(commitFromLockInfo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit object: %w", err)
+	}
+
+	if commitVerifier != nil {
+		fingerprint, err := commitVerifier.Verify(commit)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", inc.Git, err)
+		}
+		logboek.Context(ctx).Info().LogF("Commit %s is signed with trusted key %s\n", commit.Hash, fingerprint)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	return &gitSource{
+		repo:       r.repo,
+		commitHash: commit.Hash.String(),
+		tree:       tree,
+	}, nil
+}
+
 func (i *Include) GetName() string {
-	if i.repo == nil {
+	if i.source == nil {
 		return ""
 	}
-	return i.repo.GetName()
+	return i.source.GetName()
 }
 
 func (i *Include) WalkObjects(fn func(toPath, origPath string) error) error {
@@ -207,11 +248,7 @@ func (i *Include) GetFile(ctx context.Context, relPath string) ([]byte, error) {
 		return nil, fmt.Errorf("file not found in include: %s", relPath)
 	}
 
-	data, err := i.repo.ReadCommitFile(ctx, i.commitHash, filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read commit file: %w", err)
-	}
-	return data, nil
+	return i.source.ReadFile(ctx, filePath)
 }
 
 func (i *Include) GetFilesByGlob(ctx context.Context, pattern string) (map[string][]byte, error) {
@@ -273,7 +310,7 @@ func IsDirExists(ctx context.Context, includes []*Include, relPath string) bool
 		if i == nil {
 			continue
 		}
-		exists, _ := i.repo.IsCommitDirectoryExist(ctx, i.commitHash, relPath)
+		exists, _ := i.source.IsDirExist(ctx, relPath)
 		if exists {
 			return true
 		}
@@ -286,7 +323,7 @@ func IsFileExists(ctx context.Context, includes []*Include, relPath string) bool
 		if i == nil {
 			continue
 		}
-		exists, _ := i.repo.IsCommitFileExist(ctx, i.commitHash, relPath)
+		exists, _ := i.source.IsFileExist(ctx, relPath)
 		if exists {
 			return true
 		}
@@ -307,7 +344,7 @@ func FindWerfConfig(ctx context.Context, includes []*Include, cfgPaths []string)
 				if err != nil {
 					return "", nil, fmt.Errorf("unable to read config file %q: %w", cfgPath, err)
 				}
-				logboek.Context(ctx).Debug().LogF("Found config file %q in %q\n", cfgPath, include.repo.GetName())
+				logboek.Context(ctx).Debug().LogF("Found config file %q in %q\n", cfgPath, include.GetName())
 				return cfgPath, data, nil
 			}
 		}
@@ -0,0 +1,131 @@
+package includes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/docker_registry"
+	"github.com/werf/werf/v2/pkg/werf"
+)
+
+const ociIncludesCacheVersion = "1"
+
+func ociReference(repo, tag string) string {
+	return fmt.Sprintf("%s:%s", repo, tag)
+}
+
+func getOCIDigest(ctx context.Context, repo, tag string) (string, error) {
+	desc, err := docker_registry.API().GetRepoImageDescriptor(ctx, ociReference(repo, tag))
+	if err != nil {
+		return "", fmt.Errorf("unable to get digest of %s: %w", ociReference(repo, tag), err)
+	}
+	return desc.Digest.String(), nil
+}
+
+// newOCISource returns the files of the OCI artifact pinned to the digest.
+// The tar layers are unpacked in order, the other layers are added as the files named by the title annotation.
+// The artifact is cached in the local cache by digest.
+func newOCISource(ctx context.Context, repo, tag, digest string) (*filesSource, error) {
+	src := newFilesSource(ociReference(repo, tag), digest)
+
+	cachePath := filepath.Join(werf.GetLocalCacheDir(), "includes", "oci", ociIncludesCacheVersion, strings.ReplaceAll(digest, ":", "-")+".tar")
+	if f, err := os.Open(cachePath); err == nil {
+		defer f.Close()
+		logboek.Context(ctx).Debug().LogF("Using cached OCI include %s@%s from %s\n", repo, digest, cachePath)
+		if err := src.addTar(f); err != nil {
+			return nil, fmt.Errorf("unable to read cached OCI include %s: %w", cachePath, err)
+		}
+		return src, nil
+	}
+
+	img, err := docker_registry.API().PullImage(ctx, fmt.Sprintf("%s@%s", repo, digest))
+	if err != nil {
+		return nil, fmt.Errorf("unable to pull %s@%s: %w", repo, digest, err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get manifest of %s@%s: %w", repo, digest, err)
+	}
+
+	for _, desc := range manifest.Layers {
+		if err := addOCILayer(src, img, desc); err != nil {
+			return nil, fmt.Errorf("unable to read layer %s of %s@%s: %w", desc.Digest, repo, digest, err)
+		}
+	}
+
+	if err := writeOCICache(src, cachePath); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: unable to cache OCI include %s@%s: %s\n", repo, digest, err)
+	}
+
+	return src, nil
+}
+
+func addOCILayer(src *filesSource, img v1.Image, desc v1.Descriptor) error {
+	layer, err := img.LayerByDigest(desc.Digest)
+	if err != nil {
+		return err
+	}
+
+	if isTarMediaType(string(desc.MediaType)) {
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return src.addTar(rc)
+	}
+
+	title := desc.Annotations[ocispec.AnnotationTitle]
+	if title == "" {
+		return fmt.Errorf("layer with media type %q has no %s annotation", desc.MediaType, ocispec.AnnotationTitle)
+	}
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	src.addFile(title, data)
+
+	return nil
+}
+
+func isTarMediaType(mediaType string) bool {
+	return strings.Contains(mediaType, ".tar") || strings.HasSuffix(mediaType, "/tar") || strings.Contains(mediaType, "tar+")
+}
+
+func writeOCICache(src *filesSource, cachePath string) error {
+	if err := os.MkdirAll(filepath.Dir(cachePath), os.ModePerm); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(cachePath), filepath.Base(cachePath)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := src.writeTar(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), cachePath)
+}
@@ -58,6 +58,9 @@ func initRemoteRepos(ctx context.Context, cfg Config) (*gitRepositoriesWithCache
 	repoCache := newGitRepositoriesWithCache()
 	err := logboek.Context(ctx).Default().LogBlock("Initializing remote repositories").DoError(func() error {
 		for _, i := range cfg.Includes {
+			if i.Git == "" {
+				continue
+			}
 			if err := repoCache.add(ctx, i); err != nil {
 				return err
 			}
@@ -0,0 +1,217 @@
+package includes
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// source is the content of the include pinned to the commit or the digest.
+// The paths are relative to the root of the source, e.g. dir/file.txt.
+type source interface {
+	GetName() string
+	WalkFiles(fn func(path string) error) error
+	ReadFile(ctx context.Context, path string) ([]byte, error)
+	IsFileExist(ctx context.Context, path string) (bool, error)
+	IsDirExist(ctx context.Context, path string) (bool, error)
+}
+
+type gitSource struct {
+	repo       GitRepository
+	commitHash string
+	tree       *object.Tree
+}
+
+func (s *gitSource) GetName() string {
+	return s.repo.GetName()
+}
+
+func (s *gitSource) WalkFiles(fn func(path string) error) error {
+	return s.tree.Files().ForEach(func(f *object.File) error {
+		return fn(f.Name)
+	})
+}
+
+func (s *gitSource) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	data, err := s.repo.ReadCommitFile(ctx, s.commitHash, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit file: %w", err)
+	}
+	return data, nil
+}
+
+func (s *gitSource) IsFileExist(ctx context.Context, path string) (bool, error) {
+	return s.repo.IsCommitFileExist(ctx, s.commitHash, path)
+}
+
+func (s *gitSource) IsDirExist(ctx context.Context, path string) (bool, error) {
+	return s.repo.IsCommitDirectoryExist(ctx, s.commitHash, path)
+}
+
+func (s *gitSource) String() string {
+	return fmt.Sprintf("repo: %s commit: %s", s.GetName(), s.commitHash)
+}
+
+// filesSource keeps the regular files of the OCI artifact or the archive in memory.
+type filesSource struct {
+	name   string
+	digest string
+	files  map[string][]byte
+}
+
+func newFilesSource(name, digest string) *filesSource {
+	return &filesSource{
+		name:   name,
+		digest: digest,
+		files:  make(map[string][]byte),
+	}
+}
+
+func (s *filesSource) GetName() string {
+	return s.name
+}
+
+func (s *filesSource) WalkFiles(fn func(path string) error) error {
+	paths := make([]string, 0, len(s.files))
+	for p := range s.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *filesSource) ReadFile(_ context.Context, path string) ([]byte, error) {
+	data, ok := s.files[cleanSourcePath(path)]
+	if !ok {
+		return nil, fmt.Errorf("file %q not found in %s", path, s.name)
+	}
+	return data, nil
+}
+
+func (s *filesSource) IsFileExist(_ context.Context, path string) (bool, error) {
+	_, ok := s.files[cleanSourcePath(path)]
+	return ok, nil
+}
+
+func (s *filesSource) IsDirExist(_ context.Context, path string) (bool, error) {
+	dir := cleanSourcePath(path)
+	if dir == "" {
+		return len(s.files) > 0, nil
+	}
+
+	for p := range s.files {
+		if strings.HasPrefix(p, dir+"/") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *filesSource) String() string {
+	return fmt.Sprintf("source: %s digest: %s", s.name, s.digest)
+}
+
+func (s *filesSource) addFile(filePath string, data []byte) {
+	if p := cleanSourcePath(filePath); p != "" {
+		s.files[p] = data
+	}
+}
+
+// addTar adds the regular files of the tar or the gzipped tar, the files of the previous tars are overridden,
+// the OCI whiteout files remove the files of the previous tars.
+func (s *filesSource) addTar(r io.Reader) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("unable to read gzip: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	} else {
+		r = br
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read tar: %w", err)
+		}
+
+		p := cleanSourcePath(hdr.Name)
+		dir, base := path.Split(p)
+		switch {
+		case base == whiteoutOpaque:
+			s.removeDir(strings.TrimSuffix(dir, "/"))
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			target := path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))
+			delete(s.files, target)
+			s.removeDir(target)
+			continue
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("unable to read %q from tar: %w", hdr.Name, err)
+		}
+		s.files[p] = data
+	}
+}
+
+func (s *filesSource) removeDir(dir string) {
+	for p := range s.files {
+		if dir == "" || strings.HasPrefix(p, dir+"/") {
+			delete(s.files, p)
+		}
+	}
+}
+
+func (s *filesSource) writeTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := s.WalkFiles(func(p string) error {
+		data := s.files[p]
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: p, Mode: 0o644, Size: int64(len(data))}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write tar: %w", err)
+	}
+	return tw.Close()
+}
+
+// cleanSourcePath returns the path relative to the source root, the path cannot point outside the root.
+func cleanSourcePath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
@@ -0,0 +1,208 @@
+package includes
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilesSourceAddTar(t *testing.T) {
+	src := newFilesSource("oci.example.com/templates:v1", "")
+
+	if err := src.addTar(bytes.NewReader(newTar(t, map[string]string{
+		"./.werf/cleanup.tpl":  "cleanup",
+		"/.helm/Chart.yaml":    "chart",
+		".helm/values.yaml":    "values",
+		"../escape/Dockerfile": "FROM alpine",
+	}))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := src.addTar(bytes.NewReader(newGzipTar(t, map[string]string{
+		".helm/Chart.yaml":      "chart v2",
+		".helm/.wh.values.yaml": "",
+		"escape/.wh..wh..opq":   "",
+		"backend.Dockerfile":    "FROM node",
+	}))); err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	if err := src.WalkFiles(func(path string) error {
+		paths = append(paths, path)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{".helm/Chart.yaml", ".werf/cleanup.tpl", "backend.Dockerfile"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("WalkFiles() = %v, want %v", paths, want)
+	}
+
+	data, err := src.ReadFile(context.Background(), ".helm/Chart.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "chart v2" {
+		t.Errorf("ReadFile() = %q, want %q", data, "chart v2")
+	}
+
+	if exist, _ := src.IsDirExist(context.Background(), ".helm"); !exist {
+		t.Errorf("IsDirExist(.helm) = false, want true")
+	}
+	if exist, _ := src.IsDirExist(context.Background(), ".hel"); exist {
+		t.Errorf("IsDirExist(.hel) = true, want false")
+	}
+	if exist, _ := src.IsFileExist(context.Background(), "/backend.Dockerfile"); !exist {
+		t.Errorf("IsFileExist(/backend.Dockerfile) = false, want true")
+	}
+}
+
+func TestNewArchiveSource(t *testing.T) {
+	projectDir := t.TempDir()
+	data := newGzipTar(t, map[string]string{"common/.werf/cleanup.tpl": "cleanup"})
+	if err := os.WriteFile(filepath.Join(projectDir, "templates.tar.gz"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+
+	lockedDigest, err := getArchiveDigest(projectDir, "templates.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lockedDigest != digest {
+		t.Errorf("getArchiveDigest() = %q, want %q", lockedDigest, digest)
+	}
+
+	src, err := newArchiveSource(projectDir, "templates.tar.gz", digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exist, _ := src.IsFileExist(context.Background(), "common/.werf/cleanup.tpl"); !exist {
+		t.Errorf("IsFileExist() = false, want true")
+	}
+
+	_, err = newArchiveSource(projectDir, "templates.tar.gz", "sha256:0000")
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("newArchiveSource() error = %v, want digest mismatch", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		include includeConf
+		wantErr string
+	}{
+		{
+			name:    "git",
+			include: includeConf{Git: "https://example.com/common.git", Branch: "main", Add: "/", To: "/"},
+		},
+		{
+			name:    "oci",
+			include: includeConf{OCI: "registry.example.com/platform/templates", Tag: "v1.2.0", Add: "/", To: "/"},
+		},
+		{
+			name:    "archive",
+			include: includeConf{Archive: "templates.tar.gz", Add: "/", To: "/"},
+		},
+		{
+			name:    "no source",
+			include: includeConf{Add: "/", To: "/"},
+			wantErr: "specify only `git` or `oci` or `archive`",
+		},
+		{
+			name:    "several sources",
+			include: includeConf{Git: "https://example.com/common.git", Archive: "templates.tar.gz", Branch: "main", Add: "/", To: "/"},
+			wantErr: "specify only `git` or `oci` or `archive`",
+		},
+		{
+			name:    "oci without tag",
+			include: includeConf{OCI: "registry.example.com/platform/templates", Add: "/", To: "/"},
+			wantErr: "specify only `tag` for `oci`",
+		},
+		{
+			name:    "archive with ref",
+			include: includeConf{Archive: "templates.tar.gz", Tag: "v1", Add: "/", To: "/"},
+			wantErr: "cannot be used with `archive`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(Config{Includes: []includeConf{tt.include}})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("validate() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadLockInfo(t *testing.T) {
+	lockInfo, err := readLockInfo(&lockConfig{IncludeLock: []includeLockConf{
+		{Git: "https://example.com/common.git", Branch: "main", Commit: "21640b8e619ba4dd480fedf144f7424aa217a2eb"},
+		{OCI: "registry.example.com/platform/templates", Tag: "v1.2.0", Digest: "sha256:aaaa"},
+		{Archive: "templates.tar.gz", Digest: "sha256:bbbb"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if commit, err := lockInfo.GetCommit("https://example.com/common.git", "main"); err != nil || commit != "21640b8e619ba4dd480fedf144f7424aa217a2eb" {
+		t.Errorf("GetCommit() = %q, %v", commit, err)
+	}
+	if digest, err := lockInfo.GetDigest("registry.example.com/platform/templates", "v1.2.0"); err != nil || digest != "sha256:aaaa" {
+		t.Errorf("GetDigest(oci) = %q, %v", digest, err)
+	}
+	if digest, err := lockInfo.GetDigest("templates.tar.gz", ""); err != nil || digest != "sha256:bbbb" {
+		t.Errorf("GetDigest(archive) = %q, %v", digest, err)
+	}
+	if _, err := lockInfo.GetDigest("registry.example.com/platform/templates", "v1.3.0"); err == nil {
+		t.Errorf("GetDigest(not locked) error = nil, want error")
+	}
+}
+
+func newTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newGzipTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(newTar(t, files)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,541 @@
+package includes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/path_matcher"
+)
+
+type FileChange string
+
+const (
+	FileChangeAdded   FileChange = "added"
+	FileChangeRemoved FileChange = "removed"
+	FileChangeChanged FileChange = "changed"
+)
+
+type UpdateOptions struct {
+	FileReader GiterminismManagerFileReader
+	ProjectDir string
+	// Sources are the git repositories, the OCI repositories or the archives of the includes to update, all includes are updated if empty.
+	// The includes, which are not locked yet, are always resolved.
+	Sources []string
+}
+
+// UpdatePlan is the pending update of werf-includes.lock.
+type UpdatePlan struct {
+	Includes []IncludeUpdate `json:"includes"`
+
+	projectDir  string
+	config      Config
+	remoteRepos *gitRepositoriesWithCache
+	current     lockConfig
+	updated     lockConfig
+}
+
+// IncludeUpdate is the locked and the latest version of the include.
+// The version is the commit for the git include and the digest for the OCI artifact and the archive.
+type IncludeUpdate struct {
+	Source string `json:"source"`
+	Ref    string `json:"ref,omitempty"`
+	Locked string `json:"locked,omitempty"`
+	Latest string `json:"latest"`
+	// Commits are the commits of the git include between the locked and the latest ones, newest first.
+	Commits []CommitInfo `json:"commits,omitempty"`
+	// ChangedFiles are the paths in the git include repository, which are changed between the locked and the latest commits and matched by the include.
+	ChangedFiles []string `json:"changedFiles,omitempty"`
+}
+
+type CommitInfo struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Subject string `json:"subject"`
+}
+
+// FileDiff is the difference of the file in the merged files of the includes.
+type FileDiff struct {
+	Path   string     `json:"path"`
+	Change FileChange `json:"change"`
+	Diff   string     `json:"diff,omitempty"`
+}
+
+// PlanUpdate resolves the latest versions of the selected includes, the other locked includes are kept.
+func PlanUpdate(ctx context.Context, opts UpdateOptions) (*UpdatePlan, error) {
+	cfg, err := NewConfig(ctx, opts.FileReader, GetWerfIncludesConfigRelPath(), true)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize includes: %w", err)
+	}
+
+	entries, err := lockEntries(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range opts.Sources {
+		if !slices.ContainsFunc(entries, func(e includeLockConf) bool { return e.sourceName() == s }) {
+			return nil, fmt.Errorf("include %q not found in %s", s, GetWerfIncludesConfigRelPath())
+		}
+	}
+
+	current, err := readLockConfigFile(filepath.Join(opts.ProjectDir, GetWerfIncludesLockConfigRelPath()))
+	if err != nil {
+		return nil, err
+	}
+
+	locked := make(map[string]includeLockConf)
+	for _, l := range current.IncludeLock {
+		ref, err := l.Ref()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get ref for include %s: %w", l.sourceName(), err)
+		}
+		locked[lockId(l.sourceName(), ref)] = l
+	}
+
+	remoteRepos, err := initRemoteRepos(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize remote repositories: %w", err)
+	}
+
+	plan := &UpdatePlan{
+		projectDir:  opts.ProjectDir,
+		config:      cfg,
+		remoteRepos: remoteRepos,
+		current:     current,
+	}
+
+	for _, e := range entries {
+		ref, err := e.Ref()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get ref for include %s: %w", e.sourceName(), err)
+		}
+		id := lockId(e.sourceName(), ref)
+
+		lockedEntry, isLocked := locked[id]
+		if isLocked && len(opts.Sources) > 0 && !slices.Contains(opts.Sources, e.sourceName()) {
+			plan.updated.IncludeLock = append(plan.updated.IncludeLock, lockedEntry)
+			continue
+		}
+
+		latestEntry, err := e.update(ctx, remoteRepos, opts.ProjectDir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to update lock config: %w", err)
+		}
+		plan.updated.IncludeLock = append(plan.updated.IncludeLock, *latestEntry)
+
+		u := IncludeUpdate{
+			Source: e.sourceName(),
+			Ref:    ref,
+			Latest: latestEntry.version(),
+		}
+		if isLocked {
+			u.Locked = lockedEntry.version()
+		}
+
+		if e.Git != "" && u.Locked != "" && u.IsOutdated() {
+			if err := plan.setGitChanges(&u, id); err != nil {
+				logboek.Context(ctx).Warn().LogF("WARNING: Unable to get changes of include %s: %s\n", u.Source, err)
+			}
+		}
+
+		plan.Includes = append(plan.Includes, u)
+	}
+
+	return plan, nil
+}
+
+func (u IncludeUpdate) IsOutdated() bool {
+	return u.Locked != u.Latest
+}
+
+func (p *UpdatePlan) IsOutdated() bool {
+	return slices.ContainsFunc(p.Includes, IncludeUpdate.IsOutdated)
+}
+
+// Apply writes the updated werf-includes.lock.
+func (p *UpdatePlan) Apply() error {
+	return writeLockConfig(p.updated, filepath.Join(p.projectDir, GetWerfIncludesLockConfigRelPath()))
+}
+
+// FileDiffs returns the difference of the merged files of the includes before and after the update.
+// The files of the project, which override the files of the includes, are skipped.
+func (p *UpdatePlan) FileDiffs(ctx context.Context, isProjectFile func(ctx context.Context, relPath string) (bool, error), contextLines int) ([]FileDiff, error) {
+	currentCfg, updatedCfg, err := p.diffConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	currentLockInfo, err := readLockInfo(&p.current)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read include lock info: %w", err)
+	}
+	currentIncludes, err := GetIncludes(ctx, currentCfg, currentLockInfo, p.remoteRepos, p.projectDir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get locked includes: %w", err)
+	}
+
+	updatedLockInfo, err := readLockInfo(&p.updated)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read include lock info: %w", err)
+	}
+	updatedIncludes, err := GetIncludes(ctx, updatedCfg, updatedLockInfo, p.remoteRepos, p.projectDir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get updated includes: %w", err)
+	}
+
+	from, to := mergedFiles(currentIncludes), mergedFiles(updatedIncludes)
+
+	var res []FileDiff
+	for _, relPath := range sortedKeys(from, to) {
+		fromFile, hasFrom := from[relPath]
+		toFile, hasTo := to[relPath]
+		if hasFrom && hasTo && fromFile.id() == toFile.id() {
+			continue
+		}
+
+		exist, err := isProjectFile(ctx, relPath)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			continue
+		}
+
+		var fromData, toData []byte
+		if hasFrom {
+			if fromData, err = fromFile.read(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if hasTo {
+			if toData, err = toFile.read(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		d := FileDiff{Path: relPath}
+		switch {
+		case !hasFrom:
+			d.Change = FileChangeAdded
+		case !hasTo:
+			d.Change = FileChangeRemoved
+		case string(fromData) != string(toData):
+			d.Change = FileChangeChanged
+		default:
+			continue
+		}
+
+		d.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:       difflib.SplitLines(string(fromData)),
+			B:       difflib.SplitLines(string(toData)),
+			Context: contextLines,
+		})
+
+		res = append(res, d)
+	}
+
+	return res, nil
+}
+
+// diffConfigs returns the includes config with the locked includes only and the includes config to compare it with.
+// The previous content of the replaced archive is not available, so such archive is not compared.
+func (p *UpdatePlan) diffConfigs(ctx context.Context) (Config, Config, error) {
+	current := make(map[string]bool)
+	for _, l := range p.current.IncludeLock {
+		ref, err := l.Ref()
+		if err != nil {
+			return Config{}, Config{}, fmt.Errorf("unable to get ref for include %s: %w", l.sourceName(), err)
+		}
+		current[lockId(l.sourceName(), ref)] = true
+	}
+
+	var currentCfg, updatedCfg Config
+	for _, inc := range p.config.Includes {
+		ref, err := inc.Ref()
+		if err != nil {
+			return Config{}, Config{}, err
+		}
+
+		isLocked := current[lockId(inc.sourceName(), ref)]
+		if isLocked && inc.Archive != "" && slices.ContainsFunc(p.Includes, func(u IncludeUpdate) bool {
+			return u.Source == inc.Archive && u.IsOutdated()
+		}) {
+			logboek.Context(ctx).Warn().LogF("WARNING: The locked content of archive %s is not available, its files are not compared\n", inc.Archive)
+			continue
+		}
+
+		if isLocked {
+			currentCfg.Includes = append(currentCfg.Includes, inc)
+		}
+		updatedCfg.Includes = append(updatedCfg.Includes, inc)
+	}
+
+	return currentCfg, updatedCfg, nil
+}
+
+func (p *UpdatePlan) setGitChanges(u *IncludeUpdate, id string) error {
+	r, err := p.remoteRepos.getRepository(u.Source)
+	if err != nil {
+		return err
+	}
+
+	repo, err := r.repo.PlainOpen()
+	if err != nil {
+		return fmt.Errorf("plain open: %w", err)
+	}
+
+	lockedCommit, err := repo.CommitObject(plumbing.NewHash(u.Locked))
+	if err != nil {
+		return fmt.Errorf("unable to get locked commit %s: %w", u.Locked, err)
+	}
+	latestCommit, err := repo.CommitObject(plumbing.NewHash(u.Latest))
+	if err != nil {
+		return fmt.Errorf("unable to get latest commit %s: %w", u.Latest, err)
+	}
+
+	isAncestor, err := lockedCommit.IsAncestor(latestCommit)
+	if err != nil {
+		return fmt.Errorf("unable to check commit ancestry: %w", err)
+	}
+	if isAncestor {
+		if u.Commits, err = newCommits(repo, lockedCommit, latestCommit); err != nil {
+			return err
+		}
+	}
+
+	lockedTree, err := lockedCommit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get tree: %w", err)
+	}
+	latestTree, err := latestCommit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	changes, err := object.DiffTree(lockedTree, latestTree)
+	if err != nil {
+		return fmt.Errorf("unable to diff trees: %w", err)
+	}
+
+	var matchers []path_matcher.PathMatcher
+	for _, inc := range p.config.Includes {
+		ref, err := inc.Ref()
+		if err != nil {
+			return err
+		}
+		if lockId(inc.sourceName(), ref) == id {
+			matchers = append(matchers, path_matcher.NewPathMatcher(path_matcher.PathMatcherOptions{
+				BasePath:     inc.Add,
+				IncludeGlobs: inc.IncludePaths,
+				ExcludeGlobs: inc.ExcludePaths,
+			}))
+		}
+	}
+
+	changedFiles := make(map[string]struct{})
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && slices.ContainsFunc(matchers, func(pm path_matcher.PathMatcher) bool { return pm.IsPathMatched(name) }) {
+				changedFiles[name] = struct{}{}
+			}
+		}
+	}
+	u.ChangedFiles = sortedKeys(changedFiles)
+
+	return nil
+}
+
+// newCommits returns the commits reachable from the latest commit and not reachable from the locked one, newest first.
+func newCommits(repo *git.Repository, lockedCommit, latestCommit *object.Commit) ([]CommitInfo, error) {
+	seen := make(map[plumbing.Hash]bool)
+	if err := object.NewCommitPreorderIter(lockedCommit, nil, nil).ForEach(func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to walk commits: %w", err)
+	}
+
+	isSeen := object.CommitFilter(func(c *object.Commit) bool { return seen[c.Hash] })
+	isNew := object.CommitFilter(func(c *object.Commit) bool { return !seen[c.Hash] })
+
+	var commits []*object.Commit
+	if err := object.NewFilterCommitIter(latestCommit, &isNew, &isSeen).ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to walk commits: %w", err)
+	}
+
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Committer.When.After(commits[j].Committer.When)
+	})
+
+	res := make([]CommitInfo, 0, len(commits))
+	for _, c := range commits {
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		res = append(res, CommitInfo{
+			Hash:    c.Hash.String(),
+			Author:  c.Author.Name,
+			Subject: subject,
+		})
+	}
+	return res, nil
+}
+
+func (p *UpdatePlan) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	for _, u := range p.Includes {
+		name := u.Source
+		if u.Ref != "" {
+			name += " " + u.Ref
+		}
+
+		switch {
+		case u.Locked == "":
+			fmt.Fprintf(&b, "%s: not locked -> %s\n", name, u.Latest)
+		case !u.IsOutdated():
+			fmt.Fprintf(&b, "%s: up to date (%s)\n", name, u.Latest)
+		default:
+			fmt.Fprintf(&b, "%s: %s -> %s\n", name, u.Locked, u.Latest)
+		}
+
+		if len(u.Commits) > 0 {
+			b.WriteString("  Commits:\n")
+			for _, c := range u.Commits {
+				fmt.Fprintf(&b, "    %s %s (%s)\n", shortHash(c.Hash), c.Subject, c.Author)
+			}
+		}
+
+		if len(u.ChangedFiles) > 0 {
+			b.WriteString("  Changed files:\n")
+			for _, f := range u.ChangedFiles {
+				fmt.Fprintf(&b, "    %s\n", f)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func WriteFileDiffsText(w io.Writer, diffs []FileDiff) error {
+	var b strings.Builder
+
+	if len(diffs) == 0 {
+		b.WriteString("\nNo file changes\n")
+	} else {
+		b.WriteString("\nFiles:\n")
+	}
+
+	for _, f := range diffs {
+		switch f.Change {
+		case FileChangeAdded:
+			fmt.Fprintf(&b, "  + %s\n", f.Path)
+		case FileChangeRemoved:
+			fmt.Fprintf(&b, "  - %s\n", f.Path)
+		default:
+			fmt.Fprintf(&b, "  ~ %s\n", f.Path)
+		}
+		if f.Diff != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(f.Diff, "\n"), "\n") {
+				fmt.Fprintf(&b, "      %s\n", line)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// includeFile is the file of the include in the merged files of the includes.
+type includeFile struct {
+	include  *Include
+	origPath string
+}
+
+func (f includeFile) id() string {
+	return fmt.Sprintf("%s %s", f.include.source, f.origPath)
+}
+
+func (f includeFile) read(ctx context.Context) ([]byte, error) {
+	data, err := f.include.source.ReadFile(ctx, f.origPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %q from include %s: %w", f.origPath, f.include.GetName(), err)
+	}
+	return data, nil
+}
+
+// mergedFiles returns the files of the includes, the file of the first include wins.
+func mergedFiles(includes []*Include) map[string]includeFile {
+	res := make(map[string]includeFile)
+	for _, i := range includes {
+		for toPath, origPath := range i.objects {
+			if _, ok := res[toPath]; !ok {
+				res[toPath] = includeFile{include: i, origPath: origPath}
+			}
+		}
+	}
+	return res
+}
+
+func readLockConfigFile(path string) (lockConfig, error) {
+	config := lockConfig{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return config, nil
+		}
+		return config, fmt.Errorf("unable to read %q: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("the includes lock config validation failed: %w", err)
+	}
+	return config, nil
+}
+
+func (i *includeLockConf) version() string {
+	if i.Git != "" {
+		return i.Commit
+	}
+	return i.Digest
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func sortedKeys[V any](maps ...map[string]V) []string {
+	keys := make(map[string]struct{})
+	for _, m := range maps {
+		for k := range m {
+			keys[k] = struct{}{}
+		}
+	}
+
+	res := make([]string, 0, len(keys))
+	for k := range keys {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,172 @@
+package includes
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestNewCommits(t *testing.T) {
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := newTestCommit(t, repo, "base", 1)
+	side := newTestCommit(t, repo, "side", 2, base)
+	locked := newTestCommit(t, repo, "locked", 3, base)
+	fix := newTestCommit(t, repo, "fix\n\nDetails", 4, locked)
+	merge := newTestCommit(t, repo, "merge side", 5, fix, side)
+
+	commits, err := newCommits(repo, commitObject(t, repo, locked), commitObject(t, repo, merge))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subjects []string
+	for _, c := range commits {
+		subjects = append(subjects, c.Subject)
+	}
+
+	want := []string{"merge side", "fix", "side"}
+	if !reflect.DeepEqual(subjects, want) {
+		t.Errorf("newCommits() = %v, want %v", subjects, want)
+	}
+}
+
+func TestReadLockConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg, err := readLockConfigFile(filepath.Join(dir, "werf-includes.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.IncludeLock) != 0 {
+		t.Errorf("readLockConfigFile(not exist) = %v, want empty", cfg.IncludeLock)
+	}
+
+	lockPath := filepath.Join(dir, "werf-includes.lock")
+	if err := writeLockConfig(lockConfig{IncludeLock: []includeLockConf{
+		{Git: "https://example.com/common.git", Branch: "main", Commit: "21640b8e619ba4dd480fedf144f7424aa217a2eb"},
+		{Archive: "templates.tar.gz", Digest: "sha256:bbbb"},
+	}}, lockPath); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = readLockConfigFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.IncludeLock) != 2 || cfg.IncludeLock[0].version() != "21640b8e619ba4dd480fedf144f7424aa217a2eb" || cfg.IncludeLock[1].version() != "sha256:bbbb" {
+		t.Errorf("readLockConfigFile() = %v", cfg.IncludeLock)
+	}
+
+	if err := os.WriteFile(lockPath, []byte("includes: {"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readLockConfigFile(lockPath); err == nil {
+		t.Errorf("readLockConfigFile(invalid) error = nil, want error")
+	}
+}
+
+func TestMergedFiles(t *testing.T) {
+	first := &Include{source: newFilesSource("first", ""), objects: map[string]string{"werf.yaml": "werf.yaml", ".helm/values.yaml": "values.yaml"}}
+	second := &Include{source: newFilesSource("second", ""), objects: map[string]string{"werf.yaml": "werf.yaml", "backend.Dockerfile": "Dockerfile"}}
+
+	files := mergedFiles([]*Include{first, second})
+
+	if got := files["werf.yaml"].include; got != first {
+		t.Errorf("werf.yaml include = %s, want first", got.GetName())
+	}
+	if got := files["backend.Dockerfile"]; got.include != second || got.origPath != "Dockerfile" {
+		t.Errorf("backend.Dockerfile = %s %s, want second Dockerfile", got.include.GetName(), got.origPath)
+	}
+	if len(files) != 3 {
+		t.Errorf("len(mergedFiles()) = %d, want 3", len(files))
+	}
+}
+
+func TestUpdatePlanWriteText(t *testing.T) {
+	plan := &UpdatePlan{Includes: []IncludeUpdate{
+		{
+			Source:       "https://example.com/common.git",
+			Ref:          "main",
+			Locked:       "21640b8e619ba4dd480fedf144f7424aa217a2eb",
+			Latest:       "9b1f0e2a6fd4b1c4d1f5b7a4e0c3d2b1a0f9e8d7",
+			Commits:      []CommitInfo{{Hash: "9b1f0e2a6fd4b1c4d1f5b7a4e0c3d2b1a0f9e8d7", Author: "Jane", Subject: "Bump chart"}},
+			ChangedFiles: []string{".helm/Chart.yaml"},
+		},
+		{Source: "templates.tar.gz", Locked: "sha256:bbbb", Latest: "sha256:bbbb"},
+		{Source: "registry.example.com/platform/templates", Ref: "v1.2.0", Latest: "sha256:aaaa"},
+	}}
+
+	if !plan.IsOutdated() {
+		t.Errorf("IsOutdated() = false, want true")
+	}
+
+	var buf bytes.Buffer
+	if err := plan.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `https://example.com/common.git main: 21640b8e619ba4dd480fedf144f7424aa217a2eb -> 9b1f0e2a6fd4b1c4d1f5b7a4e0c3d2b1a0f9e8d7
+  Commits:
+    9b1f0e2a6fd4 Bump chart (Jane)
+  Changed files:
+    .helm/Chart.yaml
+templates.tar.gz: up to date (sha256:bbbb)
+registry.example.com/platform/templates v1.2.0: not locked -> sha256:aaaa
+`
+	if buf.String() != want {
+		t.Errorf("WriteText() = %q, want %q", buf.String(), want)
+	}
+}
+
+func newTestCommit(t *testing.T, repo *git.Repository, message string, hour int, parents ...plumbing.Hash) plumbing.Hash {
+	t.Helper()
+
+	tree := repo.Storer.NewEncodedObject()
+	if err := (&object.Tree{}).Encode(tree); err != nil {
+		t.Fatal(err)
+	}
+	treeHash, err := repo.Storer.SetEncodedObject(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature := object.Signature{Name: "Jane", Email: "jane@example.com", When: time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC)}
+	commit := &object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+
+	obj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func commitObject(t *testing.T, repo *git.Repository, hash plumbing.Hash) *object.Commit {
+	t.Helper()
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commit
+}
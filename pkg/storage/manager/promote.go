@@ -0,0 +1,132 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/v2/pkg/image"
+	"github.com/werf/werf/v2/pkg/storage"
+)
+
+type PromoteImagesOptions struct {
+	// Commit is a git commit, final images of which are promoted.
+	Commit            string
+	CustomTagFuncList []image.CustomTagFunc
+}
+
+// PromoteImages copies final images built for the commit from the stages storage into the final stages storage without rebuild.
+// The final stages storage is the primary repo of the target environment,
+// so managed images, image metadata and custom tags are registered in it.
+func (m *StorageManager) PromoteImages(ctx context.Context, imageNameList []string, opts PromoteImagesOptions) (map[string]*image.StageDesc, error) {
+	targetStagesStorage, ok := m.FinalStagesStorage.(storage.PrimaryStagesStorage)
+	if !ok {
+		return nil, fmt.Errorf("unable to promote images into %s: repo expected", m.FinalStagesStorage.String())
+	}
+
+	stageIDs, err := m.GetImagesStageIDsByCommit(ctx, imageNameList, opts.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	var notBuiltImageNameList []string
+	for _, imageName := range imageNameList {
+		if _, ok := stageIDs[imageName]; !ok {
+			notBuiltImageNameList = append(notBuiltImageNameList, imageName)
+		}
+	}
+	if len(notBuiltImageNameList) > 0 {
+		return nil, fmt.Errorf("images %s are not built for the commit %s in the repo %s", strings.Join(notBuiltImageNameList, ", "), opts.Commit, m.StagesStorage.String())
+	}
+
+	res := make(map[string]*image.StageDesc)
+	for _, imageName := range imageNameList {
+		stageID := stageIDs[imageName]
+
+		desc, err := m.CopyStageIntoFinalStorage(ctx, *stageID, m.FinalStagesStorage, CopyStageIntoStorageOptions{
+			IsMultiplatformImage: stageID.IsMultiplatform,
+			LogDetailedName:      imageName,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to promote image %s: %w", imageName, err)
+		}
+		if desc == nil {
+			return nil, fmt.Errorf("unable to promote image %s: stage %s not found in the repo %s", imageName, stageID.String(), m.StagesStorage.String())
+		}
+
+		if err := m.publishPromotedImageMetadata(ctx, targetStagesStorage, imageName, opts.Commit, desc); err != nil {
+			return nil, err
+		}
+
+		for _, tagFunc := range opts.CustomTagFuncList {
+			tag := tagFunc(imageName, desc.Info.Tag)
+			if err := targetStagesStorage.AddStageCustomTag(ctx, desc, tag); err != nil {
+				return nil, fmt.Errorf("unable to add stage %s custom tag %s in the storage %s: %w", desc.StageID.String(), tag, targetStagesStorage.String(), err)
+			}
+			if err := targetStagesStorage.RegisterStageCustomTag(ctx, m.ProjectName, desc, tag); err != nil {
+				return nil, fmt.Errorf("unable to register stage %s custom tag %s in the storage %s: %w", desc.StageID.String(), tag, targetStagesStorage.String(), err)
+			}
+			logboek.Context(ctx).LogFDetails("  tag: %s:%s\n", desc.Info.Repository, tag)
+		}
+
+		res[imageName] = desc
+	}
+
+	return res, nil
+}
+
+// GetImagesStageIDsByCommit returns stage IDs of the final images built for the commit using image metadata of the stages storage.
+// Images not built for the commit are absent in the result.
+func (m *StorageManager) GetImagesStageIDsByCommit(ctx context.Context, imageNameList []string, commit string) (map[string]*image.StageID, error) {
+	imageMetadataByImageName, _, err := m.StagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, m.ProjectName, imageNameList, storage.WithCache())
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image metadata of the repo %s: %w", m.StagesStorage.String(), err)
+	}
+
+	res := make(map[string]*image.StageID)
+	for imageName, stageIDCommitList := range imageMetadataByImageName {
+		for stageIDStr, commitList := range stageIDCommitList {
+			if !slices.Contains(commitList, commit) {
+				continue
+			}
+
+			stageID, err := storage.ParseStageID(stageIDStr)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse image %s metadata stage ID %q: %w", imageName, stageIDStr, err)
+			}
+
+			// The image could be rebuilt for the same commit, the latest one is used.
+			if prev, ok := res[imageName]; !ok || prev.CreationTs < stageID.CreationTs {
+				res[imageName] = stageID
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func (m *StorageManager) publishPromotedImageMetadata(ctx context.Context, targetStagesStorage storage.StagesStorage, imageName, commit string, desc *image.StageDesc) error {
+	exist, err := targetStagesStorage.IsManagedImageExist(ctx, m.ProjectName, imageName, storage.WithCache())
+	if err != nil {
+		return fmt.Errorf("unable to check existence of managed image: %w", err)
+	}
+	if !exist {
+		if err := targetStagesStorage.AddManagedImage(ctx, m.ProjectName, imageName); err != nil {
+			return fmt.Errorf("unable to add image %q to the managed images of project %q: %w", imageName, m.ProjectName, err)
+		}
+	}
+
+	exist, err = targetStagesStorage.IsImageMetadataExist(ctx, m.ProjectName, imageName, commit, desc.StageID.String(), storage.WithCache())
+	if err != nil {
+		return fmt.Errorf("unable to get image %s metadata by commit %s and stage ID %s: %w", imageName, commit, desc.StageID.String(), err)
+	}
+	if !exist {
+		if err := targetStagesStorage.PutImageMetadata(ctx, m.ProjectName, imageName, commit, desc.StageID.String()); err != nil {
+			return fmt.Errorf("unable to put image %s metadata by commit %s and stage ID %s: %w", imageName, commit, desc.StageID.String(), err)
+		}
+	}
+
+	return nil
+}
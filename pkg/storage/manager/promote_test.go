@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/v2/pkg/docker_registry"
+	"github.com/werf/werf/v2/pkg/image"
+	"github.com/werf/werf/v2/pkg/logging"
+	"github.com/werf/werf/v2/pkg/storage"
+)
+
+var _ = Describe("Promote images", func() {
+	const (
+		projectName = "testproject"
+		commit      = "9d8059842b6fde712c58315ca0ab4713d90761c0"
+		fromRepo    = "registry.example.com/testproject/dev"
+		toRepo      = "registry.example.com/testproject/prod"
+	)
+
+	stageID := image.NewStageID(strings.Repeat("a", 56), 1700000000000)
+	rebuiltStageID := image.NewStageID(strings.Repeat("b", 56), 1700000001000)
+
+	var ctx context.Context
+	var registry *DockerRegistryStub
+	var fromStorage, toStorage *storage.RepoStagesStorage
+	var storageManager *StorageManager
+
+	BeforeEach(func(ctx0 context.Context) {
+		ctx = logging.WithLogger(ctx0)
+
+		registry = NewDockerRegistryStub()
+		fromStorage = storage.NewRepoStagesStorage(&storage.NewRepoStagesStorageOptions{RepoAddress: fromRepo, DockerRegistry: registry, SkipMetaCheck: true})
+		toStorage = storage.NewRepoStagesStorage(&storage.NewRepoStagesStorageOptions{RepoAddress: toRepo, DockerRegistry: registry, SkipMetaCheck: true})
+		storageManager = NewStorageManager(projectName, fromStorage, toStorage, nil, nil, nil)
+	})
+
+	putStage := func(stagesStorage *storage.RepoStagesStorage, stageID image.StageID) {
+		ref := stagesStorage.ConstructStageImageName(projectName, stageID.Digest, stageID.CreationTs)
+		Expect(registry.PushImage(ctx, ref, &docker_registry.PushImageOptions{})).To(Succeed())
+	}
+
+	It("should return the latest stage built for the commit", func() {
+		Expect(fromStorage.PutImageMetadata(ctx, projectName, "backend", commit, stageID.String())).To(Succeed())
+		Expect(fromStorage.PutImageMetadata(ctx, projectName, "backend", commit, rebuiltStageID.String())).To(Succeed())
+		Expect(fromStorage.PutImageMetadata(ctx, projectName, "frontend", "other-commit", stageID.String())).To(Succeed())
+
+		stageIDs, err := storageManager.GetImagesStageIDsByCommit(ctx, []string{"backend", "frontend"}, commit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stageIDs).To(Equal(map[string]*image.StageID{"backend": rebuiltStageID}))
+	})
+
+	It("should copy the image built for the commit into the repo", func() {
+		putStage(fromStorage, *stageID)
+		Expect(fromStorage.PutImageMetadata(ctx, projectName, "backend", commit, stageID.String())).To(Succeed())
+
+		descs, err := storageManager.PromoteImages(ctx, []string{"backend"}, PromoteImagesOptions{
+			Commit:            commit,
+			CustomTagFuncList: []image.CustomTagFunc{func(imageName, _ string) string { return imageName + "-prod" }},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(descs).To(HaveKey("backend"))
+		Expect(descs["backend"].StageID.String()).To(Equal(stageID.String()))
+
+		Expect(toStorage.GetStageDesc(ctx, projectName, *stageID)).NotTo(BeNil())
+		Expect(toStorage.IsManagedImageExist(ctx, projectName, "backend")).To(BeTrue())
+		Expect(toStorage.IsImageMetadataExist(ctx, projectName, "backend", commit, stageID.String())).To(BeTrue())
+		Expect(registry.ImagesByReference).To(HaveKey(toRepo + ":backend-prod"))
+	})
+
+	It("should fail if the image is not built for the commit", func() {
+		putStage(fromStorage, *stageID)
+		Expect(fromStorage.PutImageMetadata(ctx, projectName, "backend", "other-commit", stageID.String())).To(Succeed())
+
+		_, err := storageManager.PromoteImages(ctx, []string{"backend"}, PromoteImagesOptions{Commit: commit})
+		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("images backend are not built for the commit %s in the repo %s", commit, fromRepo))))
+	})
+
+	It("should fail if the stage of the image metadata does not exist in the repo", func() {
+		Expect(fromStorage.PutImageMetadata(ctx, projectName, "backend", commit, stageID.String())).To(Succeed())
+
+		_, err := storageManager.PromoteImages(ctx, []string{"backend"}, PromoteImagesOptions{Commit: commit})
+		Expect(err).To(MatchError(ContainSubstring("unable to promote image backend")))
+		Expect(toStorage.IsImageMetadataExist(ctx, projectName, "backend", commit, stageID.String())).To(BeFalse())
+	})
+
+	It("should not copy the image already present in the repo", func() {
+		Expect(fromStorage.PutImageMetadata(ctx, projectName, "backend", commit, stageID.String())).To(Succeed())
+		putStage(toStorage, *stageID)
+		Expect(toStorage.AddManagedImage(ctx, projectName, "backend")).To(Succeed())
+		Expect(toStorage.PutImageMetadata(ctx, projectName, "backend", commit, stageID.String())).To(Succeed())
+		pushedBefore := registry.PushedCount
+
+		// The stage is absent in the source repo, so it could not be copied.
+		descs, err := storageManager.PromoteImages(ctx, []string{"backend"}, PromoteImagesOptions{Commit: commit})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(descs["backend"].StageID.String()).To(Equal(stageID.String()))
+		Expect(registry.PushedCount).To(Equal(pushedBefore))
+	})
+})
+
+// DockerRegistryStub is an in-memory registry, which stores image infos by reference.
+type DockerRegistryStub struct {
+	docker_registry.Interface
+
+	ImagesByReference map[string]*image.Info
+	PushedCount       int
+}
+
+func NewDockerRegistryStub() *DockerRegistryStub {
+	return &DockerRegistryStub{ImagesByReference: make(map[string]*image.Info)}
+}
+
+func (registry *DockerRegistryStub) Tags(_ context.Context, reference string, _ ...docker_registry.Option) ([]string, error) {
+	var tags []string
+	for ref := range registry.ImagesByReference {
+		if repo, tag := image.ParseRepositoryAndTag(ref); repo == reference {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func (registry *DockerRegistryStub) IsTagExist(_ context.Context, reference string, _ ...docker_registry.Option) (bool, error) {
+	_, ok := registry.ImagesByReference[reference]
+	return ok, nil
+}
+
+func (registry *DockerRegistryStub) GetRepoImage(_ context.Context, reference string) (*image.Info, error) {
+	info, ok := registry.ImagesByReference[reference]
+	if !ok {
+		return nil, fmt.Errorf("%s: image %s not found", transport.ManifestUnknownErrorCode, reference)
+	}
+	return info, nil
+}
+
+func (registry *DockerRegistryStub) TryGetRepoImage(_ context.Context, reference string) (*image.Info, error) {
+	return registry.ImagesByReference[reference], nil
+}
+
+func (registry *DockerRegistryStub) PushImage(_ context.Context, reference string, opts *docker_registry.PushImageOptions) error {
+	registry.PushedCount++
+	registry.ImagesByReference[reference] = newImageInfoStub(reference, opts.Labels)
+	return nil
+}
+
+func (registry *DockerRegistryStub) CopyImage(_ context.Context, sourceReference, destinationReference string, _ docker_registry.CopyImageOptions) error {
+	info, ok := registry.ImagesByReference[sourceReference]
+	if !ok {
+		return fmt.Errorf("%s: image %s not found", transport.ManifestUnknownErrorCode, sourceReference)
+	}
+
+	registry.PushedCount++
+	registry.ImagesByReference[destinationReference] = newImageInfoStub(destinationReference, info.Labels)
+	return nil
+}
+
+func (registry *DockerRegistryStub) TagRepoImage(_ context.Context, repoImage *image.Info, tag string) error {
+	reference := fmt.Sprintf("%s:%s", repoImage.Repository, tag)
+	registry.PushedCount++
+	registry.ImagesByReference[reference] = newImageInfoStub(reference, repoImage.Labels)
+	return nil
+}
+
+func newImageInfoStub(reference string, labels map[string]string) *image.Info {
+	repo, tag := image.ParseRepositoryAndTag(reference)
+	return &image.Info{Name: reference, Repository: repo, Tag: tag, Labels: labels}
+}
@@ -208,12 +208,17 @@ func (m *StorageManager) GetServiceValuesRepo() string {
 }
 
 func (m *StorageManager) GetImageInfoGetter(imageName string, stageDesc *image.StageDesc, opts image.InfoGetterOptions) *image.InfoGetter {
+	var getter *image.InfoGetter
 	if m.FinalStagesStorage != nil {
 		finalImageName := m.FinalStagesStorage.ConstructStageImageName(m.ProjectName, stageDesc.StageID.Digest, stageDesc.StageID.CreationTs)
-		return image.NewInfoGetter(imageName, finalImageName, opts)
+		getter = image.NewInfoGetter(imageName, finalImageName, opts)
+	} else {
+		getter = image.NewInfoGetter(imageName, stageDesc.Info.Name, opts)
 	}
 
-	return image.NewInfoGetter(imageName, stageDesc.Info.Name, opts)
+	getter.SetStageInfo(stageDesc.Info)
+
+	return getter
 }
 
 func (m *StorageManager) InitCache(ctx context.Context) error {
@@ -228,6 +233,13 @@ func (m *StorageManager) InitCache(ctx context.Context) error {
 	return nil
 }
 
+// ResetCache drops the cached stages list of the final repo to get the actual stages on the next InitCache.
+func (m *StorageManager) ResetCache() {
+	m.FinalStagesListCacheMux.Lock()
+	defer m.FinalStagesListCacheMux.Unlock()
+	m.FinalStagesListCache = nil
+}
+
 func (m *StorageManager) EnableParallel(parallelTasksLimit int) {
 	m.parallel = true
 	m.parallelTasksLimit = parallelTasksLimit
@@ -657,7 +669,7 @@ func (m *StorageManager) CopyStageIntoFinalStorage(ctx context.Context, stageID
 	}
 
 	if opts.ShouldBeBuiltMode {
-		return nil, fmt.Errorf("%s with digest %s is not exist in the final repo", opts.LogDetailedName, stageID.Digest)
+		return nil, fmt.Errorf("%s with digest %s is not exist in the final repo: %w", opts.LogDetailedName, stageID.Digest, ErrStageNotFound)
 	}
 
 	var stageDescCopy *image.StageDesc
@@ -73,6 +73,15 @@ func getDigestAndCreationTsFromRepoStageImageTag(repoStageImageTag string) (stri
 	}
 }
 
+// ParseStageID parses stage ID of the image metadata record: DIGEST-CREATION_TS or DIGEST of the multi-platform image.
+func ParseStageID(stageID string) (*image.StageID, error) {
+	digest, creationTs, err := getDigestAndCreationTsFromRepoStageImageTag(stageID)
+	if err != nil {
+		return nil, err
+	}
+	return image.NewStageID(digest, creationTs), nil
+}
+
 func isUnexpectedTagFormatError(err error) bool {
 	return strings.HasPrefix(err.Error(), UnexpectedTagFormatErrorPrefix)
 }
@@ -335,7 +344,7 @@ func (storage *RepoStagesStorage) CheckStageCustomTag(ctx context.Context, stage
 	}
 
 	if customTagImgInfo == nil {
-		return fmt.Errorf("custom tag %q not found", tag)
+		return fmt.Errorf("%w: %q", ErrCustomTagNotFound, tag)
 	}
 
 	if customTagImgInfo.ID != stageDesc.Info.ID {